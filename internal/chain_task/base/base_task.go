@@ -1,8 +1,13 @@
 package base
 
 import (
+	"sync"
+	"time"
+
 	"github.com/difyz9/ytb2bili/internal/chain_task/manager"
+	"github.com/difyz9/ytb2bili/internal/core/types"
 	"github.com/difyz9/ytb2bili/pkg/cos"
+	"go.uber.org/zap"
 )
 
 // BaseTask 基础任务实现
@@ -10,8 +15,17 @@ type BaseTask struct {
 	Name         string
 	StateManager *manager.StateManager
 	Client       *cos.CosClient
+
+	logger *zap.SugaredLogger
+
+	progress     *manager.ProgressBus
+	progressMu   sync.Mutex
+	lastProgress time.Time
 }
 
+// progressInterval 同一步骤发布进度事件的最小间隔
+const progressInterval = 500 * time.Millisecond
+
 // TaskOption 定义选项函数类型
 type TaskOption func(*BaseTask)
 
@@ -27,6 +41,76 @@ func (t *BaseTask) GetName() string {
 	return t.Name
 }
 
+// SetLogger 设置任务使用的日志器，创建任务步骤时设置为同时写入步骤日志的日志器
+func (t *BaseTask) SetLogger(logger *zap.SugaredLogger) {
+	t.logger = logger
+}
+
+// Logger 获取任务使用的日志器，未设置时使用全局日志器
+func (t *BaseTask) Logger() *zap.SugaredLogger {
+	if t.logger != nil {
+		return t.logger
+	}
+	return zap.S()
+}
+
+// SetProgressBus 设置任务发布执行进度的事件总线
+func (t *BaseTask) SetProgressBus(progress *manager.ProgressBus) {
+	t.progress = progress
+}
+
+// ReportProgress 发布百分比形式的执行进度
+func (t *BaseTask) ReportProgress(percent float64, message string) {
+	t.publishProgress(manager.ProgressEvent{
+		Unit:    manager.ProgressUnitPercent,
+		Percent: percent,
+		Message: message,
+	}, percent >= 100)
+}
+
+// ReportProgressCount 发布按数量计算的执行进度，例如翻译分组 N/M、上传分块 N/M
+func (t *BaseTask) ReportProgressCount(current, total int, unit, message string) {
+	event := manager.ProgressEvent{
+		Current: int64(current),
+		Total:   int64(total),
+		Unit:    unit,
+		Message: message,
+	}
+	if total > 0 {
+		event.Percent = float64(current) * 100 / float64(total)
+	}
+	t.publishProgress(event, current >= total)
+}
+
+// publishProgress 发布进度事件，同一步骤最多每 progressInterval 发布一次，完成时总是发布
+func (t *BaseTask) publishProgress(event manager.ProgressEvent, done bool) {
+	if t.progress == nil {
+		return
+	}
+
+	t.progressMu.Lock()
+	now := time.Now()
+	if !done && now.Sub(t.lastProgress) < progressInterval {
+		t.progressMu.Unlock()
+		return
+	}
+	t.lastProgress = now
+	t.progressMu.Unlock()
+
+	event.Type = manager.ProgressEventProgress
+	event.Step = t.Name
+	event.Time = now
+	if t.StateManager != nil {
+		event.VideoID = t.StateManager.VideoID
+	}
+	t.progress.Publish(event)
+}
+
+// GetResourceClass 获取任务资源类别，默认不限制并发
+func (t *BaseTask) GetResourceClass() types.ResourceClass {
+	return types.ResourceClassDefault
+}
+
 // InsertTask 插入任务记录
 func (t *BaseTask) InsertTask() error {
 
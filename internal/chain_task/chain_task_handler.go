@@ -1,11 +1,13 @@
 package chain_task
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"path/filepath"
+	"runtime/debug"
 	"time"
 
-	"github.com/difyz9/ytb2bili/internal/chain_task/handlers"
 	"github.com/difyz9/ytb2bili/internal/chain_task/manager"
 	"github.com/difyz9/ytb2bili/internal/core"
 	models2 "github.com/difyz9/ytb2bili/internal/core/models"
@@ -14,6 +16,7 @@ import (
 	"github.com/difyz9/ytb2bili/pkg/store/model"
 
 	"sync"
+	"sync/atomic"
 
 	"github.com/robfig/cron/v3"
 	"go.uber.org/zap"
@@ -21,6 +24,9 @@ import (
 	"gorm.io/gorm"
 )
 
+// defaultSweepInterval 未配置 sweep_interval 时兜底扫描待处理任务的间隔
+const defaultSweepInterval = time.Minute
+
 // ChainTaskHandler 任务链执行器的实现
 type ChainTaskHandler struct {
 	App *core.AppServer
@@ -28,120 +34,318 @@ type ChainTaskHandler struct {
 	SavedVideoService *services.SavedVideoService
 	TaskStepService   *services.TaskStepService
 
-	isRunning bool
 	Task      *cron.Cron
 	Db        *gorm.DB
+	Scheduler *manager.ResourceScheduler
+	Cancels   *manager.CancelRegistry
+	Progress  *manager.ProgressBus
+	Notifier  *manager.JobNotifier
+	Pauses    *services.PauseService
+	Lease     *services.Lease
+	pool      *WorkerPool
+	queue     *services.FairQueue // 待处理视频在播放列表、频道之间轮转
 	mutex     sync.Mutex
+	stopping  atomic.Bool // 实例正在关闭，不再分发任务
 }
 
-func NewChainTaskHandler(app *core.AppServer, task *cron.Cron, db *gorm.DB, savedVideoService *services.SavedVideoService, taskStepService *services.TaskStepService) *ChainTaskHandler {
+func NewChainTaskHandler(app *core.AppServer, task *cron.Cron, db *gorm.DB, savedVideoService *services.SavedVideoService, taskStepService *services.TaskStepService, scheduler *manager.ResourceScheduler, cancels *manager.CancelRegistry, progress *manager.ProgressBus, notifier *manager.JobNotifier, pauses *services.PauseService, lease *services.Lease) *ChainTaskHandler {
+	workers := 1
+	if app.Config.PipelineConfig != nil && app.Config.PipelineConfig.Workers > 0 {
+		workers = app.Config.PipelineConfig.Workers
+	}
+
 	return &ChainTaskHandler{
 		App:               app,
 		Task:              task,
 		Db:                db,
 		SavedVideoService: savedVideoService,
 		TaskStepService:   taskStepService,
+		Scheduler:         scheduler,
+		Cancels:           cancels,
+		Progress:          progress,
+		Notifier:          notifier,
+		Pauses:            pauses,
+		Lease:             lease,
+		pool:              NewWorkerPool(workers),
+		queue:             services.NewFairQueue(),
 		mutex:             sync.Mutex{},
-		isRunning:         false,
 	}
 }
 
 // SetUp 启动任务消费者
 func (h *ChainTaskHandler) SetUp() {
-	// 应用启动时重置所有"运行中"的任务步骤
+	// 应用启动时回收上次运行遗留的任务
 	h.resetRunningTasksOnStartup()
 
-	// 添加定时任务
-	h.Task.AddFunc("*/5 * * * * *", func() {
+	// 提交、重试和 worker 空闲时立即分发任务，并定期兜底扫描其他实例提交的任务
+	go h.listen()
+	sweepInterval := h.sweepInterval()
+	h.Task.AddFunc(fmt.Sprintf("@every %s", sweepInterval), h.Notifier.Notify)
+	h.Notifier.Notify()
 
-		h.mutex.Lock()
-		defer h.mutex.Unlock()
+	// 定期为正在执行的任务续约，并回收其他实例失联后遗留的任务
+	h.Task.AddFunc(fmt.Sprintf("@every %s", h.Lease.HeartbeatInterval()), h.heartbeat)
 
-		if h.isRunning {
-			h.App.Logger.Debug("当前有任务正在执行，跳过本次请求")
-			return
+	// 启动 cron 调度器
+	h.Task.Start()
+	h.App.Logger.Infof("✓ Task dispatcher started, sweeping for tasks every %s (workers: %d, instance: %s)", sweepInterval, h.pool.Size(), h.Lease.Owner)
+}
+
+// sweepInterval 兜底扫描待处理任务的间隔
+func (h *ChainTaskHandler) sweepInterval() time.Duration {
+	if h.App.Config.PipelineConfig != nil && h.App.Config.PipelineConfig.SweepInterval > 0 {
+		return time.Duration(h.App.Config.PipelineConfig.SweepInterval) * time.Second
+	}
+	return defaultSweepInterval
+}
+
+// listen 收到唤醒信号后分发任务，并在下一个步骤到达重试时间时再次唤醒
+// 准备阶段暂停期间不分发任务，恢复后由恢复接口或兜底扫描唤醒
+func (h *ChainTaskHandler) listen() {
+	for range h.Notifier.C() {
+		if h.stopping.Load() || h.preparePaused() {
+			continue
 		}
+		h.dispatch()
 
-		// 1. 优先处理重试的任务步骤
-		retrySteps, err := h.getRetrySteps()
+		nextRetryAt, err := h.TaskStepService.GetNextRetryAt()
 		if err != nil {
-			h.App.Logger.Errorf("查询重试步骤失败: %v", err)
-		} else if len(retrySteps) > 0 {
-			h.App.Logger.Infof("发现 %d 个待重试的步骤", len(retrySteps))
-			h.isRunning = true
-
-			// 执行重试步骤
-			for _, step := range retrySteps {
-				h.App.Logger.Infof("🔄 开始重试步骤: %s - %s", step.VideoID, step.StepName)
-				if err := h.RunSingleTaskStep(step.VideoID, step.StepName); err != nil {
-					h.App.Logger.Errorf("重试步骤失败: %v", err)
-				}
-			}
+			h.App.Logger.Errorf("查询下次重试时间失败: %v", err)
+		} else if nextRetryAt != nil {
+			h.Notifier.NotifyAt(*nextRetryAt)
+		}
+	}
+}
 
-			h.isRunning = false
-			return
+// preparePaused 准备阶段是否已暂停，查询失败时视为未暂停
+func (h *ChainTaskHandler) preparePaused() bool {
+	pause, err := h.Pauses.FindPause(model.PauseScopePrepare)
+	if err != nil {
+		h.App.Logger.Errorf("查询暂停状态失败: %v", err)
+		return false
+	}
+	if pause != nil {
+		h.App.Logger.Debugf("⏸️ 准备阶段已暂停（%s），跳过本次调度", pause.Reason)
+		return true
+	}
+	return false
+}
+
+// heartbeat 为当前实例持有的视频和步骤续约，并回收租约已过期的任务
+func (h *ChainTaskHandler) heartbeat() {
+	if _, err := h.SavedVideoService.RenewVideoLeases(h.Lease); err != nil {
+		h.App.Logger.Errorf("视频租约续约失败: %v", err)
+	}
+	if _, err := h.TaskStepService.RenewStepLeases(); err != nil {
+		h.App.Logger.Errorf("任务步骤租约续约失败: %v", err)
+	}
+
+	steps, videos, err := h.TaskStepService.ReclaimExpiredLeases()
+	if err != nil {
+		h.App.Logger.Errorf("回收过期租约失败: %v", err)
+	} else if steps > 0 || videos > 0 {
+		h.App.Logger.Warnf("♻️ 已回收 %d 个视频和 %d 个步骤（执行它们的实例已失联）", videos, steps)
+		h.Notifier.Notify()
+	}
+}
+
+// dispatch 将待处理的任务分发到工作池
+// 状态流转: 001 (待处理) → 002 (处理中) → 200 (准备完成) 或 999 (失败)
+func (h *ChainTaskHandler) dispatch() {
+	// 上一轮分发尚未结束时跳过，避免重复认领
+	if !h.mutex.TryLock() {
+		return
+	}
+	defer h.mutex.Unlock()
+
+	if h.stopping.Load() {
+		return
+	}
+
+	if h.pool.Available() == 0 {
+		h.App.Logger.Debug("所有 worker 都在忙，跳过本次调度")
+		return
+	}
+
+	// 1. 优先处理已到重试时间的任务步骤（手动重试和按重试策略自动重试）
+	retrySteps, err := h.getRetrySteps()
+	if err != nil {
+		h.App.Logger.Errorf("查询重试步骤失败: %v", err)
+	} else if len(retrySteps) > 0 {
+		h.App.Logger.Infof("发现 %d 个待重试的步骤", len(retrySteps))
+
+		// 按视频分组，同一视频的步骤在同一个 worker 中按顺序执行
+		var videoIDs []string
+		stepsByVideo := make(map[string][]string)
+		for _, step := range retrySteps {
+			if _, ok := stepsByVideo[step.VideoID]; !ok {
+				videoIDs = append(videoIDs, step.VideoID)
+			}
+			stepsByVideo[step.VideoID] = append(stepsByVideo[step.VideoID], step.StepName)
 		}
 
-		// 2. 处理新的视频任务
-		// 查询状态为 '001' 的任务
-		pendingTasks, err := h.getPendingTasks()
-		if err != nil {
-			h.App.Logger.Errorf("查询待处理任务失败: %v", err)
-			return
+		for _, videoID := range videoIDs {
+			if h.pool.Available() == 0 {
+				return
+			}
+			h.dispatchRetrySteps(videoID, stepsByVideo[videoID])
 		}
+	}
+
+	// 2. 处理新的视频任务
+	available := h.pool.Available()
+	if available == 0 {
+		return
+	}
 
-		if len(pendingTasks) == 0 {
-			h.App.Logger.Debug("没有待处理的任务")
+	// 查询状态为 '001' 的任务
+	pendingTasks, err := h.getPendingTasks(available)
+	if err != nil {
+		h.App.Logger.Errorf("查询待处理任务失败: %v", err)
+		return
+	}
+
+	if len(pendingTasks) == 0 {
+		h.App.Logger.Debug("没有待处理的任务")
+		return
+	}
+
+	for _, task := range pendingTasks {
+		if h.pool.Available() == 0 {
 			return
 		}
+		h.dispatchVideo(task)
+	}
+}
+
+// dispatchVideo 认领视频并在 worker 中执行完整任务链
+func (h *ChainTaskHandler) dispatchVideo(task *models2.TbVideo) {
+	if !h.pool.TryAcquire(task.VideoId) {
+		h.App.Logger.Debugf("视频 %s 正在处理中，跳过", task.VideoId)
+		return
+	}
+
+	// 使用条件更新认领任务并获取租约，保证同一个视频只会被一个实例的一个 worker 处理
+	claimed, err := h.SavedVideoService.ClaimVideoWithLease(task.Id, model.VideoStatusPending, model.VideoStatusProcessing, h.Lease, services.StatusChange{
+		Actor:  model.VideoStatusActorPipeline,
+		Reason: "开始执行准备阶段",
+	})
+	if err != nil {
+		h.pool.Release(task.VideoId)
+		h.App.Logger.Errorf("更新任务状态为处理中时出错: %v", err)
+		return
+	}
+	if !claimed {
+		h.pool.Release(task.VideoId)
+		h.App.Logger.Debugf("视频 %s 已被其他 worker 或实例认领，跳过", task.VideoId)
+		return
+	}
+
+	h.App.Logger.Infof("找到待处理任务，VideoId: %s", task.VideoId)
+
+	video := *task
+	h.pool.Go(video.VideoId, func() {
+		// worker 空闲后立即分发下一个任务
+		defer h.Notifier.Notify()
+		ctx, release := h.Cancels.Register(context.Background(), video.VideoId)
+		defer release()
+		defer h.releaseVideo(video.Id)
 
-		// 状态流转
+		h.App.Logger.Debugf("开始执行任务链: %s", video.VideoId)
+		h.RunTaskChain(ctx, video)
+		h.App.Logger.Debugf("任务链执行完成: %s", video.VideoId)
+	})
+}
 
-		// 001 (待处理) → 002 (处理中) → 100 (完成) 或 999 (失败)
+// dispatchRetrySteps 在 worker 中按顺序重试同一视频的多个步骤
+func (h *ChainTaskHandler) dispatchRetrySteps(videoID string, stepNames []string) {
+	if !h.pool.TryAcquire(videoID) {
+		h.App.Logger.Debugf("视频 %s 正在处理中，稍后再重试其步骤", videoID)
+		return
+	}
 
-		// 执行第一个待处理任务
-		task := pendingTasks[0]
-		h.App.Logger.Infof("找到待处理任务，VideoId: %s", task.VideoId)
+	h.pool.Go(videoID, func() {
+		// worker 空闲后立即分发下一个任务
+		defer h.Notifier.Notify()
+		ctx, release := h.Cancels.Register(context.Background(), videoID)
+		defer release()
 
-		// 更新任务状态为处理中
-		if err := h.updateSavedVideoStatus(task.Id, "002"); err != nil {
-			h.App.Logger.Errorf("更新任务状态为处理中时出错: %v", err)
+		// 获取视频租约，避免其他实例同时重试该视频的步骤
+		video, err := h.SavedVideoService.GetVideoByVideoID(videoID)
+		if err != nil {
+			h.App.Logger.Errorf("获取视频信息失败: %v", err)
+			return
+		}
+		acquired, err := h.SavedVideoService.AcquireVideo(video.ID, h.Lease)
+		if err != nil {
+			h.App.Logger.Errorf("获取视频租约失败: %v", err)
 			return
 		}
+		if !acquired {
+			h.App.Logger.Debugf("视频 %s 正由其他实例处理，稍后再重试其步骤", videoID)
+			return
+		}
+		defer h.releaseVideo(video.ID)
 
-		h.isRunning = true
-		h.App.Logger.Debug("开始执行任务链")
+		for _, stepName := range stepNames {
+			if ctx.Err() != nil {
+				h.App.Logger.Infof("⏹️ 视频 %s 已取消，停止重试剩余步骤", videoID)
+				return
+			}
 
-		// 执行任务链
-		h.RunTaskChain(*task)
+			// 认领步骤，避免与其他 worker 重复执行
+			claimed, err := h.TaskStepService.ClaimPendingStep(videoID, stepName)
+			if err != nil {
+				h.App.Logger.Errorf("认领任务步骤失败: %v", err)
+				continue
+			}
+			if !claimed {
+				continue
+			}
 
-		h.isRunning = false
-		h.App.Logger.Debug("任务链执行完成")
+			h.App.Logger.Infof("🔄 开始重试步骤: %s - %s", videoID, stepName)
+			if err := h.RunSingleTaskStep(ctx, videoID, stepName); err != nil {
+				h.App.Logger.Errorf("重试步骤失败: %v", err)
+			}
+		}
 	})
-
-	// 启动 cron 调度器
-	h.Task.Start()
-	h.App.Logger.Info("✓ Cron scheduler started, checking for tasks every 5 seconds")
 }
 
-// resetRunningTasksOnStartup 应用启动时重置所有"运行中"的任务步骤
+// resetRunningTasksOnStartup 应用启动时回收当前实例重启前遗留的任务
+// 只回收当前实例持有的租约和已过期的租约，不影响其他实例正在执行的任务
 func (h *ChainTaskHandler) resetRunningTasksOnStartup() {
-	h.App.Logger.Info("🔄 正在重置应用重启前的运行中任务...")
+	h.App.Logger.Info("🔄 正在回收应用重启前的运行中任务...")
 
-	// 重置所有"运行中"状态的任务步骤为"待执行"
-	err := h.TaskStepService.ResetAllRunningTasks()
+	ownSteps, ownVideos, err := h.TaskStepService.ReclaimOwnLeases()
+	if err != nil {
+		h.App.Logger.Errorf("❌ 回收运行中任务失败: %v", err)
+		return
+	}
+	steps, videos, err := h.TaskStepService.ReclaimExpiredLeases()
 	if err != nil {
-		h.App.Logger.Errorf("❌ 重置运行中任务失败: %v", err)
+		h.App.Logger.Errorf("❌ 回收过期租约失败: %v", err)
 		return
 	}
 
-	h.App.Logger.Info("✅ 已重置所有运行中的任务步骤，它们将在下次调度时重新执行")
+	h.App.Logger.Infof("✅ 已回收 %d 个视频和 %d 个任务步骤，它们将在下次调度时重新执行", ownVideos+videos, ownSteps+steps)
+}
+
+// releaseVideo 释放当前实例持有的视频租约
+// 实例关闭时保留租约，由关闭流程统一回收并重新排队
+func (h *ChainTaskHandler) releaseVideo(id uint) {
+	if h.Cancels.ShuttingDown() {
+		return
+	}
+	if err := h.SavedVideoService.ReleaseVideo(id, h.Lease); err != nil {
+		h.App.Logger.Errorf("释放视频租约失败: %v", err)
+	}
 }
 
 // getPendingTasks 获取状态为 '001' 的待处理任务（从 SavedVideo 表查询）
-func (h *ChainTaskHandler) getPendingTasks() ([]*models2.TbVideo, error) {
+// 优先级高的视频先处理，同一优先级在播放列表、频道之间轮转
+func (h *ChainTaskHandler) getPendingTasks(limit int) ([]*models2.TbVideo, error) {
 	// 使用 SavedVideoService 查询状态为 '001' 的任务
-	savedVideos, err := h.SavedVideoService.GetPendingVideos(10)
+	savedVideos, err := h.SavedVideoService.GetPendingVideos(h.queue, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -154,7 +358,7 @@ func (h *ChainTaskHandler) getPendingTasks() ([]*models2.TbVideo, error) {
 			URL:       sv.URL,
 			Title:     sv.Title,
 			VideoId:   sv.VideoID,
-			Status:    sv.Status,
+			Status:    string(sv.Status),
 			CreatedAt: sv.CreatedAt,
 			UpdatedAt: sv.UpdatedAt,
 		}
@@ -164,67 +368,111 @@ func (h *ChainTaskHandler) getPendingTasks() ([]*models2.TbVideo, error) {
 	return tasks, nil
 }
 
-// getRetrySteps 获取状态为 'pending' 的重试步骤
+// getRetrySteps 获取已到重试时间的待执行步骤
 func (h *ChainTaskHandler) getRetrySteps() ([]*model.TaskStep, error) {
 	return h.TaskStepService.GetPendingSteps()
 }
-func (h *ChainTaskHandler) RunTaskChain(video models2.TbVideo) {
 
+// RunTaskChain 执行视频的完整准备阶段任务链，ctx 被取消时尽快终止
+// 每次执行记录在 cw_pipeline_runs 中
+func (h *ChainTaskHandler) RunTaskChain(ctx context.Context, video models2.TbVideo) {
+	// 通过步骤控制接口排队的视频只执行指定范围内的步骤
+	kind, stepRange := model.PipelineRunKindChain, ""
+	savedVideo, err := h.SavedVideoService.GetVideoByID(video.Id)
+	if err != nil {
+		h.App.Logger.Errorf("获取视频信息失败: %v", err)
+		savedVideo = nil
+	} else if stepRange = runRangeLabel(savedVideo.RunFrom, savedVideo.RunUntil); stepRange != "" {
+		kind = model.PipelineRunKindRange
+	}
+
+	run, err := h.TaskStepService.StartRun(video.VideoId, kind, model.VideoStatusActorPipeline, VideoPipelineName(h.App.Config, savedVideo), stepRange)
+	if err != nil {
+		h.App.Logger.Errorf("记录任务执行失败: %v", err)
+	}
+
+	err = h.runTaskChain(ctx, run, video, savedVideo)
+	finishRun(h.TaskStepService, h.App.Logger, ctx, run, err)
+}
+
+// runTaskChain 执行准备阶段任务链，返回 nil 表示全部步骤执行成功
+// savedVideo 记录了步骤范围时只执行范围内的步骤，为 nil 时执行完整的准备阶段
+func (h *ChainTaskHandler) runTaskChain(ctx context.Context, run *model.PipelineRun, video models2.TbVideo, savedVideo *model.SavedVideo) error {
 	currentDir, err := filepath.Abs(h.App.Config.FileUpDir)
 	if err != nil {
 		h.App.Logger.Errorf("获取文件上传目录失败: %v", err)
 		// 任务失败，更新状态为失败
-		if updateErr := h.SavedVideoService.UpdateStatus(video.Id, "999"); updateErr != nil {
+		if updateErr := h.updateSavedVideoStatus(video.Id, model.VideoStatusFailed, fmt.Sprintf("获取文件上传目录失败: %v", err)); updateErr != nil {
 			h.App.Logger.Errorf("更新任务状态为失败时出错: %v", updateErr)
 		}
-		return
-
+		return fmt.Errorf("获取文件上传目录失败: %v", err)
 	}
 
-	// 初始化任务步骤
-	if err := h.TaskStepService.InitTaskSteps(video.VideoId); err != nil {
-		h.App.Logger.Errorf("初始化任务步骤失败: %v", err)
+	// 按配置解析流水线（视频可以指定流水线），步骤之间按依赖关系组成 DAG，没有依赖关系的步骤并行执行
+	pipeline, err := ResolveVideoPipeline(h.App.Config, savedVideo)
+	if err != nil {
+		h.App.Logger.Errorf("❌ 加载流水线失败: %v", err)
+		if updateErr := h.updateSavedVideoStatus(video.Id, model.VideoStatusFailed, fmt.Sprintf("加载流水线失败: %v", err)); updateErr != nil {
+			h.App.Logger.Errorf("更新任务状态为失败时出错: %v", updateErr)
+		}
+		return fmt.Errorf("加载流水线失败: %v", err)
 	}
 
 	stateManager := manager.NewStateManager(video.Id, video.VideoId, currentDir, video.CreatedAt)
-	chain := manager.NewTaskChain()
-
-
-	//// 任务1: 下载视频
-	downloadTask := handlers.NewDownloadVideo("下载视频", h.App, stateManager, h.App.CosClient, h.SavedVideoService)
-	chain.AddTask(h.wrapTaskWithStepTracking(downloadTask, video.VideoId))
-
-	// 任务2: 生成字幕文件
-	extractAudioTask := handlers.NewExtractAudio("分离音频", h.App, stateManager, h.App.CosClient)
-	chain.AddTask(h.wrapTaskWithStepTracking(extractAudioTask, video.VideoId))
-
-	// 任务3: 使用 Whisper 转录生成字幕（如果启用）
-	if h.App.Config.WhisperConfig != nil && h.App.Config.WhisperConfig.Enabled {
-		h.App.Logger.Info("✓ Whisper 已启用，将使用 Whisper 进行语音转录")
-		whisperTask := handlers.NewWhisperHandler(
-			"Whisper转录",
-			h.App,
-			stateManager,
-			h.App.CosClient,
-			h.App.Config.WhisperConfig.ModelPath,
-			h.App.Config.WhisperConfig.Language,
-			h.App.Config.WhisperConfig.Threads,
-		)
-		chain.AddTask(h.wrapTaskWithStepTracking(whisperTask, video.VideoId))
-	} else {
-		// 备用方案：使用原有的字幕生成方法
-		h.App.Logger.Info("使用默认字幕生成方法")
-		subtitleTask := handlers.NewGenerateSubtitles("生成字幕", h.App, stateManager, h.App.CosClient, h.SavedVideoService)
-		chain.AddTask(h.wrapTaskWithStepTracking(subtitleTask, video.VideoId))
+	chain := manager.NewTaskChain().
+		WithScheduler(h.Scheduler).
+		WithTimeoutPolicy(manager.NewTimeoutPolicy(h.App.Config)).
+		WithPauseGate(newStepPauseGate(h.Pauses, pipeline, video.VideoId, h.Progress, h.App.Logger))
+
+	steps := pipeline.StageSteps(StagePrepare)
+	stopReason := ""
+	if savedVideo != nil && (savedVideo.RunFrom != "" || savedVideo.RunUntil != "") {
+		// 步骤范围只生效一次，执行结束（实例关闭中断时除外）后清除
+		defer func() {
+			if manager.IsShutdown(ctx) {
+				return
+			}
+			if err := h.SavedVideoService.ClearRunRange(video.Id); err != nil {
+				h.App.Logger.Errorf("清除步骤范围失败: %v", err)
+			}
+		}()
+
+		steps, err = pipeline.PrepareRange(savedVideo.RunFrom, savedVideo.RunUntil)
+		if err != nil {
+			h.App.Logger.Errorf("❌ %v", err)
+			if updateErr := h.updateSavedVideoStatus(video.Id, model.VideoStatusFailed, err.Error()); updateErr != nil {
+				h.App.Logger.Errorf("更新任务状态为失败时出错: %v", updateErr)
+			}
+			return err
+		}
+		stopReason = "指定范围的步骤已执行完，准备阶段仍有未完成的步骤，等待继续执行"
+		if savedVideo.RunUntil != "" {
+			stopReason = fmt.Sprintf("已执行到步骤 %s，等待继续执行", savedVideo.RunUntil)
+		}
+
+		// 范围外的步骤不执行，恢复它们保存的执行结果，产物沿用工作目录中的文件
+		restorePipelineContext(h.TaskStepService, h.App.Logger, chain, savedVideo, "")
+		h.App.Logger.Infof("🎯 只执行步骤 %s", runRangeLabel(savedVideo.RunFrom, savedVideo.RunUntil))
 	}
-	chain.AddTask(handlers.NewDownloadImgHandler("下载封面", h.App, stateManager, h.App.CosClient))
-	// 任务3: 翻译字幕（动态检查配置）
-	translateTask := handlers.NewTranslateSubtitle("翻译字幕", h.App, stateManager, h.App.CosClient, h.Db, "")
-	chain.AddTask(h.wrapTaskWithStepTracking(translateTask, video.VideoId))
 
-	// 任务4: 生成视频标题和描述（动态检查配置）
-	metadataTask := handlers.NewGenerateMetadata("生成视频元数据", h.App, stateManager, h.App.CosClient, "", h.Db, h.SavedVideoService)
-	chain.AddTask(h.wrapTaskWithStepTracking(metadataTask, video.VideoId))
+	env := h.stepEnv(stateManager)
+	for _, step := range steps {
+		task, err := newStepTask(env, step)
+		if err != nil {
+			h.App.Logger.Errorf("❌ %v", err)
+			if updateErr := h.updateSavedVideoStatus(video.Id, model.VideoStatusFailed, err.Error()); updateErr != nil {
+				h.App.Logger.Errorf("更新任务状态为失败时出错: %v", updateErr)
+			}
+			return err
+		}
+		chain.AddTaskWithDeps(h.wrapTaskWithStepTracking(task, step, video.VideoId, savedVideo, stateManager, run.GetID()), step.DependsOn...)
+	}
+	h.App.Logger.Infof("使用流水线 %s: %d 个准备阶段步骤", pipeline.Name, len(chain.Tasks))
+
+	// 根据流水线初始化任务步骤
+	if err := h.TaskStepService.InitTaskSteps(video.VideoId, pipeline.StepDefinitions()); err != nil {
+		h.App.Logger.Errorf("初始化任务步骤失败: %v", err)
+	}
 
 	// 注意: 上传任务已移至 UploadScheduler 定时执行
 	// - 视频上传: 每小时上传一个视频
@@ -234,11 +482,17 @@ func (h *ChainTaskHandler) RunTaskChain(video models2.TbVideo) {
 	startTime := time.Now()
 
 	// 执行任务链
-	result := chain.Run(true)
+	result := chain.Run(ctx, true)
 
 	duration := time.Since(startTime)
 	h.App.Logger.Infof("任务链执行完成, 耗时: %v", duration)
 
+	// 任务被取消时视频状态已由取消接口更新，不再覆盖
+	if ctx.Err() != nil {
+		h.App.Logger.Infof("⏹️ 任务 %s 已取消", video.VideoId)
+		return ctx.Err()
+	}
+
 	// 检查任务链是否成功执行（如果context中有错误信息，则认为失败）
 	success := true
 	if errorMsg, exists := result["error"]; exists && errorMsg != nil {
@@ -246,28 +500,66 @@ func (h *ChainTaskHandler) RunTaskChain(video models2.TbVideo) {
 		h.App.Logger.Errorf("任务链执行过程中发生错误: %v", errorMsg)
 	}
 
-	// 根据执行结果更新任务状态
-	if success {
+	// 根据执行结果更新任务状态（仅当视频仍处于处理中时更新，避免覆盖取消等外部修改）
+	if success && stopReason != "" && !h.prepareStageCompleted(pipeline, video.VideoId) {
+		// 只执行了部分步骤，准备阶段尚未全部完成，不进入上传阶段
+		if err := h.updateSavedVideoStatus(video.Id, model.VideoStatusStopped, stopReason); err != nil {
+			h.App.Logger.Errorf("更新任务状态为已停止时出错: %v", err)
+		} else {
+			h.App.Logger.Infof("⏹️ 任务 %s: %s，状态已更新为已停止", video.VideoId, stopReason)
+		}
+	} else if success {
 		// 任务成功完成，更新状态为完成
-		if err := h.updateSavedVideoStatus(video.Id, "200"); err != nil {
+		if err := h.updateSavedVideoStatus(video.Id, model.VideoStatusReady, "准备阶段全部完成"); err != nil {
 			h.App.Logger.Errorf("更新任务状态为完成时出错: %v", err)
 		} else {
 			h.App.Logger.Infof("任务 %s 执行成功，状态已更新为完成", video.VideoId)
 		}
 	} else {
 		// 任务失败，更新状态为失败
-		if err := h.updateSavedVideoStatus(video.Id, "999"); err != nil {
+		if err := h.updateSavedVideoStatus(video.Id, model.VideoStatusFailed, fmt.Sprintf("准备阶段执行失败: %v", result["error"])); err != nil {
 			h.App.Logger.Errorf("更新任务状态为失败时出错: %v", err)
 		} else {
 			h.App.Logger.Errorf("任务 %s 执行失败，状态已更新为失败", video.VideoId)
 		}
+		return fmt.Errorf("%v", result["error"])
+	}
+
+	return nil
+}
+
+// prepareStageCompleted 视频准备阶段的步骤是否已全部完成或跳过，查询失败时视为未完成
+func (h *ChainTaskHandler) prepareStageCompleted(pipeline *Pipeline, videoID string) bool {
+	records, err := h.TaskStepService.GetTaskStepsByVideoID(videoID)
+	if err != nil {
+		h.App.Logger.Errorf("获取任务步骤失败: %v", err)
+		return false
+	}
+	stepByName := make(map[string]model.TaskStep, len(records))
+	for _, record := range records {
+		stepByName[record.StepName] = record
+	}
+	completed, _ := prepareCompleted(pipeline, stepByName)
+	return completed
+}
+
+// RunSingleTaskStep 执行单个任务步骤，ctx 被取消时步骤标记为已取消
+// 调用方需先通过 ClaimPendingStep 把步骤认领为运行中，执行期间步骤不会回到待执行状态
+// 每次执行记录在 cw_pipeline_runs 中
+func (h *ChainTaskHandler) RunSingleTaskStep(ctx context.Context, videoID, stepName string) error {
+	run, err := h.TaskStepService.StartRun(videoID, model.PipelineRunKindStep, model.VideoStatusActorRetry, videoPipelineName(h.App.Config, h.SavedVideoService, videoID), stepName)
+	if err != nil {
+		h.App.Logger.Errorf("记录任务执行失败: %v", err)
 	}
 
+	err = h.runSingleTaskStep(ctx, run, videoID, stepName)
+	finishRun(h.TaskStepService, h.App.Logger, ctx, run, err)
+	return err
 }
 
-// RunSingleTaskStep 执行单个任务步骤
-func (h *ChainTaskHandler) RunSingleTaskStep(videoID, stepName string) error {
-	// 注意：此方法假设调用方已经获得了锁，因此不在这里加锁
+// runSingleTaskStep 执行单个任务步骤
+func (h *ChainTaskHandler) runSingleTaskStep(ctx context.Context, run *model.PipelineRun, videoID, stepName string) error {
+	// 注意：调用方需通过工作池保证同一视频的步骤不会被并发执行
 
 	// 获取视频信息
 	savedVideo, err := h.SavedVideoService.GetVideoByVideoID(videoID)
@@ -281,7 +573,7 @@ func (h *ChainTaskHandler) RunSingleTaskStep(videoID, stepName string) error {
 		URL:       savedVideo.URL,
 		Title:     savedVideo.Title,
 		VideoId:   savedVideo.VideoID,
-		Status:    savedVideo.Status,
+		Status:    string(savedVideo.Status),
 		CreatedAt: savedVideo.CreatedAt,
 		UpdatedAt: savedVideo.UpdatedAt,
 	}
@@ -295,67 +587,55 @@ func (h *ChainTaskHandler) RunSingleTaskStep(videoID, stepName string) error {
 	// 创建状态管理器
 	stateManager := manager.NewStateManager(video.Id, video.VideoId, currentDir, video.CreatedAt)
 
-	// 重置步骤状态
-	if err := h.TaskStepService.ResetTaskStep(videoID, stepName); err != nil {
-		h.App.Logger.Errorf("重置任务步骤失败: %v", err)
+	// 按当前流水线创建步骤对应的任务
+	var task types.Task
+	var step PipelineStep
+	pipeline, err := ResolveVideoPipeline(h.App.Config, savedVideo)
+	if err == nil {
+		var ok bool
+		step, ok = pipeline.Step(stepName)
+		if !ok {
+			err = fmt.Errorf("步骤 %s 不在流水线 %s 中", stepName, pipeline.Name)
+		} else {
+			task, err = newStepTask(h.stepEnv(stateManager), step)
+		}
 	}
-
-	// 更新步骤状态为运行中
-	if err := h.TaskStepService.UpdateTaskStepStatus(videoID, stepName, "running"); err != nil {
-		h.App.Logger.Errorf("更新任务步骤状态失败: %v", err)
+	if err != nil {
+		// 步骤不在流水线中时没有任务类型，按全局重试策略记录
+		step.Name = stepName
+		taskErr := types.NewTaskError(types.ErrCodeNotConfigured, err.Error()).WithMessage("%v", err)
+		recordStepFailure(h.TaskStepService, h.App.Logger, h.App.Config, videoID, step, model.TaskStepStatusFailed, taskErr)
+		return err
 	}
 
 	// 创建单个任务的链
-	chain := manager.NewTaskChain()
-	var task types.Task
-
-	// 根据步骤名称创建对应的任务
-	switch stepName {
-	case "下载视频":
-		task = handlers.NewDownloadVideo("下载视频", h.App, stateManager, h.App.CosClient, h.SavedVideoService)
-	case "分离音频":
-		task = handlers.NewExtractAudio("分离音频", h.App, stateManager, h.App.CosClient)
-	case "Whisper转录":
-		// 从配置中读取 Whisper 参数
-		if h.App.Config.WhisperConfig != nil && h.App.Config.WhisperConfig.Enabled {
-			task = handlers.NewWhisperHandler(
-				"Whisper转录",
-				h.App,
-				stateManager,
-				h.App.CosClient,
-				h.App.Config.WhisperConfig.ModelPath,
-				h.App.Config.WhisperConfig.Language,
-				h.App.Config.WhisperConfig.Threads,
-			)
-		} else {
-			return fmt.Errorf("Whisper 未启用或配置不完整")
-		}
-	case "生成字幕":
-		task = handlers.NewGenerateSubtitles("生成字幕", h.App, stateManager, h.App.CosClient, h.SavedVideoService)
-	case "翻译字幕":
-		// 不再在这里检查配置，让任务运行时动态检查最新配置
-		task = handlers.NewTranslateSubtitle("翻译字幕", h.App, stateManager, h.App.CosClient, h.Db, "")
-	case "生成元数据":
-		// 不再在这里检查配置，让任务运行时动态检查最新配置
-		task = handlers.NewGenerateMetadata("生成元数据", h.App, stateManager, h.App.CosClient, "", h.Db, h.SavedVideoService)
-	case "上传到Bilibili":
-		task = handlers.NewUploadToBilibili("上传到Bilibili", h.App, stateManager, h.App.CosClient, h.SavedVideoService)
-	case "上传字幕到Bilibili":
-		fmt.Printf("注意: '上传字幕到Bilibili' 任务步骤已被注释掉，如需启用请取消注释相关代码。\n")
-		//task = handlers.NewUploadSubtitleToBilibili("上传字幕到Bilibili", h.App, stateManager, h.App.CosClient, h.SavedVideoService)
-	default:
-		return fmt.Errorf("未知的任务步骤: %s", stepName)
+	chain := manager.NewTaskChain().
+		WithScheduler(h.Scheduler).
+		WithTimeoutPolicy(manager.NewTimeoutPolicy(h.App.Config)).
+		WithPauseGate(newStepPauseGate(h.Pauses, pipeline, videoID, h.Progress, h.App.Logger))
+	restorePipelineContext(h.TaskStepService, h.App.Logger, chain, savedVideo, stepName)
+	chain.AddTask(task)
+
+	// 满足 skip_if 条件时不执行，按执行成功继续推进下游步骤
+	if skip, reason := evalSkipConditions(step, SkipEnv{Video: savedVideo, StateManager: stateManager, Context: chain.Context}); skip {
+		if err := h.TaskStepService.SkipTaskStep(videoID, stepName, model.TaskStepSkippedByCondition, reason); err != nil {
+			h.App.Logger.Errorf("更新任务步骤状态失败: %v", err)
+		}
+		publishStepEvent(h.Progress, videoID, stepName, model.TaskStepStatusSkipped)
+		h.App.Logger.Infof("⏭️ 任务步骤 %s 已跳过: %s", stepName, reason)
+		h.continueAfterRetry(pipeline, savedVideo, stepName)
+		return nil
 	}
 
-	// 添加任务到链
-	if task != nil {
-		chain.AddTask(task)
+	// 步骤已由调用方认领为运行中，只清除上一次执行的错误和结果并记录本次执行
+	if err := h.TaskStepService.StartClaimedStep(videoID, stepName, run.GetID()); err != nil {
+		h.App.Logger.Errorf("更新任务步骤状态失败: %v", err)
 	}
 
 	h.App.Logger.Infof("开始执行单个任务步骤: %s (VideoID: %s)", stepName, videoID)
 
 	// 执行任务
-	result := chain.Run(false)
+	result := chain.Run(ctx, false)
 
 	// 检查执行结果
 	success := true
@@ -366,7 +646,16 @@ func (h *ChainTaskHandler) RunSingleTaskStep(videoID, stepName string) error {
 	}
 
 	// 更新步骤状态
-	if success {
+	if reason, skipped := types.TakeSkipReason(result); skipped && success {
+		if err := h.TaskStepService.SkipTaskStep(videoID, stepName, model.TaskStepSkippedByTask, reason); err != nil {
+			h.App.Logger.Errorf("更新任务步骤状态失败: %v", err)
+		}
+		if err := h.TaskStepService.UpdateTaskStepResult(videoID, stepName, result); err != nil {
+			h.App.Logger.Errorf("更新任务步骤结果失败: %v", err)
+		}
+		h.App.Logger.Infof("⏭️ 任务步骤 %s 已跳过: %s", stepName, reason)
+		h.continueAfterRetry(pipeline, savedVideo, stepName)
+	} else if success {
 		if err := h.TaskStepService.UpdateTaskStepStatus(videoID, stepName, "completed"); err != nil {
 			h.App.Logger.Errorf("更新任务步骤状态失败: %v", err)
 		}
@@ -374,10 +663,23 @@ func (h *ChainTaskHandler) RunSingleTaskStep(videoID, stepName string) error {
 			h.App.Logger.Errorf("更新任务步骤结果失败: %v", err)
 		}
 		h.App.Logger.Infof("任务步骤 %s 执行成功", stepName)
-	} else {
-		if err := h.TaskStepService.UpdateTaskStepStatus(videoID, stepName, "failed", errorMsg); err != nil {
+		h.continueAfterRetry(pipeline, savedVideo, stepName)
+	} else if manager.IsShutdown(ctx) {
+		// 步骤保持执行中，由关闭流程重新加入重试队列
+		h.App.Logger.Warnf("⏸️ 实例关闭，任务步骤 %s 已中断", stepName)
+		return context.Cause(ctx)
+	} else if ctx.Err() != nil {
+		if err := h.TaskStepService.UpdateTaskStepStatus(videoID, stepName, model.TaskStepStatusCancelled, "任务已取消"); err != nil {
 			h.App.Logger.Errorf("更新任务步骤状态失败: %v", err)
 		}
+		h.App.Logger.Infof("⏹️ 任务步骤 %s 已取消", stepName)
+		return fmt.Errorf("任务步骤 %s 已取消", stepName)
+	} else if chain.TimedOut(stepName) {
+		recordStepFailure(h.TaskStepService, h.App.Logger, h.App.Config, videoID, step, model.TaskStepStatusTimeout, types.TaskErrorFromContext(result))
+		h.App.Logger.Errorf("⏱️ %s", errorMsg)
+		return fmt.Errorf("%w: %s", errStepTimedOut, errorMsg)
+	} else {
+		recordStepFailure(h.TaskStepService, h.App.Logger, h.App.Config, videoID, step, model.TaskStepStatusFailed, types.TaskErrorFromContext(result))
 		h.App.Logger.Errorf("任务步骤 %s 执行失败: %s", stepName, errorMsg)
 		return fmt.Errorf("任务执行失败: %s", errorMsg)
 	}
@@ -385,29 +687,166 @@ func (h *ChainTaskHandler) RunSingleTaskStep(videoID, stepName string) error {
 	return nil
 }
 
+// continueAfterRetry 步骤重试成功后继续推进视频的流程
+// 被该步骤阻塞的下游步骤重新加入执行队列；准备阶段全部完成后视频从失败（999）恢复为准备就绪（200），
+// 视频上传步骤重试成功后从上传失败（299）恢复为视频已上传（300）
+func (h *ChainTaskHandler) continueAfterRetry(pipeline *Pipeline, video *model.SavedVideo, stepName string) {
+	resumed, err := h.TaskStepService.ResumeBlockedSteps(video.VideoID)
+	if err != nil {
+		h.App.Logger.Errorf("恢复被阻塞的任务步骤失败: %v", err)
+		return
+	}
+	if len(resumed) > 0 {
+		h.App.Logger.Infof("▶️ 视频 %s 的下游步骤已重新加入执行队列: %v", video.VideoID, resumed)
+		return
+	}
+
+	if stepName == uploadStepName(pipeline, "upload_video") {
+		if _, err := h.SavedVideoService.ClaimVideo(video.ID, model.VideoStatusUploadFailed, model.VideoStatusUploaded, services.StatusChange{
+			Actor:  model.VideoStatusActorRetry,
+			Reason: "视频上传步骤重试成功",
+		}); err != nil {
+			h.App.Logger.Errorf("更新视频状态失败: %v", err)
+		}
+		return
+	}
 
-// wrapTaskWithStepTracking 包装任务以添加步骤跟踪
-func (h *ChainTaskHandler) wrapTaskWithStepTracking(task types.Task, videoID string) types.Task {
+	steps, err := h.TaskStepService.GetTaskStepsByVideoID(video.VideoID)
+	if err != nil {
+		h.App.Logger.Errorf("获取任务步骤失败: %v", err)
+		return
+	}
+	for _, step := range steps {
+		if pipeline.IsUploadStep(step.StepName) {
+			continue
+		}
+		if step.Status != model.TaskStepStatusCompleted && step.Status != model.TaskStepStatusSkipped {
+			return
+		}
+	}
+
+	claimed, err := h.SavedVideoService.ClaimVideo(video.ID, model.VideoStatusFailed, model.VideoStatusReady, services.StatusChange{
+		Actor:  model.VideoStatusActorRetry,
+		Reason: fmt.Sprintf("步骤 %s 重试成功，准备阶段全部完成", stepName),
+	})
+	if err != nil {
+		h.App.Logger.Errorf("更新视频状态失败: %v", err)
+		return
+	}
+	if claimed {
+		h.App.Logger.Infof("✅ 视频 %s 的准备阶段已在重试后全部完成，状态已更新为准备就绪", video.VideoID)
+	}
+}
+
+// restorePipelineContext 将已完成步骤保存的执行结果恢复到任务链上下文
+// 单步重试和上传步骤单独执行时，仍然可以读取前面步骤的输出（封面路径、字幕路径、BVID 等）
+func restorePipelineContext(taskStepService *services.TaskStepService, logger *zap.SugaredLogger, chain *manager.TaskChain, video *model.SavedVideo, stepName string) {
+	results, err := taskStepService.LoadStepResults(video.VideoID, stepName)
+	if err != nil {
+		logger.Warnf("⚠️ 恢复任务链上下文失败: %v", err)
+	} else {
+		manager.PipelineContext(chain.Context).Merge(results)
+	}
+
+	if _, ok := chain.Context[manager.VideoDurationKey]; !ok && video.Duration > 0 {
+		chain.Context[manager.VideoDurationKey] = video.Duration
+	}
+}
+
+// errStepTimedOut 单步执行超时
+var errStepTimedOut = errors.New("任务执行超时")
+
+// finishRun 根据执行结果记录 PipelineRun 的结束状态
+func finishRun(taskStepService *services.TaskStepService, logger *zap.SugaredLogger, ctx context.Context, run *model.PipelineRun, err error) {
+	status, errorMsg := model.TaskStepStatusCompleted, ""
+	switch {
+	case err == nil:
+	case manager.IsShutdown(ctx):
+		status, errorMsg = model.RunStatusInterrupted, manager.ErrShutdown.Error()
+	case ctx.Err() != nil:
+		status, errorMsg = model.TaskStepStatusCancelled, "任务已取消"
+	case errors.Is(err, errStepTimedOut):
+		status, errorMsg = model.TaskStepStatusTimeout, err.Error()
+	default:
+		status, errorMsg = model.TaskStepStatusFailed, err.Error()
+	}
+	if err := taskStepService.FinishRun(run, status, errorMsg); err != nil {
+		logger.Errorf("记录任务执行结果失败: %v", err)
+	}
+}
+
+// recordStepFailure 记录步骤失败及其结构化错误，按重试策略安排自动重试
+func recordStepFailure(taskStepService *services.TaskStepService, logger *zap.SugaredLogger, config *types.AppConfig, videoID string, step PipelineStep, status string, taskErr *types.TaskError) {
+	if taskErr == nil {
+		taskErr = types.NewTaskError(types.ErrCodeUnknown, "")
+	}
+
+	stepName := step.Name
+	policy := services.NewRetryPolicy(config, stepName, step.Task)
+	nextRetryAt, err := taskStepService.FailTaskStep(videoID, stepName, status, taskErr, policy)
+	if err != nil {
+		logger.Errorf("更新任务步骤状态失败: %v", err)
+		return
+	}
+	if nextRetryAt != nil {
+		logger.Infof("🔁 步骤 %s (VideoID: %s) 失败 [%s]，将于 %s 自动重试",
+			stepName, videoID, taskErr.Code, nextRetryAt.Format("2006-01-02 15:04:05"))
+	} else if !taskErr.Retryable {
+		logger.Warnf("🛑 步骤 %s (VideoID: %s) 失败 [%s/%s]，该错误无法通过重试恢复",
+			stepName, videoID, taskErr.Category, taskErr.Code)
+	}
+}
+
+// stepEnv 创建任务步骤时使用的依赖
+func (h *ChainTaskHandler) stepEnv(stateManager *manager.StateManager) StepEnv {
+	return StepEnv{
+		App:               h.App,
+		StateManager:      stateManager,
+		SavedVideoService: h.SavedVideoService,
+		Db:                h.Db,
+		Progress:          h.Progress,
+	}
+}
+
+// wrapTaskWithStepTracking 包装任务以添加步骤跟踪，runID 为步骤所属的执行记录
+// video 和 stateManager 用于在执行前判断步骤的跳过条件
+func (h *ChainTaskHandler) wrapTaskWithStepTracking(task types.Task, step PipelineStep, videoID string, video *model.SavedVideo, stateManager *manager.StateManager, runID uint) types.Task {
 	return &TaskStepWrapper{
 		task:            task,
+		step:            step,
 		videoID:         videoID,
+		video:           video,
+		stateManager:    stateManager,
+		runID:           runID,
 		taskStepService: h.TaskStepService,
+		config:          h.App.Config,
 		logger:          h.App.Logger,
+		progress:        h.Progress,
 	}
 }
 
 // TaskStepWrapper 任务步骤包装器
 type TaskStepWrapper struct {
 	task            types.Task
+	step            PipelineStep
 	videoID         string
+	video           *model.SavedVideo
+	stateManager    *manager.StateManager
+	runID           uint
 	taskStepService *services.TaskStepService
+	config          *types.AppConfig
 	logger          *zap.SugaredLogger
+	progress        *manager.ProgressBus
 }
 
 func (w *TaskStepWrapper) GetName() string {
 	return w.task.GetName()
 }
 
+func (w *TaskStepWrapper) GetResourceClass() types.ResourceClass {
+	return w.task.GetResourceClass()
+}
+
 func (w *TaskStepWrapper) InsertTask() error {
 	return w.task.InsertTask()
 }
@@ -416,16 +855,76 @@ func (w *TaskStepWrapper) UpdateStatus(status, message string) error {
 	return w.task.UpdateStatus(status, message)
 }
 
-func (w *TaskStepWrapper) Execute(context map[string]interface{}) bool {
+// Block 前置步骤失败时将步骤标记为被阻塞
+func (w *TaskStepWrapper) Block(reason string) {
+	if err := w.taskStepService.UpdateTaskStepStatus(w.videoID, w.task.GetName(), model.TaskStepStatusBlocked, reason); err != nil {
+		w.logger.Errorf("更新任务步骤状态失败: %v", err)
+	}
+}
+
+// MarkCancelled 任务链被取消时将尚未执行的步骤标记为已取消
+func (w *TaskStepWrapper) MarkCancelled(reason string) {
+	if err := w.taskStepService.UpdateTaskStepStatus(w.videoID, w.task.GetName(), model.TaskStepStatusCancelled, reason); err != nil {
+		w.logger.Errorf("更新任务步骤状态失败: %v", err)
+	}
+}
+
+// MarkTimedOut 步骤超过超时时间时将其标记为执行超时，并按重试策略安排自动重试
+func (w *TaskStepWrapper) MarkTimedOut(reason string) {
+	taskErr := types.NewTaskError(types.ErrCodeTimeout, reason).WithMessage("%s", reason)
+	recordStepFailure(w.taskStepService, w.logger, w.config, w.videoID, w.step, model.TaskStepStatusTimeout, taskErr)
+}
+
+func (w *TaskStepWrapper) Execute(ctx context.Context, context map[string]interface{}) (success bool) {
 	stepName := w.task.GetName()
 
+	// 任务发生 panic 时记录为不可重试的内部错误，避免步骤一直停留在执行中
+	defer func() {
+		if r := recover(); r != nil {
+			w.logger.Errorf("❌ 步骤 %s (VideoID: %s) 执行异常: %v\n%s", stepName, w.videoID, r, debug.Stack())
+			taskErr := types.NewTaskError(types.ErrCodeInternal, fmt.Sprintf("%v", r))
+			types.SetTaskError(context, taskErr)
+			publishStepEvent(w.progress, w.videoID, stepName, model.TaskStepStatusFailed)
+			recordStepFailure(w.taskStepService, w.logger, w.config, w.videoID, w.step, model.TaskStepStatusFailed, taskErr)
+			success = false
+		}
+	}()
+
+	// 手动跳过或满足 skip_if 条件的步骤不执行，下游步骤照常执行
+	if skippedBy, reason := stepSkip(w.taskStepService, w.step, SkipEnv{Video: w.video, StateManager: w.stateManager, Context: context}); skippedBy != "" {
+		if skippedBy != model.TaskStepSkippedByOperator {
+			if err := w.taskStepService.SkipTaskStep(w.videoID, stepName, skippedBy, reason); err != nil {
+				w.logger.Errorf("更新任务步骤状态失败: %v", err)
+			}
+		}
+		stepLogf(w.task, "==== 跳过步骤 %s: %s", stepName, reason)
+		publishStepEvent(w.progress, w.videoID, stepName, model.TaskStepStatusSkipped)
+		return true
+	}
+
 	// 更新步骤状态为运行中
-	if err := w.taskStepService.UpdateTaskStepStatus(w.videoID, stepName, "running"); err != nil {
+	if err := w.taskStepService.StartTaskStep(w.videoID, stepName, w.runID); err != nil {
 		w.logger.Errorf("更新任务步骤状态失败: %v", err)
 	}
 
 	// 执行原始任务
-	success := w.task.Execute(context)
+	stepLogf(w.task, "==== 开始执行步骤 %s", stepName)
+	publishStepEvent(w.progress, w.videoID, stepName, model.TaskStepStatusRunning)
+	success = w.task.Execute(ctx, context)
+	stepLogf(w.task, "==== 步骤 %s 执行结束，成功: %v", stepName, success)
+
+	// 任务判断无需处理时记录为已跳过
+	if reason, skipped := types.TakeSkipReason(context); skipped && success {
+		publishStepEvent(w.progress, w.videoID, stepName, model.TaskStepStatusSkipped)
+		if err := w.taskStepService.SkipTaskStep(w.videoID, stepName, model.TaskStepSkippedByTask, reason); err != nil {
+			w.logger.Errorf("更新任务步骤状态失败: %v", err)
+		}
+		if err := w.taskStepService.UpdateTaskStepResult(w.videoID, stepName, stepResult(context)); err != nil {
+			w.logger.Errorf("更新任务步骤结果失败: %v", err)
+		}
+		return true
+	}
+	publishStepEvent(w.progress, w.videoID, stepName, stepResultStatus(ctx, success))
 
 	// 更新步骤状态
 	if success {
@@ -434,30 +933,63 @@ func (w *TaskStepWrapper) Execute(context map[string]interface{}) bool {
 		}
 
 		// 保存执行结果
-		result := map[string]interface{}{}
-		for k, v := range context {
-			if k != "error" { // 排除错误信息
-				result[k] = v
-			}
-		}
-		if err := w.taskStepService.UpdateTaskStepResult(w.videoID, stepName, result); err != nil {
+		if err := w.taskStepService.UpdateTaskStepResult(w.videoID, stepName, stepResult(context)); err != nil {
 			w.logger.Errorf("更新任务步骤结果失败: %v", err)
 		}
-	} else {
-		errorMsg := ""
-		if err, exists := context["error"]; exists {
-			errorMsg = fmt.Sprintf("%v", err)
+	} else if ctx.Err() != nil {
+		// 超时状态由任务链通过 MarkTimedOut 记录，这里只处理取消
+		// 实例关闭时步骤保持执行中，由关闭流程重新排队
+		if !manager.IsTimeout(ctx) && !manager.IsShutdown(ctx) {
+			if err := w.taskStepService.UpdateTaskStepStatus(w.videoID, stepName, model.TaskStepStatusCancelled, "任务已取消"); err != nil {
+				w.logger.Errorf("更新任务步骤状态失败: %v", err)
+			}
 		}
+	} else {
+		recordStepFailure(w.taskStepService, w.logger, w.config, w.videoID, w.step, model.TaskStepStatusFailed, types.TaskErrorFromContext(context))
+	}
 
-		if err := w.taskStepService.UpdateTaskStepStatus(w.videoID, stepName, "failed", errorMsg); err != nil {
-			w.logger.Errorf("更新任务步骤状态失败: %v", err)
+	return success
+}
+
+// stepResult 步骤执行后要保存的结果，排除错误信息
+func stepResult(context map[string]interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	for k, v := range context {
+		if k != "error" && k != types.ErrorInfoKey {
+			result[k] = v
 		}
 	}
+	return result
+}
 
-	return success
+// stepResultStatus 步骤执行结束后的状态，用于发布步骤结束事件
+func stepResultStatus(ctx context.Context, success bool) string {
+	switch {
+	case success:
+		return model.TaskStepStatusCompleted
+	case ctx.Err() != nil && manager.IsTimeout(ctx):
+		return model.TaskStepStatusTimeout
+	case manager.IsShutdown(ctx):
+		return model.RunStatusInterrupted
+	case ctx.Err() != nil:
+		return model.TaskStepStatusCancelled
+	default:
+		return model.TaskStepStatusFailed
+	}
 }
 
-// updateSavedVideoStatus 更新 SavedVideo 的状态
-func (h *ChainTaskHandler) updateSavedVideoStatus(id uint, status string) error {
-	return h.SavedVideoService.UpdateStatus(id, status)
+// updateSavedVideoStatus 将处理中（002）的 SavedVideo 更新为最终状态
+// 视频已不处于处理中（例如被取消）时不做修改
+func (h *ChainTaskHandler) updateSavedVideoStatus(id uint, status model.VideoStatus, reason string) error {
+	updated, err := h.SavedVideoService.ClaimVideo(id, model.VideoStatusProcessing, status, services.StatusChange{
+		Actor:  model.VideoStatusActorPipeline,
+		Reason: reason,
+	})
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return fmt.Errorf("视频状态已不是处理中，未更新为 %s", status)
+	}
+	return nil
 }
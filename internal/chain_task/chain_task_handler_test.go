@@ -0,0 +1,58 @@
+package chain_task
+
+import (
+	"context"
+	"testing"
+
+	"github.com/difyz9/ytb2bili/internal/core/types"
+	"github.com/difyz9/ytb2bili/pkg/store/model"
+	"go.uber.org/zap"
+)
+
+// panicTask 执行时发生 panic 的任务
+type panicTask struct{ name string }
+
+func (t *panicTask) Execute(ctx context.Context, context map[string]interface{}) bool {
+	panic("index out of range")
+}
+func (t *panicTask) GetName() string                           { return t.name }
+func (t *panicTask) GetResourceClass() types.ResourceClass     { return types.ResourceClassDefault }
+func (t *panicTask) InsertTask() error                         { return nil }
+func (t *panicTask) UpdateStatus(status, message string) error { return nil }
+
+func TestTaskStepWrapperRecordsPanic(t *testing.T) {
+	h, video := newRangeHandler(t, map[string]string{"下载": model.TaskStepStatusCompleted})
+	pipeline, err := ResolvePipeline(h.App.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	step, _ := pipeline.Step("转录")
+	w := &TaskStepWrapper{
+		task:            &panicTask{name: step.Name},
+		step:            step,
+		videoID:         video.VideoID,
+		video:           video,
+		taskStepService: h.TaskStepService,
+		config:          h.App.Config,
+		logger:          zap.NewNop().Sugar(),
+	}
+
+	taskContext := map[string]interface{}{}
+	if w.Execute(context.Background(), taskContext) {
+		t.Fatal("Execute() = true, want false")
+	}
+	if taskErr := types.TaskErrorFromContext(taskContext); taskErr == nil || taskErr.Code != types.ErrCodeInternal {
+		t.Errorf("context error = %+v, want %s", taskErr, types.ErrCodeInternal)
+	}
+
+	// 步骤不能停留在执行中，也不会自动重试
+	record, err := h.TaskStepService.GetTaskStepByName(video.VideoID, step.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Status != model.TaskStepStatusFailed || record.ErrorCode != string(types.ErrCodeInternal) ||
+		record.ErrorRetryable || record.NextRetryAt != nil {
+		t.Fatalf("转录: status = %s, error_code = %s, retryable = %v, next_retry_at = %v",
+			record.Status, record.ErrorCode, record.ErrorRetryable, record.NextRetryAt)
+	}
+}
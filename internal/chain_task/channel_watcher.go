@@ -0,0 +1,287 @@
+package chain_task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/difyz9/ytb2bili/internal/chain_task/manager"
+	"github.com/difyz9/ytb2bili/internal/core"
+	"github.com/difyz9/ytb2bili/internal/core/models"
+	"github.com/difyz9/ytb2bili/internal/core/services"
+	"github.com/difyz9/ytb2bili/pkg/utils"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// defaultChannelCheckInterval 未配置 channel_check_interval 时检查订阅频道的间隔
+const defaultChannelCheckInterval = 30 * time.Minute
+
+var (
+	// ErrInvalidChannelURL URL 不是 YouTube 频道
+	ErrInvalidChannelURL = errors.New("无效的频道 URL")
+	// ErrChannelExists 频道已订阅
+	ErrChannelExists = errors.New("频道已订阅")
+	// ErrInvalidChannelSettings 频道的默认设置无效
+	ErrInvalidChannelSettings = errors.New("频道设置无效")
+)
+
+// ChannelCheckResult 一次订阅或检查的结果
+type ChannelCheckResult struct {
+	Channel *models.TbChannel `json:"channel"`
+	Checked int               `json:"checked"` // 检查的最新视频数量
+	Added   []string          `json:"added"`   // 加入处理队列的视频ID，按上传时间从早到晚排列
+	Skipped int               `json:"skipped"` // 已提交过而跳过的视频数量
+}
+
+// ChannelWatcher 频道订阅的新视频检查
+// 按 channel_check_interval 定期获取订阅频道的最新上传，比最近看到的视频（last_seen_video_id）更新的视频
+// 按频道的默认设置（流水线、投稿分区、标题模板、优先级）加入处理队列
+type ChannelWatcher struct {
+	App            *core.AppServer
+	ChannelService *services.ChannelService
+	Notifier       *manager.JobNotifier
+	Task           *cron.Cron
+	logger         *zap.SugaredLogger
+
+	writes sync.Mutex // 串行写入频道订阅和加入的视频，避免并发订阅重复创建；获取频道上传较慢，不持有锁
+}
+
+// NewChannelWatcher 创建频道订阅检查
+func NewChannelWatcher(app *core.AppServer, task *cron.Cron, channelService *services.ChannelService, notifier *manager.JobNotifier) *ChannelWatcher {
+	return &ChannelWatcher{
+		App:            app,
+		ChannelService: channelService,
+		Notifier:       notifier,
+		Task:           task,
+		logger:         app.Logger,
+	}
+}
+
+// SetUp 按 channel_check_interval 定期检查订阅的频道
+func (w *ChannelWatcher) SetUp() {
+	interval := w.interval()
+	if interval <= 0 {
+		w.logger.Info("✓ Channel watcher disabled")
+		return
+	}
+	w.Task.AddFunc(fmt.Sprintf("@every %s", interval), func() {
+		w.CheckAll(interval)
+	})
+	w.logger.Infof("✓ Channel watcher started, checking every %s", interval)
+}
+
+// interval 检查订阅频道的间隔，<=0 表示不自动检查
+func (w *ChannelWatcher) interval() time.Duration {
+	if w.App.Config.PipelineConfig != nil && w.App.Config.PipelineConfig.ChannelCheck != 0 {
+		return time.Duration(w.App.Config.PipelineConfig.ChannelCheck) * time.Second
+	}
+	return defaultChannelCheckInterval
+}
+
+// CheckAll 检查所有未暂停、且距离上次检查已超过 interval 的频道
+// 多个实例共享数据库时，每个频道在同一周期内只由一个实例检查
+func (w *ChannelWatcher) CheckAll(interval time.Duration) {
+	channels, err := w.ChannelService.GetDueChannels(interval)
+	if err != nil {
+		w.logger.Errorf("❌ 查询需要检查的频道失败: %v", err)
+		return
+	}
+
+	for i := range channels {
+		channel := &channels[i]
+		claimed, err := w.ChannelService.ClaimCheck(channel, interval)
+		if err != nil {
+			w.logger.Errorf("❌ 认领频道 %s 的检查失败: %v", channel.ChannelId, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		if _, err := w.Check(context.Background(), channel); err != nil {
+			w.logger.Errorf("❌ 检查频道 %s 失败: %v", channel.ChannelId, err)
+		}
+	}
+}
+
+// ValidatePipeline 检查频道指定的流水线是否存在，为空表示使用配置的流水线
+func (w *ChannelWatcher) ValidatePipeline(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, err := ResolveNamedPipeline(w.App.Config, name); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidChannelSettings, err)
+	}
+	return nil
+}
+
+// Subscribe 订阅频道，settings 为新视频的默认设置
+// 订阅时记录频道当前最新的视频，只有之后上传的视频会加入处理队列；backfill>0 时同时加入最近的 backfill 个视频
+func (w *ChannelWatcher) Subscribe(ctx context.Context, channelURL string, settings models.TbChannel, backfill int) (*ChannelCheckResult, error) {
+	videosURL := utils.ChannelVideosURL(channelURL)
+	if videosURL == "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidChannelURL, channelURL)
+	}
+	if err := w.ValidatePipeline(settings.Pipeline); err != nil {
+		return nil, err
+	}
+
+	limit := settings.Limit()
+	if backfill > limit {
+		limit = backfill
+	}
+	fetched, err := w.fetch(ctx, videosURL, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	w.writes.Lock()
+	defer w.writes.Unlock()
+
+	channel := settings
+	channel.ChannelId = fetched.ChannelID
+	if channel.ChannelId == "" && strings.HasPrefix(fetched.ID, "UC") {
+		channel.ChannelId = fetched.ID
+	}
+	if channel.ChannelId == "" {
+		return nil, fmt.Errorf("%w: 无法获取频道ID: %s", ErrInvalidChannelURL, channelURL)
+	}
+	if _, err := w.ChannelService.GetByChannelID(channel.ChannelId); err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrChannelExists, channel.ChannelId)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("查询频道失败: %v", err)
+	}
+
+	channel.Title = firstNonEmpty(fetched.Channel, fetched.Uploader, fetched.Title)
+	channel.CustomUrl = channelURL
+	entries := uploadedEntries(fetched.Entries)
+	if len(entries) > 0 {
+		channel.LastSeenVideoId = entries[0].ID
+	}
+	if err := w.ChannelService.CreateChannel(&channel); err != nil {
+		return nil, fmt.Errorf("保存频道订阅失败: %v", err)
+	}
+	w.logger.Infof("📺 已订阅频道 %s（%s），最新视频: %s", channel.ChannelId, channel.Title, channel.LastSeenVideoId)
+
+	if backfill > len(entries) {
+		backfill = len(entries)
+	}
+	return w.addEntries(&channel, len(entries), entries[:backfill], channel.LastSeenVideoId)
+}
+
+// Check 检查频道的最新上传，把比最近看到的视频更新的视频加入处理队列
+// 最近看到的视频不在最新的 check_limit 个视频中时（新视频过多或视频已删除），最新的视频全部视为新视频，已提交过的视频会被跳过
+func (w *ChannelWatcher) Check(ctx context.Context, channel *models.TbChannel) (*ChannelCheckResult, error) {
+	fetched, err := w.fetch(ctx, channel.VideosURL(), channel.Limit())
+	if err != nil {
+		if recordErr := w.ChannelService.RecordCheck(channel.ChannelId, "", err.Error()); recordErr != nil {
+			w.logger.Errorf("记录频道 %s 的检查结果失败: %v", channel.ChannelId, recordErr)
+		}
+		return nil, err
+	}
+
+	entries := uploadedEntries(fetched.Entries)
+	fresh, lastSeen := newUploads(entries, channel.LastSeenVideoId)
+	if channel.LastSeenVideoId != "" && len(fresh) == len(entries) && len(entries) > 0 {
+		w.logger.Warnf("⚠️ 频道 %s 最近看到的视频 %s 不在最新的 %d 个视频中，最新的视频全部视为新视频",
+			channel.ChannelId, channel.LastSeenVideoId, len(entries))
+	}
+
+	w.writes.Lock()
+	defer w.writes.Unlock()
+	return w.addEntries(channel, len(entries), fresh, lastSeen)
+}
+
+// newUploads 从按上传时间从新到旧排列的视频中选出比 lastSeen 更新的视频，并返回新的最近看到的视频
+// lastSeen 不在 entries 中时全部视为新视频；entries 为空时最近看到的视频不变
+func newUploads(entries []utils.PlaylistEntry, lastSeen string) ([]utils.PlaylistEntry, string) {
+	var fresh []utils.PlaylistEntry
+	for _, entry := range entries {
+		if entry.ID == lastSeen {
+			break
+		}
+		fresh = append(fresh, entry)
+	}
+	if len(entries) > 0 {
+		lastSeen = entries[0].ID
+	}
+	return fresh, lastSeen
+}
+
+// addEntries 按上传时间从早到晚把视频加入处理队列并记录检查结果，entries 按上传时间从新到旧排列
+// 有新视频时立即唤醒任务分发
+func (w *ChannelWatcher) addEntries(channel *models.TbChannel, checked int, entries []utils.PlaylistEntry, lastSeen string) (*ChannelCheckResult, error) {
+	ordered := make([]utils.PlaylistEntry, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		ordered = append(ordered, entries[i])
+	}
+
+	added, skipped, err := w.ChannelService.AddEntries(channel, ordered)
+	if len(added) > 0 {
+		w.Notifier.Notify()
+	}
+
+	checkErr := ""
+	if err != nil {
+		// 加入失败时不更新最近看到的视频，下次检查时重新加入
+		checkErr, lastSeen = err.Error(), ""
+	}
+	if recordErr := w.ChannelService.RecordCheck(channel.ChannelId, lastSeen, checkErr); recordErr != nil {
+		w.logger.Errorf("记录频道 %s 的检查结果失败: %v", channel.ChannelId, recordErr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("加入频道视频失败: %v", err)
+	}
+
+	if len(added) > 0 {
+		w.logger.Infof("📺 频道 %s（%s）: 新增 %d 个视频，跳过 %d 个", channel.ChannelId, channel.Title, len(added), skipped)
+	}
+
+	if updated, err := w.ChannelService.GetByChannelID(channel.ChannelId); err == nil {
+		channel = updated
+	}
+	if added == nil {
+		added = []string{}
+	}
+	return &ChannelCheckResult{
+		Channel: channel,
+		Checked: checked,
+		Added:   added,
+		Skipped: skipped,
+	}, nil
+}
+
+// fetch 使用 yt-dlp 获取频道最新的 limit 个上传，按上传时间从新到旧排列
+func (w *ChannelWatcher) fetch(ctx context.Context, videosURL string, limit int) (*utils.FlatPlaylist, error) {
+	ctx, cancel := context.WithTimeout(ctx, playlistFetchTimeout)
+	defer cancel()
+
+	ytdlp := utils.NewYtDlpManager(w.logger, w.App.Config.YtDlpPath)
+	return ytdlp.FlatPlaylist(ctx, videosURL, ytdlpListOptions(w.App, limit))
+}
+
+// uploadedEntries 过滤掉直播预告和直播中的视频，它们结束后作为新视频出现在上传列表中
+func uploadedEntries(entries []utils.PlaylistEntry) []utils.PlaylistEntry {
+	uploaded := make([]utils.PlaylistEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.LiveStatus == "is_upcoming" || entry.LiveStatus == "is_live" {
+			continue
+		}
+		uploaded = append(uploaded, entry)
+	}
+	return uploaded
+}
+
+// firstNonEmpty 返回第一个非空字符串
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
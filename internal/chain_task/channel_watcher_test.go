@@ -0,0 +1,60 @@
+package chain_task
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/difyz9/ytb2bili/pkg/utils"
+)
+
+func entryIDs(entries []utils.PlaylistEntry) []string {
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.ID)
+	}
+	return ids
+}
+
+func entriesOf(ids ...string) []utils.PlaylistEntry {
+	entries := make([]utils.PlaylistEntry, 0, len(ids))
+	for _, id := range ids {
+		entries = append(entries, utils.PlaylistEntry{ID: id})
+	}
+	return entries
+}
+
+func TestNewUploads(t *testing.T) {
+	tests := []struct {
+		name         string
+		entries      []utils.PlaylistEntry
+		lastSeen     string
+		wantFresh    []string
+		wantLastSeen string
+	}{
+		{"没有新视频", entriesOf("c", "b", "a"), "c", []string{}, "c"},
+		{"最近看到的视频之前的都是新视频", entriesOf("e", "d", "c", "b"), "c", []string{"e", "d"}, "e"},
+		{"最近看到的视频不在列表中", entriesOf("e", "d"), "c", []string{"e", "d"}, "e"},
+		{"第一次检查", entriesOf("b", "a"), "", []string{"b", "a"}, "b"},
+		{"频道没有视频", nil, "c", []string{}, "c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fresh, lastSeen := newUploads(tt.entries, tt.lastSeen)
+			if got := entryIDs(fresh); !slices.Equal(got, tt.wantFresh) || lastSeen != tt.wantLastSeen {
+				t.Errorf("newUploads() = %v, %q, want %v, %q", got, lastSeen, tt.wantFresh, tt.wantLastSeen)
+			}
+		})
+	}
+}
+
+func TestUploadedEntries(t *testing.T) {
+	entries := []utils.PlaylistEntry{
+		{ID: "upcoming", LiveStatus: "is_upcoming"},
+		{ID: "live", LiveStatus: "is_live"},
+		{ID: "was_live", LiveStatus: "was_live"},
+		{ID: "video"},
+	}
+	if got, want := entryIDs(uploadedEntries(entries)), []string{"was_live", "video"}; !slices.Equal(got, want) {
+		t.Errorf("uploadedEntries() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/difyz9/ytb2bili/internal/chain_task/manager"
+)
+
+// bilibiliChunkPattern 匹配 bilibili-go-sdk 每个分块上传完成时的日志，例如 "✅ Chunk 3/10 uploaded successfully (30.0% complete)"
+var bilibiliChunkPattern = regexp.MustCompile(`Chunk (\d+)/(\d+) uploaded successfully`)
+
+// bilibiliUploads SDK 没有提供上传进度回调，分块进度从 SDK 写入标准库 log 的日志中解析
+var bilibiliUploads = &bilibiliUploadProgress{}
+
+// bilibiliUploadProgress 记录正在上传视频文件的任务，将 SDK 日志中的分块进度发布给对应任务
+// 日志中没有视频信息，只有一个视频在上传时才能确定进度所属的任务，多个视频同时上传时不发布分块进度
+type bilibiliUploadProgress struct {
+	once   sync.Once
+	mutex  sync.Mutex
+	active map[*UploadToBilibili]struct{}
+	output io.Writer // 标准库 log 原来的输出
+}
+
+// track 开始记录任务的分块上传进度，上传结束后必须调用返回的函数
+func (p *bilibiliUploadProgress) track(task *UploadToBilibili) func() {
+	p.once.Do(func() {
+		p.active = make(map[*UploadToBilibili]struct{})
+		p.output = log.Writer()
+		log.SetOutput(p)
+	})
+
+	p.mutex.Lock()
+	p.active[task] = struct{}{}
+	p.mutex.Unlock()
+
+	return func() {
+		p.mutex.Lock()
+		delete(p.active, task)
+		p.mutex.Unlock()
+	}
+}
+
+// Write 将日志写入原来的输出，并解析其中的分块进度
+func (p *bilibiliUploadProgress) Write(b []byte) (int, error) {
+	if match := bilibiliChunkPattern.FindSubmatch(b); match != nil {
+		var task *UploadToBilibili
+		p.mutex.Lock()
+		if len(p.active) == 1 {
+			for active := range p.active {
+				task = active
+			}
+		}
+		p.mutex.Unlock()
+
+		if task != nil {
+			current, _ := strconv.Atoi(string(match[1]))
+			total, _ := strconv.Atoi(string(match[2]))
+			task.ReportProgressCount(current, total, manager.ProgressUnitChunks, fmt.Sprintf("已上传 %d/%d 个分块", current, total))
+		}
+	}
+	return p.output.Write(b)
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -76,33 +77,52 @@ func NewDeepSeekClient(apiKey string) *DeepSeekClient {
 	}
 }
 
-// ChatCompletion 执行对话补全（带重试机制）
-func (c *DeepSeekClient) ChatCompletion(systemPrompt, userPrompt string) (string, error) {
+// ChatCompletion 执行对话补全（带重试机制），ctx 被取消时中止请求和重试等待
+func (c *DeepSeekClient) ChatCompletion(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
 	var lastErr error
 
 	for attempt := 0; attempt <= c.MaxRetries; attempt++ {
 		if attempt > 0 {
-			time.Sleep(c.RetryDelay * time.Duration(attempt))
+			if err := sleepContext(ctx, c.RetryDelay*time.Duration(attempt)); err != nil {
+				return "", err
+			}
 		}
 
-		result, err := c.doRequest(systemPrompt, userPrompt)
+		result, err := c.doRequest(ctx, systemPrompt, userPrompt)
 		if err == nil {
 			return result, nil
 		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
 
 		lastErr = err
 
 		// 如果是API限制错误，延长等待时间
 		if strings.Contains(err.Error(), "rate limit") || strings.Contains(err.Error(), "429") {
-			time.Sleep(time.Duration(attempt+1) * 5 * time.Second)
+			if err := sleepContext(ctx, time.Duration(attempt+1)*5*time.Second); err != nil {
+				return "", err
+			}
 		}
 	}
 
 	return "", fmt.Errorf("重试 %d 次后仍然失败: %v", c.MaxRetries, lastErr)
 }
 
+// sleepContext 等待指定时长，ctx 被取消时提前返回错误
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // doRequest 执行单次API请求
-func (c *DeepSeekClient) doRequest(systemPrompt, userPrompt string) (string, error) {
+func (c *DeepSeekClient) doRequest(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
 	request := DeepSeekRequest{
 		Model: "deepseek-chat",
 		Messages: []DeepSeekMessage{
@@ -127,7 +147,7 @@ func (c *DeepSeekClient) doRequest(systemPrompt, userPrompt string) (string, err
 		return "", fmt.Errorf("序列化请求失败: %v", err)
 	}
 
-	req, err := http.NewRequest("POST", c.BaseURL, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", fmt.Errorf("创建请求失败: %v", err)
 	}
@@ -163,7 +183,7 @@ func (c *DeepSeekClient) doRequest(systemPrompt, userPrompt string) (string, err
 }
 
 // ChatCompletionWithUsage 执行对话补全并返回使用量统计
-func (c *DeepSeekClient) ChatCompletionWithUsage(systemPrompt, userPrompt string) (string, *DeepSeekUsage, error) {
+func (c *DeepSeekClient) ChatCompletionWithUsage(ctx context.Context, systemPrompt, userPrompt string) (string, *DeepSeekUsage, error) {
 	request := DeepSeekRequest{
 		Model: "deepseek-chat",
 		Messages: []DeepSeekMessage{
@@ -184,7 +204,7 @@ func (c *DeepSeekClient) ChatCompletionWithUsage(systemPrompt, userPrompt string
 		return "", nil, fmt.Errorf("序列化请求失败: %v", err)
 	}
 
-	req, err := http.NewRequest("POST", c.BaseURL, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", nil, fmt.Errorf("创建请求失败: %v", err)
 	}
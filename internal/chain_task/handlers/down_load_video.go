@@ -2,23 +2,31 @@ package handlers
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/difyz9/ytb2bili/internal/chain_task/base"
 	"github.com/difyz9/ytb2bili/internal/chain_task/manager"
 	"github.com/difyz9/ytb2bili/internal/core"
+	"github.com/difyz9/ytb2bili/internal/core/types"
 	"github.com/difyz9/ytb2bili/internal/core/services"
 	"github.com/difyz9/ytb2bili/pkg/cos"
 	"github.com/difyz9/ytb2bili/pkg/utils"
 	"gorm.io/gorm"
 )
 
+// ytDlpProgressPattern 匹配 yt-dlp 的下载进度行，例如 "[download]  45.3% of 100.00MiB at 2.00MiB/s ETA 00:30"
+var ytDlpProgressPattern = regexp.MustCompile(`^\[download\]\s+(\d+(?:\.\d+)?)%`)
+
 type DownloadVideo struct {
 	base.BaseTask
 	App               *core.AppServer
@@ -47,12 +55,12 @@ func (t *DownloadVideo) findYtDlp() (string, error) {
 	}
 
 	// 创建 yt-dlp 管理器
-	manager := utils.NewYtDlpManager(t.App.Logger, installDir)
+	manager := utils.NewYtDlpManager(t.Logger(), installDir)
 
 	// 检查是否已安装
 	if manager.IsInstalled() {
 		path := manager.GetBinaryPath()
-		t.App.Logger.Debugf("找到 yt-dlp: %s", path)
+		t.Logger().Debugf("找到 yt-dlp: %s", path)
 		return path, nil
 	}
 
@@ -82,24 +90,29 @@ func (t *DownloadVideo) getVideoURL() string {
 	return fmt.Sprintf("https://www.youtube.com/watch?v=%s", videoID)
 }
 
-func (t *DownloadVideo) Execute(context map[string]interface{}) bool {
-	t.App.Logger.Info("========================================")
-	t.App.Logger.Info("DownloadVideo Handler Version: with-cookies-support-v3") // 版本标记
-	t.App.Logger.Infof("开始下载视频: %s", t.StateManager.VideoID)
-	t.App.Logger.Info("========================================")
+// GetResourceClass 视频下载受网络带宽限制
+func (t *DownloadVideo) GetResourceClass() types.ResourceClass {
+	return types.ResourceClassNetwork
+}
+
+func (t *DownloadVideo) Execute(ctx context.Context, context map[string]interface{}) bool {
+	t.Logger().Info("========================================")
+	t.Logger().Info("DownloadVideo Handler Version: with-cookies-support-v3") // 版本标记
+	t.Logger().Infof("开始下载视频: %s", t.StateManager.VideoID)
+	t.Logger().Info("========================================")
 
 	// 1. 查找 yt-dlp 可执行文件
 	ytdlpPath, err := t.findYtDlp()
 	if err != nil {
-		t.App.Logger.Errorf("❌ %v", err)
-		context["error"] = err.Error()
+		t.Logger().Errorf("❌ %v", err)
+		types.SetTaskError(context, types.NewTaskError(types.ErrCodeToolMissing, err.Error()).WithMessage("%v", err))
 		return false
 	}
 
 	// 2. 确保下载目录存在
 	if err := os.MkdirAll(t.StateManager.CurrentDir, 0755); err != nil {
-		t.App.Logger.Errorf("❌ 创建下载目录失败: %v", err)
-		context["error"] = err.Error()
+		t.Logger().Errorf("❌ 创建下载目录失败: %v", err)
+		types.SetTaskError(context, types.NewTaskError(types.ErrCodeIO, err.Error()).WithMessage("创建下载目录失败: %v", err))
 		return false
 	}
 
@@ -110,26 +123,31 @@ func (t *DownloadVideo) Execute(context map[string]interface{}) bool {
 
 	// 第一次尝试：使用代理（如果配置了）
 	if useProxy {
-		t.App.Logger.Info("🔄 尝试使用代理下载...")
-		if t.executeDownload(ytdlpPath, videoURL, true, context) {
+		t.Logger().Info("🔄 尝试使用代理下载...")
+		if t.executeDownload(ctx, ytdlpPath, videoURL, true, context) {
 			return true
 		}
-		t.App.Logger.Warn("⚠️ 代理下载失败，尝试不使用代理重试...")
+		if ctx.Err() != nil {
+			return false
+		}
+		t.Logger().Warn("⚠️ 代理下载失败，尝试不使用代理重试...")
 	}
 
 	// 第二次尝试：不使用代理
-	t.App.Logger.Info("🔄 尝试不使用代理下载...")
-	return t.executeDownload(ytdlpPath, videoURL, false, context)
+	t.Logger().Info("🔄 尝试不使用代理下载...")
+	return t.executeDownload(ctx, ytdlpPath, videoURL, false, context)
 }
 
 // executeDownload 执行实际的下载操作
-func (t *DownloadVideo) executeDownload(ytdlpPath, videoURL string, useProxy bool, context map[string]interface{}) bool {
+// ctx 被取消时会终止 yt-dlp 进程
+func (t *DownloadVideo) executeDownload(ctx context.Context, ytdlpPath, videoURL string, useProxy bool, context map[string]interface{}) bool {
 	// 构建下载命令
 	command := []string{
 		ytdlpPath,
 		"-P", t.StateManager.CurrentDir,
 		"-o", "%(id)s.%(ext)s",
 		"--merge-output-format", "mp4",
+		"--newline", // 每次进度更新输出一行，用于解析下载进度
 	}
 
 	// 检查是否存在 cookies.txt
@@ -144,66 +162,87 @@ func (t *DownloadVideo) executeDownload(ytdlpPath, videoURL string, useProxy boo
 	if _, err := os.Stat(cookiesPath); err == nil {
 		absPath, _ := filepath.Abs(cookiesPath)
 		command = append(command, "--cookies", absPath)
-		t.App.Logger.Infof("🍪 使用 Cookies 文件: %s", absPath)
+		t.Logger().Infof("🍪 使用 Cookies 文件: %s", absPath)
 	} else {
 		// 如果没有 cookies 文件，尝试从浏览器读取（Chrome 优先）
-		t.App.Logger.Info("🍪 未找到 cookies 文件，尝试从浏览器读取...")
+		t.Logger().Info("🍪 未找到 cookies 文件，尝试从浏览器读取...")
 		command = append(command, "--cookies-from-browser", "chrome")
-		t.App.Logger.Info("🍪 将从 Chrome 浏览器读取 cookies")
-		t.App.Logger.Warn("⚠️ 未找到 cookies.txt，可能会遇到 'Sign in to confirm you're not a bot' 错误")
+		t.Logger().Info("🍪 将从 Chrome 浏览器读取 cookies")
+		t.Logger().Warn("⚠️ 未找到 cookies.txt，可能会遇到 'Sign in to confirm you're not a bot' 错误")
 	}
 
 	// 添加代理配置（如果需要）
 	if useProxy && t.App.Config != nil && t.App.Config.ProxyConfig != nil && 
 		t.App.Config.ProxyConfig.UseProxy && t.App.Config.ProxyConfig.ProxyHost != "" {
 		command = append(command, "--proxy", t.App.Config.ProxyConfig.ProxyHost)
-		t.App.Logger.Infof("📡 使用代理: %s", t.App.Config.ProxyConfig.ProxyHost)
+		t.Logger().Infof("📡 使用代理: %s", t.App.Config.ProxyConfig.ProxyHost)
 	} else if !useProxy {
-		t.App.Logger.Info("🌐 不使用代理")
+		t.Logger().Info("🌐 不使用代理")
 	}
 
 	// 添加视频标识符和URL
 	command = append(command, "--", t.StateManager.VideoID)
 	command = append(command, videoURL)
 
-	t.App.Logger.Infof("执行命令: %s", strings.Join(command, " "))
-	t.App.Logger.Infof("下载目录: %s", t.StateManager.CurrentDir)
-	t.App.Logger.Infof("视频URL: %s", videoURL)
+	t.Logger().Infof("执行命令: %s", strings.Join(command, " "))
+	t.Logger().Infof("下载目录: %s", t.StateManager.CurrentDir)
+	t.Logger().Infof("视频URL: %s", videoURL)
 
 	// 创建命令并设置输出管道
-	cmd := exec.Command(command[0], command[1:]...)
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
 	cmd.Dir = t.StateManager.CurrentDir
 
 	// 捕获标准输出和标准错误
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		t.App.Logger.Errorf("❌ 创建标准输出管道失败: %v", err)
-		context["error"] = err.Error()
+		t.Logger().Errorf("❌ 创建标准输出管道失败: %v", err)
+		types.SetTaskError(context, types.NewTaskError(types.ErrCodeIO, err.Error()).WithMessage("%v", err))
 		return false
 	}
 
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
-		t.App.Logger.Errorf("❌ 创建标准错误管道失败: %v", err)
-		context["error"] = err.Error()
+		t.Logger().Errorf("❌ 创建标准错误管道失败: %v", err)
+		types.SetTaskError(context, types.NewTaskError(types.ErrCodeIO, err.Error()).WithMessage("%v", err))
 		return false
 	}
 
 	// 启动命令
 	if err := cmd.Start(); err != nil {
-		t.App.Logger.Errorf("❌ 启动下载命令失败: %v", err)
-		context["error"] = err.Error()
+		t.Logger().Errorf("❌ 启动下载命令失败: %v", err)
+		types.SetTaskError(context, types.ClassifyError(err.Error()).WithMessage("启动下载命令失败: %v", err))
 		return false
 	}
 
-	// 实时读取输出
-	go t.logOutput(stdout, "INFO")
-	go t.logOutput(stderr, "ERROR")
+	// 实时读取输出，stderr 中的 ERROR 行用于识别失败原因
+	// 必须读完输出后再调用 Wait，否则 Wait 关闭管道时可能丢失最后的错误信息
+	var wg sync.WaitGroup
+	var errorLines []string
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		t.logOutput(stdout, "INFO")
+	}()
+	go func() {
+		defer wg.Done()
+		errorLines = t.logOutput(stderr, "ERROR")
+	}()
+	wg.Wait()
 
 	// 等待命令完成
 	if err := cmd.Wait(); err != nil {
-		t.App.Logger.Errorf("❌ 视频下载失败: %v", err)
-		context["error"] = fmt.Sprintf("下载失败: %v", err)
+		if ctx.Err() != nil {
+			t.Logger().Warnf("⏹️ 视频下载已取消: %s", t.StateManager.VideoID)
+			context["error"] = "下载已取消"
+			return false
+		}
+		taskErr := ParseYtDlpError(errorLines)
+		if taskErr == nil {
+			taskErr = types.ClassifyError(err.Error())
+		}
+		taskErr.WithMessage("下载失败: %s", taskErr.Message)
+		t.Logger().Errorf("❌ 视频下载失败 [%s]: %v", taskErr.Code, err)
+		types.SetTaskError(context, taskErr)
 		return false
 	}
 
@@ -211,26 +250,29 @@ func (t *DownloadVideo) executeDownload(ytdlpPath, videoURL string, useProxy boo
 	downloadedFile := t.findDownloadedFile()
 	if downloadedFile == "" {
 		errMsg := "下载完成但未找到视频文件"
-		t.App.Logger.Error("❌ " + errMsg)
-		context["error"] = errMsg
+		t.Logger().Error("❌ " + errMsg)
+		types.SetTaskError(context, types.NewTaskError(types.ErrCodeUnknown, errMsg).WithMessage("%s", errMsg))
 		return false
 	}
 
 	// 11. 保存文件信息到 context
-	context["downloaded_file"] = downloadedFile
-	t.App.Logger.Infof("✓ 视频下载成功: %s", downloadedFile)
+	context[manager.KeyDownloadedFile] = downloadedFile
+	t.Logger().Infof("✓ 视频下载成功: %s", downloadedFile)
 
 	// 12. 获取视频元数据（标题、描述等）
-	t.App.Logger.Info("📋 获取视频元数据...")
-	metadata, err := t.getVideoMetadata(ytdlpPath)
+	t.Logger().Info("📋 获取视频元数据...")
+	metadata, err := t.getVideoMetadata(ctx, ytdlpPath)
 	if err != nil {
-		t.App.Logger.Warnf("⚠️ 获取视频元数据失败: %v，将使用默认值", err)
+		t.Logger().Warnf("⚠️ 获取视频元数据失败: %v，将使用默认值", err)
 	} else {
-		context["original_title"] = metadata.Title
-		context["original_description"] = metadata.Description
-		t.App.Logger.Infof("✓ 原始标题: %s", metadata.Title)
+		context[manager.KeyOriginalTitle] = metadata.Title
+		context[manager.KeyOriginalDescription] = metadata.Description
+		if metadata.Duration > 0 {
+			context[manager.VideoDurationKey] = metadata.Duration
+		}
+		t.Logger().Infof("✓ 原始标题: %s", metadata.Title)
 		if metadata.Description != "" {
-			t.App.Logger.Infof("✓ 原始描述: %s", t.truncateString(metadata.Description, 100))
+			t.Logger().Infof("✓ 原始描述: %s", t.truncateString(metadata.Description, 100))
 		}
 
 		// 保存到数据库
@@ -239,49 +281,60 @@ func (t *DownloadVideo) executeDownload(ytdlpPath, videoURL string, useProxy boo
 			if err == nil {
 				savedVideo.Title = metadata.Title
 				savedVideo.Description = metadata.Description
+				savedVideo.Duration = metadata.Duration
 				if err := t.SavedVideoService.UpdateVideo(savedVideo); err != nil {
-					t.App.Logger.Errorf("❌ 保存原始元数据到数据库失败: %v", err)
+					t.Logger().Errorf("❌ 保存原始元数据到数据库失败: %v", err)
 				} else {
-					t.App.Logger.Info("✅ 原始元数据已保存到数据库")
+					t.Logger().Info("✅ 原始元数据已保存到数据库")
 				}
 			}
 		}
 	}
 
-	t.App.Logger.Info("========================================")
+	t.Logger().Info("========================================")
 
 	return true
 }
 
-// logOutput 实时输出日志
-func (t *DownloadVideo) logOutput(reader io.Reader, level string) {
+// logOutput 实时输出日志，返回 yt-dlp 输出的 ERROR 行
+func (t *DownloadVideo) logOutput(reader io.Reader, level string) []string {
+	var errorLines []string
 	scanner := bufio.NewScanner(reader)
 	for scanner.Scan() {
 		line := scanner.Text()
 		if line == "" {
 			continue
 		}
+		if isYtDlpErrorLine(line) {
+			errorLines = append(errorLines, line)
+		}
 
 		// 解析进度信息
 		if strings.Contains(line, "[download]") {
 			if strings.Contains(line, "Destination:") {
-				t.App.Logger.Infof("📥 %s", line)
+				t.Logger().Infof("📥 %s", line)
 			} else if strings.Contains(line, "%") {
 				// 进度信息，使用 Debug 级别避免日志过多
-				t.App.Logger.Debugf("⏳ %s", line)
+				t.Logger().Debugf("⏳ %s", line)
+				if match := ytDlpProgressPattern.FindStringSubmatch(line); match != nil {
+					if percent, err := strconv.ParseFloat(match[1], 64); err == nil {
+						t.ReportProgress(percent, strings.TrimSpace(strings.TrimPrefix(line, "[download]")))
+					}
+				}
 			} else {
-				t.App.Logger.Infof("📥 %s", line)
+				t.Logger().Infof("📥 %s", line)
 			}
 		} else if strings.Contains(line, "[ffmpeg]") {
-			t.App.Logger.Infof("🔄 %s", line)
+			t.Logger().Infof("🔄 %s", line)
 		} else {
 			if level == "ERROR" {
-				t.App.Logger.Warnf("⚠️  %s", line)
+				t.Logger().Warnf("⚠️  %s", line)
 			} else {
-				t.App.Logger.Debugf("%s", line)
+				t.Logger().Debugf("%s", line)
 			}
 		}
 	}
+	return errorLines
 }
 
 // findDownloadedFile 查找下载的视频文件
@@ -329,7 +382,7 @@ type VideoMetadataInfo struct {
 }
 
 // getVideoMetadata 使用 yt-dlp 获取视频元数据（带代理回退）
-func (t *DownloadVideo) getVideoMetadata(ytdlpPath string) (*VideoMetadataInfo, error) {
+func (t *DownloadVideo) getVideoMetadata(ctx context.Context, ytdlpPath string) (*VideoMetadataInfo, error) {
 	videoURL := t.getVideoURL()
 
 	// 构建基础命令参数
@@ -345,11 +398,11 @@ func (t *DownloadVideo) getVideoMetadata(ytdlpPath string) (*VideoMetadataInfo,
 	if _, err := os.Stat(cookiesPath); err == nil {
 		absPath, _ := filepath.Abs(cookiesPath)
 		args = append(args, "--cookies", absPath)
-		t.App.Logger.Debugf("🍪 使用 Cookies 文件获取元数据: %s", absPath)
+		t.Logger().Debugf("🍪 使用 Cookies 文件获取元数据: %s", absPath)
 	} else {
 		// 从浏览器读取 cookies
 		args = append(args, "--cookies-from-browser", "chrome")
-		t.App.Logger.Debug("🍪 从 Chrome 浏览器读取 cookies 获取元数据")
+		t.Logger().Debug("🍪 从 Chrome 浏览器读取 cookies 获取元数据")
 	}
 	
 	// 尝试使用代理
@@ -358,25 +411,25 @@ func (t *DownloadVideo) getVideoMetadata(ytdlpPath string) (*VideoMetadataInfo,
 	
 	if useProxy {
 		args = append(args, "--proxy", t.App.Config.ProxyConfig.ProxyHost)
-		t.App.Logger.Debugf("📡 使用代理获取元数据: %s", t.App.Config.ProxyConfig.ProxyHost)
+		t.Logger().Debugf("📡 使用代理获取元数据: %s", t.App.Config.ProxyConfig.ProxyHost)
 	}
 	
 	args = append(args, videoURL)
 	
 	// 第一次尝试（可能带代理）
-	cmd := exec.Command(ytdlpPath, args...)
+	cmd := exec.CommandContext(ctx, ytdlpPath, args...)
 	output, err := cmd.Output()
 	
 	// 如果使用代理失败，尝试不使用代理
-	if err != nil && useProxy {
-		t.App.Logger.Warnf("⚠️ 使用代理获取元数据失败，尝试不使用代理...")
+	if err != nil && useProxy && ctx.Err() == nil {
+		t.Logger().Warnf("⚠️ 使用代理获取元数据失败，尝试不使用代理...")
 		argsNoProxy := []string{"--dump-json", "--no-download", videoURL}
-		cmd = exec.Command(ytdlpPath, argsNoProxy...)
+		cmd = exec.CommandContext(ctx, ytdlpPath, argsNoProxy...)
 		output, err = cmd.Output()
 		if err != nil {
 			return nil, fmt.Errorf("获取元数据失败: %v", err)
 		}
-		t.App.Logger.Info("✓ 不使用代理成功获取元数据")
+		t.Logger().Info("✓ 不使用代理成功获取元数据")
 	} else if err != nil {
 		return nil, fmt.Errorf("获取元数据失败: %v", err)
 	}
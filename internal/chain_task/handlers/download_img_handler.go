@@ -4,10 +4,11 @@ import (
 	"github.com/difyz9/ytb2bili/internal/chain_task/base"
 	"github.com/difyz9/ytb2bili/internal/chain_task/manager"
 	"github.com/difyz9/ytb2bili/internal/core"
+	"github.com/difyz9/ytb2bili/internal/core/types"
 	"github.com/difyz9/ytb2bili/internal/core/models"
 	"github.com/difyz9/ytb2bili/pkg/cos"
 	"github.com/difyz9/ytb2bili/pkg/utils"
-	"fmt"
+	"context"
 	"gorm.io/gorm"
 	"time"
 )
@@ -30,7 +31,16 @@ func NewDownloadImgHandler(name string, app *core.AppServer, stateManager *manag
 
 }
 
-func (t *DownloadImgHandler) Execute(context map[string]interface{}) bool {
+// GetResourceClass 封面下载受网络带宽限制
+func (t *DownloadImgHandler) GetResourceClass() types.ResourceClass {
+	return types.ResourceClassNetwork
+}
+
+func (t *DownloadImgHandler) Execute(ctx context.Context, context map[string]interface{}) bool {
+	if ctx.Err() != nil {
+		context["error"] = "任务已取消"
+		return false
+	}
 
 	opt := utils.DownloadOptions{
 		SavePath:         t.StateManager.CurrentDir,
@@ -50,14 +60,14 @@ func (t *DownloadImgHandler) Execute(context map[string]interface{}) bool {
 
 	for k, v := range results {
 		if v.Success {
-			fmt.Printf("下载成功: %s - %s (%d bytes)\n", k, v.FilePath, v.FileSize)
+			t.Logger().Infof("下载成功: %s - %s (%d bytes)", k, v.FilePath, v.FileSize)
 			cosKeyName, _ := t.Client.UploadImageToCOS(v.FilePath, "")
 
 			// 如果是最高质量的封面，保存到context中供后续上传使用
 			if k == string(utils.QualityMax) {
 				maxQualityCoverPath = v.FilePath
-				context["cover_image_path"] = v.FilePath
-				t.App.Logger.Infof("✓ 最高质量封面已下载: %s", v.FilePath)
+				context[manager.KeyCoverImagePath] = v.FilePath
+				t.Logger().Infof("✓ 最高质量封面已下载: %s", v.FilePath)
 			}
 
 			// 更新数据库记录
@@ -72,7 +82,7 @@ func (t *DownloadImgHandler) Execute(context map[string]interface{}) bool {
 
 			}
 		} else {
-			fmt.Printf("下载失败: %s - %s\n", k, v.ErrorMessage)
+			t.Logger().Warnf("下载失败: %s - %s", k, v.ErrorMessage)
 		}
 	}
 
@@ -80,8 +90,8 @@ func (t *DownloadImgHandler) Execute(context map[string]interface{}) bool {
 	if maxQualityCoverPath == "" {
 		for _, v := range results {
 			if v.Success {
-				context["cover_image_path"] = v.FilePath
-				t.App.Logger.Infof("✓ 备用质量封面已设置: %s", v.FilePath)
+				context[manager.KeyCoverImagePath] = v.FilePath
+				t.Logger().Infof("✓ 备用质量封面已设置: %s", v.FilePath)
 				break
 			}
 		}
@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/difyz9/ytb2bili/internal/chain_task/base"
+	"github.com/difyz9/ytb2bili/internal/chain_task/manager"
+	"github.com/difyz9/ytb2bili/internal/core"
+	"github.com/difyz9/ytb2bili/internal/core/types"
+	"github.com/difyz9/ytb2bili/pkg/cos"
+)
+
+// 传给外部命令的环境变量
+const (
+	ExecEnvStep        = "YTB2BILI_STEP"         // 步骤名称
+	ExecEnvVideoID     = "YTB2BILI_VIDEO_ID"     // 视频ID
+	ExecEnvWorkDir     = "YTB2BILI_WORK_DIR"     // 视频工作目录
+	ExecEnvVideo       = "YTB2BILI_VIDEO"        // 视频文件
+	ExecEnvSRT         = "YTB2BILI_SRT"          // 原始字幕
+	ExecEnvZhSRT       = "YTB2BILI_ZH_SRT"       // 中文字幕
+	ExecEnvCover       = "YTB2BILI_COVER"        // 封面图片
+	ExecEnvContextFile = "YTB2BILI_CONTEXT_FILE" // 任务链上下文（JSON 文件，只读）
+	ExecEnvOutputFile  = "YTB2BILI_OUTPUT_FILE"  // 命令写入 JSON 对象，成功后合并到任务链上下文
+)
+
+// execTempFailExitCode 默认视为临时失败（可重试）的退出码，即 sysexits.h 中的 EX_TEMPFAIL
+const execTempFailExitCode = 75
+
+// maxExecErrorLines 失败时保留在错误详情中的输出行数
+const maxExecErrorLines = 20
+
+// ExecHandler 执行外部命令的任务步骤，用于在内置步骤之间插入自定义处理（片头、质检脚本等）
+// 视频、字幕、封面等路径通过环境变量传给命令，命令的输出写入步骤日志；
+// 退出码为 0 表示成功，退出码在 RetryExitCodes 中时按临时错误自动重试，其他退出码视为永久失败
+type ExecHandler struct {
+	base.BaseTask
+	App            *core.AppServer
+	Command        []string            // 命令及参数
+	Dir            string              // 工作目录，为空时使用视频工作目录
+	Env            map[string]string   // 额外的环境变量
+	Resource       types.ResourceClass // 资源类别，调度器按类别限制并发数
+	RetryExitCodes []int               // 可重试的退出码
+}
+
+func NewExecHandler(name string, app *core.AppServer, stateManager *manager.StateManager, client *cos.CosClient, command []string) *ExecHandler {
+	return &ExecHandler{
+		BaseTask: base.BaseTask{
+			Name:         name,
+			StateManager: stateManager,
+			Client:       client,
+		},
+		App:            app,
+		Command:        command,
+		Resource:       types.ResourceClassDefault,
+		RetryExitCodes: []int{execTempFailExitCode},
+	}
+}
+
+// ShellCommand 将命令行字符串包装为通过系统 shell 执行的命令
+func ShellCommand(command string) []string {
+	if runtime.GOOS == "windows" {
+		return []string{"cmd", "/C", command}
+	}
+	return []string{"sh", "-c", command}
+}
+
+// GetResourceClass 外部命令的资源类别由流水线配置决定
+func (t *ExecHandler) GetResourceClass() types.ResourceClass {
+	return t.Resource
+}
+
+func (t *ExecHandler) Execute(ctx context.Context, context map[string]interface{}) bool {
+	if len(t.Command) == 0 {
+		types.SetTaskError(context, types.NewTaskError(types.ErrCodeNotConfigured, "").WithMessage("步骤 %s 未配置要执行的命令", t.Name))
+		return false
+	}
+
+	contextFile, err := t.writeContextFile(context)
+	if err != nil {
+		types.SetTaskError(context, types.NewTaskError(types.ErrCodeIO, err.Error()).WithMessage("写入上下文文件失败: %v", err))
+		return false
+	}
+	defer os.Remove(contextFile)
+	outputFile := strings.TrimSuffix(contextFile, ".json") + ".output.json"
+	defer os.Remove(outputFile)
+
+	cmd := exec.CommandContext(ctx, t.Command[0], t.Command[1:]...)
+	cmd.Dir = t.Dir
+	if cmd.Dir == "" {
+		cmd.Dir = t.StateManager.CurrentDir
+	}
+	cmd.Env = append(os.Environ(), t.environ(context, contextFile, outputFile)...)
+	// 命令被取消后，等待其子进程关闭输出管道的最长时间
+	cmd.WaitDelay = 10 * time.Second
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		types.SetTaskError(context, types.NewTaskError(types.ErrCodeIO, err.Error()).WithMessage("%v", err))
+		return false
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		types.SetTaskError(context, types.NewTaskError(types.ErrCodeIO, err.Error()).WithMessage("%v", err))
+		return false
+	}
+
+	t.Logger().Infof("▶️ [%s] 执行命令: %s", t.Name, strings.Join(t.Command, " "))
+	if err := cmd.Start(); err != nil {
+		t.Logger().Errorf("❌ [%s] 启动命令失败: %v", t.Name, err)
+		types.SetTaskError(context, types.ClassifyError(err.Error()).WithMessage("启动命令失败: %v", err))
+		return false
+	}
+
+	// 必须读完输出后再调用 Wait，否则 Wait 关闭管道时可能丢失最后的输出
+	output := &execOutput{}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		t.logOutput(stdout, "stdout", output)
+	}()
+	go func() {
+		defer wg.Done()
+		t.logOutput(stderr, "stderr", output)
+	}()
+	wg.Wait()
+
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			t.Logger().Warnf("⏹️ [%s] 命令已终止: %v", t.Name, ctx.Err())
+			context["error"] = fmt.Sprintf("命令已终止: %v", ctx.Err())
+			return false
+		}
+
+		taskErr := t.exitError(err, output.tail())
+		t.Logger().Errorf("❌ [%s] 命令执行失败 [%s]: %v", t.Name, taskErr.Code, err)
+		types.SetTaskError(context, taskErr)
+		return false
+	}
+
+	// 合并命令输出的 JSON 对象
+	values, err := readExecOutput(outputFile)
+	if err != nil {
+		t.Logger().Errorf("❌ [%s] 解析命令输出失败: %v", t.Name, err)
+		types.SetTaskError(context, types.NewTaskError(types.ErrCodeInvalidInput, err.Error()).WithMessage("解析命令输出的 JSON 失败: %v", err))
+		return false
+	}
+	manager.PipelineContext(context).Merge(values)
+
+	t.Logger().Infof("✅ [%s] 命令执行成功，输出 %d 个上下文字段", t.Name, len(values))
+	return true
+}
+
+// environ 生成传给命令的环境变量，前面步骤的输出优先于默认路径
+func (t *ExecHandler) environ(context map[string]interface{}, contextFile, outputFile string) []string {
+	pipelineContext := manager.PipelineContext(context)
+	firstNonEmpty := func(values ...string) string {
+		for _, v := range values {
+			if v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	env := []string{
+		ExecEnvStep + "=" + t.Name,
+		ExecEnvVideoID + "=" + t.StateManager.VideoID,
+		ExecEnvWorkDir + "=" + t.StateManager.CurrentDir,
+		ExecEnvVideo + "=" + firstNonEmpty(pipelineContext.DownloadedFile(), t.StateManager.InputVideoPath),
+		ExecEnvSRT + "=" + firstNonEmpty(pipelineContext.SubtitlePath(), t.StateManager.OriginalSRT),
+		ExecEnvZhSRT + "=" + firstNonEmpty(pipelineContext.ZhSRTPath(), t.StateManager.TranslateSRT),
+		ExecEnvCover + "=" + firstNonEmpty(pipelineContext.CoverImagePath(), t.StateManager.ImageCover),
+		ExecEnvContextFile + "=" + contextFile,
+		ExecEnvOutputFile + "=" + outputFile,
+	}
+	for k, v := range t.Env {
+		env = append(env, k+"="+v)
+	}
+	return env
+}
+
+// writeContextFile 将任务链上下文写入工作目录下的临时 JSON 文件供命令读取
+func (t *ExecHandler) writeContextFile(context map[string]interface{}) (string, error) {
+	values := make(map[string]interface{}, len(context))
+	for k, v := range context {
+		if k != "error" && k != types.ErrorInfoKey {
+			values[k] = v
+		}
+	}
+	data, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(t.StateManager.CurrentDir, 0755); err != nil {
+		return "", err
+	}
+	file, err := os.CreateTemp(t.StateManager.CurrentDir, ".exec-*.json")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if _, err := file.Write(data); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// exitError 根据退出码生成结构化错误，RetryExitCodes 中的退出码可重试
+func (t *ExecHandler) exitError(err error, tail []string) *types.TaskError {
+	detail := strings.Join(tail, "\n")
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return types.ClassifyError(err.Error()).WithMessage("命令执行失败: %v", err)
+	}
+
+	code := exitErr.ExitCode()
+	taskErr := types.NewTaskError(types.ErrCodeCommandFailed, detail).WithMessage("命令执行失败，退出码 %d", code)
+	for _, retryCode := range t.RetryExitCodes {
+		if code == retryCode {
+			taskErr.Retryable = true
+			break
+		}
+	}
+	return taskErr
+}
+
+// readExecOutput 读取命令写入的 JSON 对象，命令没有输出时返回 nil
+// error 字段由任务链管理，不允许命令覆盖
+func readExecOutput(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) || (err == nil && len(strings.TrimSpace(string(data))) == 0) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	delete(values, "error")
+	delete(values, types.ErrorInfoKey)
+	return values, nil
+}
+
+// execOutput 命令的合并输出，保留最后几行用于错误详情
+type execOutput struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (o *execOutput) add(line string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.lines = append(o.lines, line)
+	if len(o.lines) > maxExecErrorLines {
+		o.lines = o.lines[len(o.lines)-maxExecErrorLines:]
+	}
+}
+
+func (o *execOutput) tail() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]string(nil), o.lines...)
+}
+
+// logOutput 逐行读取命令输出并写入步骤日志
+// 单行超过 1MB 或读取出错时停止解析，剩余输出直接丢弃，避免管道写满导致命令阻塞
+func (t *ExecHandler) logOutput(reader io.Reader, stream string, output *execOutput) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		output.add(line)
+		if stream == "stderr" {
+			t.Logger().Warnf("⚠️  [%s] [%s] %s", t.Name, stream, line)
+		} else {
+			t.Logger().Debugf("[%s] [%s] %s", t.Name, stream, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Logger().Warnf("⚠️  [%s] [%s] 读取输出失败，剩余输出不再记录: %v", t.Name, stream, err)
+		io.Copy(io.Discard, reader)
+	}
+}
@@ -1,12 +1,15 @@
 package handlers
 
 import (
-	"fmt"
+	"context"
 	"github.com/difyz9/ytb2bili/internal/chain_task/base"
 	"github.com/difyz9/ytb2bili/internal/chain_task/manager"
 	"github.com/difyz9/ytb2bili/internal/core"
+	"github.com/difyz9/ytb2bili/internal/core/types"
 	"github.com/difyz9/ytb2bili/pkg/cos"
 	"github.com/difyz9/ytb2bili/pkg/utils"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zapio"
 	"gorm.io/gorm"
 )
 
@@ -27,11 +30,24 @@ func NewExtractAudio(name string, app *core.AppServer, stateManager *manager.Sta
 	}
 }
 
-func (t *ExtractAudio) Execute(context map[string]interface{}) bool {
-	fmt.Println("开始分离音频")
-	if err := utils.ExtractWaveAudio(t.StateManager.InputVideoPath, t.StateManager.OriginalMP3); err != nil {
-		fmt.Println("--- 分离音频失败-----")
+// GetResourceClass ffmpeg 音频分离是 CPU 密集型任务
+func (t *ExtractAudio) GetResourceClass() types.ResourceClass {
+	return types.ResourceClassCPU
+}
+
+func (t *ExtractAudio) Execute(ctx context.Context, context map[string]interface{}) bool {
+	t.Logger().Info("开始分离音频")
+	// ffmpeg 的输出逐行写入步骤日志
+	output := &zapio.Writer{Log: t.Logger().Desugar(), Level: zapcore.DebugLevel}
+	err := utils.ExtractWaveAudioContext(ctx, t.StateManager.InputVideoPath, t.StateManager.OriginalMP3, output)
+	output.Close()
+	if err != nil {
+		t.Logger().Errorf("--- 分离音频失败: %v", err)
+		if ctx.Err() != nil {
+			context["error"] = err.Error()
+			return false
+		}
 	}
-	fmt.Println("分离音频完成")
+	t.Logger().Info("分离音频完成")
 	return true
 }
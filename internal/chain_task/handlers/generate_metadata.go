@@ -13,6 +13,7 @@ import (
 	"github.com/difyz9/ytb2bili/internal/chain_task/manager"
 	"github.com/difyz9/ytb2bili/internal/core"
 	"github.com/difyz9/ytb2bili/internal/core/services"
+	"github.com/difyz9/ytb2bili/internal/core/types"
 	"github.com/difyz9/ytb2bili/pkg/cos"
 	"gorm.io/gorm"
 )
@@ -58,71 +59,82 @@ type VideoMetadata struct {
 	Tags        []string `json:"tags"`
 }
 
-func (g *GenerateMetadata) Execute(context map[string]interface{}) bool {
-	g.App.Logger.Info("========================================")
-	g.App.Logger.Infof("开始生成视频标题和描述: VideoID=%s", g.StateManager.VideoID)
-	g.App.Logger.Info("========================================")
+// GetResourceClass 元数据生成受 AI 服务配额限制
+func (g *GenerateMetadata) GetResourceClass() types.ResourceClass {
+	return types.ResourceClassLLM
+}
+
+func (g *GenerateMetadata) Execute(ctx context.Context, context map[string]interface{}) bool {
+	g.Logger().Info("========================================")
+	g.Logger().Infof("开始生成视频标题和描述: VideoID=%s", g.StateManager.VideoID)
+	g.Logger().Info("========================================")
 
 	// 0. 检查是否使用 Gemini
 	useGemini := false
 	if g.App.Config.GeminiConfig != nil && g.App.Config.GeminiConfig.Enabled && g.App.Config.GeminiConfig.UseForMetadata {
 		useGemini = true
-		g.App.Logger.Info("🤖 使用 Gemini 多模态服务生成元数据")
+		g.Logger().Info("🤖 使用 Gemini 多模态服务生成元数据")
 
 		// 如果配置了视频分析，尝试使用视频文件
 		if g.App.Config.GeminiConfig.AnalyzeVideo {
-			if success := g.executeWithGeminiVideo(context); success {
+			if success := g.executeWithGeminiVideo(ctx, context); success {
 				return true
 			}
-			g.App.Logger.Warn("⚠️ Gemini 视频分析失败，回退到文本模式")
+			if ctx.Err() != nil {
+				return false
+			}
+			g.Logger().Warn("⚠️ Gemini 视频分析失败，回退到文本模式")
 		}
 
 		// 使用 Gemini 处理字幕文本
-		if success := g.executeWithGeminiText(context); success {
+		if success := g.executeWithGeminiText(ctx, context); success {
 			return true
 		}
-		g.App.Logger.Warn("⚠️ Gemini 文本分析失败，回退到 DeepSeek")
+		if ctx.Err() != nil {
+			return false
+		}
+		g.Logger().Warn("⚠️ Gemini 文本分析失败，回退到 DeepSeek")
 		useGemini = false
 	}
 
 	// 使用 DeepSeek（默认或回退）
 	if !useGemini {
-		return g.executeWithDeepSeek(context)
+		return g.executeWithDeepSeek(ctx, context)
 	}
 
 	return false
 }
 
 // executeWithDeepSeek 使用 DeepSeek 生成元数据
-func (g *GenerateMetadata) executeWithDeepSeek(context map[string]interface{}) bool {
+func (g *GenerateMetadata) executeWithDeepSeek(ctx context.Context, context map[string]interface{}) bool {
 	// 0. 动态获取最新的DeepSeek客户端
 	client, err := g.getCurrentDeepSeekClient()
 	if err != nil {
-		g.App.Logger.Errorf("❌ %v", err)
+		g.Logger().Errorf("❌ %v", err)
 		// 使用默认值而不是失败
-		context["video_title"] = g.StateManager.VideoID
-		context["video_description"] = "包含字幕的视频"
+		context[manager.KeyVideoTitle] = g.StateManager.VideoID
+		context[manager.KeyVideoDescription] = "包含字幕的视频"
 		return true
 	}
 
-	g.App.Logger.Infof("🔑 使用 DeepSeek 配置生成元数据")
+	g.Logger().Infof("🔑 使用 DeepSeek 配置生成元数据")
 	// 更新当前使用的客户端
 	g.DeepSeekClient = client
 
 	// 1. 检查中文字幕文件是否存在
 	zhSRTPath := filepath.Join(g.StateManager.CurrentDir, "zh.srt")
 	if _, err := os.Stat(zhSRTPath); os.IsNotExist(err) {
-		g.App.Logger.Warn("⚠️  中文字幕文件不存在，使用默认标题和描述")
+		g.Logger().Warn("⚠️  中文字幕文件不存在，使用默认标题和描述")
 		// 使用默认值
-		context["video_title"] = g.StateManager.VideoID
-		context["video_description"] = fmt.Sprintf("包含字幕的视频")
+		context[manager.KeyVideoTitle] = g.StateManager.VideoID
+		context[manager.KeyVideoDescription] = fmt.Sprintf("包含字幕的视频")
 		return true // 没有字幕文件不算失败
 	}
 
 	// 2. 读取中文字幕内容
 	srtContent, err := os.ReadFile(zhSRTPath)
 	if err != nil {
-		g.App.Logger.Errorf("❌ 读取中文字幕文件失败: %v", err)
+		g.Logger().Errorf("❌ 读取中文字幕文件失败: %v", err)
 		context["error"] = "读取翻译字幕失败，请确保字幕翻译步骤已完成"
 		return false
 	}
@@ -130,13 +142,13 @@ func (g *GenerateMetadata) executeWithDeepSeek(context map[string]interface{}) b
 	// 3. 解析字幕提取文本
 	subtitleText := g.extractTextFromSRT(string(srtContent))
 	if subtitleText == "" {
-		g.App.Logger.Warn("⚠️  字幕内容为空，使用默认标题和描述")
-		context["video_title"] = g.StateManager.VideoID
-		context["video_description"] = fmt.Sprintf("包含字幕的视频")
+		g.Logger().Warn("⚠️  字幕内容为空，使用默认标题和描述")
+		context[manager.KeyVideoTitle] = g.StateManager.VideoID
+		context[manager.KeyVideoDescription] = fmt.Sprintf("包含字幕的视频")
 		return true
 	}
 
-	g.App.Logger.Infof("📝 提取到字幕文本，总长度: %d 字符", len(subtitleText))
+	g.Logger().Infof("📝 提取到字幕文本，总长度: %d 字符", len(subtitleText))
 
 	// 4. 截取前1000字符用于生成标题和描述（避免token过多）
 	maxLength := 1000
@@ -145,14 +157,14 @@ func (g *GenerateMetadata) executeWithDeepSeek(context map[string]interface{}) b
 	}
 
 	// 5. 调用 DeepSeek API 生成标题和描述
-	g.App.Logger.Info("🤖 调用 DeepSeek API 生成标题和描述...")
-	metadata, err := g.generateMetadataFromDeepSeek(subtitleText)
+	g.Logger().Info("🤖 调用 DeepSeek API 生成标题和描述...")
+	metadata, err := g.generateMetadataFromDeepSeek(ctx, subtitleText)
 	if err != nil {
-		g.App.Logger.Errorf("❌ 生成标题和描述失败: %v", err)
-		g.App.Logger.Warn("⚠️  将使用默认标题和描述，不影响视频上传")
+		g.Logger().Errorf("❌ 生成标题和描述失败: %v", err)
+		g.Logger().Warn("⚠️  将使用默认标题和描述，不影响视频上传")
 		// 使用默认值
-		context["video_title"] = g.StateManager.VideoID
-		context["video_description"] = fmt.Sprintf("包含字幕的视频")
+		context[manager.KeyVideoTitle] = g.StateManager.VideoID
+		context[manager.KeyVideoDescription] = fmt.Sprintf("包含字幕的视频")
 		return true // API调用失败不算整个任务失败
 	}
 
@@ -160,28 +172,28 @@ func (g *GenerateMetadata) executeWithDeepSeek(context map[string]interface{}) b
 	if len([]rune(metadata.Title)) > 80 {
 		runes := []rune(metadata.Title)
 		metadata.Title = string(runes[:77]) + "..."
-		g.App.Logger.Warnf("⚠️  标题过长，已截断为80字符")
+		g.Logger().Warnf("⚠️  标题过长，已截断为80字符")
 	}
 
 	// 7. 保存到 context
-	context["video_title"] = metadata.Title
-	context["video_description"] = metadata.Description
-	context["video_tags"] = metadata.Tags
+	context[manager.KeyVideoTitle] = metadata.Title
+	context[manager.KeyVideoDescription] = metadata.Description
+	context[manager.KeyVideoTags] = metadata.Tags
 
 	// 8. 保存到 meta.json 文件
-	g.App.Logger.Info("💾 保存元数据到 meta.json 文件...")
+	g.Logger().Info("💾 保存元数据到 meta.json 文件...")
 	if err := g.saveMetadataToFile(metadata); err != nil {
-		g.App.Logger.Errorf("❌ 保存 meta.json 文件失败: %v", err)
+		g.Logger().Errorf("❌ 保存 meta.json 文件失败: %v", err)
 		// 不影响任务继续执行
 	} else {
-		g.App.Logger.Info("✅ meta.json 文件已保存")
+		g.Logger().Info("✅ meta.json 文件已保存")
 	}
 
 	// 9. 保存到数据库
-	g.App.Logger.Info("💾 保存生成的元数据到数据库...")
+	g.Logger().Info("💾 保存生成的元数据到数据库...")
 	savedVideo, err := g.SavedVideoService.GetVideoByVideoID(g.StateManager.VideoID)
 	if err != nil {
-		g.App.Logger.Errorf("❌ 获取视频记录失败: %v", err)
+		g.Logger().Errorf("❌ 获取视频记录失败: %v", err)
 		// 不影响任务继续执行
 	} else {
 		// 更新生成的元数据
@@ -190,19 +202,19 @@ func (g *GenerateMetadata) executeWithDeepSeek(context map[string]interface{}) b
 		savedVideo.GeneratedTags = strings.Join(metadata.Tags, ",")
 
 		if err := g.SavedVideoService.UpdateVideo(savedVideo); err != nil {
-			g.App.Logger.Errorf("❌ 保存元数据到数据库失败: %v", err)
+			g.Logger().Errorf("❌ 保存元数据到数据库失败: %v", err)
 		} else {
-			g.App.Logger.Info("✅ 元数据已保存到数据库")
+			g.Logger().Info("✅ 元数据已保存到数据库")
 		}
 	}
 
 	// 10. 输出生成结果
-	g.App.Logger.Info("========================================")
-	g.App.Logger.Info("✅ 视频元数据生成成功！")
-	g.App.Logger.Infof("📌 标题: %s", metadata.Title)
-	g.App.Logger.Infof("📝 描述: %s", g.truncateString(metadata.Description, 100))
-	g.App.Logger.Infof("🏷️  标签: %v", metadata.Tags)
-	g.App.Logger.Info("========================================")
+	g.Logger().Info("========================================")
+	g.Logger().Info("✅ 视频元数据生成成功！")
+	g.Logger().Infof("📌 标题: %s", metadata.Title)
+	g.Logger().Infof("📝 描述: %s", g.truncateString(metadata.Description, 100))
+	g.Logger().Infof("🏷️  标签: %v", metadata.Tags)
+	g.Logger().Info("========================================")
 
 	return true
 }
@@ -235,7 +247,7 @@ func isNumber(s string) bool {
 }
 
 // generateMetadataFromDeepSeek 调用 DeepSeek API 生成标题和描述
-func (g *GenerateMetadata) generateMetadataFromDeepSeek(subtitleText string) (*VideoMetadata, error) {
+func (g *GenerateMetadata) generateMetadataFromDeepSeek(ctx context.Context, subtitleText string) (*VideoMetadata, error) {
 	prompt := fmt.Sprintf(`请根据以下视频字幕内容，生成一个吸引人的视频标题、详细描述和3-5个相关标签。
 
 字幕内容：
@@ -256,12 +268,12 @@ func (g *GenerateMetadata) generateMetadataFromDeepSeek(subtitleText string) (*V
 请直接返回JSON格式的结果，不要包含任何其他说明文字。`, subtitleText)
 
 	// 使用 DeepSeekClient 调用 API
-	content, usage, err := g.DeepSeekClient.ChatCompletionWithUsage("你是一个专业的视频内容分析助手，擅长根据视频字幕生成吸引人的标题和描述。", prompt)
+	content, usage, err := g.DeepSeekClient.ChatCompletionWithUsage(ctx, "你是一个专业的视频内容分析助手，擅长根据视频字幕生成吸引人的标题和描述。", prompt)
 	if err != nil {
 		return nil, fmt.Errorf("调用 DeepSeek API 失败: %v", err)
 	}
 
-	g.App.Logger.Debugf("DeepSeek 原始返回: %s", content)
+	g.Logger().Debugf("DeepSeek 原始返回: %s", content)
 
 	// 提取JSON部分（可能包含在代码块中）
 	content = strings.TrimSpace(content)
@@ -287,7 +299,7 @@ func (g *GenerateMetadata) generateMetadataFromDeepSeek(subtitleText string) (*V
 
 	// Token使用情况
 	if usage != nil {
-		g.App.Logger.Infof("💰 Token使用: 输入=%d, 输出=%d, 总计=%d",
+		g.Logger().Infof("💰 Token使用: 输入=%d, 输出=%d, 总计=%d",
 			usage.PromptTokens,
 			usage.CompletionTokens,
 			usage.TotalTokens)
@@ -321,7 +333,7 @@ func (g *GenerateMetadata) saveMetadataToFile(metadata *VideoMetadata) error {
 		return fmt.Errorf("写入meta.json文件失败: %v", err)
 	}
 
-	g.App.Logger.Infof("📁 meta.json 文件已保存: %s", metaFilePath)
+	g.Logger().Infof("📁 meta.json 文件已保存: %s", metaFilePath)
 	return nil
 }
 
@@ -335,8 +347,8 @@ func (g *GenerateMetadata) truncateString(s string, maxLen int) string {
 }
 
 // executeWithGeminiVideo 使用 Gemini 分析视频文件生成元数据
-func (g *GenerateMetadata) executeWithGeminiVideo(taskContext map[string]interface{}) bool {
-	g.App.Logger.Info("🎬 使用 Gemini 多模态分析视频文件...")
+func (g *GenerateMetadata) executeWithGeminiVideo(parent context.Context, taskContext map[string]interface{}) bool {
+	g.Logger().Info("🎬 使用 Gemini 多模态分析视频文件...")
 
 	// 1. 创建 Gemini 客户端
 	client, err := NewGeminiClient(
@@ -346,7 +358,7 @@ func (g *GenerateMetadata) executeWithGeminiVideo(taskContext map[string]interfa
 		g.App.Config.GeminiConfig.MaxTokens,
 	)
 	if err != nil {
-		g.App.Logger.Errorf("❌ 创建 Gemini 客户端失败: %v", err)
+		g.Logger().Errorf("❌ 创建 Gemini 客户端失败: %v", err)
 		return false
 	}
 	defer client.Close()
@@ -354,37 +366,37 @@ func (g *GenerateMetadata) executeWithGeminiVideo(taskContext map[string]interfa
 	// 2. 查找视频文件
 	videoFiles := g.findVideoFiles()
 	if len(videoFiles) == 0 {
-		g.App.Logger.Warn("⚠️ 未找到视频文件")
+		g.Logger().Warn("⚠️ 未找到视频文件")
 		return false
 	}
 	videoPath := videoFiles[0]
-	g.App.Logger.Infof("📹 找到视频文件: %s", filepath.Base(videoPath))
+	g.Logger().Infof("📹 找到视频文件: %s", filepath.Base(videoPath))
 
 	// 3. 上传视频到 Gemini
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(g.App.Config.GeminiConfig.Timeout)*time.Second)
+	ctx, cancel := context.WithTimeout(parent, time.Duration(g.App.Config.GeminiConfig.Timeout)*time.Second)
 	defer cancel()
 
-	g.App.Logger.Info("⏫ 上传视频到 Gemini...")
+	g.Logger().Info("⏫ 上传视频到 Gemini...")
 	uploadedFile, err := client.UploadFile(ctx, videoPath, filepath.Base(videoPath))
 	if err != nil {
-		g.App.Logger.Errorf("❌ 上传视频失败: %v", err)
+		g.Logger().Errorf("❌ 上传视频失败: %v", err)
 		return false
 	}
-	g.App.Logger.Infof("✓ 视频上传成功: %s", uploadedFile.Name)
+	g.Logger().Infof("✓ 视频上传成功: %s", uploadedFile.Name)
 
 	// 4. 等待文件处理完成
-	g.App.Logger.Info("⏳ 等待 Gemini 处理视频...")
+	g.Logger().Info("⏳ 等待 Gemini 处理视频...")
 	if err := client.WaitForFileProcessing(ctx, uploadedFile); err != nil {
-		g.App.Logger.Errorf("❌ 视频处理失败: %v", err)
+		g.Logger().Errorf("❌ 视频处理失败: %v", err)
 		return false
 	}
-	g.App.Logger.Info("✓ 视频处理完成")
+	g.Logger().Info("✓ 视频处理完成")
 
 	// 5. 生成元数据
-	g.App.Logger.Info("🤖 调用 Gemini 生成元数据...")
+	g.Logger().Info("🤖 调用 Gemini 生成元数据...")
 	metadata, err := client.GenerateMetadataFromVideo(ctx, uploadedFile)
 	if err != nil {
-		g.App.Logger.Errorf("❌ 生成元数据失败: %v", err)
+		g.Logger().Errorf("❌ 生成元数据失败: %v", err)
 		return false
 	}
 
@@ -393,31 +405,31 @@ func (g *GenerateMetadata) executeWithGeminiVideo(taskContext map[string]interfa
 }
 
 // executeWithGeminiText 使用 Gemini 分析字幕文本生成元数据
-func (g *GenerateMetadata) executeWithGeminiText(taskContext map[string]interface{}) bool {
-	g.App.Logger.Info("📝 使用 Gemini 分析字幕文本...")
+func (g *GenerateMetadata) executeWithGeminiText(parent context.Context, taskContext map[string]interface{}) bool {
+	g.Logger().Info("📝 使用 Gemini 分析字幕文本...")
 
 	// 1. 检查中文字幕文件
 	zhSRTPath := filepath.Join(g.StateManager.CurrentDir, "zh.srt")
 	if _, err := os.Stat(zhSRTPath); os.IsNotExist(err) {
-		g.App.Logger.Warn("⚠️ 中文字幕文件不存在")
+		g.Logger().Warn("⚠️ 中文字幕文件不存在")
 		return false
 	}
 
 	// 2. 读取字幕内容
 	srtContent, err := os.ReadFile(zhSRTPath)
 	if err != nil {
-		g.App.Logger.Errorf("❌ 读取字幕文件失败: %v", err)
+		g.Logger().Errorf("❌ 读取字幕文件失败: %v", err)
 		return false
 	}
 
 	// 3. 提取文本
 	subtitleText := g.extractTextFromSRT(string(srtContent))
 	if subtitleText == "" {
-		g.App.Logger.Warn("⚠️ 字幕内容为空")
+		g.Logger().Warn("⚠️ 字幕内容为空")
 		return false
 	}
 
-	g.App.Logger.Infof("📝 提取到字幕文本，总长度: %d 字符", len(subtitleText))
+	g.Logger().Infof("📝 提取到字幕文本，总长度: %d 字符", len(subtitleText))
 
 	// 4. 截取文本（避免token过多）
 	maxLength := 2000
@@ -433,19 +445,19 @@ func (g *GenerateMetadata) executeWithGeminiText(taskContext map[string]interfac
 		g.App.Config.GeminiConfig.MaxTokens,
 	)
 	if err != nil {
-		g.App.Logger.Errorf("❌ 创建 Gemini 客户端失败: %v", err)
+		g.Logger().Errorf("❌ 创建 Gemini 客户端失败: %v", err)
 		return false
 	}
 	defer client.Close()
 
 	// 6. 生成元数据
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(g.App.Config.GeminiConfig.Timeout)*time.Second)
+	ctx, cancel := context.WithTimeout(parent, time.Duration(g.App.Config.GeminiConfig.Timeout)*time.Second)
 	defer cancel()
 
-	g.App.Logger.Info("🤖 调用 Gemini 生成元数据...")
+	g.Logger().Info("🤖 调用 Gemini 生成元数据...")
 	metadata, err := client.GenerateMetadataFromText(ctx, subtitleText)
 	if err != nil {
-		g.App.Logger.Errorf("❌ 生成元数据失败: %v", err)
+		g.Logger().Errorf("❌ 生成元数据失败: %v", err)
 		return false
 	}
 
@@ -459,7 +471,7 @@ func (g *GenerateMetadata) saveMetadataResults(metadata *VideoMetadata, taskCont
 	if len([]rune(metadata.Title)) > 80 {
 		runes := []rune(metadata.Title)
 		metadata.Title = string(runes[:77]) + "..."
-		g.App.Logger.Warnf("⚠️ 标题过长，已截断为80字符")
+		g.Logger().Warnf("⚠️ 标题过长，已截断为80字符")
 	}
 
 	// 2. 保存到 context
@@ -468,37 +480,37 @@ func (g *GenerateMetadata) saveMetadataResults(metadata *VideoMetadata, taskCont
 	taskContext["video_tags"] = metadata.Tags
 
 	// 3. 保存到 meta.json 文件
-	g.App.Logger.Info("💾 保存元数据到 meta.json 文件...")
+	g.Logger().Info("💾 保存元数据到 meta.json 文件...")
 	if err := g.saveMetadataToFile(metadata); err != nil {
-		g.App.Logger.Errorf("❌ 保存 meta.json 文件失败: %v", err)
+		g.Logger().Errorf("❌ 保存 meta.json 文件失败: %v", err)
 	} else {
-		g.App.Logger.Info("✅ meta.json 文件已保存")
+		g.Logger().Info("✅ meta.json 文件已保存")
 	}
 
 	// 4. 保存到数据库
-	g.App.Logger.Info("💾 保存生成的元数据到数据库...")
+	g.Logger().Info("💾 保存生成的元数据到数据库...")
 	savedVideo, err := g.SavedVideoService.GetVideoByVideoID(g.StateManager.VideoID)
 	if err != nil {
-		g.App.Logger.Errorf("❌ 获取视频记录失败: %v", err)
+		g.Logger().Errorf("❌ 获取视频记录失败: %v", err)
 	} else {
 		savedVideo.GeneratedTitle = metadata.Title
 		savedVideo.GeneratedDesc = metadata.Description
 		savedVideo.GeneratedTags = strings.Join(metadata.Tags, ",")
 
 		if err := g.SavedVideoService.UpdateVideo(savedVideo); err != nil {
-			g.App.Logger.Errorf("❌ 保存元数据到数据库失败: %v", err)
+			g.Logger().Errorf("❌ 保存元数据到数据库失败: %v", err)
 		} else {
-			g.App.Logger.Info("✅ 元数据已保存到数据库")
+			g.Logger().Info("✅ 元数据已保存到数据库")
 		}
 	}
 
 	// 5. 输出生成结果
-	g.App.Logger.Info("========================================")
-	g.App.Logger.Info("✅ 视频元数据生成成功！")
-	g.App.Logger.Infof("📌 标题: %s", metadata.Title)
-	g.App.Logger.Infof("📝 描述: %s", g.truncateString(metadata.Description, 100))
-	g.App.Logger.Infof("🏷️ 标签: %v", metadata.Tags)
-	g.App.Logger.Info("========================================")
+	g.Logger().Info("========================================")
+	g.Logger().Info("✅ 视频元数据生成成功！")
+	g.Logger().Infof("📌 标题: %s", metadata.Title)
+	g.Logger().Infof("📝 描述: %s", g.truncateString(metadata.Description, 100))
+	g.Logger().Infof("🏷️ 标签: %v", metadata.Tags)
+	g.Logger().Info("========================================")
 
 	return true
 }
@@ -510,7 +522,7 @@ func (g *GenerateMetadata) findVideoFiles() []string {
 
 	files, err := os.ReadDir(g.StateManager.CurrentDir)
 	if err != nil {
-		g.App.Logger.Errorf("读取目录失败: %v", err)
+		g.Logger().Errorf("读取目录失败: %v", err)
 		return videoFiles
 	}
 
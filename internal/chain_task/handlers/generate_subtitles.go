@@ -5,9 +5,11 @@ import (
 	"github.com/difyz9/ytb2bili/internal/chain_task/manager"
 	"github.com/difyz9/ytb2bili/internal/core"
 	"github.com/difyz9/ytb2bili/internal/core/services"
+	"github.com/difyz9/ytb2bili/internal/core/types"
 	"github.com/difyz9/ytb2bili/pkg/cos"
 	"github.com/difyz9/ytb2bili/pkg/store/model"
 	"github.com/difyz9/ytb2bili/pkg/utils"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -64,53 +66,55 @@ func (t *GenerateSubtitles) generateSRT(subtitles []model.SavedVideoSubtitle) st
 	return srtContent.String()
 }
 
-func (t *GenerateSubtitles) Execute(context map[string]interface{}) bool {
-	t.App.Logger.Info("========================================")
-	t.App.Logger.Info("开始生成字幕文件")
-	t.App.Logger.Info("========================================")
+func (t *GenerateSubtitles) Execute(ctx context.Context, context map[string]interface{}) bool {
+	t.Logger().Info("========================================")
+	t.Logger().Info("开始生成字幕文件")
+	t.Logger().Info("========================================")
 
 	// 1. 从数据库读取视频信息
 	savedVideo, err := t.SavedVideoService.GetVideoByID(t.StateManager.Id)
 	if err != nil {
-		t.App.Logger.Errorf("❌ 查询视频信息失败: %v", err)
+		t.Logger().Errorf("❌ 查询视频信息失败: %v", err)
 		context["error"] = err.Error()
 		return false
 	}
 
 	if savedVideo == nil {
 		errMsg := "视频信息不存在"
-		t.App.Logger.Error("❌ " + errMsg)
+		t.Logger().Error("❌ " + errMsg)
 		context["error"] = errMsg
 		return false
 	}
 
 	// 2. 检查字幕数据是否存在
 	if savedVideo.Subtitles == "" || savedVideo.Subtitles == "null" {
-		t.App.Logger.Warn("⚠️  视频没有字幕数据，跳过字幕生成")
+		t.Logger().Warn("⚠️  视频没有字幕数据，跳过字幕生成")
+		types.SkipTask(context, "视频没有字幕数据")
 		return true // 没有字幕不算错误，继续执行后续任务
 	}
 
 	// 3. 解析字幕 JSON 数据
 	var subtitles []model.SavedVideoSubtitle
 	if err := json.Unmarshal([]byte(savedVideo.Subtitles), &subtitles); err != nil {
-		t.App.Logger.Errorf("❌ 解析字幕数据失败: %v", err)
+		t.Logger().Errorf("❌ 解析字幕数据失败: %v", err)
 		context["error"] = fmt.Sprintf("解析字幕数据失败: %v", err)
 		return false
 	}
 
 	if len(subtitles) == 0 {
-		t.App.Logger.Warn("⚠️  字幕数据为空，跳过字幕生成")
+		t.Logger().Warn("⚠️  字幕数据为空，跳过字幕生成")
+		types.SkipTask(context, "字幕数据为空")
 		return true
 	}
 
-	t.App.Logger.Infof("📝 找到 %d 条字幕", len(subtitles))
+	t.Logger().Infof("📝 找到 %d 条字幕", len(subtitles))
 
 	// 4. 生成 SRT 内容
 	srtContent := t.generateSRT(subtitles)
 
 	// 5. 确保输出目录存在
 	if err := os.MkdirAll(t.StateManager.CurrentDir, 0755); err != nil {
-		t.App.Logger.Errorf("❌ 创建字幕目录失败: %v", err)
+		t.Logger().Errorf("❌ 创建字幕目录失败: %v", err)
 		context["error"] = err.Error()
 		return false
 	}
@@ -121,7 +125,7 @@ func (t *GenerateSubtitles) Execute(context map[string]interface{}) bool {
 
 	// 7. 写入 SRT 文件
 	if err := os.WriteFile(srtFilePath, []byte(srtContent), 0644); err != nil {
-		t.App.Logger.Errorf("❌ 写入字幕文件失败: %v", err)
+		t.Logger().Errorf("❌ 写入字幕文件失败: %v", err)
 		context["error"] = fmt.Sprintf("写入字幕文件失败: %v", err)
 		return false
 	}
@@ -129,7 +133,7 @@ func (t *GenerateSubtitles) Execute(context map[string]interface{}) bool {
 	// 8. 验证文件是否创建成功
 	if _, err := os.Stat(srtFilePath); os.IsNotExist(err) {
 		errMsg := "字幕文件创建失败"
-		t.App.Logger.Error("❌ " + errMsg)
+		t.Logger().Error("❌ " + errMsg)
 		context["error"] = errMsg
 		return false
 	}
@@ -138,32 +142,32 @@ func (t *GenerateSubtitles) Execute(context map[string]interface{}) bool {
 	enSrtFilePath := filepath.Join(t.StateManager.CurrentDir, enSrtFileName)
 
 	if err := utils.CopyFile(srtFilePath, enSrtFilePath); err != nil {
-		t.App.Logger.Errorf("❌ 复制英文字幕文件失败: %v", err)
+		t.Logger().Errorf("❌ 复制英文字幕文件失败: %v", err)
 		context["error"] = fmt.Sprintf("复制英文字幕文件失败: %v", err)
 	}
 
 	// 9. 保存字幕文件路径到 context，供后续任务使用
-	context["subtitle_file"] = srtFilePath
-	context["subtitle_count"] = len(subtitles)
+	context[manager.KeySubtitleFile] = srtFilePath
+	context[manager.KeySubtitleCount] = len(subtitles)
 
 	// 10. 显示字幕预览（前3条）
 	previewCount := 3
 	if len(subtitles) < previewCount {
 		previewCount = len(subtitles)
 	}
-	t.App.Logger.Info("📋 字幕预览（前3条）：")
+	t.Logger().Info("📋 字幕预览（前3条）：")
 	for i := 0; i < previewCount; i++ {
 		sub := subtitles[i]
-		t.App.Logger.Infof("  [%d] %.2fs-%.2fs: %s",
+		t.Logger().Infof("  [%d] %.2fs-%.2fs: %s",
 			i+1,
 			sub.Offset,
 			sub.Offset+sub.Duration,
 			truncateString(sub.Text, 50))
 	}
 
-	t.App.Logger.Infof("✓ 字幕文件生成成功: %s", srtFilePath)
-	t.App.Logger.Infof("✓ 共生成 %d 条字幕", len(subtitles))
-	t.App.Logger.Info("========================================")
+	t.Logger().Infof("✓ 字幕文件生成成功: %s", srtFilePath)
+	t.Logger().Infof("✓ 共生成 %d 条字幕", len(subtitles))
+	t.Logger().Info("========================================")
 
 	return true
 }
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
@@ -67,7 +68,7 @@ func NewTask03Handler(name string, app *core.AppServer, db *gorm.DB, stateManage
 }
 
 // Execute 执行任务
-func (t *Task03Handler) Execute(context map[string]interface{}) bool {
+func (t *Task03Handler) Execute(ctx context.Context, context map[string]interface{}) bool {
 	videoID := t.StateManager.VideoID
 
 	// 获取字幕 URL
@@ -113,16 +114,16 @@ func (t *Task03Handler) getVideoSrtURL(videoID string) (string, error) {
 		t.App.Config.ProxyConfig.UseProxy && t.App.Config.ProxyConfig.ProxyHost != ""
 
 	if useProxy {
-		t.App.Logger.Info("🔄 尝试使用代理获取字幕URL...")
+		t.Logger().Info("🔄 尝试使用代理获取字幕URL...")
 		srtURL, err := t.fetchSrtURL(videoURL, true)
 		if err == nil {
 			return srtURL, nil
 		}
-		t.App.Logger.Warnf("⚠️ 代理获取字幕URL失败: %v，尝试不使用代理重试...", err)
+		t.Logger().Warnf("⚠️ 代理获取字幕URL失败: %v，尝试不使用代理重试...", err)
 	}
 
 	// 不使用代理重试
-	t.App.Logger.Info("🔄 尝试不使用代理获取字幕URL...")
+	t.Logger().Info("🔄 尝试不使用代理获取字幕URL...")
 	return t.fetchSrtURL(videoURL, false)
 }
 
@@ -171,16 +172,16 @@ func (t *Task03Handler) getSrtFile(srtURL string) (*TranscriptData, error) {
 		t.App.Config.ProxyConfig.UseProxy && t.App.Config.ProxyConfig.ProxyHost != ""
 
 	if useProxy {
-		t.App.Logger.Info("🔄 尝试使用代理获取字幕内容...")
+		t.Logger().Info("🔄 尝试使用代理获取字幕内容...")
 		transcript, err := t.fetchSrtContent(srtURL, true)
 		if err == nil {
 			return transcript, nil
 		}
-		t.App.Logger.Warnf("⚠️ 代理获取字幕内容失败: %v，尝试不使用代理重试...", err)
+		t.Logger().Warnf("⚠️ 代理获取字幕内容失败: %v，尝试不使用代理重试...", err)
 	}
 
 	// 不使用代理重试
-	t.App.Logger.Info("🔄 尝试不使用代理获取字幕内容...")
+	t.Logger().Info("🔄 尝试不使用代理获取字幕内容...")
 	return t.fetchSrtContent(srtURL, false)
 }
 
@@ -246,9 +247,9 @@ func (t *Task03Handler) createHTTPClient(useProxy bool) *http.Client {
 			client = &http.Client{
 				Transport: transport,
 			}
-			t.App.Logger.Infof("📡 使用代理: %s", t.App.Config.ProxyConfig.ProxyHost)
+			t.Logger().Infof("📡 使用代理: %s", t.App.Config.ProxyConfig.ProxyHost)
 		} else {
-			t.App.Logger.Warnf("⚠️ 代理URL解析失败: %v", err)
+			t.Logger().Warnf("⚠️ 代理URL解析失败: %v", err)
 		}
 	}
 
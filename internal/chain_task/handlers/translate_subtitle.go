@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,6 +11,7 @@ import (
 	"github.com/difyz9/ytb2bili/internal/chain_task/base"
 	"github.com/difyz9/ytb2bili/internal/chain_task/manager"
 	"github.com/difyz9/ytb2bili/internal/core"
+	"github.com/difyz9/ytb2bili/internal/core/types"
 	"github.com/difyz9/ytb2bili/pkg/cos"
 	"github.com/difyz9/ytb2bili/pkg/utils"
 	"gorm.io/gorm"
@@ -53,6 +55,9 @@ func (t *TranslateSubtitle) getCurrentAPIKey() (string, error) {
 	return apiKey, nil
 }
 
+// chineseSourceRatio 字幕中文字符占比达到该值时认为源语言已是中文，不再翻译
+const chineseSourceRatio = 0.5
+
 // SRTEntry SRT字幕条目
 type SRTEntry struct {
 	Index    int
@@ -60,51 +65,46 @@ type SRTEntry struct {
 	Text     string
 }
 
-func (t *TranslateSubtitle) Execute(context map[string]interface{}) bool {
-	t.App.Logger.Info("========================================")
-	t.App.Logger.Infof("开始翻译字幕: VideoID=%s", t.StateManager.VideoID)
-	t.App.Logger.Info("========================================")
-
-	// 0. 动态获取最新的API Key配置
-	currentAPIKey, err := t.getCurrentAPIKey()
-	if err != nil {
-		t.App.Logger.Errorf("❌ %v", err)
-		context["error"] = t.getTranslationError(err)
-		return false
-	}
+// GetResourceClass 字幕翻译受 AI 服务配额限制
+func (t *TranslateSubtitle) GetResourceClass() types.ResourceClass {
+	return types.ResourceClassLLM
+}
 
-	t.App.Logger.Infof("🔑 使用DeepSeek API Key: %s", maskAPIKey(currentAPIKey))
-	// 更新当前使用的API Key
-	t.APIKey = currentAPIKey
+func (t *TranslateSubtitle) Execute(ctx context.Context, context map[string]interface{}) bool {
+	t.Logger().Info("========================================")
+	t.Logger().Infof("开始翻译字幕: VideoID=%s", t.StateManager.VideoID)
+	t.Logger().Info("========================================")
 
 	// 1. 检查英文字幕文件是否存在（由 GenerateSubtitles 任务生成）
 	enSRTPath := filepath.Join(t.StateManager.CurrentDir, fmt.Sprintf("%s.srt", t.StateManager.VideoID))
 	if _, err := os.Stat(enSRTPath); os.IsNotExist(err) {
-		t.App.Logger.Warn("⚠️  英文字幕文件不存在，跳过翻译")
+		t.Logger().Warn("⚠️  英文字幕文件不存在，跳过翻译")
+		types.SkipTask(context, "没有可翻译的字幕文件")
 		return true // 没有字幕文件不算失败
 	}
 
 	// 2. 读取并解析英文字幕文件
 	srtContent, err := os.ReadFile(enSRTPath)
 	if err != nil {
-		t.App.Logger.Errorf("❌ 读取英文字幕文件失败: %v", err)
-		context["error"] = "字幕文件读取失败，请确认字幕生成步骤已完成"
+		t.Logger().Errorf("❌ 读取英文字幕文件失败: %v", err)
+		types.SetTaskError(context, types.NewTaskError(types.ErrCodeMissingInput, err.Error()).WithMessage("字幕文件读取失败，请确认字幕生成步骤已完成"))
 		return false
 	}
 
 	srtEntries, err := t.parseSRTContent(string(srtContent))
 	if err != nil {
-		t.App.Logger.Errorf("❌ 解析SRT文件失败: %v", err)
-		context["error"] = "字幕文件格式错误，无法解析SRT内容"
+		t.Logger().Errorf("❌ 解析SRT文件失败: %v", err)
+		types.SetTaskError(context, types.NewTaskError(types.ErrCodeInvalidInput, err.Error()).WithMessage("字幕文件格式错误，无法解析SRT内容"))
 		return false
 	}
 
 	if len(srtEntries) == 0 {
-		t.App.Logger.Warn("⚠️  字幕内容为空，跳过翻译")
+		t.Logger().Warn("⚠️  字幕内容为空，跳过翻译")
+		types.SkipTask(context, "字幕内容为空")
 		return true
 	}
 
-	t.App.Logger.Infof("📝 找到 %d 条字幕", len(srtEntries))
+	t.Logger().Infof("📝 找到 %d 条字幕", len(srtEntries))
 
 	// 3. 提取文本进行翻译
 	var texts []string
@@ -112,14 +112,41 @@ func (t *TranslateSubtitle) Execute(context map[string]interface{}) bool {
 		texts = append(texts, entry.Text)
 	}
 
+	// 源语言已是中文时不需要翻译，直接使用原字幕作为中文字幕
+	zhSRTPath := filepath.Join(t.StateManager.CurrentDir, "zh.srt")
+	if ratio := utils.ChineseRatio(strings.Join(texts, "\n")); ratio >= chineseSourceRatio {
+		if err := utils.CopyFile(enSRTPath, zhSRTPath); err != nil {
+			t.Logger().Errorf("❌ 复制中文字幕失败: %v", err)
+			types.SetTaskError(context, types.NewTaskError(types.ErrCodeIO, err.Error()).WithMessage("保存中文字幕文件失败，请检查磁盘空间和文件权限"))
+			return false
+		}
+		t.Logger().Infof("⏭️ 字幕中文字符占比 %.0f%%，源语言已是中文，跳过翻译", ratio*100)
+		context[manager.KeyEnSRTPath] = enSRTPath
+		context[manager.KeyZhSRTPath] = zhSRTPath
+		types.SkipTask(context, fmt.Sprintf("源语言已是中文（中文字符占比 %.0f%%），直接使用原字幕", ratio*100))
+		return true
+	}
+
+	// 动态获取最新的API Key配置
+	currentAPIKey, err := t.getCurrentAPIKey()
+	if err != nil {
+		t.Logger().Errorf("❌ %v", err)
+		types.SetTaskError(context, t.getTranslationError(err))
+		return false
+	}
+
+	t.Logger().Infof("🔑 使用DeepSeek API Key: %s", maskAPIKey(currentAPIKey))
+	// 更新当前使用的API Key
+	t.APIKey = currentAPIKey
+
 	// 4. 执行并发翻译
 	totalGroups := (len(texts) + t.GroupSize - 1) / t.GroupSize
-	t.App.Logger.Infof("� 开始并发翻译，每组 %d 句，共 %d 组，并发数: %d", t.GroupSize, totalGroups, t.MaxWorkers)
+	t.Logger().Infof("� 开始并发翻译，每组 %d 句，共 %d 组，并发数: %d", t.GroupSize, totalGroups, t.MaxWorkers)
 
-	translatedTexts, err := t.translateTextsInGroupsConcurrent(texts)
+	translatedTexts, err := t.translateTextsInGroupsConcurrent(ctx, texts)
 	if err != nil {
-		t.App.Logger.Errorf("❌ 翻译失败: %v", err)
-		context["error"] = t.getTranslationError(err)
+		t.Logger().Errorf("❌ 翻译失败: %v", err)
+		types.SetTaskError(context, t.getTranslationError(err))
 		return false
 	}
 
@@ -127,39 +154,38 @@ func (t *TranslateSubtitle) Execute(context map[string]interface{}) bool {
 	translatedSRT := t.generateTranslatedSRTContent(srtEntries, translatedTexts)
 
 	// 6. 保存中文字幕文件
-	zhSRTPath := filepath.Join(t.StateManager.CurrentDir, "zh.srt")
 	if err := os.WriteFile(zhSRTPath, []byte(translatedSRT), 0644); err != nil {
-		t.App.Logger.Errorf("❌ 保存中文字幕失败: %v", err)
-		context["error"] = "保存翻译字幕文件失败，请检查磁盘空间和文件权限"
+		t.Logger().Errorf("❌ 保存中文字幕失败: %v", err)
+		types.SetTaskError(context, types.NewTaskError(types.ErrCodeIO, err.Error()).WithMessage("保存翻译字幕文件失败，请检查磁盘空间和文件权限"))
 		return false
 	}
 
 	// 7. 字幕质量校验和优化
 	optimizedPath, validationResult, err := t.validateAndOptimizeSubtitles(enSRTPath, zhSRTPath)
 	if err != nil {
-		t.App.Logger.Warnf("⚠️  字幕校验失败，使用原始翻译: %v", err)
+		t.Logger().Warnf("⚠️  字幕校验失败，使用原始翻译: %v", err)
 	} else {
 		if validationResult.MissingEntries > 0 {
-			t.App.Logger.Infof("🔧 检测到 %d 个问题条目，已尝试修复 %d 个",
+			t.Logger().Infof("🔧 检测到 %d 个问题条目，已尝试修复 %d 个",
 				validationResult.MissingEntries, len(validationResult.FixedEntries))
 
 			if optimizedPath != "" {
 				// 使用优化后的文件替换原文件
 				if err := os.Rename(optimizedPath, zhSRTPath); err == nil {
-					t.App.Logger.Info("✨ 已应用字幕优化结果")
+					t.Logger().Info("✨ 已应用字幕优化结果")
 				}
 			}
 		}
 	}
 
 	// 8. 保存文件路径到 context
-	context["en_srt_path"] = enSRTPath
-	context["zh_srt_path"] = zhSRTPath
-	context["translated_count"] = len(translatedTexts)
+	context[manager.KeyEnSRTPath] = enSRTPath
+	context[manager.KeyZhSRTPath] = zhSRTPath
+	context[manager.KeyTranslatedCount] = len(translatedTexts)
 
 	// 添加校验结果信息
 	if validationResult != nil {
-		context["validation_result"] = map[string]interface{}{
+		context[manager.KeyValidationResult] = map[string]interface{}{
 			"total_entries":   validationResult.TotalEntries,
 			"valid_entries":   validationResult.ValidEntries,
 			"missing_entries": validationResult.MissingEntries,
@@ -167,9 +193,9 @@ func (t *TranslateSubtitle) Execute(context map[string]interface{}) bool {
 		}
 	}
 
-	t.App.Logger.Infof("✓ 中文字幕已保存: %s", zhSRTPath)
-	t.App.Logger.Infof("✓ 翻译完成: %d/%d 条字幕", len(translatedTexts), len(texts))
-	t.App.Logger.Info("========================================")
+	t.Logger().Infof("✓ 中文字幕已保存: %s", zhSRTPath)
+	t.Logger().Infof("✓ 翻译完成: %d/%d 条字幕", len(translatedTexts), len(texts))
+	t.Logger().Info("========================================")
 
 	return true
 }
@@ -240,8 +266,8 @@ func (t *TranslateSubtitle) generateTranslatedSRTContent(entries []SRTEntry, tra
 	return builder.String()
 }
 
-// translateTextsInGroupsConcurrent 并发分组翻译文本
-func (t *TranslateSubtitle) translateTextsInGroupsConcurrent(texts []string) ([]string, error) {
+// translateTextsInGroupsConcurrent 并发分组翻译文本，ctx 被取消后剩余分组不再请求 API
+func (t *TranslateSubtitle) translateTextsInGroupsConcurrent(ctx context.Context, texts []string) ([]string, error) {
 	totalGroups := (len(texts) + t.GroupSize - 1) / t.GroupSize
 	results := make([][]string, totalGroups)
 
@@ -269,14 +295,18 @@ func (t *TranslateSubtitle) translateTextsInGroupsConcurrent(texts []string) ([]
 		wg.Add(1)
 		go func(workerID int) {
 			defer wg.Done()
-			t.App.Logger.Debugf("🔧 启动翻译工作者 %d", workerID)
+			t.Logger().Debugf("🔧 启动翻译工作者 %d", workerID)
 
 			for task := range taskChannel {
-				t.App.Logger.Infof("⏳ 工作者 %d 处理第 %d/%d 组 (%d句)",
+				t.Logger().Infof("⏳ 工作者 %d 处理第 %d/%d 组 (%d句)",
 					workerID, task.groupIndex+1, totalGroups, len(task.texts))
 
 				// 使用简化的翻译方法
-				translated, err := t.translateGroupSimple(task.texts)
+				var translated []string
+				err := ctx.Err()
+				if err == nil {
+					translated, err = t.translateGroupSimple(ctx, task.texts)
+				}
 
 				resultChannel <- struct {
 					groupIndex int
@@ -315,9 +345,12 @@ func (t *TranslateSubtitle) translateTextsInGroupsConcurrent(texts []string) ([]
 
 	// 处理结果
 	var lastErr error
+	finished := 0
 	for result := range resultChannel {
+		finished++
+		t.ReportProgressCount(finished, totalGroups, manager.ProgressUnitGroups, fmt.Sprintf("已处理 %d/%d 组", finished, totalGroups))
 		if result.err != nil {
-			t.App.Logger.Errorf("❌ 第 %d 组翻译失败: %v", result.groupIndex+1, result.err)
+			t.Logger().Errorf("❌ 第 %d 组翻译失败: %v", result.groupIndex+1, result.err)
 			lastErr = result.err
 			continue
 		}
@@ -338,7 +371,7 @@ func (t *TranslateSubtitle) translateTextsInGroupsConcurrent(texts []string) ([]
 }
 
 // translateGroupSimple 简化的组翻译（无上下文，更快速）
-func (t *TranslateSubtitle) translateGroupSimple(texts []string) ([]string, error) {
+func (t *TranslateSubtitle) translateGroupSimple(ctx context.Context, texts []string) ([]string, error) {
 	if len(texts) == 0 {
 		return []string{}, nil
 	}
@@ -361,7 +394,7 @@ func (t *TranslateSubtitle) translateGroupSimple(texts []string) ([]string, erro
 
 注意：只返回翻译的中文文本，不要添加序号、解释或其他内容。`, len(texts), len(texts))
 
-	translatedText, err := t.callDeepSeekAPI(systemPrompt, combinedText)
+	translatedText, err := t.callDeepSeekAPI(ctx, systemPrompt, combinedText)
 	if err != nil {
 		return nil, err
 	}
@@ -375,7 +408,7 @@ func (t *TranslateSubtitle) translateGroupSimple(texts []string) ([]string, erro
 
 	// 确保数量匹配
 	if len(translatedSentences) != len(texts) {
-		t.App.Logger.Warnf("⚠️  翻译结果数量不匹配: 期望%d句，实际%d句，正在修正...", len(texts), len(translatedSentences))
+		t.Logger().Warnf("⚠️  翻译结果数量不匹配: 期望%d句，实际%d句，正在修正...", len(texts), len(translatedSentences))
 		for len(translatedSentences) < len(texts) {
 			translatedSentences = append(translatedSentences, "[翻译缺失]")
 		}
@@ -388,7 +421,7 @@ func (t *TranslateSubtitle) translateGroupSimple(texts []string) ([]string, erro
 }
 
 // translateTextsInGroups 分组翻译文本（带上下文）- 保留原方法作为备用
-func (t *TranslateSubtitle) translateTextsInGroups(texts []string) ([]string, error) {
+func (t *TranslateSubtitle) translateTextsInGroups(ctx context.Context, texts []string) ([]string, error) {
 	var translatedTexts []string
 	totalGroups := (len(texts) + t.GroupSize - 1) / t.GroupSize
 
@@ -423,11 +456,11 @@ func (t *TranslateSubtitle) translateTextsInGroups(texts []string) ([]string, er
 			nextContext = texts[end:nextEnd]
 		}
 
-		t.App.Logger.Infof("⏳ 翻译第 %d/%d 组 (上下文: 前%d句, 当前%d句, 后%d句)",
+		t.Logger().Infof("⏳ 翻译第 %d/%d 组 (上下文: 前%d句, 当前%d句, 后%d句)",
 			groupNum, totalGroups, len(prevContext), len(currentGroup), len(nextContext))
 
 		// 带上下文翻译
-		groupTranslated, err := t.translateGroupWithContext(currentGroup, prevContext, nextContext)
+		groupTranslated, err := t.translateGroupWithContext(ctx, currentGroup, prevContext, nextContext)
 		if err != nil {
 			return nil, fmt.Errorf("翻译第 %d 组失败: %v", groupNum, err)
 		}
@@ -442,7 +475,7 @@ func (t *TranslateSubtitle) translateTextsInGroups(texts []string) ([]string, er
 }
 
 // translateGroupWithContext 带上下文翻译一组文本
-func (t *TranslateSubtitle) translateGroupWithContext(texts []string, prevContext []string, nextContext []string) ([]string, error) {
+func (t *TranslateSubtitle) translateGroupWithContext(ctx context.Context, texts []string, prevContext []string, nextContext []string) ([]string, error) {
 	// 构建包含上下文的完整文本
 	var fullTexts []string
 	targetStartIndex := 0
@@ -494,7 +527,7 @@ func (t *TranslateSubtitle) translateGroupWithContext(texts []string, prevContex
 
 注意：只返回翻译的中文文本，不要添加序号、解释或其他内容。`, len(texts), contextInfo, len(texts))
 
-	translatedText, err := t.callDeepSeekAPI(systemPrompt, combinedText)
+	translatedText, err := t.callDeepSeekAPI(ctx, systemPrompt, combinedText)
 	if err != nil {
 		return nil, err
 	}
@@ -508,7 +541,7 @@ func (t *TranslateSubtitle) translateGroupWithContext(texts []string, prevContex
 
 	// 确保数量匹配
 	if len(translatedSentences) != len(texts) {
-		t.App.Logger.Warnf("⚠️  翻译结果数量不匹配: 期望%d句，实际%d句，正在修正...", len(texts), len(translatedSentences))
+		t.Logger().Warnf("⚠️  翻译结果数量不匹配: 期望%d句，实际%d句，正在修正...", len(texts), len(translatedSentences))
 		for len(translatedSentences) < len(texts) {
 			translatedSentences = append(translatedSentences, "[翻译缺失]")
 		}
@@ -521,7 +554,7 @@ func (t *TranslateSubtitle) translateGroupWithContext(texts []string, prevContex
 }
 
 // callDeepSeekAPI 调用DeepSeek API（实时获取最新的API Key）
-func (t *TranslateSubtitle) callDeepSeekAPI(systemPrompt, userPrompt string) (string, error) {
+func (t *TranslateSubtitle) callDeepSeekAPI(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
 	// 实时从配置中获取最新的API Key
 	currentAPIKey, err := t.getCurrentAPIKey()
 	if err != nil {
@@ -529,10 +562,10 @@ func (t *TranslateSubtitle) callDeepSeekAPI(systemPrompt, userPrompt string) (st
 	}
 
 	// 添加调试日志，显示当前使用的API Key（用于验证热更新是否生效）
-	t.App.Logger.Debugf("🔑 当前使用API Key: %s", maskAPIKey(currentAPIKey))
+	t.Logger().Debugf("🔑 当前使用API Key: %s", maskAPIKey(currentAPIKey))
 
 	client := NewDeepSeekClient(currentAPIKey)
-	response, err := client.ChatCompletion(systemPrompt, userPrompt)
+	response, err := client.ChatCompletion(ctx, systemPrompt, userPrompt)
 	if err != nil {
 		return "", fmt.Errorf("调用DeepSeek API失败: %v", err)
 	}
@@ -540,48 +573,55 @@ func (t *TranslateSubtitle) callDeepSeekAPI(systemPrompt, userPrompt string) (st
 	return response, nil
 }
 
-// getTranslationError 将翻译错误转换为用户友好的错误信息
-func (t *TranslateSubtitle) getTranslationError(err error) string {
+// getTranslationError 将翻译错误转换为带错误码和用户友好提示的结构化错误
+func (t *TranslateSubtitle) getTranslationError(err error) *types.TaskError {
 	errorStr := err.Error()
+	newError := func(code types.ErrorCode, message string) *types.TaskError {
+		return types.NewTaskError(code, errorStr).WithMessage("翻译失败：%s", message)
+	}
 
 	if strings.Contains(errorStr, "DeepSeek API Key 未配置") {
-		return "翻译失败：DeepSeek API Key未配置，请在设置中配置API Key"
+		return newError(types.ErrCodeNotConfigured, "DeepSeek API Key未配置，请在设置中配置API Key")
+	}
+
+	if strings.Contains(errorStr, "DeepSeek 翻译服务未启用") {
+		return newError(types.ErrCodeNotConfigured, "DeepSeek 翻译服务未启用，请在设置中启用")
 	}
 
 	if strings.Contains(errorStr, "401") || strings.Contains(errorStr, "unauthorized") {
-		return "翻译失败：DeepSeek API Key无效或已过期，请检查API Key设置"
+		return newError(types.ErrCodeAuthFailed, "DeepSeek API Key无效或已过期，请检查API Key设置")
 	}
 
 	if strings.Contains(errorStr, "429") || strings.Contains(errorStr, "rate limit") {
-		return "翻译失败：API调用频率过快，请稍后重试"
+		return newError(types.ErrCodeRateLimited, "API调用频率过快，请稍后重试")
 	}
 
 	if strings.Contains(errorStr, "insufficient_quota") || strings.Contains(errorStr, "quota") {
-		return "翻译失败：DeepSeek账户余额不足，请充值后重试"
+		return newError(types.ErrCodeQuotaExceeded, "DeepSeek账户余额不足，请充值后重试")
 	}
 
 	if strings.Contains(errorStr, "timeout") || strings.Contains(errorStr, "deadline exceeded") {
-		return "翻译失败：网络超时，请检查网络连接后重试"
+		return newError(types.ErrCodeTimeout, "网络超时，请检查网络连接后重试")
 	}
 
 	if strings.Contains(errorStr, "connection") {
-		return "翻译失败：网络连接异常，请检查网络状态"
+		return newError(types.ErrCodeNetwork, "网络连接异常，请检查网络状态")
 	}
 
 	if strings.Contains(errorStr, "max_tokens") {
-		return "翻译失败：字幕内容过长，请尝试分段处理"
+		return newError(types.ErrCodeInvalidInput, "字幕内容过长，请尝试分段处理")
 	}
 
 	if strings.Contains(errorStr, "context_length_exceeded") {
-		return "翻译失败：单次翻译内容过多，系统将自动分批重试"
+		return newError(types.ErrCodeInvalidInput, "单次翻译内容过多，系统将自动分批重试")
 	}
 
 	if strings.Contains(errorStr, "API Key") {
-		return "翻译失败：API Key配置问题，请检查设置"
+		return newError(types.ErrCodeAuthFailed, "API Key配置问题，请检查设置")
 	}
 
 	// 通用翻译错误
-	return "翻译失败：AI翻译服务暂时不可用，请稍后重试"
+	return newError(types.ErrCodeServiceUnavailable, "AI翻译服务暂时不可用，请稍后重试")
 }
 
 // maskAPIKey 隐藏API Key的敏感信息用于日志显示
@@ -604,7 +644,7 @@ func (t *TranslateSubtitle) validateAndOptimizeSubtitles(originalPath, translate
 	}
 
 	// 创建校验器
-	validator := utils.NewSubtitleValidator(t.App.Logger, apiKey)
+	validator := utils.NewSubtitleValidator(t.Logger(), apiKey)
 
 	// 生成优化后的文件路径
 	optimizedPath := filepath.Join(t.StateManager.CurrentDir, "zh_optimized.srt")
@@ -4,9 +4,11 @@ import (
 	"github.com/difyz9/ytb2bili/internal/chain_task/base"
 	"github.com/difyz9/ytb2bili/internal/chain_task/manager"
 	"github.com/difyz9/ytb2bili/internal/core"
+	"github.com/difyz9/ytb2bili/internal/core/types"
 	"github.com/difyz9/ytb2bili/internal/core/models"
 	"github.com/difyz9/ytb2bili/pkg/cos"
 	"github.com/difyz9/ytb2bili/pkg/utils"
+	"context"
 	"fmt"
 	"gorm.io/gorm"
 )
@@ -30,7 +32,12 @@ func NewUploadM3u82CosHandler(name string, app *core.AppServer, stateManager *ma
 	}
 }
 
-func (t *UploadM3u82CosHandler) Execute(context map[string]interface{}) bool {
+// GetResourceClass 上传到 COS 受上传带宽限制
+func (t *UploadM3u82CosHandler) GetResourceClass() types.ResourceClass {
+	return types.ResourceClassUpload
+}
+
+func (t *UploadM3u82CosHandler) Execute(ctx context.Context, context map[string]interface{}) bool {
 	//audio/mpegurl
 	m3U8Files, err2 := utils.ParseM3U8File(t.StateManager.M3u8FileName)
 
@@ -4,10 +4,12 @@ import (
 	"github.com/difyz9/ytb2bili/internal/chain_task/base"
 	"github.com/difyz9/ytb2bili/internal/chain_task/manager"
 	"github.com/difyz9/ytb2bili/internal/core"
+	"github.com/difyz9/ytb2bili/internal/core/types"
 	"github.com/difyz9/ytb2bili/internal/core/services"
 	"github.com/difyz9/ytb2bili/internal/storage"
 	"github.com/difyz9/bilibili-go-sdk/bilibili"
 	"github.com/difyz9/ytb2bili/pkg/cos"
+	"context"
 	"os"
 	"path/filepath"
 )
@@ -30,36 +32,41 @@ func NewUploadSubtitleToBilibili(name string, app *core.AppServer, stateManager
 	}
 }
 
-func (t *UploadSubtitleToBilibili) Execute(context map[string]interface{}) bool {
-	t.App.Logger.Info("========================================")
-	t.App.Logger.Info("开始上传字幕到 Bilibili")
-	t.App.Logger.Info("========================================")
+// GetResourceClass 字幕上传与视频上传共享 Bilibili 上传限额
+func (t *UploadSubtitleToBilibili) GetResourceClass() types.ResourceClass {
+	return types.ResourceClassUpload
+}
+
+func (t *UploadSubtitleToBilibili) Execute(ctx context.Context, context map[string]interface{}) bool {
+	t.Logger().Info("========================================")
+	t.Logger().Info("开始上传字幕到 Bilibili")
+	t.Logger().Info("========================================")
 
 	// 1. 检查是否有BVID（视频已上传成功）
-	bvid, exists := context["bili_bvid"].(string)
-	if !exists || bvid == "" {
+	bvid := manager.PipelineContext(context).BiliBVID()
+	if bvid == "" {
 		// 尝试从数据库获取BVID
 		savedVideo, err := t.SavedVideoService.GetVideoByVideoID(t.StateManager.VideoID)
 		if err != nil || savedVideo.BiliBVID == "" {
-			t.App.Logger.Warn("⚠️  没有找到BVID，跳过字幕上传")
+			t.Logger().Warn("⚠️  没有找到BVID，跳过字幕上传")
 			return true // 不算失败，只是跳过
 		}
 		bvid = savedVideo.BiliBVID
 	}
 
-	t.App.Logger.Infof("📺 视频BVID: %s", bvid)
+	t.Logger().Infof("📺 视频BVID: %s", bvid)
 
 	// 2. 检查登录信息
 	loginStore := storage.GetDefaultStore()
 	if !loginStore.IsValid() {
-		t.App.Logger.Error("❌ 没有有效的 Bilibili 登录信息，无法上传字幕")
+		t.Logger().Error("❌ 没有有效的 Bilibili 登录信息，无法上传字幕")
 		context["error"] = "未登录 Bilibili"
 		return false
 	}
 
 	loginInfo, err := loginStore.Load()
 	if err != nil {
-		t.App.Logger.Errorf("❌ 加载登录信息失败: %v", err)
+		t.Logger().Errorf("❌ 加载登录信息失败: %v", err)
 		context["error"] = "加载登录信息失败"
 		return false
 	}
@@ -67,7 +74,7 @@ func (t *UploadSubtitleToBilibili) Execute(context map[string]interface{}) bool
 	// 3. 查找字幕文件
 	subtitleFiles := t.findSubtitleFiles()
 	if len(subtitleFiles) == 0 {
-		t.App.Logger.Warn("⚠️  未找到字幕文件，跳过字幕上传")
+		t.Logger().Warn("⚠️  未找到字幕文件，跳过字幕上传")
 		return true // 不算失败，只是跳过
 	}
 
@@ -78,30 +85,35 @@ func (t *UploadSubtitleToBilibili) Execute(context map[string]interface{}) bool
 	// 5. 上传字幕文件
 	uploadedCount := 0
 	for _, subtitleFile := range subtitleFiles {
-		t.App.Logger.Infof("📝 正在上传字幕: %s", filepath.Base(subtitleFile.Path))
+		if ctx.Err() != nil {
+			t.Logger().Warn("⏹️ 任务已取消，停止上传剩余字幕")
+			context["error"] = "上传已取消"
+			return false
+		}
+		t.Logger().Infof("📝 正在上传字幕: %s", filepath.Base(subtitleFile.Path))
 
 		err := uploader.UploadSubtitle(bvid, subtitleFile.Path, subtitleFile.Language)
 		if err != nil {
-			t.App.Logger.Errorf("❌ 上传字幕失败 %s: %v", subtitleFile.Path, err)
+			t.Logger().Errorf("❌ 上传字幕失败 %s: %v", subtitleFile.Path, err)
 			// 继续上传其他字幕文件，不因为一个失败就停止
 			continue
 		}
 
-		t.App.Logger.Infof("✅ 字幕上传成功: %s (%s)", filepath.Base(subtitleFile.Path), subtitleFile.Language)
+		t.Logger().Infof("✅ 字幕上传成功: %s (%s)", filepath.Base(subtitleFile.Path), subtitleFile.Language)
 		uploadedCount++
 	}
 
 	// 6. 记录结果
 	if uploadedCount > 0 {
-		t.App.Logger.Info("========================================")
-		t.App.Logger.Infof("✅ 字幕上传完成！成功上传 %d 个字幕文件", uploadedCount)
-		t.App.Logger.Infof("  视频链接: https://www.bilibili.com/video/%s", bvid)
-		t.App.Logger.Info("========================================")
+		t.Logger().Info("========================================")
+		t.Logger().Infof("✅ 字幕上传完成！成功上传 %d 个字幕文件", uploadedCount)
+		t.Logger().Infof("  视频链接: https://www.bilibili.com/video/%s", bvid)
+		t.Logger().Info("========================================")
 
 		context["subtitle_upload_count"] = uploadedCount
 		return true
 	} else {
-		t.App.Logger.Error("❌ 没有成功上传任何字幕文件")
+		t.Logger().Error("❌ 没有成功上传任何字幕文件")
 		context["error"] = "字幕上传失败"
 		return false
 	}
@@ -137,7 +149,7 @@ func (t *UploadSubtitleToBilibili) findSubtitleFiles() []SubtitleFileInfo {
 				Path:     fullPath,
 				Language: item.language,
 			})
-			t.App.Logger.Infof("🎯 找到字幕文件: %s (%s)", item.filename, item.language)
+			t.Logger().Infof("🎯 找到字幕文件: %s (%s)", item.filename, item.language)
 		}
 	}
 
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -14,21 +15,22 @@ import (
 	"github.com/difyz9/ytb2bili/internal/chain_task/manager"
 	"github.com/difyz9/ytb2bili/internal/core"
 	"github.com/difyz9/ytb2bili/internal/core/services"
+	"github.com/difyz9/ytb2bili/internal/core/types"
 	"github.com/difyz9/ytb2bili/internal/storage"
 	"github.com/difyz9/ytb2bili/pkg/cos"
 	"github.com/difyz9/ytb2bili/pkg/utils"
 )
 
 // fetchAndSaveMetadata 尝试从 YouTube 获取元数据并保存到数据库
-func (t *UploadToBilibili) fetchAndSaveMetadata(videoID string) error {
-	t.App.Logger.Infof("🔄 尝试补充获取视频元数据: %s", videoID)
+func (t *UploadToBilibili) fetchAndSaveMetadata(ctx context.Context, videoID string) error {
+	t.Logger().Infof("🔄 尝试补充获取视频元数据: %s", videoID)
 
 	// 1. 找到 yt-dlp
 	var installDir string
 	if t.App.Config != nil && t.App.Config.YtDlpPath != "" {
 		installDir = t.App.Config.YtDlpPath
 	}
-	manager := utils.NewYtDlpManager(t.App.Logger, installDir)
+	manager := utils.NewYtDlpManager(t.Logger(), installDir)
 	if !manager.IsInstalled() {
 		return fmt.Errorf("未找到 yt-dlp")
 	}
@@ -61,7 +63,7 @@ func (t *UploadToBilibili) fetchAndSaveMetadata(videoID string) error {
 	}
 
 	// 3. 执行命令
-	cmd := exec.Command(command[0], command[1:]...)
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
 	output, err := cmd.Output()
 	if err != nil {
 		return fmt.Errorf("执行 yt-dlp 失败: %v", err)
@@ -87,7 +89,7 @@ func (t *UploadToBilibili) fetchAndSaveMetadata(videoID string) error {
 		return fmt.Errorf("更新数据库失败: %v", err)
 	}
 
-	t.App.Logger.Infof("✅ 成功补充获取并保存元数据: %s", metadata.Title)
+	t.Logger().Infof("✅ 成功补充获取并保存元数据: %s", metadata.Title)
 	return nil
 }
 
@@ -109,75 +111,92 @@ func NewUploadToBilibili(name string, app *core.AppServer, stateManager *manager
 	}
 }
 
-func (t *UploadToBilibili) Execute(context map[string]interface{}) bool {
-	t.App.Logger.Info("========================================")
-	t.App.Logger.Info("开始上传视频到 Bilibili")
-	t.App.Logger.Info("========================================")
+// GetResourceClass 上传视频受 Bilibili 上传带宽和频率限制
+func (t *UploadToBilibili) GetResourceClass() types.ResourceClass {
+	return types.ResourceClassUpload
+}
+
+func (t *UploadToBilibili) Execute(ctx context.Context, context map[string]interface{}) bool {
+	t.Logger().Info("========================================")
+	t.Logger().Info("开始上传视频到 Bilibili")
+	t.Logger().Info("========================================")
 
 	// 1. 检查登录信息
 	loginStore := storage.GetDefaultStore()
 	if !loginStore.IsValid() {
-		t.App.Logger.Error("❌ 没有有效的 Bilibili 登录信息，请先扫码登录")
-		context["error"] = "未登录 Bilibili"
+		t.Logger().Error("❌ 没有有效的 Bilibili 登录信息，请先扫码登录")
+		types.SetTaskError(context, types.NewTaskError(types.ErrCodeNotLoggedIn, "").WithMessage("未登录 Bilibili"))
 		return false
 	}
 
 	loginInfo, err := loginStore.Load()
 	if err != nil {
-		t.App.Logger.Errorf("❌ 加载登录信息失败: %v", err)
-		context["error"] = fmt.Sprintf("加载登录信息失败: %v", err)
+		t.Logger().Errorf("❌ 加载登录信息失败: %v", err)
+		types.SetTaskError(context, types.NewTaskError(types.ErrCodeNotLoggedIn, err.Error()).WithMessage("加载登录信息失败: %v", err))
 		return false
 	}
 
-	t.App.Logger.Infof("✓ 已加载登录信息，用户 MID: %d", loginInfo.TokenInfo.Mid)
+	t.Logger().Infof("✓ 已加载登录信息，用户 MID: %d", loginInfo.TokenInfo.Mid)
 
 	// 2. 查找下载的视频文件
 	videoFiles := t.findVideoFiles()
 	if len(videoFiles) == 0 {
 		errMsg := "未找到视频文件"
-		t.App.Logger.Error("❌ " + errMsg)
-		context["error"] = errMsg
+		t.Logger().Error("❌ " + errMsg)
+		types.SetTaskError(context, types.NewTaskError(types.ErrCodeMissingInput, "").WithMessage("%s", errMsg))
 		return false
 	}
 
 	videoPath := videoFiles[0] // 使用第一个视频文件
-	t.App.Logger.Infof("📹 找到视频文件: %s", filepath.Base(videoPath))
+	t.Logger().Infof("📹 找到视频文件: %s", filepath.Base(videoPath))
 
 	// 3. 创建上传客户端
 	uploadClient := bilibili.NewUploadClient(loginInfo)
 
 	// 4. 上传视频文件到 Bilibili
-	t.App.Logger.Info("⏫ 开始上传视频到 Bilibili...")
+	// SDK 不支持中途取消上传，只能在各阶段开始前检查是否已取消
+	if ctx.Err() != nil {
+		context["error"] = "上传已取消"
+		return false
+	}
+	t.Logger().Info("⏫ 开始上传视频到 Bilibili...")
+	untrack := bilibiliUploads.track(t)
 	video, err := uploadClient.UploadVideo(videoPath)
+	untrack()
 	if err != nil {
 		userFriendlyError := t.getUserFriendlyError(err, "上传视频")
-		t.App.Logger.Errorf("❌ 上传视频失败: %v", err)
-		context["error"] = userFriendlyError
+		t.Logger().Errorf("❌ 上传视频失败: %v", err)
+		types.SetTaskError(context, userFriendlyError)
 		return false
 	}
 
-	t.App.Logger.Infof("✓ 视频上传成功！")
-	t.App.Logger.Infof("  Filename: %s", video.Filename)
-	t.App.Logger.Infof("  Title: %s", video.Title)
+	t.Logger().Infof("✓ 视频上传成功！")
+	t.Logger().Infof("  Filename: %s", video.Filename)
+	t.Logger().Infof("  Title: %s", video.Title)
 
 	// 5. 准备投稿信息
-	studio := t.buildStudioInfo(video, context)
+	studio := t.buildStudioInfo(ctx, video, context)
 
 	// 6. 提交视频到 Bilibili
-	t.App.Logger.Info("📝 提交视频投稿信息...")
+	if ctx.Err() != nil {
+		t.Logger().Warnf("⏹️ 视频文件已上传，但任务已取消，不再提交投稿: %s", t.StateManager.VideoID)
+		context["error"] = "上传已取消"
+		return false
+	}
+	t.Logger().Info("📝 提交视频投稿信息...")
 	result, err := uploadClient.SubmitVideo(studio)
 	if err != nil {
 		userFriendlyError := t.getUserFriendlyError(err, "提交视频")
-		t.App.Logger.Errorf("❌ 提交视频失败: %v", err)
-		context["error"] = userFriendlyError
+		t.Logger().Errorf("❌ 提交视频失败: %v", err)
+		types.SetTaskError(context, userFriendlyError)
 		return false
 	}
 
 	// 7. 检查提交结果
 	if result.Code != 0 {
 		errMsg := fmt.Sprintf("提交失败: code=%d, message=%s", result.Code, result.Message)
-		t.App.Logger.Error("❌ " + errMsg)
-		context["error"] = errMsg
+		t.Logger().Error("❌ " + errMsg)
+		types.SetTaskError(context, types.NewTaskError(types.ErrCodeRejected, errMsg).WithMessage("%s", errMsg))
 		return false
 	}
 
@@ -186,10 +205,10 @@ func (t *UploadToBilibili) Execute(context map[string]interface{}) bool {
 	context["bili_result"] = result
 
 	// 10. 保存结果信息到数据库和context
-	t.App.Logger.Info("💾 保存上传结果到数据库...")
+	t.Logger().Info("💾 保存上传结果到数据库...")
 	savedVideo, err := t.SavedVideoService.GetVideoByVideoID(t.StateManager.VideoID)
 	if err != nil {
-		t.App.Logger.Errorf("❌ 获取视频记录失败: %v", err)
+		t.Logger().Errorf("❌ 获取视频记录失败: %v", err)
 	} else {
 		// 尝试从 result.Data 中解析 BVID 和 AID
 		if result.Data != nil {
@@ -198,36 +217,36 @@ func (t *UploadToBilibili) Execute(context map[string]interface{}) bool {
 					if bvidStr, ok := bvid.(string); ok {
 						savedVideo.BiliBVID = bvidStr
 						// 保存BVID到context供后续字幕上传使用
-						context["bili_bvid"] = bvidStr
-						t.App.Logger.Infof("📺 BVID: %s", bvidStr)
+						context[manager.KeyBiliBVID] = bvidStr
+						t.Logger().Infof("📺 BVID: %s", bvidStr)
 					}
 				}
 				if aid, exists := dataMap["aid"]; exists {
 					if aidFloat, ok := aid.(float64); ok {
 						savedVideo.BiliAID = int64(aidFloat)
 						// 保存AID到context
-						context["bili_aid"] = int64(aidFloat)
-						t.App.Logger.Infof("🆔 AID: %d", int64(aidFloat))
+						context[manager.KeyBiliAID] = int64(aidFloat)
+						t.Logger().Infof("🆔 AID: %d", int64(aidFloat))
 					}
 				}
 			}
 		}
 
 		if err := t.SavedVideoService.UpdateVideo(savedVideo); err != nil {
-			t.App.Logger.Errorf("❌ 保存上传结果到数据库失败: %v", err)
+			t.Logger().Errorf("❌ 保存上传结果到数据库失败: %v", err)
 		} else {
-			t.App.Logger.Info("✅ 上传结果已保存到数据库")
+			t.Logger().Info("✅ 上传结果已保存到数据库")
 		}
 	}
 
 	// 10. 输出成功信息
-	t.App.Logger.Info("========================================")
-	t.App.Logger.Infof("✓ 视频投稿成功！")
+	t.Logger().Info("========================================")
+	t.Logger().Infof("✓ 视频投稿成功！")
 	if savedVideo != nil && savedVideo.BiliBVID != "" {
-		t.App.Logger.Infof("  BVID: %s", savedVideo.BiliBVID)
-		t.App.Logger.Infof("  访问链接: https://www.bilibili.com/video/%s", savedVideo.BiliBVID)
+		t.Logger().Infof("  BVID: %s", savedVideo.BiliBVID)
+		t.Logger().Infof("  访问链接: https://www.bilibili.com/video/%s", savedVideo.BiliBVID)
 	}
-	t.App.Logger.Info("========================================")
+	t.Logger().Info("========================================")
 
 	return true
 }
@@ -239,7 +258,7 @@ func (t *UploadToBilibili) findVideoFiles() []string {
 
 	files, err := os.ReadDir(t.StateManager.CurrentDir)
 	if err != nil {
-		t.App.Logger.Errorf("读取目录失败: %v", err)
+		t.Logger().Errorf("读取目录失败: %v", err)
 		return videoFiles
 	}
 
@@ -262,7 +281,7 @@ func (t *UploadToBilibili) findVideoFiles() []string {
 }
 
 // buildStudioInfo 构建投稿信息
-func (t *UploadToBilibili) buildStudioInfo(video *bilibili.Video, context map[string]interface{}) *bilibili.Studio {
+func (t *UploadToBilibili) buildStudioInfo(ctx context.Context, video *bilibili.Video, context map[string]interface{}) *bilibili.Studio {
 	// 默认值
 	title := t.StateManager.VideoID
 	desc := "自动上传的视频"
@@ -272,15 +291,15 @@ func (t *UploadToBilibili) buildStudioInfo(video *bilibili.Video, context map[st
 	// 从数据库查询视频的标题和描述信息
 	savedVideo, err := t.SavedVideoService.GetVideoByVideoID(t.StateManager.VideoID)
 	if err != nil {
-		t.App.Logger.Warnf("⚠️ 无法从数据库获取视频信息: %v，将使用默认值", err)
+		t.Logger().Warnf("⚠️ 无法从数据库获取视频信息: %v，将使用默认值", err)
 	} else {
 		// 如果标题为空，尝试补充获取元数据
 		if savedVideo.Title == "" {
-			if err := t.fetchAndSaveMetadata(t.StateManager.VideoID); err == nil {
+			if err := t.fetchAndSaveMetadata(ctx, t.StateManager.VideoID); err == nil {
 				// 重新获取
 				savedVideo, _ = t.SavedVideoService.GetVideoByVideoID(t.StateManager.VideoID)
 			} else {
-				t.App.Logger.Warnf("⚠️ 补充获取元数据失败: %v", err)
+				t.Logger().Warnf("⚠️ 补充获取元数据失败: %v", err)
 			}
 		}
 
@@ -299,31 +318,39 @@ func (t *UploadToBilibili) buildStudioInfo(video *bilibili.Video, context map[st
 
 		// 根据配置选择标题来源
 		biliConfig := t.App.Config.BilibiliConfig
-		if biliConfig != nil && biliConfig.CustomTitleTemplate != "" {
+		titleTemplate := ""
+		if biliConfig != nil {
+			titleTemplate = biliConfig.CustomTitleTemplate
+		}
+		if savedVideo.TitleTemplate != "" {
+			// 视频指定的标题模板（例如来自频道订阅的默认设置）优先于配置
+			titleTemplate = savedVideo.TitleTemplate
+		}
+		if titleTemplate != "" {
 			// 使用自定义标题模板
-			title = biliConfig.CustomTitleTemplate
+			title = titleTemplate
 			// 清理原标题中的标签
 			cleanedOriginalTitle := cleanTitle(savedVideo.Title)
 			title = strings.ReplaceAll(title, "{original_title}", cleanedOriginalTitle)
 			title = strings.ReplaceAll(title, "{ai_title}", savedVideo.GeneratedTitle)
-			t.App.Logger.Infof("✓ 使用自定义标题模板: %s", title)
+			t.Logger().Infof("✓ 使用自定义标题模板: %s", title)
 		} else if biliConfig != nil && !biliConfig.UseOriginalTitle {
 			// 配置为使用AI生成标题
 			if savedVideo.GeneratedTitle != "" {
 				title = savedVideo.GeneratedTitle
-				t.App.Logger.Infof("✓ 使用AI生成的标题: %s", title)
+				t.Logger().Infof("✓ 使用AI生成的标题: %s", title)
 			} else if savedVideo.Title != "" {
 				title = cleanTitle(savedVideo.Title)
-				t.App.Logger.Infof("✓ AI标题不存在，回退使用原始标题（已清理标签）: %s", title)
+				t.Logger().Infof("✓ AI标题不存在，回退使用原始标题（已清理标签）: %s", title)
 			}
 		} else {
 			// 默认使用原始标题（YouTube原标题）
 			if savedVideo.Title != "" {
 				title = cleanTitle(savedVideo.Title)
-				t.App.Logger.Infof("✓ 使用YouTube原始标题（已清理标签）: %s", title)
+				t.Logger().Infof("✓ 使用YouTube原始标题（已清理标签）: %s", title)
 			} else if savedVideo.GeneratedTitle != "" {
 				title = savedVideo.GeneratedTitle
-				t.App.Logger.Infof("✓ 原始标题不存在，回退使用AI标题: %s", title)
+				t.Logger().Infof("✓ 原始标题不存在，回退使用AI标题: %s", title)
 			}
 		}
 
@@ -332,9 +359,9 @@ func (t *UploadToBilibili) buildStudioInfo(video *bilibili.Video, context map[st
 		titleRunes := []rune(title)
 		if len(titleRunes) > maxTitleLength {
 			title = string(titleRunes[:maxTitleLength])
-			t.App.Logger.Warnf("⚠️ 标题过长，已截断至 %d 字符: %s", maxTitleLength, title)
+			t.Logger().Warnf("⚠️ 标题过长，已截断至 %d 字符: %s", maxTitleLength, title)
 		}
-		t.App.Logger.Infof("📝 标题长度: %d/%d 字符", len([]rune(title)), maxTitleLength)
+		t.Logger().Infof("📝 标题长度: %d/%d 字符", len([]rune(title)), maxTitleLength)
 
 		// 过滤无效的描述（YouTube的默认描述）
 		isValidDescription := func(desc string) bool {
@@ -362,18 +389,18 @@ func (t *UploadToBilibili) buildStudioInfo(video *bilibili.Video, context map[st
 			desc = biliConfig.CustomDescTemplate
 			desc = strings.ReplaceAll(desc, "{original_desc}", savedVideo.Description)
 			desc = strings.ReplaceAll(desc, "{ai_desc}", savedVideo.GeneratedDesc)
-			t.App.Logger.Infof("✓ 使用自定义描述模板")
+			t.Logger().Infof("✓ 使用自定义描述模板")
 		} else if biliConfig != nil && biliConfig.UseOriginalDesc {
 			// 配置为使用原始描述
 			if isValidDescription(savedVideo.Description) {
 				desc = savedVideo.Description
-				t.App.Logger.Infof("✓ 使用YouTube原始描述")
+				t.Logger().Infof("✓ 使用YouTube原始描述")
 			} else if savedVideo.GeneratedDesc != "" {
 				desc = savedVideo.GeneratedDesc
-				t.App.Logger.Infof("✓ 原始描述无效，回退使用AI描述")
+				t.Logger().Infof("✓ 原始描述无效，回退使用AI描述")
 			} else {
 				desc = ""
-				t.App.Logger.Info("✓ 无有效描述，仅使用原视频链接")
+				t.Logger().Info("✓ 无有效描述，仅使用原视频链接")
 			}
 		} else {
 			// 默认使用AI生成的描述 + 原视频简介
@@ -383,35 +410,35 @@ func (t *UploadToBilibili) buildStudioInfo(video *bilibili.Video, context map[st
 			// 获取AI生成的精炼介绍（100字以内）
 			if savedVideo.GeneratedDesc != "" {
 				aiIntro = savedVideo.GeneratedDesc
-				t.App.Logger.Infof("✓ AI生成的精炼介绍: %s", aiIntro)
+				t.Logger().Infof("✓ AI生成的精炼介绍: %s", aiIntro)
 			}
 
 			// 获取原视频简介
 			if isValidDescription(savedVideo.Description) {
 				originalDesc = savedVideo.Description
-				t.App.Logger.Infof("✓ 原视频简介长度: %d 字符", len([]rune(originalDesc)))
+				t.Logger().Infof("✓ 原视频简介长度: %d 字符", len([]rune(originalDesc)))
 			}
 
 			// 拼接描述：AI介绍 + 分隔线 + 原视频简介
 			if aiIntro != "" && originalDesc != "" {
 				desc = fmt.Sprintf("%s\n\n━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n📄 原视频简介：\n%s", aiIntro, originalDesc)
-				t.App.Logger.Info("✓ 使用AI介绍 + 原视频简介")
+				t.Logger().Info("✓ 使用AI介绍 + 原视频简介")
 			} else if aiIntro != "" {
 				desc = aiIntro
-				t.App.Logger.Info("✓ 仅使用AI介绍")
+				t.Logger().Info("✓ 仅使用AI介绍")
 			} else if originalDesc != "" {
 				desc = originalDesc
-				t.App.Logger.Info("✓ 仅使用原视频简介")
+				t.Logger().Info("✓ 仅使用原视频简介")
 			} else {
 				desc = ""
-				t.App.Logger.Info("✓ 无有效描述，仅使用原视频链接")
+				t.Logger().Info("✓ 无有效描述，仅使用原视频链接")
 			}
 		}
 
 		// 使用AI生成的标签
 		if savedVideo.GeneratedTags != "" {
 			tags = savedVideo.GeneratedTags
-			t.App.Logger.Infof("✓ 使用数据库中AI生成的标签: %s", tags)
+			t.Logger().Infof("✓ 使用数据库中AI生成的标签: %s", tags)
 		}
 
 		// B站简介字数限制（2000字）
@@ -425,12 +452,12 @@ func (t *UploadToBilibili) buildStudioInfo(video *bilibili.Video, context map[st
 
 		// 计算链接后缀的长度（字符数）
 		linkSuffixLength := len([]rune(linkSuffix))
-		t.App.Logger.Infof("🔗 原视频链接后缀长度: %d 字符", linkSuffixLength)
+		t.Logger().Infof("🔗 原视频链接后缀长度: %d 字符", linkSuffixLength)
 
 		// 预先截断描述，确保有足够空间给链接
 		descRunes := []rune(desc)
 		originalDescLength := len(descRunes)
-		t.App.Logger.Infof("📄 原始描述长度: %d 字符", originalDescLength)
+		t.Logger().Infof("📄 原始描述长度: %d 字符", originalDescLength)
 
 		// 计算可用的描述长度（留20个字符的安全缓冲）
 		maxAllowedDescLength := maxDescLength - linkSuffixLength - 20
@@ -442,33 +469,33 @@ func (t *UploadToBilibili) buildStudioInfo(video *bilibili.Video, context map[st
 		if len(descRunes) > maxAllowedDescLength {
 			if maxAllowedDescLength > 3 {
 				desc = string(descRunes[:maxAllowedDescLength]) + "..."
-				t.App.Logger.Warnf("⚠️ 描述过长，已截断至 %d 字符（原长度: %d）", maxAllowedDescLength, originalDescLength)
+				t.Logger().Warnf("⚠️ 描述过长，已截断至 %d 字符（原长度: %d）", maxAllowedDescLength, originalDescLength)
 			} else {
 				desc = ""
-				t.App.Logger.Warn("⚠️ 空间不足，已清空描述内容，仅保留原视频链接")
+				t.Logger().Warn("⚠️ 空间不足，已清空描述内容，仅保留原视频链接")
 			}
 		}
 
 		// 添加链接后缀
 		if linkSuffix != "" {
 			desc += linkSuffix
-			t.App.Logger.Infof("✓ 已添加原视频链接到描述")
+			t.Logger().Infof("✓ 已添加原视频链接到描述")
 		}
 
 		// 最终检查长度
 		finalDescLength := len([]rune(desc))
-		t.App.Logger.Infof("📝 最终描述长度: %d/%d 字符", finalDescLength, maxDescLength)
+		t.Logger().Infof("📝 最终描述长度: %d/%d 字符", finalDescLength, maxDescLength)
 
 		// 最后的安全检查，如果还是超长，强制截断
 		if finalDescLength > maxDescLength {
 			desc = string([]rune(desc)[:maxDescLength])
-			t.App.Logger.Errorf("❌ 描述仍然超长！强制截断至 %d 字符", maxDescLength)
+			t.Logger().Errorf("❌ 描述仍然超长！强制截断至 %d 字符", maxDescLength)
 		}
 	}
 
 	// 从 context 获取下载的封面图片并上传作为封面
-	if coverImagePath, ok := context["cover_image_path"].(string); ok && coverImagePath != "" {
-		t.App.Logger.Infof("📸 找到封面图片: %s", filepath.Base(coverImagePath))
+	if coverImagePath := manager.PipelineContext(context).CoverImagePath(); coverImagePath != "" {
+		t.Logger().Infof("📸 找到封面图片: %s", filepath.Base(coverImagePath))
 
 		// 创建上传客户端并上传封面
 		loginStore := storage.GetDefaultStore()
@@ -477,10 +504,10 @@ func (t *UploadToBilibili) buildStudioInfo(video *bilibili.Video, context map[st
 			uploadClient := bilibili.NewUploadClient(loginInfo)
 			uploadedCoverURL, err := uploadClient.UploadCover(coverImagePath)
 			if err != nil {
-				t.App.Logger.Errorf("❌ 上传封面失败: %v", err)
+				t.Logger().Errorf("❌ 上传封面失败: %v", err)
 			} else {
 				coverURL = uploadedCoverURL
-				t.App.Logger.Infof("✓ 封面上传成功: %s", coverURL)
+				t.Logger().Infof("✓ 封面上传成功: %s", coverURL)
 			}
 		}
 	}
@@ -490,12 +517,12 @@ func (t *UploadToBilibili) buildStudioInfo(video *bilibili.Video, context map[st
 	hasZhSubtitle := false
 	if _, err := os.Stat(zhSRTPath); err == nil {
 		hasZhSubtitle = true
-		t.App.Logger.Info("✓ 检测到中文字幕文件")
+		t.Logger().Info("✓ 检测到中文字幕文件")
 	}
 
 	// 更新video对象的Title为翻译后的标题
 	video.Title = title
-	t.App.Logger.Infof("✓ 设置视频Title为: %s", title)
+	t.Logger().Infof("✓ 设置视频Title为: %s", title)
 
 	// 读取配置
 	copyright := 1 // 默认自制
@@ -530,6 +557,11 @@ func (t *UploadToBilibili) buildStudioInfo(video *bilibili.Video, context map[st
 		upCloseReward = t.App.Config.BilibiliConfig.UpCloseReward
 	}
 
+	// 视频指定的分区（例如来自频道订阅的默认设置）优先于配置
+	if savedVideo != nil && savedVideo.BiliTid > 0 {
+		tid = savedVideo.BiliTid
+	}
+
 	// 如果是转载且没有提供来源，使用视频URL作为来源
 	if copyright == 2 && source == "" {
 		if savedVideo != nil {
@@ -562,28 +594,28 @@ func (t *UploadToBilibili) buildStudioInfo(video *bilibili.Video, context map[st
 
 	// 记录暂不支持的高级配置（需要SDK更新）
 	if selectionReserve > 0 {
-		t.App.Logger.Warnf("⚠️ 参与活动功能(selection_reserve=%d)暂不被SDK支持，已忽略", selectionReserve)
+		t.Logger().Warnf("⚠️ 参与活动功能(selection_reserve=%d)暂不被SDK支持，已忽略", selectionReserve)
 	}
 	if upSelectionReply > 0 {
-		t.App.Logger.Warnf("⚠️ 推荐评论功能(up_selection_reply=%d)暂不被SDK支持，已忽略", upSelectionReply)
+		t.Logger().Warnf("⚠️ 推荐评论功能(up_selection_reply=%d)暂不被SDK支持，已忽略", upSelectionReply)
 	}
 	if upCloseReply > 0 {
-		t.App.Logger.Warnf("⚠️ 关闭评论功能(up_close_reply=%d)暂不被SDK支持，已忽略", upCloseReply)
+		t.Logger().Warnf("⚠️ 关闭评论功能(up_close_reply=%d)暂不被SDK支持，已忽略", upCloseReply)
 	}
 	if upCloseReward > 0 {
-		t.App.Logger.Warnf("⚠️ 关闭打赏功能(up_close_reward=%d)暂不被SDK支持，已忽略", upCloseReward)
+		t.Logger().Warnf("⚠️ 关闭打赏功能(up_close_reward=%d)暂不被SDK支持，已忽略", upCloseReward)
 	}
 
-	t.App.Logger.Infof("📋 投稿信息:")
-	t.App.Logger.Infof("  标题: %s", studio.Title)
-	t.App.Logger.Infof("  简介: %s", t.truncateString(studio.Desc, 100))
-	t.App.Logger.Infof("  标签: %s", studio.Tag)
-	t.App.Logger.Infof("  分区: %d", studio.Tid)
-	t.App.Logger.Infof("  封面: %s", studio.Cover)
-	t.App.Logger.Infof("  字幕: %v", studio.OpenSubtitle)
-	t.App.Logger.Infof("  类型: %d (1=自制, 2=转载)", studio.Copyright)
+	t.Logger().Infof("📋 投稿信息:")
+	t.Logger().Infof("  标题: %s", studio.Title)
+	t.Logger().Infof("  简介: %s", t.truncateString(studio.Desc, 100))
+	t.Logger().Infof("  标签: %s", studio.Tag)
+	t.Logger().Infof("  分区: %d", studio.Tid)
+	t.Logger().Infof("  封面: %s", studio.Cover)
+	t.Logger().Infof("  字幕: %v", studio.OpenSubtitle)
+	t.Logger().Infof("  类型: %d (1=自制, 2=转载)", studio.Copyright)
 	if studio.Copyright == 2 {
-		t.App.Logger.Infof("  来源: %s", studio.Source)
+		t.Logger().Infof("  来源: %s", studio.Source)
 	}
 
 	return studio
@@ -607,66 +639,69 @@ func (t *UploadToBilibili) truncateTitle(title string, maxLen int) string {
 	return string(runes[:maxLen-3]) + "..."
 }
 
-// getUserFriendlyError 将技术错误转换为用户友好的错误信息
-func (t *UploadToBilibili) getUserFriendlyError(err error, operation string) string {
+// getUserFriendlyError 将技术错误转换为带错误码和用户友好提示的结构化错误
+func (t *UploadToBilibili) getUserFriendlyError(err error, operation string) *types.TaskError {
 	errorStr := err.Error()
+	newError := func(code types.ErrorCode, message string) *types.TaskError {
+		return types.NewTaskError(code, errorStr).WithMessage("%s失败：%s", operation, message)
+	}
 
 	// 网络相关错误
 	if strings.Contains(errorStr, "broken pipe") || strings.Contains(errorStr, "connection reset") {
-		return fmt.Sprintf("%s失败：网络连接中断，请检查网络状态后重试", operation)
+		return newError(types.ErrCodeNetwork, "网络连接中断，请检查网络状态后重试")
 	}
 
 	if strings.Contains(errorStr, "timeout") || strings.Contains(errorStr, "deadline exceeded") {
-		return fmt.Sprintf("%s失败：网络超时，请稍后重试", operation)
+		return newError(types.ErrCodeTimeout, "网络超时，请稍后重试")
 	}
 
 	if strings.Contains(errorStr, "connection refused") {
-		return fmt.Sprintf("%s失败：无法连接到B站服务器，请检查网络连接", operation)
+		return newError(types.ErrCodeNetwork, "无法连接到B站服务器，请检查网络连接")
 	}
 
 	if strings.Contains(errorStr, "no such host") || strings.Contains(errorStr, "dns") {
-		return fmt.Sprintf("%s失败：网络域名解析失败，请检查网络设置", operation)
+		return newError(types.ErrCodeNetwork, "网络域名解析失败，请检查网络设置")
 	}
 
 	// 文件相关错误
 	if strings.Contains(errorStr, "no such file") || strings.Contains(errorStr, "file not found") {
-		return fmt.Sprintf("%s失败：找不到视频文件，请确认文件已正确下载", operation)
+		return newError(types.ErrCodeMissingInput, "找不到视频文件，请确认文件已正确下载")
 	}
 
 	if strings.Contains(errorStr, "permission denied") {
-		return fmt.Sprintf("%s失败：文件访问权限不足", operation)
+		return newError(types.ErrCodeIO, "文件访问权限不足")
 	}
 
 	if strings.Contains(errorStr, "file too large") {
-		return fmt.Sprintf("%s失败：文件过大，超出B站上传限制", operation)
+		return newError(types.ErrCodeFileTooLarge, "文件过大，超出B站上传限制")
 	}
 
 	// B站API相关错误
 	if strings.Contains(errorStr, "401") || strings.Contains(errorStr, "unauthorized") {
-		return fmt.Sprintf("%s失败：登录状态已过期，请重新登录", operation)
+		return newError(types.ErrCodeAuthFailed, "登录状态已过期，请重新登录")
 	}
 
 	if strings.Contains(errorStr, "403") || strings.Contains(errorStr, "forbidden") {
-		return fmt.Sprintf("%s失败：账号权限不足或被限制", operation)
+		return newError(types.ErrCodeForbidden, "账号权限不足或被限制")
 	}
 
 	if strings.Contains(errorStr, "429") || strings.Contains(errorStr, "rate limit") {
-		return fmt.Sprintf("%s失败：操作频率过快，请稍后再试", operation)
+		return newError(types.ErrCodeRateLimited, "操作频率过快，请稍后再试")
 	}
 
 	if strings.Contains(errorStr, "500") || strings.Contains(errorStr, "internal server error") {
-		return fmt.Sprintf("%s失败：B站服务器临时异常，请稍后重试", operation)
+		return newError(types.ErrCodeServiceUnavailable, "B站服务器临时异常，请稍后重试")
 	}
 
 	if strings.Contains(errorStr, "upload chunks") {
-		return fmt.Sprintf("%s失败：视频分片上传中断，可能是网络不稳定导致，请重试", operation)
+		return newError(types.ErrCodeNetwork, "视频分片上传中断，可能是网络不稳定导致，请重试")
 	}
 
 	// 通用错误处理
 	if strings.Contains(errorStr, "failed to") {
-		return fmt.Sprintf("%s失败：操作执行失败，请稍后重试", operation)
+		return newError(types.ErrCodeUnknown, "操作执行失败，请稍后重试")
 	}
 
 	// 如果是未知错误，返回简化的错误信息
-	return fmt.Sprintf("%s失败：发生未知错误，请重试或联系技术支持", operation)
+	return newError(types.ErrCodeUnknown, "发生未知错误，请重试或联系技术支持")
 }
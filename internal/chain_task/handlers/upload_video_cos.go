@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"gorm.io/gorm"
 	"github.com/difyz9/ytb2bili/internal/chain_task/base"
 	"github.com/difyz9/ytb2bili/internal/chain_task/manager"
 	"github.com/difyz9/ytb2bili/internal/core"
+	"github.com/difyz9/ytb2bili/internal/core/types"
 	"github.com/difyz9/ytb2bili/internal/core/models"
 	"github.com/difyz9/ytb2bili/pkg/cos"
 	"github.com/difyz9/ytb2bili/pkg/utils"
@@ -53,7 +55,12 @@ func (t *UploadVideo2CosHandler) ProcessThumbnail() {
 	}
 }
 
-func (t *UploadVideo2CosHandler) Execute(context map[string]interface{}) bool {
+// GetResourceClass 上传到 COS 受上传带宽限制
+func (t *UploadVideo2CosHandler) GetResourceClass() types.ResourceClass {
+	return types.ResourceClassUpload
+}
+
+func (t *UploadVideo2CosHandler) Execute(ctx context.Context, context map[string]interface{}) bool {
 
 	fmt.Println("视频转码并上传腾讯cos")
 	t.ProcessThumbnail()
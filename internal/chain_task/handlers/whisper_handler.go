@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -9,6 +10,7 @@ import (
 	"github.com/difyz9/ytb2bili/internal/chain_task/base"
 	"github.com/difyz9/ytb2bili/internal/chain_task/manager"
 	"github.com/difyz9/ytb2bili/internal/core"
+	"github.com/difyz9/ytb2bili/internal/core/types"
 	"github.com/difyz9/ytb2bili/pkg/cos"
 	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
 	"gorm.io/gorm"
@@ -44,42 +46,47 @@ func NewWhisperHandler(name string, app *core.AppServer, stateManager *manager.S
 	}
 }
 
-func (h *WhisperHandler) Execute(context map[string]interface{}) bool {
-	fmt.Println("开始使用 Whisper 转录音频")
+// GetResourceClass Whisper 转录是 CPU 密集型任务
+func (h *WhisperHandler) GetResourceClass() types.ResourceClass {
+	return types.ResourceClassCPU
+}
+
+func (h *WhisperHandler) Execute(ctx context.Context, context map[string]interface{}) bool {
+	h.Logger().Info("开始使用 Whisper 转录音频")
 	
 	// 检查 WAV 音频文件是否存在
 	if _, err := os.Stat(h.StateManager.OriginalWAV); os.IsNotExist(err) {
-		fmt.Printf("错误: WAV 音频文件不存在: %s\n", h.StateManager.OriginalWAV)
+		h.Logger().Errorf("错误: WAV 音频文件不存在: %s", h.StateManager.OriginalWAV)
 		context["error"] = fmt.Sprintf("WAV 音频文件不存在: %s", h.StateManager.OriginalWAV)
 		return false
 	}
 	
 	// 检查模型文件是否存在
 	if _, err := os.Stat(h.ModelPath); os.IsNotExist(err) {
-		fmt.Printf("错误: Whisper 模型文件不存在: %s\n", h.ModelPath)
+		h.Logger().Errorf("错误: Whisper 模型文件不存在: %s", h.ModelPath)
 		context["error"] = fmt.Sprintf("Whisper 模型文件不存在: %s", h.ModelPath)
 		return false
 	}
 	
-	fmt.Printf("📝 使用 Whisper 转录: %s\n", h.StateManager.OriginalWAV)
-	fmt.Printf("   模型: %s\n", h.ModelPath)
-	fmt.Printf("   语言: %s\n", h.Language)
-	fmt.Printf("   线程: %d\n", h.Threads)
+	h.Logger().Infof("📝 使用 Whisper 转录: %s", h.StateManager.OriginalWAV)
+	h.Logger().Infof("   模型: %s", h.ModelPath)
+	h.Logger().Infof("   语言: %s", h.Language)
+	h.Logger().Infof("   线程: %d", h.Threads)
 	
 	// 执行转录，生成 SRT 字幕文件
-	if err := h.transcribe(h.ModelPath, h.StateManager.OriginalWAV, h.Language, h.Threads, true, h.StateManager.OriginalSRT); err != nil {
-		fmt.Printf("❌ Whisper 转录失败: %v\n", err)
+	if err := h.transcribe(ctx, h.ModelPath, h.StateManager.OriginalWAV, h.Language, h.Threads, true, h.StateManager.OriginalSRT); err != nil {
+		h.Logger().Errorf("❌ Whisper 转录失败: %v", err)
 		context["error"] = fmt.Sprintf("Whisper 转录失败: %v", err)
 		return false
 	}
 	
-	fmt.Printf("✅ Whisper 转录完成，字幕文件保存至: %s\n", h.StateManager.OriginalSRT)
-	context["subtitle_path"] = h.StateManager.OriginalSRT
+	h.Logger().Infof("✅ Whisper 转录完成，字幕文件保存至: %s", h.StateManager.OriginalSRT)
+	context[manager.KeySubtitlePath] = h.StateManager.OriginalSRT
 	return true
 }
 
 // transcribe 执行语音识别
-func (h *WhisperHandler) transcribe(modelPath, wavPath, language string, threads int, outputSRT bool, outputPath string) error {
+func (h *WhisperHandler) transcribe(ctx context.Context, modelPath, wavPath, language string, threads int, outputSRT bool, outputPath string) error {
 	// 加载模型
 	model, err := whisper.New(modelPath)
 	if err != nil {
@@ -112,10 +119,19 @@ func (h *WhisperHandler) transcribe(modelPath, wavPath, language string, threads
 	// 启用翻译模式（如果需要）
 	context.SetTranslate(false)
 
-	// 处理音频
-	if err := context.Process(samples, nil, nil, nil); err != nil {
+	// 处理音频，ctx 被取消时在编码开始前中止识别，识别进度发布到进度事件总线
+	progress := func(percent int) {
+		h.ReportProgress(float64(percent), fmt.Sprintf("语音识别 %d%%", percent))
+	}
+	if err := context.Process(samples, func() bool { return ctx.Err() == nil }, nil, progress); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("转录已取消: %v", ctx.Err())
+		}
 		return fmt.Errorf("处理音频失败: %v", err)
 	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("转录已取消: %v", ctx.Err())
+	}
 
 	// 创建输出文件
 	outFile, err := os.Create(outputPath)
@@ -149,8 +165,8 @@ func (h *WhisperHandler) transcribe(modelPath, wavPath, language string, threads
 			// 字幕文本
 			fmt.Fprintf(outFile, "%s\n\n", strings.TrimSpace(segment.Text))
 
-			// 同时输出到控制台
-			fmt.Printf("[%6s --> %6s]  %s\n",
+			// 同时输出到步骤日志
+			h.Logger().Infof("[%6s --> %6s]  %s",
 				segment.Start.Truncate(time.Millisecond),
 				segment.End.Truncate(time.Millisecond),
 				segment.Text)
@@ -164,8 +180,8 @@ func (h *WhisperHandler) transcribe(modelPath, wavPath, language string, threads
 				segment.End.Truncate(time.Millisecond),
 				segment.Text)
 
-			// 同时输出到控制台
-			fmt.Printf("[%6s --> %6s]  %s\n",
+			// 同时输出到步骤日志
+			h.Logger().Infof("[%6s --> %6s]  %s",
 				segment.Start.Truncate(time.Millisecond),
 				segment.End.Truncate(time.Millisecond),
 				segment.Text)
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/difyz9/ytb2bili/internal/core/types"
+)
+
+// maxYtDlpErrorLines 最多保留的 yt-dlp 错误行数
+const maxYtDlpErrorLines = 5
+
+// ytDlpErrorRules yt-dlp 特有的错误，优先于通用规则匹配
+var ytDlpErrorRules = []struct {
+	keyword string
+	code    types.ErrorCode
+	message string
+}{
+	// YouTube 对下载请求返回 403 通常是临时限流或签名过期，稍后重试即可恢复
+	{"http error 403", types.ErrCodeRateLimited, "YouTube 拒绝了下载请求（HTTP 403），稍后自动重试"},
+	{"requested format is not available", types.ErrCodeVideoUnavailable, "视频没有可下载的格式"},
+	{"unable to extract", types.ErrCodeToolMissing, "yt-dlp 无法解析页面，请更新 yt-dlp 到最新版本"},
+	{"nsig extraction failed", types.ErrCodeToolMissing, "yt-dlp 无法解析页面，请更新 yt-dlp 到最新版本"},
+}
+
+// isYtDlpErrorLine 判断 yt-dlp 输出行是否为错误信息
+func isYtDlpErrorLine(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "ERROR:")
+}
+
+// ParseYtDlpError 根据 yt-dlp 输出的 ERROR 行识别失败原因，没有错误行时返回 nil
+func ParseYtDlpError(lines []string) *types.TaskError {
+	if len(lines) == 0 {
+		return nil
+	}
+	if len(lines) > maxYtDlpErrorLines {
+		lines = lines[len(lines)-maxYtDlpErrorLines:]
+	}
+
+	detail := strings.Join(lines, "\n")
+	lower := strings.ToLower(detail)
+	for _, rule := range ytDlpErrorRules {
+		if strings.Contains(lower, rule.keyword) {
+			return types.NewTaskError(rule.code, detail).WithMessage("%s", rule.message)
+		}
+	}
+	return types.ClassifyError(detail)
+}
@@ -0,0 +1,98 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrShutdown 实例关闭时中断任务的原因
+// 被中断的任务不记录为已取消，保持执行中状态，由关闭流程回收并重新排队
+var ErrShutdown = errors.New("实例正在关闭，任务已中断")
+
+// IsShutdown 判断任务的 ctx 是否因实例关闭而结束
+func IsShutdown(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrShutdown)
+}
+
+// CancelRegistry 记录正在执行的视频任务的取消函数
+// 任务链和上传任务启动时注册，API 通过 Cancel 通知任务终止，实例关闭时通过 Shutdown 中断所有任务
+type CancelRegistry struct {
+	mutex    sync.Mutex
+	cancels  map[string]map[*int]context.CancelCauseFunc // 视频ID -> 该视频所有正在执行的任务
+	shutdown atomic.Bool
+}
+
+// NewCancelRegistry 创建取消注册表
+func NewCancelRegistry() *CancelRegistry {
+	return &CancelRegistry{
+		cancels: make(map[string]map[*int]context.CancelCauseFunc),
+	}
+}
+
+// Register 为视频创建可取消的 context，任务结束后必须调用返回的 release
+func (r *CancelRegistry) Register(parent context.Context, videoID string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(parent)
+	token := new(int)
+
+	r.mutex.Lock()
+	if r.shutdown.Load() {
+		cancel(ErrShutdown)
+	}
+	if r.cancels[videoID] == nil {
+		r.cancels[videoID] = make(map[*int]context.CancelCauseFunc)
+	}
+	r.cancels[videoID][token] = cancel
+	r.mutex.Unlock()
+
+	release := func() {
+		r.mutex.Lock()
+		delete(r.cancels[videoID], token)
+		if len(r.cancels[videoID]) == 0 {
+			delete(r.cancels, videoID)
+		}
+		r.mutex.Unlock()
+		cancel(nil)
+	}
+	return ctx, release
+}
+
+// Cancel 取消视频所有正在执行的任务，返回是否有任务被取消
+func (r *CancelRegistry) Cancel(videoID string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	cancels := r.cancels[videoID]
+	for _, cancel := range cancels {
+		cancel(nil)
+	}
+	return len(cancels) > 0
+}
+
+// Shutdown 实例关闭时中断所有正在执行的任务，之后注册的任务会立即被中断，返回被中断的视频数量
+func (r *CancelRegistry) Shutdown() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.shutdown.Store(true)
+	for _, cancels := range r.cancels {
+		for _, cancel := range cancels {
+			cancel(ErrShutdown)
+		}
+	}
+	return len(r.cancels)
+}
+
+// ShuttingDown 判断实例是否已开始中断任务
+// 此时任务不再释放视频租约，由关闭流程统一回收
+func (r *CancelRegistry) ShuttingDown() bool {
+	return r.shutdown.Load()
+}
+
+// IsRunning 判断视频是否有正在执行的任务
+func (r *CancelRegistry) IsRunning(videoID string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return len(r.cancels[videoID]) > 0
+}
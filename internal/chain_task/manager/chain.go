@@ -1,67 +1,351 @@
 package manager
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"reflect"
+	"time"
+
 	"github.com/difyz9/ytb2bili/internal/core/types"
 )
 
+// BlockableTask 可以被标记为"被阻塞"的任务
+// 前置任务失败时，任务链会调用 Block 通知任务它不会被执行
+type BlockableTask interface {
+	Block(reason string)
+}
+
+// CancellableTask 可以被标记为"已取消"的任务
+// 任务链被取消时，尚未开始执行的任务会收到 MarkCancelled 通知
+type CancellableTask interface {
+	MarkCancelled(reason string)
+}
+
+// TimeoutAwareTask 可以被标记为"执行超时"的任务
+// 任务超过超时策略规定的时间后，任务链会调用 MarkTimedOut 通知任务
+type TimeoutAwareTask interface {
+	MarkTimedOut(reason string)
+}
+
+// PauseGate 任务开始执行前的暂停检查
+// 任务所属的阶段或任务类型已暂停时 Wait 阻塞到恢复为止，ctx 被取消时返回错误
+type PauseGate interface {
+	Wait(ctx context.Context, taskName string) error
+}
+
+// IsTimeout 判断任务的 ctx 是否因超过超时时间而结束
+func IsTimeout(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
+
 // TaskChain 任务链
+// 任务之间通过前置依赖组成有向无环图：没有依赖关系的任务并行执行，
+// 某个任务失败时只会阻塞依赖它的任务，其他分支继续执行
 type TaskChain struct {
-	Tasks   []types.Task
-	Context map[string]interface{}
+	Tasks        []types.Task
+	Dependencies map[string][]string // 任务名称 -> 前置任务名称列表
+	Context      map[string]interface{}
+	Scheduler    *ResourceScheduler // 资源调度器（可选），为空时不限制并发
+	Timeouts     *TimeoutPolicy     // 超时策略（可选），为空时不限制执行时间
+	Pauses       PauseGate          // 暂停检查（可选），为空时不检查
+	timedOut     map[string]bool    // 本次 Run 中超时的任务
+}
+
+// 任务在一次 Run 中的执行状态
+const (
+	nodeRunning   = "running"
+	nodeSucceeded = "succeeded"
+	nodeFailed    = "failed"
+	nodeBlocked   = "blocked"
+	nodeCancelled = "cancelled"
+)
+
+// nodeResult 单个任务的执行结果
+type nodeResult struct {
+	task     types.Task
+	success  bool
+	timedOut bool
+	message  string
+	context  map[string]interface{}
 }
 
 // NewTaskChain 创建任务链
 func NewTaskChain() *TaskChain {
 	return &TaskChain{
-		Tasks:   make([]types.Task, 0),
-		Context: make(map[string]interface{}),
+		Tasks:        make([]types.Task, 0),
+		Dependencies: make(map[string][]string),
+		Context:      make(map[string]interface{}),
+		timedOut:     make(map[string]bool),
 	}
 }
 
-// AddTask 添加任务到链中
+// WithScheduler 设置资源调度器，任务执行前按资源类别占用槽位
+func (c *TaskChain) WithScheduler(scheduler *ResourceScheduler) *TaskChain {
+	c.Scheduler = scheduler
+	return c
+}
+
+// WithTimeoutPolicy 设置超时策略，任务超过超时时间后其 ctx 会被取消
+func (c *TaskChain) WithTimeoutPolicy(policy *TimeoutPolicy) *TaskChain {
+	c.Timeouts = policy
+	return c
+}
+
+// WithPauseGate 设置暂停检查，任务在占用资源槽位和开始计时前等待暂停恢复
+func (c *TaskChain) WithPauseGate(gate PauseGate) *TaskChain {
+	c.Pauses = gate
+	return c
+}
+
+// TimedOut 判断任务在最近一次 Run 中是否因超时失败
+func (c *TaskChain) TimedOut(name string) bool {
+	return c.timedOut[name]
+}
+
+// AddTask 添加任务到链中，默认依赖上一个添加的任务（保持线性执行）
 func (c *TaskChain) AddTask(task types.Task) *TaskChain {
+	var deps []string
+	if len(c.Tasks) > 0 {
+		deps = []string{c.Tasks[len(c.Tasks)-1].GetName()}
+	}
+	return c.AddTaskWithDeps(task, deps...)
+}
+
+// AddTaskWithDeps 添加任务到链中并显式声明前置任务，不传 deps 表示没有前置依赖
+func (c *TaskChain) AddTaskWithDeps(task types.Task, deps ...string) *TaskChain {
 	if err := task.InsertTask(); err != nil {
 		log.Printf("添加任务到数据库失败: %v", err)
 	}
 	c.Tasks = append(c.Tasks, task)
+	c.Dependencies[task.GetName()] = deps
 	return c
 }
 
+// GetDependencies 获取任务的前置任务名称列表
+func (c *TaskChain) GetDependencies(name string) []string {
+	return c.Dependencies[name]
+}
+
 // Run 执行任务链
-func (c *TaskChain) Run(stopOnFailure bool) map[string]interface{} {
+// stopOnFailure 为 true 时，失败任务的所有下游任务都会被阻塞；
+// 为 false 时，下游任务忽略前置任务的失败继续执行。
+// ctx 被取消后不再启动新的任务，正在执行的任务由各自的 Execute 负责尽快退出
+func (c *TaskChain) Run(ctx context.Context, stopOnFailure bool) map[string]interface{} {
+	names := make(map[string]bool, len(c.Tasks))
 	for _, task := range c.Tasks {
-		taskName := task.GetName()
-		log.Printf("正在执行任务: %s", taskName)
+		names[task.GetName()] = true
+	}
+	for _, task := range c.Tasks {
+		for _, dep := range c.Dependencies[task.GetName()] {
+			if !names[dep] {
+				log.Printf("任务 %s 的前置任务 %s 不在任务链中，忽略该依赖", task.GetName(), dep)
+			}
+		}
+	}
 
-		// 执行任务
-		success := false
-		var message string
+	states := make(map[string]string, len(c.Tasks))
+	bases := make(map[string]map[string]interface{}, len(c.Tasks)) // 任务开始执行时的上下文
+	results := make(chan nodeResult)
+	running := 0
 
-		func() {
-			defer func() {
-				if r := recover(); r != nil {
-					message = fmt.Sprintf("任务执行异常: %v", r)
-					log.Printf("任务 %s 发生异常: %v", taskName, r)
+	for {
+		// 启动所有前置任务已完成的任务，阻塞前置任务失败的任务
+		for changed := true; changed; {
+			changed = false
+			for _, task := range c.Tasks {
+				taskName := task.GetName()
+				if states[taskName] != "" {
+					continue
+				}
 
+				if ctx.Err() != nil {
+					states[taskName] = nodeCancelled
+					changed = true
+					// 实例关闭时保持待执行，由关闭流程重新排队
+					if cancellable, ok := task.(CancellableTask); ok && !IsShutdown(ctx) {
+						cancellable.MarkCancelled("任务已取消")
+					}
+					continue
 				}
-			}()
 
-			success = task.Execute(c.Context)
-		}()
+				ready, blockedBy := c.checkDependencies(taskName, states, names, stopOnFailure)
+				if blockedBy != "" {
+					states[taskName] = nodeBlocked
+					changed = true
+					log.Printf("任务 %s 的前置任务 %s 未成功，跳过执行", taskName, blockedBy)
+					if blockable, ok := task.(BlockableTask); ok {
+						blockable.Block(fmt.Sprintf("前置任务 %s 未成功", blockedBy))
+					}
+					continue
+				}
+				if !ready {
+					continue
+				}
 
-		if success {
+				states[taskName] = nodeRunning
+				running++
+				bases[taskName] = c.snapshotContext()
+				go c.execute(ctx, task, c.snapshotContext(), results)
+			}
+		}
 
-		} else if message == "" { // 非异常导致的失败
-			// 更新任务状态为失败
+		if running == 0 {
+			break
+		}
 
-			log.Printf("任务 %s 执行失败，终止链", taskName)
-			if stopOnFailure {
-				break
+		// 等待任意一个任务结束，合并其结果后再调度下游任务
+		result := <-results
+		running--
+		taskName := result.task.GetName()
+
+		c.mergeContext(result.context, bases[taskName])
+
+		if result.success {
+			states[taskName] = nodeSucceeded
+			continue
+		}
+
+		states[taskName] = nodeFailed
+		log.Printf("任务 %s 执行失败", taskName)
+		if result.timedOut {
+			c.timedOut[taskName] = true
+		}
+		if _, exists := c.Context["error"]; !exists {
+			if result.timedOut {
+				types.SetTaskError(c.Context, types.NewTaskError(types.ErrCodeTimeout, result.message).WithMessage("%s", result.message))
+			} else if taskErr := types.TaskErrorFromContext(result.context); taskErr != nil {
+				types.SetTaskError(c.Context, taskErr)
+			} else if result.message != "" {
+				types.SetTaskError(c.Context, types.ClassifyError(result.message))
+			} else {
+				c.Context["error"] = fmt.Sprintf("任务 %s 执行失败", taskName)
 			}
 		}
 	}
 
+	// 仍未执行的任务存在循环依赖
+	for _, task := range c.Tasks {
+		taskName := task.GetName()
+		if states[taskName] != "" {
+			continue
+		}
+		log.Printf("任务 %s 存在循环依赖，无法执行", taskName)
+		if blockable, ok := task.(BlockableTask); ok {
+			blockable.Block("任务存在循环依赖")
+		}
+		if _, exists := c.Context["error"]; !exists {
+			c.Context["error"] = fmt.Sprintf("任务 %s 存在循环依赖", taskName)
+		}
+	}
+
 	return c.Context
 }
+
+// checkDependencies 检查任务的前置任务状态
+// 返回是否可以执行，以及导致阻塞的前置任务名称
+func (c *TaskChain) checkDependencies(taskName string, states map[string]string, names map[string]bool, stopOnFailure bool) (bool, string) {
+	ready := true
+	for _, dep := range c.Dependencies[taskName] {
+		if !names[dep] {
+			continue
+		}
+		switch states[dep] {
+		case nodeSucceeded:
+		case nodeFailed, nodeBlocked, nodeCancelled:
+			if stopOnFailure {
+				return false, dep
+			}
+		default:
+			ready = false
+		}
+	}
+	return ready, ""
+}
+
+// snapshotContext 复制当前上下文，每个任务在自己的副本上执行，避免并发写入
+func (c *TaskChain) snapshotContext() map[string]interface{} {
+	context := make(map[string]interface{}, len(c.Context))
+	for k, v := range c.Context {
+		if k != "error" && k != types.ErrorInfoKey {
+			context[k] = v
+		}
+	}
+	return context
+}
+
+// mergeContext 把任务新增或修改的键合并回任务链上下文
+// 只比较任务开始时的快照，并行分支各自写入的键不会被其他分支的旧值覆盖
+func (c *TaskChain) mergeContext(taskContext, base map[string]interface{}) {
+	for k, v := range taskContext {
+		if k == "error" || k == types.ErrorInfoKey {
+			continue
+		}
+		if old, exists := base[k]; exists && reflect.DeepEqual(old, v) {
+			continue
+		}
+		c.Context[k] = v
+	}
+}
+
+// execute 执行单个任务并将结果发送到 results
+func (c *TaskChain) execute(ctx context.Context, task types.Task, taskContext map[string]interface{}, results chan<- nodeResult) {
+	taskName := task.GetName()
+	log.Printf("正在执行任务: %s", taskName)
+
+	result := nodeResult{task: task, context: taskContext}
+
+	func() {
+		// 所属阶段或任务类型已暂停时等待恢复
+		if c.Pauses != nil {
+			if err := c.Pauses.Wait(ctx, taskName); err != nil {
+				result.message = "任务已取消"
+				if cancellable, ok := task.(CancellableTask); ok && !IsShutdown(ctx) {
+					cancellable.MarkCancelled(result.message)
+				}
+				return
+			}
+		}
+
+		// 按资源类别占用槽位，达到上限时等待
+		class := task.GetResourceClass()
+		if err := c.Scheduler.Acquire(ctx, class); err != nil {
+			result.message = "任务已取消"
+			if cancellable, ok := task.(CancellableTask); ok && !IsShutdown(ctx) {
+				cancellable.MarkCancelled(result.message)
+			}
+			return
+		}
+		defer c.Scheduler.Release(class)
+
+		defer func() {
+			if r := recover(); r != nil {
+				result.message = fmt.Sprintf("任务执行异常: %v", r)
+				log.Printf("任务 %s 发生异常: %v", taskName, r)
+			}
+		}()
+
+		// 按超时策略限制执行时间
+		taskCtx := ctx
+		timeout := c.Timeouts.Timeout(taskName, PipelineContext(taskContext).VideoDuration())
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			taskCtx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		result.success = task.Execute(taskCtx, taskContext)
+
+		if !result.success && ctx.Err() == nil && IsTimeout(taskCtx) {
+			result.timedOut = true
+			result.message = fmt.Sprintf("任务 %s 执行超时（超过 %v）", taskName, timeout.Round(time.Second))
+			log.Printf("%s", result.message)
+			if timeoutAware, ok := task.(TimeoutAwareTask); ok {
+				timeoutAware.MarkTimedOut(result.message)
+			}
+		}
+	}()
+
+	results <- result
+}
@@ -0,0 +1,92 @@
+package manager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/difyz9/ytb2bili/internal/core/types"
+)
+
+// funcTask 测试用任务，Execute 调用 run
+type funcTask struct {
+	name string
+	run  func(context map[string]interface{}) bool
+}
+
+func (t *funcTask) Execute(ctx context.Context, context map[string]interface{}) bool {
+	return t.run(context)
+}
+func (t *funcTask) GetName() string                           { return t.name }
+func (t *funcTask) GetResourceClass() types.ResourceClass     { return "" }
+func (t *funcTask) InsertTask() error                         { return nil }
+func (t *funcTask) UpdateStatus(status, message string) error { return nil }
+
+// setTask 把 values 写入上下文的任务
+func setTask(name string, values map[string]interface{}) *funcTask {
+	return &funcTask{name: name, run: func(context map[string]interface{}) bool {
+		for k, v := range values {
+			context[k] = v
+		}
+		return true
+	}}
+}
+
+func TestRunMergesParallelBranches(t *testing.T) {
+	tests := []struct {
+		name string
+		want map[string]interface{}
+		add  func(chain *TaskChain)
+	}{
+		{
+			name: "并行任务写入不同的键",
+			want: map[string]interface{}{"a": 1, "b": 2, "shared": "root"},
+			add: func(chain *TaskChain) {
+				chain.AddTaskWithDeps(setTask("root", map[string]interface{}{"a": 0, "b": 0, "shared": "root"}))
+				chain.AddTaskWithDeps(setTask("left", map[string]interface{}{"a": 1}), "root")
+				chain.AddTaskWithDeps(setTask("right", map[string]interface{}{"b": 2}), "root")
+			},
+		},
+		{
+			name: "下游任务看到两个分支的结果",
+			want: map[string]interface{}{"a": 1, "b": 2, "sum": 3},
+			add: func(chain *TaskChain) {
+				chain.AddTaskWithDeps(setTask("left", map[string]interface{}{"a": 1}))
+				chain.AddTaskWithDeps(setTask("right", map[string]interface{}{"b": 2}))
+				chain.AddTaskWithDeps(&funcTask{name: "join", run: func(context map[string]interface{}) bool {
+					a, _ := context["a"].(int)
+					b, _ := context["b"].(int)
+					context["sum"] = a + b
+					return true
+				}}, "left", "right")
+			},
+		},
+		{
+			name: "任务写回相同的值不覆盖其他分支",
+			want: map[string]interface{}{"a": 1},
+			add: func(chain *TaskChain) {
+				chain.AddTaskWithDeps(setTask("root", map[string]interface{}{"a": 0}))
+				chain.AddTaskWithDeps(setTask("left", map[string]interface{}{"a": 1}), "root")
+				chain.AddTaskWithDeps(setTask("right", map[string]interface{}{"a": 0}), "root")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 重复执行以覆盖两个分支不同的完成顺序
+			for i := 0; i < 50; i++ {
+				chain := NewTaskChain()
+				tt.add(chain)
+				got := chain.Run(context.Background(), true)
+				if _, failed := got["error"]; failed {
+					t.Fatalf("Run() error = %v", got["error"])
+				}
+				for k, want := range tt.want {
+					if got[k] != want {
+						t.Fatalf("context[%q] = %v, want %v", k, got[k], want)
+					}
+				}
+			}
+		})
+	}
+}
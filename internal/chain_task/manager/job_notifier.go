@@ -0,0 +1,60 @@
+package manager
+
+import (
+	"sync"
+	"time"
+)
+
+// JobNotifier 唤醒任务分发，替代定时轮询数据库
+// 提交视频、重试步骤、worker 空闲时调用 Notify，多次唤醒在分发前合并为一次
+type JobNotifier struct {
+	wake    chan struct{}
+	mutex   sync.Mutex
+	timer   *time.Timer
+	timerAt time.Time
+}
+
+// NewJobNotifier 创建任务唤醒器
+func NewJobNotifier() *JobNotifier {
+	return &JobNotifier{
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// Notify 唤醒任务分发，不会阻塞
+func (n *JobNotifier) Notify() {
+	select {
+	case n.wake <- struct{}{}:
+	default:
+	}
+}
+
+// NotifyAt 在 at 时唤醒任务分发，例如步骤的下次重试时间；已有更早的唤醒时间时忽略
+func (n *JobNotifier) NotifyAt(at time.Time) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if n.timer != nil {
+		if !n.timerAt.After(at) {
+			return
+		}
+		n.timer.Stop()
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(time.Until(at), func() {
+		n.mutex.Lock()
+		if n.timer == timer {
+			n.timer = nil
+		}
+		n.mutex.Unlock()
+		n.Notify()
+	})
+	n.timer = timer
+	n.timerAt = at
+}
+
+// C 接收唤醒信号的通道
+func (n *JobNotifier) C() <-chan struct{} {
+	return n.wake
+}
@@ -0,0 +1,147 @@
+package manager
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// 任务链上下文中各步骤输出的键
+// 步骤成功后上下文会保存到 TaskStep.ResultData，单步重试和上传时再恢复到上下文中
+const (
+	KeyDownloadedFile      = "downloaded_file"      // 下载视频: 视频文件路径
+	KeyOriginalTitle       = "original_title"       // 下载视频: 原始标题
+	KeyOriginalDescription = "original_description" // 下载视频: 原始描述
+	VideoDurationKey       = "video_duration"       // 下载视频: 视频时长（秒），用于按视频时长延长超时
+	KeyCoverImagePath      = "cover_image_path"     // 下载封面: 封面图片路径
+	KeySubtitlePath        = "subtitle_path"        // Whisper转录: 原始字幕路径
+	KeySubtitleFile        = "subtitle_file"        // 生成字幕: 原始字幕路径
+	KeySubtitleCount       = "subtitle_count"       // 生成字幕: 字幕条数
+	KeyEnSRTPath           = "en_srt_path"          // 翻译字幕: 原文字幕路径
+	KeyZhSRTPath           = "zh_srt_path"          // 翻译字幕: 中文字幕路径
+	KeyTranslatedCount     = "translated_count"     // 翻译字幕: 翻译条数
+	KeyValidationResult    = "validation_result"    // 翻译字幕: 字幕校验结果
+	KeyVideoTitle          = "video_title"          // 生成元数据: 投稿标题
+	KeyVideoDescription    = "video_description"    // 生成元数据: 投稿描述
+	KeyVideoTags           = "video_tags"           // 生成元数据: 投稿标签
+	KeyBiliBVID            = "bili_bvid"            // 上传到Bilibili: 稿件 BVID
+	KeyBiliAID             = "bili_aid"             // 上传到Bilibili: 稿件 AID
+)
+
+// PipelineContext 任务链上下文的类型化访问
+// 从 ResultData 恢复的数值是 float64、列表是 []interface{}，访问方法会统一转换
+type PipelineContext map[string]interface{}
+
+// String 获取字符串值，不存在或类型不符时返回空字符串
+func (c PipelineContext) String(key string) string {
+	s, _ := c[key].(string)
+	return s
+}
+
+// Int 获取整数值，兼容 JSON 反序列化得到的 float64
+func (c PipelineContext) Int(key string) int {
+	switch v := c[key].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	case json.Number:
+		n, _ := v.Int64()
+		return int(n)
+	}
+	return 0
+}
+
+// Strings 获取字符串列表，兼容 JSON 反序列化得到的 []interface{}
+func (c PipelineContext) Strings(key string) []string {
+	switch v := c[key].(type) {
+	case []string:
+		return v
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// File 获取文件路径，文件已不存在时返回空字符串
+func (c PipelineContext) File(key string) string {
+	path := c.String(key)
+	if path == "" {
+		return ""
+	}
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// DownloadedFile 下载的视频文件路径
+func (c PipelineContext) DownloadedFile() string {
+	return c.File(KeyDownloadedFile)
+}
+
+// CoverImagePath 下载的封面图片路径
+func (c PipelineContext) CoverImagePath() string {
+	return c.File(KeyCoverImagePath)
+}
+
+// SubtitlePath 原始字幕路径（Whisper 转录或提交时保存的字幕）
+func (c PipelineContext) SubtitlePath() string {
+	if path := c.File(KeySubtitlePath); path != "" {
+		return path
+	}
+	return c.File(KeySubtitleFile)
+}
+
+// ZhSRTPath 翻译后的中文字幕路径
+func (c PipelineContext) ZhSRTPath() string {
+	return c.File(KeyZhSRTPath)
+}
+
+// VideoTitle 生成的投稿标题
+func (c PipelineContext) VideoTitle() string {
+	return c.String(KeyVideoTitle)
+}
+
+// VideoDescription 生成的投稿描述
+func (c PipelineContext) VideoDescription() string {
+	return c.String(KeyVideoDescription)
+}
+
+// VideoTags 生成的投稿标签
+func (c PipelineContext) VideoTags() []string {
+	return c.Strings(KeyVideoTags)
+}
+
+// BiliBVID 上传后的稿件 BVID
+func (c PipelineContext) BiliBVID() string {
+	return c.String(KeyBiliBVID)
+}
+
+// BiliAID 上传后的稿件 AID
+func (c PipelineContext) BiliAID() int64 {
+	return int64(c.Int(KeyBiliAID))
+}
+
+// VideoDuration 视频时长
+func (c PipelineContext) VideoDuration() time.Duration {
+	if v, ok := c[VideoDurationKey].(float64); ok {
+		return time.Duration(v * float64(time.Second))
+	}
+	return time.Duration(c.Int(VideoDurationKey)) * time.Second
+}
+
+// Merge 合并另一个上下文，已存在的键会被覆盖
+func (c PipelineContext) Merge(values map[string]interface{}) {
+	for k, v := range values {
+		c[k] = v
+	}
+}
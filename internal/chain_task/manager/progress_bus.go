@@ -0,0 +1,95 @@
+package manager
+
+import (
+	"sync"
+	"time"
+)
+
+// 进度事件类型
+const (
+	ProgressEventProgress = "progress" // 步骤执行进度
+	ProgressEventStep     = "step"     // 步骤开始或结束
+)
+
+// ProgressStatusPaused 步骤所属阶段或任务类型已暂停，步骤等待恢复后执行（只出现在 step 事件中）
+const ProgressStatusPaused = "paused"
+
+// 进度单位
+const (
+	ProgressUnitPercent = "percent" // 百分比（yt-dlp 下载、Whisper 识别）
+	ProgressUnitGroups  = "groups"  // 字幕翻译分组
+	ProgressUnitChunks  = "chunks"  // B站分块上传
+)
+
+// progressSubscriberBuffer 每个订阅者缓存的事件数，订阅者处理不及时时丢弃新事件
+const progressSubscriberBuffer = 256
+
+// ProgressEvent 任务执行进度事件
+type ProgressEvent struct {
+	Type    string    `json:"type"`              // 事件类型，见 ProgressEvent* 常量
+	VideoID string    `json:"video_id"`          // 视频ID
+	Step    string    `json:"step"`              // 步骤名称
+	Status  string    `json:"status,omitempty"`  // 步骤状态（step 事件）: running, paused, completed, failed, cancelled, timeout, interrupted
+	Current int64     `json:"current,omitempty"` // 当前进度
+	Total   int64     `json:"total,omitempty"`   // 总量
+	Unit    string    `json:"unit,omitempty"`    // 进度单位，见 ProgressUnit* 常量
+	Percent float64   `json:"percent"`           // 完成百分比
+	Message string    `json:"message,omitempty"` // 进度说明
+	Time    time.Time `json:"time"`
+}
+
+// ProgressBus 进度事件总线，任务发布执行进度，接口订阅后推送给前端
+// 事件只在当前实例内分发，多实例部署时只能收到连接的实例上执行的任务的进度
+type ProgressBus struct {
+	mutex       sync.RWMutex
+	subscribers map[chan ProgressEvent]string // 订阅者 -> 订阅的视频ID，为空时订阅所有视频
+}
+
+// NewProgressBus 创建进度事件总线
+func NewProgressBus() *ProgressBus {
+	return &ProgressBus{
+		subscribers: make(map[chan ProgressEvent]string),
+	}
+}
+
+// Subscribe 订阅视频的进度事件，videoID 为空时订阅所有视频，不再接收时必须调用返回的 unsubscribe
+func (b *ProgressBus) Subscribe(videoID string) (<-chan ProgressEvent, func()) {
+	events := make(chan ProgressEvent, progressSubscriberBuffer)
+
+	b.mutex.Lock()
+	b.subscribers[events] = videoID
+	b.mutex.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			b.mutex.Lock()
+			delete(b.subscribers, events)
+			b.mutex.Unlock()
+			close(events)
+		})
+	}
+	return events, unsubscribe
+}
+
+// Publish 发布进度事件，不会阻塞任务执行；b 为 nil 时忽略
+func (b *ProgressBus) Publish(event ProgressEvent) {
+	if b == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	for events, videoID := range b.subscribers {
+		if videoID != "" && videoID != event.VideoID {
+			continue
+		}
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
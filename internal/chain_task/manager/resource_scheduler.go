@@ -0,0 +1,92 @@
+package manager
+
+import (
+	"context"
+
+	"github.com/difyz9/ytb2bili/internal/core/types"
+)
+
+// DefaultResourceLimits 各资源类别的默认并发上限（<= 0 表示不限制）
+var DefaultResourceLimits = map[types.ResourceClass]int{
+	types.ResourceClassCPU:     1,
+	types.ResourceClassNetwork: 4,
+	types.ResourceClassLLM:     3,
+	types.ResourceClassUpload:  1,
+}
+
+// ResourceUsage 资源类别的使用情况
+type ResourceUsage struct {
+	InUse int `json:"in_use"`
+	Limit int `json:"limit"`
+}
+
+// ResourceScheduler 按资源类别限制任务并发数
+// 所有任务链共享同一个调度器，例如同一时刻最多只有 1 个 Whisper 转录、4 个下载
+type ResourceScheduler struct {
+	limits map[types.ResourceClass]int
+	slots  map[types.ResourceClass]chan struct{} // 创建后只读，无需加锁
+}
+
+// NewResourceScheduler 根据配置创建资源调度器
+func NewResourceScheduler(config *types.AppConfig) *ResourceScheduler {
+	limits := make(map[types.ResourceClass]int)
+	for class, limit := range DefaultResourceLimits {
+		limits[class] = limit
+	}
+	if config != nil && config.PipelineConfig != nil {
+		for class, limit := range config.PipelineConfig.ResourceLimits {
+			limits[types.ResourceClass(class)] = limit
+		}
+	}
+
+	s := &ResourceScheduler{
+		limits: limits,
+		slots:  make(map[types.ResourceClass]chan struct{}),
+	}
+	for class, limit := range limits {
+		if limit > 0 {
+			s.slots[class] = make(chan struct{}, limit)
+		}
+	}
+	return s
+}
+
+// Acquire 占用一个资源槽位，达到上限时阻塞等待，ctx 被取消时返回错误
+func (s *ResourceScheduler) Acquire(ctx context.Context, class types.ResourceClass) error {
+	ch := s.channel(class)
+	if ch == nil {
+		return nil
+	}
+	select {
+	case ch <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release 释放 Acquire 占用的资源槽位
+func (s *ResourceScheduler) Release(class types.ResourceClass) {
+	if ch := s.channel(class); ch != nil {
+		<-ch
+	}
+}
+
+// Usage 获取各资源类别的当前使用情况
+func (s *ResourceScheduler) Usage() map[types.ResourceClass]ResourceUsage {
+	usage := make(map[types.ResourceClass]ResourceUsage, len(s.limits))
+	for class, limit := range s.limits {
+		usage[class] = ResourceUsage{InUse: len(s.slots[class]), Limit: limit}
+	}
+	return usage
+}
+
+func (s *ResourceScheduler) channel(class types.ResourceClass) chan struct{} {
+	if s == nil {
+		return nil
+	}
+	if class == "" {
+		class = types.ResourceClassDefault
+	}
+	return s.slots[class]
+}
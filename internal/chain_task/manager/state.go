@@ -4,6 +4,7 @@ import (
 	"github.com/difyz9/ytb2bili/internal/core/services"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -44,7 +45,7 @@ type StateManager struct {
 
 // NewStateManager 创建状态管理器
 func NewStateManager(Id uint, videoID, projectRoot string, createTim time.Time) *StateManager {
-	currentDir := filepath.Join(projectRoot, GetCurrentDateYYYYMMDD(createTim), videoID)
+	currentDir := VideoWorkDir(projectRoot, videoID, createTim)
 
 	os.MkdirAll(currentDir, os.ModePerm)
 
@@ -77,6 +78,22 @@ func NewStateManager(Id uint, videoID, projectRoot string, createTim time.Time)
 	}
 }
 
+// VideoWorkDir 视频工作目录（<项目根目录>/<创建日期>/<视频ID>）
+func VideoWorkDir(projectRoot, videoID string, createTim time.Time) string {
+	return filepath.Join(projectRoot, GetCurrentDateYYYYMMDD(createTim), videoID)
+}
+
+// StepLogPath 步骤日志文件路径（工作目录下的 logs/<步骤名称>.log）
+func (s *StateManager) StepLogPath(stepName string) string {
+	return StepLogFile(s.CurrentDir, stepName)
+}
+
+// StepLogFile 工作目录下步骤日志文件的路径
+func StepLogFile(workDir, stepName string) string {
+	name := strings.NewReplacer("/", "_", "\\", "_").Replace(stepName)
+	return filepath.Join(workDir, "logs", name+".log")
+}
+
 // GetCache 获取缓存
 func (s *StateManager) GetCache(key string) (interface{}, bool) {
 	s.mu.RLock()
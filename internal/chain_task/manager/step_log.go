@@ -0,0 +1,133 @@
+package manager
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// stepLogChunkSize 从文件末尾向前读取日志时每次读取的字节数
+const stepLogChunkSize = 64 * 1024
+
+// stepLogWriter 追加写入步骤日志文件
+// 每次写入时打开文件，任务对象被丢弃后不需要关闭
+type stepLogWriter struct {
+	mu   sync.Mutex
+	path string
+}
+
+func (w *stepLogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(w.path), os.ModePerm); err != nil {
+		return 0, err
+	}
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	return file.Write(p)
+}
+
+func (w *stepLogWriter) Sync() error {
+	return nil
+}
+
+// NewStepLogger 创建步骤日志器，日志同时写入 logger 和步骤日志文件 path
+// 步骤日志文件记录所有级别的日志，便于排查 yt-dlp、ffmpeg 等外部命令的输出
+func NewStepLogger(logger *zap.SugaredLogger, path string) *zap.SugaredLogger {
+	encoderConfig := zapcore.EncoderConfig{
+		TimeKey:     "time",
+		LevelKey:    "level",
+		MessageKey:  "msg",
+		LineEnding:  zapcore.DefaultLineEnding,
+		EncodeLevel: zapcore.CapitalLevelEncoder,
+		EncodeTime:  zapcore.TimeEncoderOfLayout("2006-01-02 15:04:05"),
+	}
+	fileCore := zapcore.NewCore(zapcore.NewConsoleEncoder(encoderConfig), &stepLogWriter{path: path}, zapcore.DebugLevel)
+
+	return logger.Desugar().WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(core, fileCore)
+	})).Sugar()
+}
+
+// ReadStepLog 读取步骤日志的最后 tail 行，tail <= 0 时读取全部
+// 返回读取到的文件位置，用于继续跟踪之后写入的日志
+func ReadStepLog(path string, tail int) ([]string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	size := info.Size()
+
+	// 从文件末尾向前读取，直到读到足够的行
+	offset := size
+	var data []byte
+	for offset > 0 && (tail <= 0 || bytes.Count(data, []byte{'\n'}) <= tail) {
+		n := int64(stepLogChunkSize)
+		if n > offset {
+			n = offset
+		}
+		offset -= n
+		chunk := make([]byte, n)
+		if _, err := file.ReadAt(chunk, offset); err != nil {
+			return nil, 0, err
+		}
+		data = append(chunk, data...)
+	}
+
+	content := strings.TrimRight(string(data), "\n")
+	if content == "" {
+		return []string{}, size, nil
+	}
+	lines := strings.Split(content, "\n")
+	if tail > 0 && len(lines) > tail {
+		lines = lines[len(lines)-tail:]
+	}
+	return lines, size, nil
+}
+
+// CopyStepLog 将步骤日志中 offset 之后新写入的内容写入 w，返回新的读取位置
+// 日志文件不存在时不做处理；文件被截断时从头读取
+func CopyStepLog(w io.Writer, path string, offset int64) (int64, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return offset, nil
+	}
+	if err != nil {
+		return offset, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return offset, err
+	}
+	size := info.Size()
+	if size < offset {
+		offset = 0
+	}
+	if size == offset {
+		return offset, nil
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return offset, err
+	}
+	n, err := io.CopyN(w, file, size-offset)
+	return offset + n, err
+}
@@ -0,0 +1,68 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/difyz9/ytb2bili/internal/core/types"
+)
+
+// DefaultStepTimeout 未配置 step_timeout 时步骤的默认超时时间
+const DefaultStepTimeout = 2 * time.Hour
+
+// TimeoutPolicy 步骤超时策略
+// 超时时间 = 步骤超时（按步骤覆盖或全局默认）+ 视频时长（分钟）× 每分钟延长的时间
+type TimeoutPolicy struct {
+	defaultTimeout time.Duration
+	stepTimeouts   map[string]time.Duration
+	perMinute      map[string]time.Duration
+}
+
+// NewTimeoutPolicy 根据配置创建超时策略
+func NewTimeoutPolicy(config *types.AppConfig) *TimeoutPolicy {
+	p := &TimeoutPolicy{
+		defaultTimeout: DefaultStepTimeout,
+		stepTimeouts:   make(map[string]time.Duration),
+		perMinute:      make(map[string]time.Duration),
+	}
+	if config == nil || config.PipelineConfig == nil {
+		return p
+	}
+
+	pipeline := config.PipelineConfig
+	if pipeline.StepTimeout > 0 {
+		p.defaultTimeout = time.Duration(pipeline.StepTimeout) * time.Second
+	} else if pipeline.StepTimeout < 0 {
+		p.defaultTimeout = 0
+	}
+	for name, seconds := range pipeline.StepTimeouts {
+		if seconds < 0 {
+			p.stepTimeouts[name] = 0
+		} else if seconds > 0 {
+			p.stepTimeouts[name] = time.Duration(seconds) * time.Second
+		}
+	}
+	for name, seconds := range pipeline.TimeoutPerMin {
+		p.perMinute[name] = time.Duration(seconds) * time.Second
+	}
+	return p
+}
+
+// Timeout 获取步骤的超时时间，返回 0 表示不限制
+func (p *TimeoutPolicy) Timeout(stepName string, videoDuration time.Duration) time.Duration {
+	if p == nil {
+		return 0
+	}
+
+	timeout := p.defaultTimeout
+	if override, ok := p.stepTimeouts[stepName]; ok {
+		timeout = override
+	}
+	if timeout <= 0 {
+		return 0
+	}
+
+	if perMinute := p.perMinute[stepName]; perMinute > 0 && videoDuration > 0 {
+		timeout += time.Duration(videoDuration.Minutes() * float64(perMinute))
+	}
+	return timeout
+}
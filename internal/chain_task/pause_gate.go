@@ -0,0 +1,77 @@
+package chain_task
+
+import (
+	"context"
+	"time"
+
+	"github.com/difyz9/ytb2bili/internal/chain_task/manager"
+	"github.com/difyz9/ytb2bili/internal/core/services"
+	"github.com/difyz9/ytb2bili/pkg/store/model"
+	"go.uber.org/zap"
+)
+
+// pausePollInterval 步骤等待暂停恢复时检查暂停状态的间隔
+const pausePollInterval = 10 * time.Second
+
+// stepPauseGate 步骤开始执行前检查所属阶段和任务类型是否已暂停
+// 暂停状态由其他实例修改时也能生效，因此等待期间定期查询数据库
+type stepPauseGate struct {
+	pauses   *services.PauseService
+	pipeline *Pipeline
+	videoID  string
+	progress *manager.ProgressBus
+	logger   *zap.SugaredLogger
+}
+
+// newStepPauseGate 创建视频任务链使用的暂停检查
+func newStepPauseGate(pauses *services.PauseService, pipeline *Pipeline, videoID string, progress *manager.ProgressBus, logger *zap.SugaredLogger) *stepPauseGate {
+	return &stepPauseGate{
+		pauses:   pauses,
+		pipeline: pipeline,
+		videoID:  videoID,
+		progress: progress,
+		logger:   logger,
+	}
+}
+
+// Wait 步骤所属阶段或任务类型已暂停时等待恢复；查询暂停状态失败时不阻塞步骤执行
+func (g *stepPauseGate) Wait(ctx context.Context, taskName string) error {
+	step, ok := g.pipeline.Step(taskName)
+	if !ok {
+		return nil
+	}
+
+	waiting := false
+	for {
+		pause, err := g.pauses.FindPause(stagePauseScope(step.Stage), model.StepPauseScope(step.Task))
+		if err != nil {
+			g.logger.Errorf("查询暂停状态失败: %v", err)
+			return nil
+		}
+		if pause == nil {
+			if waiting {
+				g.logger.Infof("▶️ 步骤 %s (VideoID: %s) 已恢复执行", taskName, g.videoID)
+			}
+			return nil
+		}
+		if !waiting {
+			waiting = true
+			g.logger.Infof("⏸️ %s 已暂停（%s），步骤 %s (VideoID: %s) 等待恢复", pause.Scope, pause.Reason, taskName, g.videoID)
+			publishStepEvent(g.progress, g.videoID, taskName, manager.ProgressStatusPaused)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pausePollInterval):
+		}
+	}
+}
+
+// stagePauseScope 返回流水线阶段对应的暂停范围
+func stagePauseScope(stage string) string {
+	if stage == StageUpload {
+		return model.PauseScopeUpload
+	}
+	return model.PauseScopePrepare
+}
@@ -0,0 +1,105 @@
+package chain_task
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/difyz9/ytb2bili/internal/core/services"
+	"github.com/difyz9/ytb2bili/pkg/store/model"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// newTestDB 创建迁移了 models 的内存 SQLite 数据库
+func newTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{
+		NamingStrategy: schema.NamingStrategy{TablePrefix: "cw_"},
+		Logger:         logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("获取数据库连接失败: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1) // 内存数据库每个连接是独立的数据库
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("迁移数据库失败: %v", err)
+	}
+	return db
+}
+
+// pausePipeline 准备阶段两个步骤、上传阶段一个步骤的流水线
+func pausePipeline() *Pipeline {
+	return &Pipeline{
+		Name: "test",
+		Steps: []PipelineStep{
+			{Name: "下载视频", Task: "download_video", Stage: StagePrepare},
+			{Name: "生成字幕", Task: "generate_subtitles", DependsOn: []string{"下载视频"}, Stage: StagePrepare},
+			{Name: "上传到Bilibili", Task: "upload_video", DependsOn: []string{"生成字幕"}, Stage: StageUpload},
+		},
+	}
+}
+
+// waitBriefly 在很短的时间内等待暂停恢复，返回 context.DeadlineExceeded 表示步骤仍被暂停阻塞
+func waitBriefly(gate *stepPauseGate, taskName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	return gate.Wait(ctx, taskName)
+}
+
+func TestStepPauseGateAfterRestart(t *testing.T) {
+	tests := []struct {
+		name        string
+		scope       string
+		taskName    string
+		wantBlocked bool
+	}{
+		{name: "暂停准备阶段", scope: model.PauseScopePrepare, taskName: "生成字幕", wantBlocked: true},
+		{name: "暂停准备阶段不影响上传", scope: model.PauseScopePrepare, taskName: "上传到Bilibili"},
+		{name: "暂停任务类型", scope: model.StepPauseScope("generate_subtitles"), taskName: "生成字幕", wantBlocked: true},
+		{name: "暂停其他任务类型", scope: model.StepPauseScope("generate_subtitles"), taskName: "下载视频"},
+		{name: "暂停上传阶段", scope: model.PauseScopeUpload, taskName: "上传到Bilibili", wantBlocked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, &model.PauseState{})
+			logger := zap.NewNop().Sugar()
+
+			// 重启前的实例暂停后退出
+			before := services.NewPauseService(db, &services.Lease{Owner: "host-a:8096"})
+			if _, err := before.Pause(tt.scope, "维护"); err != nil {
+				t.Fatalf("Pause() error = %v", err)
+			}
+
+			// 重启后的实例只能从数据库中读到暂停状态
+			after := services.NewPauseService(db, &services.Lease{Owner: "host-a:8096"})
+			gate := newStepPauseGate(after, pausePipeline(), "v1", nil, logger)
+			err := waitBriefly(gate, tt.taskName)
+			if blocked := errors.Is(err, context.DeadlineExceeded); blocked != tt.wantBlocked {
+				t.Fatalf("Wait() error = %v, want blocked = %v", err, tt.wantBlocked)
+			}
+			if !tt.wantBlocked {
+				return
+			}
+
+			// 其他实例恢复后，步骤不再等待
+			other := services.NewPauseService(db, &services.Lease{Owner: "host-b:8096"})
+			if resumed, err := other.Resume(tt.scope); err != nil || !resumed {
+				t.Fatalf("Resume() = %v, %v, want true", resumed, err)
+			}
+			if err := waitBriefly(gate, tt.taskName); err != nil {
+				t.Fatalf("恢复后 Wait() error = %v", err)
+			}
+		})
+	}
+}
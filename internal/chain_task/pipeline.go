@@ -0,0 +1,350 @@
+package chain_task
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/difyz9/ytb2bili/internal/core/services"
+	"github.com/difyz9/ytb2bili/internal/core/types"
+	"github.com/difyz9/ytb2bili/pkg/store/model"
+)
+
+// 流水线步骤的执行阶段
+const (
+	StagePrepare = "prepare" // 准备阶段，由 ChainTaskHandler 的任务链执行
+	StageUpload  = "upload"  // 上传阶段，由 UploadScheduler 定时执行
+)
+
+// DefaultPipeline 未配置 [PipelineConfig] profile 时使用的流水线
+const DefaultPipeline = "default"
+
+// pipelineConditions 流水线步骤可以使用的启用条件
+var pipelineConditions = map[string]func(config *types.AppConfig) bool{
+	// Whisper 已启用：使用 Whisper 转录生成字幕
+	"whisper": func(config *types.AppConfig) bool {
+		return config.WhisperConfig != nil && config.WhisperConfig.Enabled
+	},
+	// Gemini 直接分析视频生成元数据：生成元数据只依赖下载视频，与转录、翻译并行执行
+	"gemini_video": func(config *types.AppConfig) bool {
+		gemini := config.GeminiConfig
+		return gemini != nil && gemini.Enabled && gemini.UseForMetadata && gemini.AnalyzeVideo
+	},
+}
+
+// builtinPipelines 内置流水线，可以在 [PipelineConfig.pipelines] 中用同名流水线覆盖
+//
+//	下载视频 ──> 分离音频 ──> Whisper转录 ──> 翻译字幕 ──> 生成元数据 ──> 上传到Bilibili ──> 上传字幕到Bilibili
+//	下载封面（只依赖视频ID，独立执行）─────────────────────────────────────┘
+//
+// 未启用 Whisper 时由"生成字幕"代替"Whisper转录"，字幕来自提交时保存的数据，无前置依赖
+var builtinPipelines = map[string]*types.PipelineProfile{
+	DefaultPipeline: {
+		Description: "下载、转录、翻译、生成元数据后定时上传",
+		Steps: []types.PipelineStepConfig{
+			{Name: "下载视频", Task: "download_video"},
+			{Name: "下载封面", Task: "download_cover"},
+			{Name: "分离音频", Task: "extract_audio", DependsOn: []string{"下载视频"}},
+			{Name: "Whisper转录", Task: "whisper", DependsOn: []string{"分离音频"}, When: "whisper"},
+			{Name: "生成字幕", Task: "generate_subtitles", When: "!whisper"},
+			{Name: "翻译字幕", Task: "translate_subtitle", DependsOn: []string{"Whisper转录", "生成字幕"}},
+			{Name: "生成元数据", Task: "generate_metadata", DependsOn: []string{"翻译字幕"}, When: "!gemini_video"},
+			{Name: "生成元数据", Task: "generate_metadata", DependsOn: []string{"下载视频"}, When: "gemini_video"},
+			{Name: "上传到Bilibili", Task: "upload_video", DependsOn: []string{"生成元数据", "下载封面"}, Stage: StageUpload},
+			{Name: "上传字幕到Bilibili", Task: "upload_subtitle", DependsOn: []string{"上传到Bilibili"}, Stage: StageUpload},
+		},
+	},
+}
+
+// PipelineStep 按当前配置解析后的流水线步骤
+type PipelineStep struct {
+	Name      string
+	Task      string
+	DependsOn []string
+	Stage     string
+	SkipIf    []string
+	Options   StepOptions
+}
+
+// Pipeline 按当前配置解析后的流水线，条件不满足的步骤已被排除
+type Pipeline struct {
+	Name  string
+	Steps []PipelineStep
+}
+
+// PipelineName 当前配置使用的流水线名称
+func PipelineName(config *types.AppConfig) string {
+	if config.PipelineConfig != nil && config.PipelineConfig.Profile != "" {
+		return config.PipelineConfig.Profile
+	}
+	return DefaultPipeline
+}
+
+// VideoPipelineName 视频使用的流水线名称：视频指定了流水线（例如来自频道订阅的默认设置）时使用指定的流水线，
+// 否则使用当前配置的流水线；video 为 nil 时返回当前配置的流水线
+func VideoPipelineName(config *types.AppConfig, video *model.SavedVideo) string {
+	if video != nil && video.Pipeline != "" {
+		return video.Pipeline
+	}
+	return PipelineName(config)
+}
+
+// ResolvePipeline 根据配置解析当前使用的流水线
+func ResolvePipeline(config *types.AppConfig) (*Pipeline, error) {
+	return ResolveNamedPipeline(config, PipelineName(config))
+}
+
+// videoPipelineName 按视频ID查询视频使用的流水线名称，查询失败时返回当前配置的流水线
+func videoPipelineName(config *types.AppConfig, savedVideoService *services.SavedVideoService, videoID string) string {
+	video, err := savedVideoService.GetVideoByVideoID(videoID)
+	if err != nil {
+		return PipelineName(config)
+	}
+	return VideoPipelineName(config, video)
+}
+
+// ResolveVideoPipeline 解析视频使用的流水线，见 VideoPipelineName
+func ResolveVideoPipeline(config *types.AppConfig, video *model.SavedVideo) (*Pipeline, error) {
+	return ResolveNamedPipeline(config, VideoPipelineName(config, video))
+}
+
+// ResolveNamedPipeline 解析指定名称的流水线（自定义流水线或内置流水线）
+func ResolveNamedPipeline(config *types.AppConfig, name string) (*Pipeline, error) {
+	var custom map[string]*types.PipelineProfile
+	if config.PipelineConfig != nil {
+		custom = config.PipelineConfig.Pipelines
+	}
+
+	profile := custom[name]
+	if profile == nil {
+		profile = builtinPipelines[name]
+	}
+	if profile == nil {
+		return nil, fmt.Errorf("流水线 %s 不存在", name)
+	}
+
+	pipeline, err := resolveProfile(config, profile)
+	if err != nil {
+		return nil, fmt.Errorf("流水线 %s 配置错误: %v", name, err)
+	}
+	pipeline.Name = name
+	return pipeline, nil
+}
+
+// resolveProfile 按启用条件筛选步骤并校验依赖关系
+func resolveProfile(config *types.AppConfig, profile *types.PipelineProfile) (*Pipeline, error) {
+	declared := make(map[string]bool, len(profile.Steps))
+	for _, step := range profile.Steps {
+		declared[step.Name] = true
+	}
+
+	pipeline := &Pipeline{}
+	enabled := make(map[string]string, len(profile.Steps))
+	for _, step := range profile.Steps {
+		if step.Name == "" {
+			return nil, fmt.Errorf("步骤缺少 name")
+		}
+		if _, ok := lookupStep(step.Task); !ok {
+			return nil, fmt.Errorf("步骤 %s 的任务类型 %q 未注册，可用的任务类型: %s", step.Name, step.Task, strings.Join(RegisteredSteps(), ", "))
+		}
+		stage := step.Stage
+		if stage == "" {
+			stage = StagePrepare
+		}
+		if stage != StagePrepare && stage != StageUpload {
+			return nil, fmt.Errorf("步骤 %s 的执行阶段 %q 无效", step.Name, step.Stage)
+		}
+		for _, dep := range step.DependsOn {
+			if !declared[dep] {
+				return nil, fmt.Errorf("步骤 %s 的前置步骤 %s 不存在", step.Name, dep)
+			}
+		}
+
+		ok, err := evalCondition(config, step.When)
+		if err != nil {
+			return nil, fmt.Errorf("步骤 %s: %v", step.Name, err)
+		}
+		if !ok {
+			continue
+		}
+		if len(step.SkipIf) > 0 && step.Task == "upload_video" {
+			return nil, fmt.Errorf("视频上传步骤 %s 不能设置跳过条件", step.Name)
+		}
+		for _, cond := range step.SkipIf {
+			if _, ok := lookupSkipCondition(cond); !ok {
+				return nil, fmt.Errorf("步骤 %s 的跳过条件 %q 未注册，可用的跳过条件: %s", step.Name, cond, strings.Join(RegisteredSkipConditions(), ", "))
+			}
+		}
+		if _, exists := enabled[step.Name]; exists {
+			return nil, fmt.Errorf("步骤 %s 在当前配置下启用了多次，请检查 when 条件", step.Name)
+		}
+		enabled[step.Name] = stage
+
+		pipeline.Steps = append(pipeline.Steps, PipelineStep{
+			Name:      step.Name,
+			Task:      step.Task,
+			DependsOn: step.DependsOn,
+			Stage:     stage,
+			SkipIf:    step.SkipIf,
+			Options:   StepOptions(step.Options),
+		})
+	}
+
+	// 忽略被条件排除的前置步骤；准备阶段的步骤不能依赖上传阶段的步骤
+	for i, step := range pipeline.Steps {
+		var deps []string
+		for _, dep := range step.DependsOn {
+			depStage, ok := enabled[dep]
+			if !ok {
+				continue
+			}
+			if step.Stage == StagePrepare && depStage == StageUpload {
+				return nil, fmt.Errorf("准备阶段的步骤 %s 不能依赖上传阶段的步骤 %s", step.Name, dep)
+			}
+			deps = append(deps, dep)
+		}
+		pipeline.Steps[i].DependsOn = deps
+	}
+
+	return pipeline, nil
+}
+
+// evalCondition 计算步骤的启用条件，前缀 ! 表示取反
+func evalCondition(config *types.AppConfig, when string) (bool, error) {
+	when = strings.TrimSpace(when)
+	if when == "" {
+		return true, nil
+	}
+
+	negate := strings.HasPrefix(when, "!")
+	name := strings.TrimSpace(strings.TrimPrefix(when, "!"))
+	condition, ok := pipelineConditions[name]
+	if !ok {
+		return false, fmt.Errorf("未知的启用条件: %s", when)
+	}
+	return condition(config) != negate, nil
+}
+
+// Step 按名称获取步骤
+func (p *Pipeline) Step(name string) (PipelineStep, bool) {
+	for _, step := range p.Steps {
+		if step.Name == name {
+			return step, true
+		}
+	}
+	return PipelineStep{}, false
+}
+
+// StageSteps 获取指定执行阶段的步骤
+func (p *Pipeline) StageSteps(stage string) []PipelineStep {
+	var steps []PipelineStep
+	for _, step := range p.Steps {
+		if step.Stage == stage {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// IsUploadStep 判断步骤是否由 UploadScheduler 执行
+func (p *Pipeline) IsUploadStep(name string) bool {
+	step, ok := p.Step(name)
+	return ok && step.Stage == StageUpload
+}
+
+// uploadStepName 上传阶段中执行 task 类型任务的步骤名称，流水线中没有该步骤时返回空字符串
+func uploadStepName(pipeline *Pipeline, task string) string {
+	for _, step := range pipeline.StageSteps(StageUpload) {
+		if step.Task == task {
+			return step.Name
+		}
+	}
+	return ""
+}
+
+// PrepareRange 获取准备阶段中从 from 开始（含下游步骤）、到 until 结束（含上游步骤）的步骤，按流水线顺序排列
+// from 为空表示从头开始，until 为空表示执行到准备阶段结束
+func (p *Pipeline) PrepareRange(from, until string) ([]PipelineStep, error) {
+	steps := p.StageSteps(StagePrepare)
+	for _, name := range []string{from, until} {
+		if name == "" {
+			continue
+		}
+		if step, ok := p.Step(name); !ok || step.Stage != StagePrepare {
+			return nil, fmt.Errorf("步骤 %s 不是流水线 %s 准备阶段的步骤", name, p.Name)
+		}
+	}
+
+	selected := make(map[string]bool, len(steps))
+	for _, step := range steps {
+		selected[step.Name] = true
+	}
+	if from != "" {
+		downstream := closure(steps, from, func(step PipelineStep, reached map[string]bool) []string {
+			// 依赖已到达步骤的步骤
+			for _, dep := range step.DependsOn {
+				if reached[dep] {
+					return []string{step.Name}
+				}
+			}
+			return nil
+		})
+		for name := range selected {
+			selected[name] = downstream[name]
+		}
+	}
+	if until != "" {
+		upstream := closure(steps, until, func(step PipelineStep, reached map[string]bool) []string {
+			// 已到达步骤的前置步骤
+			if reached[step.Name] {
+				return step.DependsOn
+			}
+			return nil
+		})
+		for name := range selected {
+			selected[name] = selected[name] && upstream[name]
+		}
+	}
+
+	var result []PipelineStep
+	for _, step := range steps {
+		if selected[step.Name] {
+			result = append(result, step)
+		}
+	}
+	if from != "" && until != "" && !selected[from] {
+		return nil, fmt.Errorf("步骤 %s 不在步骤 %s 的下游", until, from)
+	}
+	return result, nil
+}
+
+// closure 从 start 出发，按 next 反复扩展到达的步骤，直到不再变化
+// 流水线中的步骤不一定按依赖顺序声明，因此不能只遍历一次
+func closure(steps []PipelineStep, start string, next func(step PipelineStep, reached map[string]bool) []string) map[string]bool {
+	reached := map[string]bool{start: true}
+	for changed := true; changed; {
+		changed = false
+		for _, step := range steps {
+			for _, name := range next(step, reached) {
+				if !reached[name] {
+					reached[name] = true
+					changed = true
+				}
+			}
+		}
+	}
+	return reached
+}
+
+// StepDefinitions 生成任务步骤定义，上传阶段的步骤同样记录在 cw_task_steps 中以展示完整流程
+func (p *Pipeline) StepDefinitions() []services.TaskStepDefinition {
+	steps := make([]services.TaskStepDefinition, 0, len(p.Steps))
+	for i, step := range p.Steps {
+		steps = append(steps, services.TaskStepDefinition{
+			Name:      step.Name,
+			Order:     i + 1,
+			DependsOn: step.DependsOn,
+			CanRetry:  true,
+		})
+	}
+	return steps
+}
@@ -0,0 +1,85 @@
+package chain_task
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/difyz9/ytb2bili/internal/core/types"
+)
+
+// rangeProfile 步骤不按依赖顺序声明的流水线
+//
+//	下载 ──> 分离音频 ──> 转录 ──┐
+//	  └───> 封面 ──────────────┴──> 元数据 ──> 上传
+var rangeProfile = &types.PipelineProfile{
+	Steps: []types.PipelineStepConfig{
+		{Name: "下载", Task: "download_video"},
+		{Name: "元数据", Task: "generate_metadata", DependsOn: []string{"转录", "封面"}},
+		{Name: "转录", Task: "generate_subtitles", DependsOn: []string{"分离音频"}},
+		{Name: "分离音频", Task: "extract_audio", DependsOn: []string{"下载"}},
+		{Name: "封面", Task: "download_cover", DependsOn: []string{"下载"}},
+		{Name: "上传", Task: "upload_video", DependsOn: []string{"元数据"}, Stage: StageUpload},
+	},
+}
+
+// rangeConfig 使用 rangeProfile 作为当前流水线的配置
+func rangeConfig() *types.AppConfig {
+	return &types.AppConfig{
+		PipelineConfig: &types.PipelineConfig{
+			Profile:   "range",
+			Pipelines: map[string]*types.PipelineProfile{"range": rangeProfile},
+		},
+	}
+}
+
+// stepNames 步骤名称列表
+func stepNames(steps []PipelineStep) []string {
+	names := make([]string, 0, len(steps))
+	for _, step := range steps {
+		names = append(names, step.Name)
+	}
+	return names
+}
+
+func TestPrepareRange(t *testing.T) {
+	pipeline, err := ResolvePipeline(rangeConfig())
+	if err != nil {
+		t.Fatalf("ResolvePipeline() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		from    string
+		until   string
+		want    []string
+		wantErr string
+	}{
+		// 下游步骤在流水线中声明在前，只遍历一次会漏掉元数据
+		{name: "从中间步骤开始不包含兄弟分支和前置步骤", from: "分离音频", want: []string{"元数据", "转录", "分离音频"}},
+		// 前置步骤在流水线中声明在后，只遍历一次会漏掉下载
+		{name: "执行到中间步骤包含间接前置步骤", until: "转录", want: []string{"下载", "转录", "分离音频"}},
+		{name: "范围两端都指定时取交集", from: "分离音频", until: "元数据", want: []string{"元数据", "转录", "分离音频"}},
+		{name: "范围不会扩展到上传阶段", from: "元数据", want: []string{"元数据"}},
+		{name: "结束步骤不在下游", from: "封面", until: "转录", wantErr: "不在步骤 封面 的下游"},
+		{name: "上传步骤不能作为范围", from: "上传", wantErr: "不是流水线 range 准备阶段的步骤"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps, err := pipeline.PrepareRange(tt.from, tt.until)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("PrepareRange(%q, %q) error = %v, want %q", tt.from, tt.until, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PrepareRange(%q, %q) error = %v", tt.from, tt.until, err)
+			}
+			if got := stepNames(steps); !slices.Equal(got, tt.want) {
+				t.Fatalf("PrepareRange(%q, %q) = %v, want %v", tt.from, tt.until, got, tt.want)
+			}
+		})
+	}
+}
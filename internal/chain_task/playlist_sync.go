@@ -0,0 +1,224 @@
+package chain_task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/difyz9/ytb2bili/internal/chain_task/manager"
+	"github.com/difyz9/ytb2bili/internal/core"
+	"github.com/difyz9/ytb2bili/internal/core/services"
+	"github.com/difyz9/ytb2bili/pkg/store/model"
+	"github.com/difyz9/ytb2bili/pkg/utils"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// defaultPlaylistSyncInterval 未配置 playlist_sync_interval 时自动同步播放列表的间隔
+const defaultPlaylistSyncInterval = time.Hour
+
+// playlistFetchTimeout 获取一个播放列表的超时时间
+const playlistFetchTimeout = 10 * time.Minute
+
+// ErrInvalidPlaylistURL URL 不是 YouTube 播放列表
+var ErrInvalidPlaylistURL = errors.New("无效的播放列表 URL")
+
+// PlaylistSyncResult 一次导入或同步的结果
+type PlaylistSyncResult struct {
+	Playlist *model.Playlist `json:"playlist"`
+	Total    int             `json:"total"`   // 播放列表中的视频数量
+	Added    []string        `json:"added"`   // 新增的视频ID，按播放列表顺序排列
+	Skipped  int             `json:"skipped"` // 已提交过而跳过的视频数量
+}
+
+// PlaylistSyncer 播放列表导入和同步
+// 使用 yt-dlp 获取播放列表中的视频，按播放列表顺序为尚未提交过的视频创建待处理（001）的记录；
+// 开启自动同步的播放列表按 playlist_sync_interval 定期同步，导入新增的视频
+type PlaylistSyncer struct {
+	App             *core.AppServer
+	PlaylistService *services.PlaylistService
+	Notifier        *manager.JobNotifier
+	Task            *cron.Cron
+	logger          *zap.SugaredLogger
+
+	writes sync.Mutex // 串行写入播放列表和导入的视频，避免并发导入重复创建；获取播放列表较慢，不持有锁
+}
+
+// NewPlaylistSyncer 创建播放列表同步
+func NewPlaylistSyncer(app *core.AppServer, task *cron.Cron, playlistService *services.PlaylistService, notifier *manager.JobNotifier) *PlaylistSyncer {
+	return &PlaylistSyncer{
+		App:             app,
+		PlaylistService: playlistService,
+		Notifier:        notifier,
+		Task:            task,
+		logger:          app.Logger,
+	}
+}
+
+// SetUp 按 playlist_sync_interval 定期同步开启了自动同步的播放列表
+func (s *PlaylistSyncer) SetUp() {
+	interval := s.interval()
+	if interval <= 0 {
+		s.logger.Info("✓ Playlist sync disabled")
+		return
+	}
+	s.Task.AddFunc(fmt.Sprintf("@every %s", interval), func() {
+		s.SyncAll(interval)
+	})
+	s.logger.Infof("✓ Playlist syncer started, syncing every %s", interval)
+}
+
+// interval 自动同步的间隔，<=0 表示不自动同步
+func (s *PlaylistSyncer) interval() time.Duration {
+	if s.App.Config.PipelineConfig != nil && s.App.Config.PipelineConfig.PlaylistSync != 0 {
+		return time.Duration(s.App.Config.PipelineConfig.PlaylistSync) * time.Second
+	}
+	return defaultPlaylistSyncInterval
+}
+
+// SyncAll 同步所有开启了自动同步、且距离上次同步已超过 interval 的播放列表
+// 多个实例共享数据库时，每个播放列表在同一周期内只由一个实例同步
+func (s *PlaylistSyncer) SyncAll(interval time.Duration) {
+	playlists, err := s.PlaylistService.GetAutoSyncPlaylists(interval)
+	if err != nil {
+		s.logger.Errorf("❌ 查询需要同步的播放列表失败: %v", err)
+		return
+	}
+
+	for i := range playlists {
+		playlist := &playlists[i]
+		claimed, err := s.PlaylistService.ClaimSync(playlist, interval)
+		if err != nil {
+			s.logger.Errorf("❌ 认领播放列表 %s 的同步失败: %v", playlist.PlaylistID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		if _, err := s.Sync(context.Background(), playlist); err != nil {
+			s.logger.Errorf("❌ 同步播放列表 %s 失败: %v", playlist.PlaylistID, err)
+		}
+	}
+}
+
+// Import 导入播放列表：保存播放列表并导入其中尚未提交过的视频
+// 已导入的播放列表再次导入时相当于立即同步，priority、autoSync 不为 nil 时更新播放列表的设置
+func (s *PlaylistSyncer) Import(ctx context.Context, playlistURL string, priority *int, autoSync *bool) (*PlaylistSyncResult, error) {
+	playlistID := utils.ExtractPlaylistID(playlistURL)
+	if playlistID == "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPlaylistURL, playlistURL)
+	}
+
+	fetched, err := s.fetch(ctx, playlistURL)
+	if err != nil {
+		return nil, err
+	}
+
+	s.writes.Lock()
+	defer s.writes.Unlock()
+
+	playlist, err := s.PlaylistService.GetByPlaylistID(playlistID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("查询播放列表失败: %v", err)
+		}
+		playlist = &model.Playlist{PlaylistID: playlistID}
+	}
+	playlist.URL = playlistURL
+	playlist.Title = fetched.Title
+	playlist.Uploader = fetched.Uploader
+	if priority != nil {
+		playlist.Priority = *priority
+	}
+	if autoSync != nil {
+		playlist.AutoSync = *autoSync
+	}
+	if playlist, err = s.PlaylistService.SavePlaylist(playlist); err != nil {
+		return nil, fmt.Errorf("保存播放列表失败: %v", err)
+	}
+
+	return s.addEntries(playlist, fetched.Entries)
+}
+
+// Sync 同步播放列表，导入播放列表中新增的视频
+func (s *PlaylistSyncer) Sync(ctx context.Context, playlist *model.Playlist) (*PlaylistSyncResult, error) {
+	fetched, err := s.fetch(ctx, playlist.URL)
+	if err != nil {
+		if recordErr := s.PlaylistService.RecordSync(playlist.ID, 0, err.Error()); recordErr != nil {
+			s.logger.Errorf("记录播放列表 %s 的同步结果失败: %v", playlist.PlaylistID, recordErr)
+		}
+		return nil, err
+	}
+
+	s.writes.Lock()
+	defer s.writes.Unlock()
+	return s.addEntries(playlist, fetched.Entries)
+}
+
+// addEntries 导入视频并记录同步结果，有新增视频时立即唤醒任务分发
+func (s *PlaylistSyncer) addEntries(playlist *model.Playlist, entries []utils.PlaylistEntry) (*PlaylistSyncResult, error) {
+	added, skipped, err := s.PlaylistService.AddEntries(playlist, entries)
+	if len(added) > 0 {
+		s.Notifier.Notify()
+	}
+
+	syncErr := ""
+	if err != nil {
+		syncErr = err.Error()
+	}
+	if recordErr := s.PlaylistService.RecordSync(playlist.ID, len(entries), syncErr); recordErr != nil {
+		s.logger.Errorf("记录播放列表 %s 的同步结果失败: %v", playlist.PlaylistID, recordErr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("导入播放列表视频失败: %v", err)
+	}
+
+	s.logger.Infof("📃 播放列表 %s（%s）: 共 %d 个视频，新增 %d 个，跳过 %d 个",
+		playlist.PlaylistID, playlist.Title, len(entries), len(added), skipped)
+
+	if updated, err := s.PlaylistService.GetByID(playlist.ID); err == nil {
+		playlist = updated
+	}
+	if added == nil {
+		added = []string{}
+	}
+	return &PlaylistSyncResult{
+		Playlist: playlist,
+		Total:    len(entries),
+		Added:    added,
+		Skipped:  skipped,
+	}, nil
+}
+
+// fetch 使用 yt-dlp 获取播放列表中的视频，使用与下载视频相同的 cookies 和代理配置
+func (s *PlaylistSyncer) fetch(ctx context.Context, playlistURL string) (*utils.FlatPlaylist, error) {
+	ctx, cancel := context.WithTimeout(ctx, playlistFetchTimeout)
+	defer cancel()
+
+	ytdlp := utils.NewYtDlpManager(s.logger, s.App.Config.YtDlpPath)
+	return ytdlp.FlatPlaylist(ctx, playlistURL, ytdlpListOptions(s.App, 0))
+}
+
+// ytdlpListOptions 获取播放列表或频道视频列表的 yt-dlp 参数
+// cookies.txt 优先从配置文件所在目录查找，其次是当前目录
+func ytdlpListOptions(app *core.AppServer, limit int) utils.FlatPlaylistOptions {
+	options := utils.FlatPlaylistOptions{Limit: limit}
+
+	cookiesPath := filepath.Join(filepath.Dir(app.Config.Path), "cookies.txt")
+	if _, err := os.Stat(cookiesPath); err != nil {
+		cookiesPath = "cookies.txt"
+	}
+	if _, err := os.Stat(cookiesPath); err == nil {
+		options.Cookies, _ = filepath.Abs(cookiesPath)
+	}
+
+	if app.Config.ProxyConfig != nil && app.Config.ProxyConfig.UseProxy && app.Config.ProxyConfig.ProxyHost != "" {
+		options.Proxy = app.Config.ProxyConfig.ProxyHost
+	}
+	return options
+}
@@ -0,0 +1,369 @@
+package chain_task
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/difyz9/ytb2bili/internal/chain_task/manager"
+	"github.com/difyz9/ytb2bili/internal/core"
+	"github.com/difyz9/ytb2bili/internal/core/services"
+	"github.com/difyz9/ytb2bili/pkg/store/model"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+)
+
+// defaultReconcileInterval 未配置 reconcile_interval 时一致性检查的间隔
+const defaultReconcileInterval = 10 * time.Minute
+
+// reconcileStatuses 一致性检查的视频状态，执行中的状态（002、201、301）由租约回收处理
+var reconcileStatuses = []model.VideoStatus{
+	model.VideoStatusReady,
+	model.VideoStatusUploadFailed,
+	model.VideoStatusSubtitleFailed,
+	model.VideoStatusFailed,
+}
+
+// videoFileExtensions 上传步骤可以使用的视频文件
+var videoFileExtensions = []string{".mp4", ".flv", ".mkv", ".webm", ".avi", ".mov"}
+
+// ReconcileFix 一致性检查修复的一个视频
+type ReconcileFix struct {
+	VideoID    string            `json:"video_id"`
+	Title      string            `json:"title"`
+	FromStatus model.VideoStatus `json:"from_status"`
+	ToStatus   model.VideoStatus `json:"to_status"`
+	ResetSteps []string          `json:"reset_steps,omitempty"` // 重置为待执行的步骤
+	Reason     string            `json:"reason"`
+	Applied    bool              `json:"applied"` // 预演或视频状态已被其他实例修改时为 false
+}
+
+// ReconcileReport 一次一致性检查的结果
+type ReconcileReport struct {
+	StartedAt       string         `json:"started_at"`
+	Duration        string         `json:"duration"`
+	DryRun          bool           `json:"dry_run"`
+	ReclaimedSteps  int            `json:"reclaimed_steps"`  // 回收的租约已过期的步骤
+	ReclaimedVideos int            `json:"reclaimed_videos"` // 回收的租约已过期的视频
+	Checked         int            `json:"checked"`          // 检查的视频数量
+	Fixes           []ReconcileFix `json:"fixes"`
+	Error           string         `json:"error,omitempty"`
+}
+
+// Applied 已修复的视频数量
+func (r *ReconcileReport) Applied() int {
+	applied := 0
+	for _, fix := range r.Fixes {
+		if fix.Applied {
+			applied++
+		}
+	}
+	return applied
+}
+
+// Reconciler 视频状态一致性检查
+// 启动时和定期检查没有被任何实例处理的视频，状态与任务步骤或工作目录中的文件不一致时修复为可以继续处理的状态：
+//   - 准备就绪（200）但视频文件不存在或准备阶段未全部完成：退回待处理（001）重新处理
+//   - 上传失败（299）但上传步骤已完成且已有 BVID：恢复为视频已上传（300）
+//   - 字幕上传失败（399）但字幕上传步骤已完成：恢复为全部完成（400）
+//   - 处理失败（999）但准备阶段已全部完成：恢复为准备就绪（200）；没有失败的步骤也没有待重试的步骤：退回待处理（001）
+//
+// 执行中的视频（002、201、301）由租约回收处理，检查开始前会先回收租约已过期的任务
+type Reconciler struct {
+	App               *core.AppServer
+	SavedVideoService *services.SavedVideoService
+	TaskStepService   *services.TaskStepService
+	Notifier          *manager.JobNotifier
+	Task              *cron.Cron
+	logger            *zap.SugaredLogger
+
+	running sync.Mutex // 同一时间只执行一次检查
+	mutex   sync.Mutex
+	last    *ReconcileReport // 最近一次检查的结果
+}
+
+// NewReconciler 创建一致性检查
+func NewReconciler(app *core.AppServer, task *cron.Cron, savedVideoService *services.SavedVideoService, taskStepService *services.TaskStepService, notifier *manager.JobNotifier) *Reconciler {
+	return &Reconciler{
+		App:               app,
+		SavedVideoService: savedVideoService,
+		TaskStepService:   taskStepService,
+		Notifier:          notifier,
+		Task:              task,
+		logger:            app.Logger,
+	}
+}
+
+// SetUp 启动时执行一次一致性检查，并按 reconcile_interval 定期检查
+func (r *Reconciler) SetUp() {
+	r.Reconcile(false)
+
+	interval := r.interval()
+	if interval <= 0 {
+		r.logger.Info("✓ Reconciler ran on startup, periodic checks disabled")
+		return
+	}
+	r.Task.AddFunc(fmt.Sprintf("@every %s", interval), func() {
+		r.Reconcile(false)
+	})
+	r.logger.Infof("✓ Reconciler started, checking every %s", interval)
+}
+
+// interval 一致性检查的间隔，<=0 表示不定期检查
+func (r *Reconciler) interval() time.Duration {
+	if r.App.Config.PipelineConfig != nil && r.App.Config.PipelineConfig.Reconcile != 0 {
+		return time.Duration(r.App.Config.PipelineConfig.Reconcile) * time.Second
+	}
+	return defaultReconcileInterval
+}
+
+// LastReport 最近一次检查的结果，尚未检查时返回 nil
+func (r *Reconciler) LastReport() *ReconcileReport {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.last
+}
+
+// Reconcile 执行一次一致性检查，dryRun 为 true 时只报告需要修复的视频，不做修改
+func (r *Reconciler) Reconcile(dryRun bool) *ReconcileReport {
+	r.running.Lock()
+	defer r.running.Unlock()
+
+	startTime := time.Now()
+	report := &ReconcileReport{
+		StartedAt: startTime.Format("2006-01-02 15:04:05"),
+		DryRun:    dryRun,
+		Fixes:     []ReconcileFix{},
+	}
+	if err := r.reconcile(report); err != nil {
+		report.Error = err.Error()
+		r.logger.Errorf("❌ 一致性检查失败: %v", err)
+	}
+	report.Duration = time.Since(startTime).Round(time.Millisecond).String()
+
+	for _, fix := range report.Fixes {
+		if fix.Applied {
+			r.logger.Infof("🩺 视频 %s: %s -> %s（%s）", fix.VideoID, fix.FromStatus, fix.ToStatus, fix.Reason)
+		}
+	}
+	applied := report.Applied()
+	if applied > 0 {
+		r.Notifier.Notify()
+	}
+	if len(report.Fixes) > 0 || report.ReclaimedSteps > 0 || report.ReclaimedVideos > 0 {
+		r.logger.Infof("🩺 一致性检查完成: 检查 %d 个视频，发现 %d 个不一致，修复 %d 个，回收 %d 个视频和 %d 个步骤",
+			report.Checked, len(report.Fixes), applied, report.ReclaimedVideos, report.ReclaimedSteps)
+	}
+
+	if !dryRun {
+		r.mutex.Lock()
+		r.last = report
+		r.mutex.Unlock()
+	}
+	return report
+}
+
+// reconcile 回收过期租约后逐个检查视频
+func (r *Reconciler) reconcile(report *ReconcileReport) error {
+	if !report.DryRun {
+		steps, videos, err := r.TaskStepService.ReclaimExpiredLeases()
+		if err != nil {
+			return fmt.Errorf("回收过期租约失败: %v", err)
+		}
+		report.ReclaimedSteps, report.ReclaimedVideos = steps, videos
+	}
+
+	pipeline, err := ResolvePipeline(r.App.Config)
+	if err != nil {
+		return fmt.Errorf("加载流水线失败: %v", err)
+	}
+	workDir, err := filepath.Abs(r.App.Config.FileUpDir)
+	if err != nil {
+		return fmt.Errorf("获取文件上传目录失败: %v", err)
+	}
+
+	videos, err := r.SavedVideoService.GetUnleasedVideos(reconcileStatuses)
+	if err != nil {
+		return fmt.Errorf("查询视频失败: %v", err)
+	}
+	report.Checked = len(videos)
+
+	pipelines := map[string]*Pipeline{pipeline.Name: pipeline}
+	for i := range videos {
+		video := &videos[i]
+		steps, err := r.TaskStepService.GetTaskStepsByVideoID(video.VideoID)
+		if err != nil {
+			return fmt.Errorf("获取视频 %s 的任务步骤失败: %v", video.VideoID, err)
+		}
+
+		// 指定了流水线的视频（例如来自频道订阅）按其流水线检查
+		name := VideoPipelineName(r.App.Config, video)
+		videoPipeline, ok := pipelines[name]
+		if !ok {
+			if videoPipeline, err = ResolveNamedPipeline(r.App.Config, name); err != nil {
+				r.logger.Warnf("⚠️ 视频 %s 的流水线 %s 加载失败，跳过检查: %v", video.VideoID, name, err)
+				continue
+			}
+			pipelines[name] = videoPipeline
+		}
+
+		fix := r.diagnose(videoPipeline, video, steps, manager.VideoWorkDir(workDir, video.VideoID, video.CreatedAt))
+		if fix == nil {
+			continue
+		}
+		if !report.DryRun {
+			if fix.Applied, err = r.apply(video, fix); err != nil {
+				return fmt.Errorf("修复视频 %s 失败: %v", video.VideoID, err)
+			}
+		}
+		report.Fixes = append(report.Fixes, *fix)
+	}
+	return nil
+}
+
+// diagnose 检查视频状态与任务步骤、工作目录是否一致，一致时返回 nil
+func (r *Reconciler) diagnose(pipeline *Pipeline, video *model.SavedVideo, steps []model.TaskStep, workDir string) *ReconcileFix {
+	stepByName := make(map[string]model.TaskStep, len(steps))
+	for _, step := range steps {
+		stepByName[step.StepName] = step
+	}
+
+	fix := &ReconcileFix{
+		VideoID:    video.VideoID,
+		Title:      video.Title,
+		FromStatus: video.Status,
+	}
+	// 退回待处理时重置准备阶段的步骤，手动跳过的步骤保持跳过
+	requeue := func(reason string) *ReconcileFix {
+		fix.ToStatus, fix.Reason = model.VideoStatusPending, reason
+		for _, step := range pipeline.StageSteps(StagePrepare) {
+			current, ok := stepByName[step.Name]
+			if !ok || current.Status == model.TaskStepStatusPending {
+				continue
+			}
+			if current.Status == model.TaskStepStatusSkipped && current.SkippedBy == model.TaskStepSkippedByOperator {
+				continue
+			}
+			fix.ResetSteps = append(fix.ResetSteps, step.Name)
+		}
+		return fix
+	}
+
+	prepareDone, incomplete := prepareCompleted(pipeline, stepByName)
+	// 流水线包含视频上传步骤时，待上传的视频必须有视频文件
+	fileMissing := uploadStepName(pipeline, "upload_video") != "" && !hasVideoFile(workDir)
+
+	switch video.Status {
+	case model.VideoStatusReady:
+		if fileMissing {
+			return requeue("准备就绪但视频文件不存在，重新处理")
+		}
+		if !prepareDone {
+			return requeue(fmt.Sprintf("准备就绪但步骤 %s 未完成，重新处理", strings.Join(incomplete, "、")))
+		}
+
+	case model.VideoStatusUploadFailed:
+		if step, ok := stepByName[uploadStepName(pipeline, "upload_video")]; ok && step.Status == model.TaskStepStatusCompleted && video.BiliBVID != "" {
+			fix.ToStatus, fix.Reason = model.VideoStatusUploaded, fmt.Sprintf("视频上传步骤已完成（BVID: %s）", video.BiliBVID)
+			return fix
+		}
+		if video.BiliBVID == "" && fileMissing {
+			return requeue("视频上传失败且视频文件不存在，重新处理")
+		}
+
+	case model.VideoStatusSubtitleFailed:
+		if step, ok := stepByName[uploadStepName(pipeline, "upload_subtitle")]; ok && step.Status == model.TaskStepStatusCompleted {
+			fix.ToStatus, fix.Reason = model.VideoStatusCompleted, "字幕上传步骤已完成"
+			return fix
+		}
+
+	case model.VideoStatusFailed:
+		if len(steps) == 0 {
+			// 没有任务步骤：流水线加载失败等，需要人工处理
+			return nil
+		}
+		if prepareDone {
+			if fileMissing {
+				return requeue("准备阶段已全部完成但视频文件不存在，重新处理")
+			}
+			fix.ToStatus, fix.Reason = model.VideoStatusReady, "准备阶段已全部完成"
+			return fix
+		}
+		if !prepareFailed(pipeline, stepByName) {
+			return requeue(fmt.Sprintf("处理失败但没有失败或待重试的步骤，步骤 %s 未执行，重新处理", strings.Join(incomplete, "、")))
+		}
+	}
+	return nil
+}
+
+// apply 修复视频状态，退回待处理时重置准备阶段的步骤
+func (r *Reconciler) apply(video *model.SavedVideo, fix *ReconcileFix) (bool, error) {
+	applied, err := r.SavedVideoService.ReconcileVideo(video.ID, fix.FromStatus, fix.ToStatus, services.StatusChange{
+		Actor:  model.VideoStatusActorReconcile,
+		Reason: fix.Reason,
+	})
+	if err != nil || !applied {
+		return false, err
+	}
+	for _, stepName := range fix.ResetSteps {
+		if err := r.TaskStepService.ResetTaskStep(video.VideoID, stepName); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// prepareCompleted 准备阶段的步骤是否全部完成或跳过，返回未完成的步骤
+// 没有步骤记录的步骤（视频处理后流水线配置发生变化）不计入
+func prepareCompleted(pipeline *Pipeline, stepByName map[string]model.TaskStep) (bool, []string) {
+	var incomplete []string
+	for _, step := range pipeline.StageSteps(StagePrepare) {
+		current, ok := stepByName[step.Name]
+		if ok && current.Status != model.TaskStepStatusCompleted && current.Status != model.TaskStepStatusSkipped {
+			incomplete = append(incomplete, step.Name)
+		}
+	}
+	return len(incomplete) == 0, incomplete
+}
+
+// prepareFailed 准备阶段是否有失败、超时、被取消、执行中或等待自动重试的步骤，有时处理失败（999）是符合预期的
+func prepareFailed(pipeline *Pipeline, stepByName map[string]model.TaskStep) bool {
+	for _, step := range pipeline.StageSteps(StagePrepare) {
+		current, ok := stepByName[step.Name]
+		if !ok {
+			continue
+		}
+		switch current.Status {
+		case model.TaskStepStatusFailed, model.TaskStepStatusTimeout, model.TaskStepStatusCancelled, model.TaskStepStatusRunning:
+			return true
+		case model.TaskStepStatusPending:
+			if current.NextRetryAt != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasVideoFile 工作目录中是否有上传步骤可以使用的视频文件
+func hasVideoFile(workDir string) bool {
+	files, err := os.ReadDir(workDir)
+	if err != nil {
+		return false
+	}
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(file.Name()))
+		for _, videoExt := range videoFileExtensions {
+			if ext == videoExt {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package chain_task
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/difyz9/ytb2bili/internal/chain_task/manager"
+	"github.com/difyz9/ytb2bili/internal/core"
+	"github.com/difyz9/ytb2bili/internal/core/services"
+	"github.com/difyz9/ytb2bili/internal/core/types"
+	"github.com/difyz9/ytb2bili/pkg/store/model"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// newTestReconciler 使用内置流水线和临时工作目录的一致性检查
+func newTestReconciler(t *testing.T) (*Reconciler, *Pipeline) {
+	t.Helper()
+	db := newTestDB(t, &model.SavedVideo{}, &model.TaskStep{}, &model.StepRun{}, &model.VideoStatusHistory{})
+	lease := &services.Lease{Owner: "host-a:8096", TTL: time.Minute}
+	app := &core.AppServer{
+		Config: &types.AppConfig{FileUpDir: t.TempDir()},
+		Logger: zap.NewNop().Sugar(),
+		DB:     db,
+	}
+	pipeline, err := ResolvePipeline(app.Config)
+	if err != nil {
+		t.Fatalf("ResolvePipeline() error = %v", err)
+	}
+	r := NewReconciler(app, nil, services.NewSavedVideoService(db, lease), services.NewTaskStepService(db, lease), manager.NewJobNotifier())
+	return r, pipeline
+}
+
+// createPreparedVideo 创建准备阶段已全部完成的视频，withFile 为 true 时在工作目录中放入视频文件
+func createPreparedVideo(t *testing.T, r *Reconciler, pipeline *Pipeline, videoID string, status model.VideoStatus, withFile bool) *model.SavedVideo {
+	t.Helper()
+	db := r.SavedVideoService.DB
+	video := &model.SavedVideo{VideoID: videoID, URL: "https://www.youtube.com/watch?v=" + videoID, Status: status}
+	if err := db.Create(video).Error; err != nil {
+		t.Fatalf("创建视频失败: %v", err)
+	}
+
+	start := time.Now().Add(-time.Hour)
+	end := start.Add(time.Minute)
+	for i, step := range pipeline.Steps {
+		record := model.TaskStep{VideoID: videoID, StepName: step.Name, StepOrder: i, Status: model.TaskStepStatusPending}
+		if step.Stage == StagePrepare {
+			record.Status, record.Attempts = model.TaskStepStatusCompleted, 1
+			record.StartTime, record.EndTime, record.ResultData = &start, &end, `{"ok":true}`
+		}
+		if err := db.Create(&record).Error; err != nil {
+			t.Fatalf("创建步骤失败: %v", err)
+		}
+	}
+
+	if withFile {
+		workDir := manager.VideoWorkDir(r.App.Config.FileUpDir, videoID, video.CreatedAt)
+		if err := os.MkdirAll(workDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(workDir, videoID+".mp4"), []byte("video"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return video
+}
+
+// videoStatus 读取视频当前状态
+func videoStatus(t *testing.T, db *gorm.DB, videoID string) model.VideoStatus {
+	t.Helper()
+	var video model.SavedVideo
+	if err := db.Where("video_id = ?", videoID).First(&video).Error; err != nil {
+		t.Fatalf("读取视频 %s 失败: %v", videoID, err)
+	}
+	return video.Status
+}
+
+func TestReconcileRequeueResetsPrepareSteps(t *testing.T) {
+	r, pipeline := newTestReconciler(t)
+	db := r.SavedVideoService.DB
+
+	// 准备就绪但工作目录被清理：退回待处理并重置准备阶段的步骤，手动跳过的步骤保持跳过
+	createPreparedVideo(t, r, pipeline, "missing", model.VideoStatusReady, false)
+	prepare := pipeline.StageSteps(StagePrepare)
+	skipped := prepare[len(prepare)-1].Name
+	if err := r.TaskStepService.SkipTaskStep("missing", skipped, model.TaskStepSkippedByOperator, "手动跳过"); err != nil {
+		t.Fatal(err)
+	}
+	// 处理失败但准备阶段已全部完成：恢复为准备就绪，不重置步骤
+	createPreparedVideo(t, r, pipeline, "done", model.VideoStatusFailed, true)
+	// 视频文件同样不存在，但正由另一个实例处理，不检查
+	leased := createPreparedVideo(t, r, pipeline, "leased", model.VideoStatusReady, false)
+	expiresAt := time.Now().Add(time.Minute)
+	if err := db.Model(leased).Updates(map[string]interface{}{"lease_owner": "host-b:8096", "lease_expires_at": &expiresAt}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 预演只报告，不修改
+	report := r.Reconcile(true)
+	if report.Error != "" || len(report.Fixes) != 2 || report.Applied() != 0 {
+		t.Fatalf("dry run: error = %q, fixes = %+v", report.Error, report.Fixes)
+	}
+	if got := videoStatus(t, db, "missing"); got != model.VideoStatusReady {
+		t.Fatalf("预演后 missing 的状态 = %s, want %s", got, model.VideoStatusReady)
+	}
+
+	report = r.Reconcile(false)
+	if report.Error != "" || report.Applied() != 2 {
+		t.Fatalf("reconcile: error = %q, fixes = %+v", report.Error, report.Fixes)
+	}
+
+	for videoID, want := range map[string]model.VideoStatus{
+		"missing": model.VideoStatusPending,
+		"done":    model.VideoStatusReady,
+		"leased":  model.VideoStatusReady,
+	} {
+		if got := videoStatus(t, db, videoID); got != want {
+			t.Errorf("%s: status = %s, want %s", videoID, got, want)
+		}
+	}
+
+	// 退回待处理的视频：准备阶段的步骤除手动跳过的以外全部重置，上传阶段的步骤保持不变
+	steps, err := r.TaskStepService.GetTaskStepsByVideoID("missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range steps {
+		if step.StepName == skipped {
+			if step.Status != model.TaskStepStatusSkipped || step.SkippedBy != model.TaskStepSkippedByOperator {
+				t.Errorf("手动跳过的步骤 %s 被重置: status = %s, skipped_by = %q", step.StepName, step.Status, step.SkippedBy)
+			}
+			continue
+		}
+		if step.Status != model.TaskStepStatusPending || step.StartTime != nil || step.EndTime != nil || step.ResultData != "" {
+			t.Errorf("步骤 %s 未重置: status = %s, start_time = %v, end_time = %v, result_data = %q",
+				step.StepName, step.Status, step.StartTime, step.EndTime, step.ResultData)
+		}
+	}
+
+	// 恢复为准备就绪的视频保留已完成的步骤
+	steps, err = r.TaskStepService.GetTaskStepsByVideoID("done")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range steps {
+		if current, _ := pipeline.Step(step.StepName); current.Stage == StagePrepare && step.Status != model.TaskStepStatusCompleted {
+			t.Errorf("步骤 %s 被重置为 %s", step.StepName, step.Status)
+		}
+	}
+
+	var history []model.VideoStatusHistory
+	if err := db.Where("video_id = ?", "missing").Find(&history).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Actor != model.VideoStatusActorReconcile || history[0].ToStatus != model.VideoStatusPending {
+		t.Errorf("missing 的状态变更记录 = %+v", history)
+	}
+}
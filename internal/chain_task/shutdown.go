@@ -0,0 +1,77 @@
+package chain_task
+
+import (
+	"context"
+	"time"
+
+	"github.com/difyz9/ytb2bili/internal/core/types"
+)
+
+// defaultShutdownGracePeriod 未配置 shutdown_grace_period 时等待执行中的步骤结束的时间
+const defaultShutdownGracePeriod = time.Minute
+
+// ShutdownGracePeriod 实例关闭时等待执行中的步骤结束的时间，超过后中断步骤并重新排队
+func ShutdownGracePeriod(config *types.AppConfig) time.Duration {
+	if config.PipelineConfig != nil && config.PipelineConfig.ShutdownGrace > 0 {
+		return time.Duration(config.PipelineConfig.ShutdownGrace) * time.Second
+	}
+	return defaultShutdownGracePeriod
+}
+
+// waitContext 等待 wait 返回，ctx 先结束时返回 ctx.Err()
+func waitContext(ctx context.Context, wait func()) error {
+	done := make(chan struct{})
+	go func() {
+		wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stop 停止分发新任务，执行中的任务不受影响
+// 返回时正在进行的分发已经结束，之后不会再有任务进入工作池
+func (h *ChainTaskHandler) Stop() {
+	h.stopping.Store(true)
+	h.mutex.Lock()
+	h.mutex.Unlock()
+	h.App.Logger.Info("⏹️ 任务分发已停止")
+}
+
+// Drain 等待工作池中执行中的任务结束，ctx 先结束时返回 ctx.Err()
+func (h *ChainTaskHandler) Drain(ctx context.Context) error {
+	return waitContext(ctx, h.pool.Wait)
+}
+
+// Stop 停止开始新的定时上传和手动上传，执行中的上传不受影响
+func (s *UploadScheduler) Stop() {
+	s.stopping.Store(true)
+	s.logger.Info("⏹️ 上传调度已停止")
+}
+
+// Drain 等待执行中的定时上传和手动上传结束，ctx 先结束时返回 ctx.Err()
+func (s *UploadScheduler) Drain(ctx context.Context) error {
+	return waitContext(ctx, func() {
+		// 定时上传在持有 mutex 期间执行
+		s.mutex.Lock()
+		s.mutex.Unlock()
+		s.manual.Wait()
+	})
+}
+
+// Resign 放弃上传调度器的领导者身份，其他实例可以立即接管定时上传
+func (s *UploadScheduler) Resign() {
+	if !s.leader.Swap(false) {
+		return
+	}
+	if err := s.StateService.ResignLeadership(uploadSchedulerName, s.Lease); err != nil {
+		s.logger.Errorf("放弃上传调度器领导者身份失败: %v", err)
+		return
+	}
+	s.logger.Infof("当前实例 %s 已放弃上传调度器的领导者身份", s.Lease.Owner)
+}
@@ -0,0 +1,130 @@
+package chain_task
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/difyz9/ytb2bili/internal/core/services"
+	"github.com/difyz9/ytb2bili/pkg/store/model"
+)
+
+var (
+	// ErrInvalidRunRange 指定的步骤范围无效或前置步骤的产物不存在
+	ErrInvalidRunRange = errors.New("无效的步骤范围")
+	// ErrVideoBusy 视频正在处理、上传或已被其他实例认领
+	ErrVideoBusy = errors.New("视频正在处理中")
+)
+
+// runRangeStatuses 可以只执行部分步骤的视频状态，已开始上传的视频需要重新提交
+var runRangeStatuses = []model.VideoStatus{
+	model.VideoStatusPending,
+	model.VideoStatusStopped,
+	model.VideoStatusReady,
+	model.VideoStatusFailed,
+	model.VideoStatusCancelled,
+}
+
+// runRangeLabel 步骤范围的描述，记录在 PipelineRun.StepName 中
+func runRangeLabel(from, until string) string {
+	if from == "" && until == "" {
+		return ""
+	}
+	if from == "" {
+		from = "开始"
+	}
+	if until == "" {
+		until = "结束"
+	}
+	return from + " → " + until
+}
+
+// ScheduleRun 重新执行视频准备阶段的部分步骤：从 from 开始（含下游步骤）、到 until 结束（含上游步骤）
+// 范围外的前置步骤必须已完成，执行时复用它们在工作目录中的产物和保存的执行结果。
+// 范围内的步骤重置为待执行（手动跳过的步骤保持跳过），视频回到待处理（001）由任务分发执行；
+// until 之后还有未完成的步骤时，视频执行完后停止在已停止（003），不会进入上传阶段。
+// from 和 until 都为空时重新执行完整的准备阶段，手动跳过的步骤也一并重置。返回将要执行的步骤
+func (h *ChainTaskHandler) ScheduleRun(video *model.SavedVideo, from, until string) ([]string, error) {
+	allowed := false
+	for _, status := range runRangeStatuses {
+		allowed = allowed || video.Status == status
+	}
+	if !allowed {
+		return nil, fmt.Errorf("%w: 当前状态 %s(%s) 不能重新执行步骤", ErrVideoBusy, video.Status, video.Status.Label())
+	}
+
+	pipeline, err := ResolveVideoPipeline(h.App.Config, video)
+	if err != nil {
+		return nil, fmt.Errorf("加载流水线失败: %v", err)
+	}
+	steps, err := pipeline.PrepareRange(from, until)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRunRange, err)
+	}
+
+	// 尚未处理过的视频没有步骤记录，先按流水线初始化
+	if err := h.TaskStepService.InitTaskSteps(video.VideoID, pipeline.StepDefinitions()); err != nil {
+		return nil, fmt.Errorf("初始化任务步骤失败: %v", err)
+	}
+	records, err := h.TaskStepService.GetTaskStepsByVideoID(video.VideoID)
+	if err != nil {
+		return nil, fmt.Errorf("获取任务步骤失败: %v", err)
+	}
+	statuses := make(map[string]string, len(records))
+	skippedByOperator := make(map[string]bool)
+	for _, record := range records {
+		statuses[record.StepName] = record.Status
+		if record.Status == model.TaskStepStatusSkipped && record.SkippedBy == model.TaskStepSkippedByOperator {
+			skippedByOperator[record.StepName] = true
+		}
+	}
+
+	label := runRangeLabel(from, until)
+	names := make([]string, 0, len(steps))
+	inRange := make(map[string]bool, len(steps))
+	for _, step := range steps {
+		inRange[step.Name] = true
+		if label != "" && skippedByOperator[step.Name] {
+			continue
+		}
+		names = append(names, step.Name)
+	}
+	var missing []string
+	for _, step := range steps {
+		for _, dep := range step.DependsOn {
+			status := statuses[dep]
+			if !inRange[dep] && status != model.TaskStepStatusCompleted && status != model.TaskStepStatusSkipped {
+				missing = append(missing, dep)
+				inRange[dep] = true // 只报告一次
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: 前置步骤 %s 尚未完成", ErrInvalidRunRange, strings.Join(missing, "、"))
+	}
+
+	reason := "重新执行步骤 " + label
+	if label == "" {
+		reason = "重新执行完整的准备阶段"
+	}
+	scheduled, err := h.SavedVideoService.ScheduleRun(video, from, until, services.StatusChange{
+		Actor:  model.VideoStatusActorAPI,
+		Reason: reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !scheduled {
+		return nil, fmt.Errorf("%w: 视频状态已变化或正由其他实例处理", ErrVideoBusy)
+	}
+
+	for _, name := range names {
+		if err := h.TaskStepService.ResetTaskStep(video.VideoID, name); err != nil {
+			return nil, fmt.Errorf("重置任务步骤 %s 失败: %v", name, err)
+		}
+	}
+
+	h.App.Logger.Infof("🎯 视频 %s 将%s: %v", video.VideoID, reason, names)
+	h.Notifier.Notify()
+	return names, nil
+}
@@ -0,0 +1,122 @@
+package chain_task
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/difyz9/ytb2bili/internal/chain_task/manager"
+	"github.com/difyz9/ytb2bili/internal/core"
+	"github.com/difyz9/ytb2bili/internal/core/services"
+	"github.com/difyz9/ytb2bili/pkg/store/model"
+	"go.uber.org/zap"
+)
+
+// newRangeHandler 使用 rangeProfile 流水线的任务处理器，视频 v1 准备就绪，准备阶段的步骤状态由 statuses 指定
+func newRangeHandler(t *testing.T, statuses map[string]string) (*ChainTaskHandler, *model.SavedVideo) {
+	t.Helper()
+	db := newTestDB(t, &model.SavedVideo{}, &model.TaskStep{}, &model.StepRun{}, &model.VideoStatusHistory{})
+	lease := &services.Lease{Owner: "host-a:8096", TTL: time.Minute}
+	h := &ChainTaskHandler{
+		App:               &core.AppServer{Config: rangeConfig(), Logger: zap.NewNop().Sugar(), DB: db},
+		SavedVideoService: services.NewSavedVideoService(db, lease),
+		TaskStepService:   services.NewTaskStepService(db, lease),
+		Notifier:          manager.NewJobNotifier(),
+	}
+
+	video := &model.SavedVideo{VideoID: "v1", URL: "https://www.youtube.com/watch?v=v1", Status: model.VideoStatusReady}
+	if err := db.Create(video).Error; err != nil {
+		t.Fatalf("创建视频失败: %v", err)
+	}
+	pipeline, err := ResolvePipeline(h.App.Config)
+	if err != nil {
+		t.Fatalf("ResolvePipeline() error = %v", err)
+	}
+	if err := h.TaskStepService.InitTaskSteps("v1", pipeline.StepDefinitions()); err != nil {
+		t.Fatalf("初始化任务步骤失败: %v", err)
+	}
+	for name, status := range statuses {
+		err := db.Model(&model.TaskStep{}).
+			Where("video_id = ? AND step_name = ?", "v1", name).
+			Updates(map[string]interface{}{"status": status, "result_data": `{"ok":true}`}).Error
+		if err != nil {
+			t.Fatalf("更新步骤 %s 失败: %v", name, err)
+		}
+	}
+	return h, video
+}
+
+// stepStatuses 视频各步骤的状态
+func stepStatuses(t *testing.T, h *ChainTaskHandler, videoID string) map[string]string {
+	t.Helper()
+	steps, err := h.TaskStepService.GetTaskStepsByVideoID(videoID)
+	if err != nil {
+		t.Fatalf("获取任务步骤失败: %v", err)
+	}
+	statuses := make(map[string]string, len(steps))
+	for _, step := range steps {
+		statuses[step.StepName] = step.Status
+	}
+	return statuses
+}
+
+func TestScheduleRunKeepsStepsOutsideRange(t *testing.T) {
+	const completed = model.TaskStepStatusCompleted
+	h, video := newRangeHandler(t, map[string]string{
+		"下载": completed, "分离音频": completed, "转录": completed, "封面": completed, "元数据": completed,
+	})
+
+	names, err := h.ScheduleRun(video, "分离音频", "")
+	if err != nil {
+		t.Fatalf("ScheduleRun() error = %v", err)
+	}
+	if want := []string{"元数据", "转录", "分离音频"}; !slices.Equal(names, want) {
+		t.Fatalf("ScheduleRun() = %v, want %v", names, want)
+	}
+
+	// 范围内的步骤重置为待执行；范围外的前置步骤和兄弟分支保留已完成的状态，执行时复用其产物
+	want := map[string]string{
+		"下载": completed, "封面": completed,
+		"分离音频": model.TaskStepStatusPending, "转录": model.TaskStepStatusPending, "元数据": model.TaskStepStatusPending,
+		"上传": model.TaskStepStatusPending,
+	}
+	for name, status := range stepStatuses(t, h, "v1") {
+		if status != want[name] {
+			t.Errorf("步骤 %s: status = %s, want %s", name, status, want[name])
+		}
+	}
+
+	saved, err := h.SavedVideoService.GetVideoByVideoID("v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Status != model.VideoStatusPending || saved.RunFrom != "分离音频" || saved.RunUntil != "" {
+		t.Errorf("video: status = %s, run_from = %q, run_until = %q", saved.Status, saved.RunFrom, saved.RunUntil)
+	}
+}
+
+func TestScheduleRunRequiresDependenciesOutsideRange(t *testing.T) {
+	const completed = model.TaskStepStatusCompleted
+	// 封面不在从分离音频开始的范围内，但元数据依赖它
+	h, video := newRangeHandler(t, map[string]string{
+		"下载": completed, "分离音频": completed, "转录": completed, "封面": model.TaskStepStatusFailed, "元数据": model.TaskStepStatusBlocked,
+	})
+
+	_, err := h.ScheduleRun(video, "分离音频", "")
+	if !errors.Is(err, ErrInvalidRunRange) {
+		t.Fatalf("ScheduleRun() error = %v, want ErrInvalidRunRange", err)
+	}
+
+	// 校验失败时不修改视频和步骤
+	if got := stepStatuses(t, h, "v1")["分离音频"]; got != completed {
+		t.Errorf("分离音频: status = %s, want %s", got, completed)
+	}
+	saved, err := h.SavedVideoService.GetVideoByVideoID("v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Status != model.VideoStatusReady || saved.RunFrom != "" {
+		t.Errorf("video: status = %s, run_from = %q", saved.Status, saved.RunFrom)
+	}
+}
@@ -0,0 +1,245 @@
+package chain_task
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/difyz9/ytb2bili/internal/chain_task/handlers"
+	"github.com/difyz9/ytb2bili/internal/chain_task/manager"
+	"github.com/difyz9/ytb2bili/internal/core"
+	"github.com/difyz9/ytb2bili/internal/core/services"
+	"github.com/difyz9/ytb2bili/internal/core/types"
+	"github.com/difyz9/ytb2bili/pkg/store/model"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// StepEnv 创建任务步骤时可用的依赖
+type StepEnv struct {
+	App               *core.AppServer
+	StateManager      *manager.StateManager
+	SavedVideoService *services.SavedVideoService
+	Db                *gorm.DB
+	Progress          *manager.ProgressBus
+}
+
+// StepOptions 流水线配置中传给任务的参数
+// TOML 中的整数解码为 int64，访问方法会统一转换
+type StepOptions map[string]interface{}
+
+// String 获取字符串参数，未设置时返回 def
+func (o StepOptions) String(key, def string) string {
+	if s, ok := o[key].(string); ok && s != "" {
+		return s
+	}
+	return def
+}
+
+// Int 获取整数参数，未设置时返回 def
+func (o StepOptions) Int(key string, def int) int {
+	switch v := o[key].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return def
+}
+
+// Strings 获取字符串列表参数
+func (o StepOptions) Strings(key string) []string {
+	switch v := o[key].(type) {
+	case []string:
+		return v
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			values = append(values, fmt.Sprintf("%v", item))
+		}
+		return values
+	}
+	return nil
+}
+
+// Ints 获取整数列表参数，未设置时返回 def
+func (o StepOptions) Ints(key string, def []int) []int {
+	items, ok := o[key].([]interface{})
+	if !ok {
+		if v, ok := o[key].([]int); ok {
+			return v
+		}
+		return def
+	}
+	values := make([]int, 0, len(items))
+	for _, item := range items {
+		values = append(values, StepOptions{"v": item}.Int("v", 0))
+	}
+	return values
+}
+
+// StringMap 获取键值对参数，例如环境变量
+func (o StepOptions) StringMap(key string) map[string]string {
+	switch v := o[key].(type) {
+	case map[string]string:
+		return v
+	case map[string]interface{}:
+		values := make(map[string]string, len(v))
+		for k, item := range v {
+			values[k] = fmt.Sprintf("%v", item)
+		}
+		return values
+	}
+	return nil
+}
+
+// StepFactory 根据步骤名称和参数创建任务
+type StepFactory func(name string, env StepEnv, options StepOptions) (types.Task, error)
+
+var (
+	stepRegistryMu sync.RWMutex
+	stepRegistry   = map[string]StepFactory{
+		"download_video": func(name string, env StepEnv, options StepOptions) (types.Task, error) {
+			return handlers.NewDownloadVideo(name, env.App, env.StateManager, env.App.CosClient, env.SavedVideoService), nil
+		},
+		"download_cover": func(name string, env StepEnv, options StepOptions) (types.Task, error) {
+			return handlers.NewDownloadImgHandler(name, env.App, env.StateManager, env.App.CosClient), nil
+		},
+		"extract_audio": func(name string, env StepEnv, options StepOptions) (types.Task, error) {
+			return handlers.NewExtractAudio(name, env.App, env.StateManager, env.App.CosClient), nil
+		},
+		"whisper": func(name string, env StepEnv, options StepOptions) (types.Task, error) {
+			whisperConfig := env.App.Config.WhisperConfig
+			if whisperConfig == nil || !whisperConfig.Enabled {
+				return nil, fmt.Errorf("Whisper 未启用或配置不完整")
+			}
+			return handlers.NewWhisperHandler(
+				name,
+				env.App,
+				env.StateManager,
+				env.App.CosClient,
+				options.String("model_path", whisperConfig.ModelPath),
+				options.String("language", whisperConfig.Language),
+				options.Int("threads", whisperConfig.Threads),
+			), nil
+		},
+		"generate_subtitles": func(name string, env StepEnv, options StepOptions) (types.Task, error) {
+			return handlers.NewGenerateSubtitles(name, env.App, env.StateManager, env.App.CosClient, env.SavedVideoService), nil
+		},
+		"translate_subtitle": func(name string, env StepEnv, options StepOptions) (types.Task, error) {
+			// API Key 在任务运行时从最新配置中读取
+			task := handlers.NewTranslateSubtitle(name, env.App, env.StateManager, env.App.CosClient, env.Db, "")
+			task.GroupSize = options.Int("group_size", task.GroupSize)
+			task.MaxWorkers = options.Int("max_workers", task.MaxWorkers)
+			return task, nil
+		},
+		"generate_metadata": func(name string, env StepEnv, options StepOptions) (types.Task, error) {
+			// AI 服务配置在任务运行时动态检查
+			return handlers.NewGenerateMetadata(name, env.App, env.StateManager, env.App.CosClient, "", env.Db, env.SavedVideoService), nil
+		},
+		"upload_video": func(name string, env StepEnv, options StepOptions) (types.Task, error) {
+			return handlers.NewUploadToBilibili(name, env.App, env.StateManager, env.App.CosClient, env.SavedVideoService), nil
+		},
+		"upload_subtitle": func(name string, env StepEnv, options StepOptions) (types.Task, error) {
+			return handlers.NewUploadSubtitleToBilibili(name, env.App, env.StateManager, env.App.CosClient, env.SavedVideoService), nil
+		},
+		"exec": func(name string, env StepEnv, options StepOptions) (types.Task, error) {
+			// command 为字符串时通过系统 shell 执行，为列表时直接执行
+			command := options.Strings("command")
+			if shell, ok := options["command"].(string); ok {
+				command = handlers.ShellCommand(shell)
+			}
+			if len(command) == 0 || command[0] == "" {
+				return nil, fmt.Errorf("exec 步骤缺少 command 参数")
+			}
+
+			task := handlers.NewExecHandler(name, env.App, env.StateManager, env.App.CosClient, command)
+			task.Dir = options.String("dir", "")
+			task.Env = options.StringMap("env")
+			task.Resource = types.ResourceClass(options.String("resource", string(task.Resource)))
+			task.RetryExitCodes = options.Ints("retry_exit_codes", task.RetryExitCodes)
+			return task, nil
+		},
+	}
+)
+
+// RegisterStep 注册任务类型，已存在的同名任务类型会被替换
+func RegisterStep(task string, factory StepFactory) {
+	stepRegistryMu.Lock()
+	defer stepRegistryMu.Unlock()
+	stepRegistry[task] = factory
+}
+
+// lookupStep 获取任务类型的创建函数
+func lookupStep(task string) (StepFactory, bool) {
+	stepRegistryMu.RLock()
+	defer stepRegistryMu.RUnlock()
+	factory, ok := stepRegistry[task]
+	return factory, ok
+}
+
+// RegisteredSteps 已注册的任务类型，按名称排序
+func RegisteredSteps() []string {
+	stepRegistryMu.RLock()
+	defer stepRegistryMu.RUnlock()
+	names := make([]string, 0, len(stepRegistry))
+	for name := range stepRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// stepLogTask 日志可以写入步骤日志的任务，嵌入 base.BaseTask 的任务都实现了该接口
+type stepLogTask interface {
+	SetLogger(logger *zap.SugaredLogger)
+	Logger() *zap.SugaredLogger
+}
+
+// progressTask 可以发布执行进度的任务，嵌入 base.BaseTask 的任务都实现了该接口
+type progressTask interface {
+	SetProgressBus(progress *manager.ProgressBus)
+}
+
+// newStepTask 根据流水线步骤创建任务
+// 任务的日志同时写入应用日志和步骤日志文件（工作目录下的 logs/<步骤名称>.log）
+func newStepTask(env StepEnv, step PipelineStep) (types.Task, error) {
+	factory, ok := lookupStep(step.Task)
+	if !ok {
+		return nil, fmt.Errorf("未知的任务类型: %s", step.Task)
+	}
+	task, err := factory(step.Name, env, step.Options)
+	if err != nil {
+		return nil, fmt.Errorf("创建任务步骤 %s 失败: %v", step.Name, err)
+	}
+	if logTask, ok := task.(stepLogTask); ok {
+		logTask.SetLogger(manager.NewStepLogger(env.App.Logger, env.StateManager.StepLogPath(step.Name)))
+	}
+	if progressTask, ok := task.(progressTask); ok {
+		progressTask.SetProgressBus(env.Progress)
+	}
+	return task, nil
+}
+
+// publishStepEvent 发布步骤开始或结束的事件
+func publishStepEvent(progress *manager.ProgressBus, videoID, stepName, status string) {
+	event := manager.ProgressEvent{
+		Type:    manager.ProgressEventStep,
+		VideoID: videoID,
+		Step:    stepName,
+		Status:  status,
+	}
+	if status == model.TaskStepStatusCompleted {
+		event.Percent = 100
+	}
+	progress.Publish(event)
+}
+
+// stepLogf 写入任务的步骤日志，用于标记每次执行的开始和结束
+func stepLogf(task types.Task, format string, args ...interface{}) {
+	if logTask, ok := task.(stepLogTask); ok {
+		logTask.Logger().Infof(format, args...)
+	}
+}
@@ -0,0 +1,234 @@
+package chain_task
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/difyz9/ytb2bili/internal/chain_task/manager"
+	"github.com/difyz9/ytb2bili/internal/core/services"
+	"github.com/difyz9/ytb2bili/pkg/store/model"
+	"github.com/difyz9/ytb2bili/pkg/utils"
+)
+
+// ErrStepNotSkippable 步骤不存在、已完成或不允许跳过
+var ErrStepNotSkippable = errors.New("步骤不能跳过")
+
+// SkipEnv 判断跳过条件时可以使用的视频信息
+type SkipEnv struct {
+	Video        *model.SavedVideo       // 视频记录，读取失败时为 nil
+	StateManager *manager.StateManager   // 视频工作目录
+	Context      manager.PipelineContext // 前置步骤的输出
+}
+
+// SkipCondition 步骤的跳过条件，返回条件是否满足以及判断依据（记录在跳过原因中）
+type SkipCondition func(env SkipEnv) (bool, string)
+
+var (
+	skipConditionsMu sync.RWMutex
+	skipConditions   = map[string]SkipCondition{
+		// 源语言已是中文：提交的字幕语言为中文，或已生成的字幕以中文为主
+		"source_chinese": func(env SkipEnv) (bool, string) {
+			if lang := subtitleLanguage(env.Video); lang != "" {
+				return utils.IsChineseLanguage(lang), "提交的字幕语言为 " + lang
+			}
+			for _, path := range subtitleFiles(env) {
+				content, err := os.ReadFile(path)
+				if err != nil {
+					continue
+				}
+				ratio := utils.ChineseRatio(string(content))
+				return ratio >= 0.5, fmt.Sprintf("字幕中文字符占比 %.0f%%", ratio*100)
+			}
+			return false, "无法确定源语言"
+		},
+		// 视频提交时附带了原生字幕，不需要语音转录
+		"native_subtitles": func(env SkipEnv) (bool, string) {
+			if count := len(nativeSubtitles(env.Video)); count > 0 {
+				return true, fmt.Sprintf("提交时附带 %d 条原生字幕", count)
+			}
+			return false, "没有原生字幕"
+		},
+	}
+)
+
+// RegisterSkipCondition 注册跳过条件，已存在的同名条件会被替换
+func RegisterSkipCondition(name string, condition SkipCondition) {
+	skipConditionsMu.Lock()
+	defer skipConditionsMu.Unlock()
+	skipConditions[name] = condition
+}
+
+// lookupSkipCondition 获取跳过条件，前缀 ! 表示取反
+func lookupSkipCondition(name string) (SkipCondition, bool) {
+	skipConditionsMu.RLock()
+	defer skipConditionsMu.RUnlock()
+	condition, ok := skipConditions[strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(name), "!"))]
+	return condition, ok
+}
+
+// RegisteredSkipConditions 已注册的跳过条件，按名称排序
+func RegisteredSkipConditions() []string {
+	skipConditionsMu.RLock()
+	defer skipConditionsMu.RUnlock()
+	names := make([]string, 0, len(skipConditions))
+	for name := range skipConditions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// evalSkipConditions 计算步骤的跳过条件，任一条件满足时返回 true 和跳过原因
+func evalSkipConditions(step PipelineStep, env SkipEnv) (bool, string) {
+	for _, name := range step.SkipIf {
+		condition, ok := lookupSkipCondition(name)
+		if !ok {
+			continue
+		}
+		negate := strings.HasPrefix(strings.TrimSpace(name), "!")
+		if matched, detail := condition(env); matched != negate {
+			return true, fmt.Sprintf("满足跳过条件 %s: %s", strings.TrimSpace(name), detail)
+		}
+	}
+	return false, ""
+}
+
+// nativeSubtitles 视频提交时附带的字幕，没有字幕或解析失败时返回 nil
+func nativeSubtitles(video *model.SavedVideo) []model.SavedVideoSubtitle {
+	if video == nil || video.Subtitles == "" || video.Subtitles == "null" {
+		return nil
+	}
+	var subtitles []model.SavedVideoSubtitle
+	if err := json.Unmarshal([]byte(video.Subtitles), &subtitles); err != nil {
+		return nil
+	}
+	return subtitles
+}
+
+// subtitleLanguage 提交的字幕中第一条标明的语言
+func subtitleLanguage(video *model.SavedVideo) string {
+	for _, subtitle := range nativeSubtitles(video) {
+		if subtitle.Lang != "" {
+			return subtitle.Lang
+		}
+	}
+	return ""
+}
+
+// subtitleFiles 已生成的原文字幕文件：Whisper 转录或生成字幕步骤的输出
+func subtitleFiles(env SkipEnv) []string {
+	var files []string
+	for _, key := range []string{manager.KeySubtitlePath, manager.KeySubtitleFile} {
+		if path := env.Context.File(key); path != "" {
+			files = append(files, path)
+		}
+	}
+	if env.StateManager != nil {
+		files = append(files,
+			filepath.Join(env.StateManager.CurrentDir, env.StateManager.VideoID+".srt"),
+			env.StateManager.OriginalSRT,
+		)
+	}
+	return files
+}
+
+// stepSkip 判断步骤在本次执行前是否应跳过，返回跳过来源和原因，不跳过时返回空字符串
+// 手动跳过的步骤保持跳过，其余步骤按 skip_if 条件重新判断
+func stepSkip(taskStepService *services.TaskStepService, step PipelineStep, env SkipEnv) (string, string) {
+	if env.Video != nil {
+		if record, err := taskStepService.GetTaskStepByName(env.Video.VideoID, step.Name); err == nil &&
+			record.Status == model.TaskStepStatusSkipped && record.SkippedBy == model.TaskStepSkippedByOperator {
+			return model.TaskStepSkippedByOperator, record.SkipReason
+		}
+	}
+	if skip, reason := evalSkipConditions(step, env); skip {
+		return model.TaskStepSkippedByCondition, reason
+	}
+	return "", ""
+}
+
+// SkipStep 手动跳过视频的步骤，下游步骤把已跳过的步骤视为已完成
+// 被该步骤阻塞的下游步骤重新加入执行队列；准备阶段因此全部完成时视频从失败（999）恢复为准备就绪（200），
+// 跳过字幕上传时视频直接进入全部完成（400）。返回重新排队的下游步骤
+func (h *ChainTaskHandler) SkipStep(video *model.SavedVideo, stepName, reason string) ([]string, error) {
+	pipeline, err := ResolveVideoPipeline(h.App.Config, video)
+	if err != nil {
+		return nil, fmt.Errorf("加载流水线失败: %v", err)
+	}
+	step, ok := pipeline.Step(stepName)
+	if !ok {
+		return nil, fmt.Errorf("%w: 步骤 %s 不在流水线 %s 中", ErrStepNotSkippable, stepName, pipeline.Name)
+	}
+	if step.Task == "upload_video" {
+		return nil, fmt.Errorf("%w: 视频上传步骤 %s 不能跳过", ErrStepNotSkippable, stepName)
+	}
+
+	// 尚未处理过的视频没有步骤记录，先按流水线初始化
+	if err := h.TaskStepService.InitTaskSteps(video.VideoID, pipeline.StepDefinitions()); err != nil {
+		return nil, fmt.Errorf("初始化任务步骤失败: %v", err)
+	}
+	record, err := h.TaskStepService.GetTaskStepByName(video.VideoID, stepName)
+	if err != nil {
+		return nil, fmt.Errorf("获取任务步骤失败: %v", err)
+	}
+	switch record.Status {
+	case model.TaskStepStatusRunning:
+		return nil, fmt.Errorf("%w: 步骤 %s 正在执行，请先取消", ErrVideoBusy, stepName)
+	case model.TaskStepStatusCompleted, model.TaskStepStatusSkipped:
+		return nil, fmt.Errorf("%w: 步骤 %s 已%s", ErrStepNotSkippable, stepName, map[string]string{
+			model.TaskStepStatusCompleted: "完成",
+			model.TaskStepStatusSkipped:   "跳过",
+		}[record.Status])
+	}
+
+	if reason == "" {
+		reason = "手动跳过"
+	}
+	if err := h.TaskStepService.SkipTaskStep(video.VideoID, stepName, model.TaskStepSkippedByOperator, reason); err != nil {
+		return nil, fmt.Errorf("更新任务步骤状态失败: %v", err)
+	}
+	publishStepEvent(h.Progress, video.VideoID, stepName, model.TaskStepStatusSkipped)
+	h.App.Logger.Infof("⏭️ 视频 %s 的步骤 %s 已手动跳过: %s", video.VideoID, stepName, reason)
+
+	if step.Task == "upload_subtitle" {
+		for _, from := range []model.VideoStatus{model.VideoStatusUploaded, model.VideoStatusSubtitleFailed} {
+			claimed, err := h.SavedVideoService.ClaimVideo(video.ID, from, model.VideoStatusCompleted, services.StatusChange{
+				Actor:  model.VideoStatusActorAPI,
+				Reason: fmt.Sprintf("跳过步骤 %s", stepName),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("更新视频状态失败: %v", err)
+			}
+			if claimed {
+				break
+			}
+		}
+		return nil, nil
+	}
+
+	resumed, err := h.TaskStepService.ResumeBlockedSteps(video.VideoID)
+	if err != nil {
+		return nil, fmt.Errorf("恢复被阻塞的任务步骤失败: %v", err)
+	}
+	if len(resumed) > 0 {
+		h.App.Logger.Infof("▶️ 视频 %s 的下游步骤已重新加入执行队列: %v", video.VideoID, resumed)
+		h.Notifier.Notify()
+		return resumed, nil
+	}
+
+	if step.Stage == StagePrepare && video.Status == model.VideoStatusFailed && h.prepareStageCompleted(pipeline, video.VideoID) {
+		if _, err := h.SavedVideoService.ClaimVideo(video.ID, model.VideoStatusFailed, model.VideoStatusReady, services.StatusChange{
+			Actor:  model.VideoStatusActorAPI,
+			Reason: fmt.Sprintf("跳过步骤 %s 后准备阶段全部完成", stepName),
+		}); err != nil {
+			return nil, fmt.Errorf("更新视频状态失败: %v", err)
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,212 @@
+package chain_task
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/difyz9/ytb2bili/internal/chain_task/manager"
+	"github.com/difyz9/ytb2bili/pkg/store/model"
+)
+
+// setVideoStatus 直接修改视频状态，模拟视频处理失败等前置状态
+func setVideoStatus(t *testing.T, h *ChainTaskHandler, video *model.SavedVideo, status model.VideoStatus) {
+	t.Helper()
+	if err := h.SavedVideoService.DB.Model(video).Update("status", status).Error; err != nil {
+		t.Fatalf("更新视频状态失败: %v", err)
+	}
+	video.Status = status
+}
+
+func TestSkipStepResumesBlockedSteps(t *testing.T) {
+	const completed = model.TaskStepStatusCompleted
+	h, video := newRangeHandler(t, map[string]string{
+		"下载": completed, "分离音频": completed, "转录": model.TaskStepStatusFailed, "封面": completed, "元数据": model.TaskStepStatusBlocked,
+	})
+	setVideoStatus(t, h, video, model.VideoStatusFailed)
+
+	resumed, err := h.SkipStep(video, "转录", "")
+	if err != nil {
+		t.Fatalf("SkipStep() error = %v", err)
+	}
+	if !slices.Equal(resumed, []string{"元数据"}) {
+		t.Fatalf("resumed = %v, want [元数据]", resumed)
+	}
+
+	record, err := h.TaskStepService.GetTaskStepByName("v1", "转录")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Status != model.TaskStepStatusSkipped || record.SkippedBy != model.TaskStepSkippedByOperator || record.SkipReason != "手动跳过" {
+		t.Fatalf("转录: status = %s, skipped_by = %s, skip_reason = %q", record.Status, record.SkippedBy, record.SkipReason)
+	}
+
+	// 重新执行时手动跳过的步骤保持跳过，不再按 skip_if 条件判断
+	pipeline, err := ResolvePipeline(h.App.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	step, _ := pipeline.Step("转录")
+	if by, _ := stepSkip(h.TaskStepService, step, SkipEnv{Video: video}); by != model.TaskStepSkippedByOperator {
+		t.Errorf("stepSkip() = %q, want %q", by, model.TaskStepSkippedByOperator)
+	}
+}
+
+func TestOperatorSkipAfterRerun(t *testing.T) {
+	tests := []struct {
+		name     string
+		rerun    func(h *ChainTaskHandler, video *model.SavedVideo) error
+		wantKept bool // 转录保持手动跳过
+	}{
+		{name: "只执行部分步骤", wantKept: true, rerun: func(h *ChainTaskHandler, video *model.SavedVideo) error {
+			names, err := h.ScheduleRun(video, "分离音频", "")
+			if err == nil && slices.Contains(names, "转录") {
+				t.Errorf("ScheduleRun() = %v, 手动跳过的步骤不应重新执行", names)
+			}
+			return err
+		}},
+		{name: "重新执行完整的准备阶段", rerun: func(h *ChainTaskHandler, video *model.SavedVideo) error {
+			_, err := h.ScheduleRun(video, "", "")
+			return err
+		}},
+		{name: "重新提交", rerun: func(h *ChainTaskHandler, video *model.SavedVideo) error {
+			cleared, err := h.TaskStepService.ClearOperatorSkips(video.VideoID)
+			if err == nil && cleared != 1 {
+				t.Errorf("ClearOperatorSkips() = %d, want 1", cleared)
+			}
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const completed = model.TaskStepStatusCompleted
+			h, video := newRangeHandler(t, map[string]string{
+				"下载": completed, "分离音频": completed, "转录": model.TaskStepStatusFailed, "封面": completed, "元数据": completed,
+			})
+			setVideoStatus(t, h, video, model.VideoStatusFailed)
+			if _, err := h.SkipStep(video, "转录", ""); err != nil {
+				t.Fatalf("SkipStep() error = %v", err)
+			}
+			saved, err := h.SavedVideoService.GetVideoByVideoID("v1")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tt.rerun(h, saved); err != nil {
+				t.Fatalf("rerun error = %v", err)
+			}
+
+			record, err := h.TaskStepService.GetTaskStepByName("v1", "转录")
+			if err != nil {
+				t.Fatal(err)
+			}
+			kept := record.Status == model.TaskStepStatusSkipped && record.SkippedBy == model.TaskStepSkippedByOperator
+			if kept != tt.wantKept {
+				t.Fatalf("转录: status = %s, skipped_by = %q, want kept = %v", record.Status, record.SkippedBy, tt.wantKept)
+			}
+			if !kept && (record.Status != model.TaskStepStatusPending || record.SkipReason != "") {
+				t.Errorf("转录: status = %s, skip_reason = %q, want pending", record.Status, record.SkipReason)
+			}
+		})
+	}
+}
+
+func TestSkipStepCompletesPrepareStage(t *testing.T) {
+	const completed = model.TaskStepStatusCompleted
+	h, video := newRangeHandler(t, map[string]string{
+		"下载": completed, "分离音频": completed, "转录": completed, "封面": completed, "元数据": model.TaskStepStatusFailed,
+	})
+	setVideoStatus(t, h, video, model.VideoStatusFailed)
+
+	if _, err := h.SkipStep(video, "元数据", "元数据已手动填写"); err != nil {
+		t.Fatalf("SkipStep() error = %v", err)
+	}
+
+	// 跳过最后一个未完成的准备步骤后，视频直接进入准备就绪等待上传
+	saved, err := h.SavedVideoService.GetVideoByVideoID("v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Status != model.VideoStatusReady {
+		t.Fatalf("video status = %s, want %s", saved.Status, model.VideoStatusReady)
+	}
+}
+
+func TestSkipStepRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		step    string
+		status  string
+		wantErr error
+	}{
+		{name: "执行中的步骤", step: "转录", status: model.TaskStepStatusRunning, wantErr: ErrVideoBusy},
+		{name: "已完成的步骤", step: "转录", status: model.TaskStepStatusCompleted, wantErr: ErrStepNotSkippable},
+		{name: "视频上传步骤", step: "上传", status: model.TaskStepStatusFailed, wantErr: ErrStepNotSkippable},
+		{name: "不在流水线中的步骤", step: "翻译", wantErr: ErrStepNotSkippable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statuses := map[string]string{}
+			if tt.status != "" {
+				statuses[tt.step] = tt.status
+			}
+			h, video := newRangeHandler(t, statuses)
+
+			if _, err := h.SkipStep(video, tt.step, ""); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SkipStep() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.status == "" {
+				return
+			}
+			if got := stepStatuses(t, h, "v1")[tt.step]; got != tt.status {
+				t.Errorf("步骤 %s: status = %s, want %s", tt.step, got, tt.status)
+			}
+		})
+	}
+}
+
+func TestEvalSkipConditions(t *testing.T) {
+	chineseDir := t.TempDir()
+	chinese := "1\n00:00:01,000 --> 00:00:02,000\n大家好，欢迎收看本期视频\n"
+	if err := os.WriteFile(filepath.Join(chineseDir, "v1.srt"), []byte(chinese), 0644); err != nil {
+		t.Fatal(err)
+	}
+	englishDir := t.TempDir()
+	english := "1\n00:00:01,000 --> 00:00:02,000\nHello everyone, welcome back\n"
+	if err := os.WriteFile(filepath.Join(englishDir, "v1.srt"), []byte(english), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	withSubtitles := &model.SavedVideo{VideoID: "v1", Subtitles: `[{"text":"Hello","lang":"en"}]`}
+	withoutSubtitles := &model.SavedVideo{VideoID: "v1"}
+
+	tests := []struct {
+		name   string
+		skipIf []string
+		env    SkipEnv
+		want   bool
+	}{
+		{name: "提交的字幕语言优先于字幕文件", skipIf: []string{"source_chinese"},
+			env: SkipEnv{Video: withSubtitles, StateManager: &manager.StateManager{CurrentDir: chineseDir, VideoID: "v1"}}},
+		{name: "根据已生成的字幕判断源语言", skipIf: []string{"source_chinese"}, want: true,
+			env: SkipEnv{Video: withoutSubtitles, StateManager: &manager.StateManager{CurrentDir: chineseDir, VideoID: "v1"}}},
+		{name: "英文字幕不跳过翻译", skipIf: []string{"source_chinese"},
+			env: SkipEnv{Video: withoutSubtitles, StateManager: &manager.StateManager{CurrentDir: englishDir, VideoID: "v1"}}},
+		{name: "无法确定源语言时不跳过", skipIf: []string{"source_chinese"}, env: SkipEnv{Video: withoutSubtitles}},
+		{name: "取反条件", skipIf: []string{"!native_subtitles"}, want: true, env: SkipEnv{Video: withoutSubtitles}},
+		{name: "未注册的条件被忽略", skipIf: []string{"no_such_condition", "native_subtitles"}, want: true, env: SkipEnv{Video: withSubtitles}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := evalSkipConditions(PipelineStep{Name: "翻译字幕", SkipIf: tt.skipIf}, tt.env)
+			if got != tt.want {
+				t.Fatalf("evalSkipConditions(%v) = %v (%s), want %v", tt.skipIf, got, reason, tt.want)
+			}
+		})
+	}
+}
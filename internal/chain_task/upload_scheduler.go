@@ -1,14 +1,16 @@
 package chain_task
 
 import (
-	"github.com/difyz9/ytb2bili/internal/chain_task/handlers"
 	"github.com/difyz9/ytb2bili/internal/chain_task/manager"
 	"github.com/difyz9/ytb2bili/internal/core"
 	"github.com/difyz9/ytb2bili/internal/core/services"
 	"github.com/difyz9/ytb2bili/internal/core/types"
+	"github.com/difyz9/ytb2bili/pkg/store/model"
+	"context"
 	"fmt"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/robfig/cron/v3"
@@ -16,20 +18,35 @@ import (
 	"gorm.io/gorm"
 )
 
+// 上传调度器在 cw_scheduler_states 中的状态名称
+const (
+	uploadSchedulerName = "upload_scheduler" // 领导者选举
+	uploadVideoJob      = "upload_video"     // 上一次检查待上传视频的时间
+	uploadSubtitleJob   = "upload_subtitle"  // 上一次检查待上传字幕的时间
+)
+
 // UploadScheduler 上传调度器
 // 负责定时上传视频和字幕到Bilibili
+// 多个实例共享数据库时只有选举出的领导者执行定时上传，上传间隔记录在数据库中，重启后沿用
 type UploadScheduler struct {
 	App               *core.AppServer
 	SavedVideoService *services.SavedVideoService
 	TaskStepService   *services.TaskStepService
+	StateService      *services.SchedulerStateService
 	Db                *gorm.DB
 	Task              *cron.Cron
+	Scheduler         *manager.ResourceScheduler
+	Cancels           *manager.CancelRegistry
+	Progress          *manager.ProgressBus
+	Pauses            *services.PauseService
+	Lease             *services.Lease
 	mutex             sync.Mutex
 	logger            *zap.SugaredLogger
+	queue             *services.FairQueue // 待上传视频在播放列表、频道之间轮转
+	manual            sync.WaitGroup      // 正在执行的手动上传
 
-	// 上传队列跟踪
-	lastVideoUploadTime    time.Time // 最后一次视频上传时间
-	lastSubtitleUploadTime time.Time // 最后一次字幕上传时间
+	leader   atomic.Bool // 当前实例是否为领导者
+	stopping atomic.Bool // 实例正在关闭，不再开始新的上传
 }
 
 // NewUploadScheduler 创建上传调度器实例
@@ -39,6 +56,12 @@ func NewUploadScheduler(
 	db *gorm.DB,
 	savedVideoService *services.SavedVideoService,
 	taskStepService *services.TaskStepService,
+	stateService *services.SchedulerStateService,
+	scheduler *manager.ResourceScheduler,
+	cancels *manager.CancelRegistry,
+	progress *manager.ProgressBus,
+	pauses *services.PauseService,
+	lease *services.Lease,
 ) *UploadScheduler {
 	return &UploadScheduler{
 		App:               app,
@@ -46,61 +69,120 @@ func NewUploadScheduler(
 		Db:                db,
 		SavedVideoService: savedVideoService,
 		TaskStepService:   taskStepService,
+		StateService:      stateService,
+		Scheduler:         scheduler,
+		Cancels:           cancels,
+		Progress:          progress,
+		Pauses:            pauses,
+		Lease:             lease,
 		logger:            app.Logger,
+		queue:             services.NewFairQueue(),
 	}
 }
 
 // SetUp 启动上传调度器
 func (s *UploadScheduler) SetUp() {
+	// 竞选领导者并定期续约，领导者失联后由其他实例接管
+	s.campaign()
+	s.Task.AddFunc(fmt.Sprintf("@every %s", s.Lease.HeartbeatInterval()), s.campaign)
+
 	// 每5分钟检查一次是否需要上传
 	s.Task.AddFunc("*/5 * * * *", func() {
+		if !s.leader.Load() {
+			return
+		}
+
 		s.mutex.Lock()
 		defer s.mutex.Unlock()
 
-		now := time.Now()
+		if s.stopping.Load() {
+			return
+		}
 
+		// 上传已暂停时跳过，不记录执行时间，恢复后的下一次检查即可上传
 		// 1. 检查是否需要上传视频（每小时一次）
-		if now.Sub(s.lastVideoUploadTime) >= time.Hour {
-			s.logger.Info("🔍 检查待上传的视频...")
-			if err := s.uploadNextVideo(); err != nil {
-				s.logger.Errorf("上传视频失败: %v", err)
-			} else {
-				s.lastVideoUploadTime = now
-			}
+		if !s.uploadPaused("upload_video") {
+			s.runHourly(uploadVideoJob, "🔍 检查待上传的视频...", func() error {
+				if err := s.uploadNextVideo(); err != nil {
+					return fmt.Errorf("上传视频失败: %v", err)
+				}
+				return nil
+			})
 		}
 
 		// 2. 检查是否需要上传字幕（视频上传1小时后）
-		if now.Sub(s.lastSubtitleUploadTime) >= time.Hour {
-			s.logger.Info("🔍 检查待上传字幕的视频...")
-			if err := s.uploadNextSubtitle(); err != nil {
-				s.logger.Errorf("上传字幕失败: %v", err)
-			} else {
-				s.lastSubtitleUploadTime = now
-			}
+		if !s.uploadPaused("upload_subtitle") {
+			s.runHourly(uploadSubtitleJob, "🔍 检查待上传字幕的视频...", func() error {
+				if err := s.uploadNextSubtitle(); err != nil {
+					return fmt.Errorf("上传字幕失败: %v", err)
+				}
+				return nil
+			})
 		}
 	})
 
 	s.logger.Info("✓ Upload scheduler started, checking every 5 minutes")
 }
 
-// uploadNextVideo 上传下一个准备好的视频
-func (s *UploadScheduler) uploadNextVideo() error {
-	// 查询状态为 '200' (准备就绪) 的视频
-	var videos []struct {
-		ID        uint
-		VideoID   string
-		Title     string
-		CreatedAt time.Time
+// campaign 竞选或续约上传调度器的领导者
+// 续约失败时立即放弃执行，避免与接管的实例同时调度
+func (s *UploadScheduler) campaign() {
+	acquired, err := s.StateService.AcquireLeadership(uploadSchedulerName, s.Lease)
+	if err != nil {
+		s.logger.Errorf("上传调度器领导者选举失败: %v", err)
+		acquired = false
 	}
 
-	err := s.Db.Table("cw_saved_videos").
-		Select("id, video_id, title, created_at").
-		Where("status = ?", "200").
-		Where("deleted_at IS NULL").
-		Order("created_at ASC").
-		Limit(1).
-		Find(&videos).Error
+	if wasLeader := s.leader.Swap(acquired); wasLeader != acquired {
+		if acquired {
+			s.logger.Infof("👑 当前实例 %s 成为上传调度器的领导者", s.Lease.Owner)
+		} else {
+			s.logger.Warnf("当前实例 %s 不再是上传调度器的领导者", s.Lease.Owner)
+		}
+	}
+}
+
+// uploadPaused 上传阶段或 task 类型的上传任务是否已暂停，查询失败时视为未暂停
+func (s *UploadScheduler) uploadPaused(task string) bool {
+	pause, err := s.Pauses.FindPause(model.PauseScopeUpload, model.StepPauseScope(task))
+	if err != nil {
+		s.logger.Errorf("查询暂停状态失败: %v", err)
+		return false
+	}
+	if pause != nil {
+		s.logger.Infof("⏸️ %s 已暂停（%s），跳过 %s", pause.Scope, pause.Reason, task)
+		return true
+	}
+	return false
+}
 
+// runHourly 距上一次执行超过1小时时执行调度任务，成功后记录执行时间
+func (s *UploadScheduler) runHourly(job, message string, run func() error) {
+	lastRunAt, err := s.StateService.GetLastRunAt(job)
+	if err != nil {
+		s.logger.Errorf("获取调度任务 %s 的执行时间失败: %v", job, err)
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(lastRunAt) < time.Hour {
+		return
+	}
+
+	s.logger.Info(message)
+	if err := run(); err != nil {
+		s.logger.Error(err)
+		return
+	}
+	if err := s.StateService.SetLastRunAt(job, now); err != nil {
+		s.logger.Errorf("记录调度任务 %s 的执行时间失败: %v", job, err)
+	}
+}
+
+// uploadNextVideo 上传下一个准备好的视频
+func (s *UploadScheduler) uploadNextVideo() error {
+	// 查询状态为 '200' (准备就绪) 的视频，优先级高的先上传，同一优先级在播放列表、频道之间轮转
+	videos, err := s.SavedVideoService.GetReadyVideos(s.queue, 1)
 	if err != nil {
 		return fmt.Errorf("查询待上传视频失败: %v", err)
 	}
@@ -113,20 +195,39 @@ func (s *UploadScheduler) uploadNextVideo() error {
 	video := videos[0]
 	s.logger.Infof("📤 开始上传视频: %s (VideoID: %s)", video.Title, video.VideoID)
 
-	// 更新状态为 '201' (上传视频中)
-	if err := s.SavedVideoService.UpdateStatus(video.ID, "201"); err != nil {
+	// 更新状态为 '201' (上传视频中) 并获取租约，多个实例同时调度时只有一个实例能认领
+	claimed, err := s.SavedVideoService.ClaimVideoWithLease(video.ID, model.VideoStatusReady, model.VideoStatusUploading, s.Lease, services.StatusChange{
+		Actor:  model.VideoStatusActorUploadScheduler,
+		Reason: "定时上传视频",
+	})
+	if err != nil {
 		return fmt.Errorf("更新视频状态失败: %v", err)
 	}
+	if !claimed {
+		s.logger.Infof("视频 %s 已被其他实例认领，跳过", video.VideoID)
+		return nil
+	}
+	defer s.releaseVideo(video.ID)
 
 	// 执行上传任务
-	if err := s.executeUploadTask(video.VideoID, "上传到Bilibili"); err != nil {
-		// 上传失败，更新状态为 '299' (上传失败)
-		s.SavedVideoService.UpdateStatus(video.ID, "299")
+	if err := s.executeUploadTask(video.VideoID, "upload_video", model.VideoStatusActorUploadScheduler); err != nil {
+		// 实例关闭时保持上传中，由关闭流程回收
+		if s.Cancels.ShuttingDown() {
+			return err
+		}
+		// 上传失败，更新状态为 '299' (上传失败)；已被取消时保持取消状态
+		s.SavedVideoService.ClaimVideo(video.ID, model.VideoStatusUploading, model.VideoStatusUploadFailed, services.StatusChange{
+			Actor:  model.VideoStatusActorUploadScheduler,
+			Reason: err.Error(),
+		})
 		return fmt.Errorf("上传视频失败: %v", err)
 	}
 
 	// 上传成功，更新状态为 '300' (视频已上传，待上传字幕)
-	if err := s.SavedVideoService.UpdateStatus(video.ID, "300"); err != nil {
+	if _, err := s.SavedVideoService.ClaimVideo(video.ID, model.VideoStatusUploading, model.VideoStatusUploaded, services.StatusChange{
+		Actor:  model.VideoStatusActorUploadScheduler,
+		Reason: "视频上传成功",
+	}); err != nil {
 		return fmt.Errorf("更新视频状态失败: %v", err)
 	}
 
@@ -136,7 +237,7 @@ func (s *UploadScheduler) uploadNextVideo() error {
 
 // uploadNextSubtitle 上传下一个待上传字幕的视频
 func (s *UploadScheduler) uploadNextSubtitle() error {
-	// 查询状态为 '300' (视频已上传，待上传字幕) 且上传时间超过1小时的视频
+	// 查询状态为 '300' (视频已上传，待上传字幕) 且上传时间超过1小时的视频，优先级高的先上传
 	var videos []struct {
 		ID        uint
 		VideoID   string
@@ -149,9 +250,9 @@ func (s *UploadScheduler) uploadNextSubtitle() error {
 
 	err := s.Db.Table("cw_saved_videos").
 		Select("id, video_id, title, updated_at, created_at").
-		Where("status = ? AND updated_at <= ?", "300", oneHourAgo).
+		Where("status = ? AND updated_at <= ?", model.VideoStatusUploaded, oneHourAgo).
 		Where("deleted_at IS NULL").
-		Order("updated_at ASC").
+		Order("priority DESC, updated_at ASC").
 		Limit(1).
 		Find(&videos).Error
 
@@ -167,20 +268,39 @@ func (s *UploadScheduler) uploadNextSubtitle() error {
 	video := videos[0]
 	s.logger.Infof("📝 开始上传字幕: %s (VideoID: %s)", video.Title, video.VideoID)
 
-	// 更新状态为 '301' (上传字幕中)
-	if err := s.SavedVideoService.UpdateStatus(video.ID, "301"); err != nil {
+	// 更新状态为 '301' (上传字幕中) 并获取租约，多个实例同时调度时只有一个实例能认领
+	claimed, err := s.SavedVideoService.ClaimVideoWithLease(video.ID, model.VideoStatusUploaded, model.VideoStatusSubtitleUploading, s.Lease, services.StatusChange{
+		Actor:  model.VideoStatusActorUploadScheduler,
+		Reason: "定时上传字幕",
+	})
+	if err != nil {
 		return fmt.Errorf("更新视频状态失败: %v", err)
 	}
+	if !claimed {
+		s.logger.Infof("视频 %s 已被其他实例认领，跳过", video.VideoID)
+		return nil
+	}
+	defer s.releaseVideo(video.ID)
 
 	// 执行上传字幕任务
-	if err := s.executeUploadTask(video.VideoID, "上传字幕到Bilibili"); err != nil {
-		// 上传失败，更新状态为 '399' (字幕上传失败)
-		s.SavedVideoService.UpdateStatus(video.ID, "399")
+	if err := s.executeUploadTask(video.VideoID, "upload_subtitle", model.VideoStatusActorUploadScheduler); err != nil {
+		// 实例关闭时保持上传中，由关闭流程回收
+		if s.Cancels.ShuttingDown() {
+			return err
+		}
+		// 上传失败，更新状态为 '399' (字幕上传失败)；已被取消时保持取消状态
+		s.SavedVideoService.ClaimVideo(video.ID, model.VideoStatusSubtitleUploading, model.VideoStatusSubtitleFailed, services.StatusChange{
+			Actor:  model.VideoStatusActorUploadScheduler,
+			Reason: err.Error(),
+		})
 		return fmt.Errorf("上传字幕失败: %v", err)
 	}
 
 	// 上传成功，更新状态为 '400' (全部完成)
-	if err := s.SavedVideoService.UpdateStatus(video.ID, "400"); err != nil {
+	if _, err := s.SavedVideoService.ClaimVideo(video.ID, model.VideoStatusSubtitleUploading, model.VideoStatusCompleted, services.StatusChange{
+		Actor:  model.VideoStatusActorUploadScheduler,
+		Reason: "字幕上传成功",
+	}); err != nil {
 		return fmt.Errorf("更新视频状态失败: %v", err)
 	}
 
@@ -188,13 +308,38 @@ func (s *UploadScheduler) uploadNextSubtitle() error {
 	return nil
 }
 
-// executeUploadTask 执行上传任务
-func (s *UploadScheduler) executeUploadTask(videoID, taskName string) error {
-	// 获取视频信息
+// executeUploadTask 按视频使用的流水线执行 task 类型（upload_video、upload_subtitle）的上传步骤，trigger 为触发者（定时上传或手动上传）
+// 执行期间在取消注册表中登记，可通过取消接口终止；每次执行记录在 cw_pipeline_runs 中
+func (s *UploadScheduler) executeUploadTask(videoID, task, trigger string) error {
 	savedVideo, err := s.SavedVideoService.GetVideoByVideoID(videoID)
 	if err != nil {
 		return fmt.Errorf("获取视频信息失败: %v", err)
 	}
+	pipeline, err := ResolveVideoPipeline(s.App.Config, savedVideo)
+	if err != nil {
+		return fmt.Errorf("加载流水线失败: %v", err)
+	}
+	step, ok := pipeline.Step(uploadStepName(pipeline, task))
+	if !ok {
+		return fmt.Errorf("流水线 %s 中没有 %s 上传步骤", pipeline.Name, task)
+	}
+
+	ctx, release := s.Cancels.Register(context.Background(), videoID)
+	defer release()
+
+	run, err := s.TaskStepService.StartRun(videoID, model.PipelineRunKindUpload, trigger, pipeline.Name, step.Name)
+	if err != nil {
+		s.logger.Errorf("记录任务执行失败: %v", err)
+	}
+
+	err = s.runUploadTask(ctx, run, savedVideo, step)
+	finishRun(s.TaskStepService, s.logger, ctx, run, err)
+	return err
+}
+
+// runUploadTask 执行上传步骤
+func (s *UploadScheduler) runUploadTask(ctx context.Context, run *model.PipelineRun, savedVideo *model.SavedVideo, step PipelineStep) error {
+	videoID, taskName := savedVideo.VideoID, step.Name
 
 	// 获取当前目录
 	currentDir, err := filepath.Abs(s.App.Config.FileUpDir)
@@ -205,23 +350,39 @@ func (s *UploadScheduler) executeUploadTask(videoID, taskName string) error {
 	// 创建状态管理器
 	stateManager := manager.NewStateManager(savedVideo.ID, savedVideo.VideoID, currentDir, savedVideo.CreatedAt)
 
-	// 更新步骤状态为运行中
-	if err := s.TaskStepService.UpdateTaskStepStatus(videoID, taskName, "running"); err != nil {
-		s.logger.Errorf("更新任务步骤状态失败: %v", err)
+	// 创建上传步骤对应的任务
+	task, err := newStepTask(StepEnv{
+		App:               s.App,
+		StateManager:      stateManager,
+		SavedVideoService: s.SavedVideoService,
+		Db:                s.Db,
+		Progress:          s.Progress,
+	}, step)
+	if err != nil {
+		return err
 	}
 
 	// 创建任务链
-	chain := manager.NewTaskChain()
-	var task types.Task
-
-	// 根据任务名称创建对应的任务
-	switch taskName {
-	case "上传到Bilibili":
-		task = handlers.NewUploadToBilibili("上传到Bilibili", s.App, stateManager, s.App.CosClient, s.SavedVideoService)
-	case "上传字幕到Bilibili":
-		task = handlers.NewUploadSubtitleToBilibili("上传字幕到Bilibili", s.App, stateManager, s.App.CosClient, s.SavedVideoService)
-	default:
-		return fmt.Errorf("未知的任务类型: %s", taskName)
+	chain := manager.NewTaskChain().
+		WithScheduler(s.Scheduler).
+		WithTimeoutPolicy(manager.NewTimeoutPolicy(s.App.Config))
+	restorePipelineContext(s.TaskStepService, s.logger, chain, savedVideo, taskName)
+
+	// 手动跳过或满足 skip_if 条件的上传步骤不执行，视频按上传成功继续推进
+	if skippedBy, reason := stepSkip(s.TaskStepService, step, SkipEnv{Video: savedVideo, StateManager: stateManager, Context: chain.Context}); skippedBy != "" {
+		if skippedBy != model.TaskStepSkippedByOperator {
+			if err := s.TaskStepService.SkipTaskStep(videoID, taskName, skippedBy, reason); err != nil {
+				s.logger.Errorf("更新任务步骤状态失败: %v", err)
+			}
+		}
+		publishStepEvent(s.Progress, videoID, taskName, model.TaskStepStatusSkipped)
+		s.logger.Infof("⏭️ 上传任务 %s 已跳过: %s", taskName, reason)
+		return nil
+	}
+
+	// 更新步骤状态为运行中
+	if err := s.TaskStepService.StartTaskStep(videoID, taskName, run.GetID()); err != nil {
+		s.logger.Errorf("更新任务步骤状态失败: %v", err)
 	}
 
 	// 添加任务到链
@@ -230,7 +391,9 @@ func (s *UploadScheduler) executeUploadTask(videoID, taskName string) error {
 	s.logger.Infof("开始执行上传任务: %s (VideoID: %s)", taskName, videoID)
 
 	// 执行任务
-	result := chain.Run(false)
+	stepLogf(task, "==== 开始执行步骤 %s", taskName)
+	publishStepEvent(s.Progress, videoID, taskName, model.TaskStepStatusRunning)
+	result := chain.Run(ctx, false)
 
 	// 检查执行结果
 	success := true
@@ -239,8 +402,41 @@ func (s *UploadScheduler) executeUploadTask(videoID, taskName string) error {
 		success = false
 		errorMsg = fmt.Sprintf("%v", errorMsgInterface)
 	}
+	stepLogf(task, "==== 步骤 %s 执行结束，成功: %v", taskName, success)
+	if !success && chain.TimedOut(taskName) {
+		publishStepEvent(s.Progress, videoID, taskName, model.TaskStepStatusTimeout)
+	} else {
+		publishStepEvent(s.Progress, videoID, taskName, stepResultStatus(ctx, success))
+	}
 
 	// 更新步骤状态
+	if !success && manager.IsShutdown(ctx) {
+		// 稿件可能已经提交，步骤保持执行中，由关闭流程标记为中断，等待人工确认
+		s.logger.Warnf("⏸️ 实例关闭，上传任务 %s 已中断", taskName)
+		return context.Cause(ctx)
+	}
+	if !success && ctx.Err() != nil {
+		if err := s.TaskStepService.UpdateTaskStepStatus(videoID, taskName, model.TaskStepStatusCancelled, "任务已取消"); err != nil {
+			s.logger.Errorf("更新任务步骤状态失败: %v", err)
+		}
+		s.logger.Infof("⏹️ 任务 %s 已取消", taskName)
+		return fmt.Errorf("任务 %s 已取消", taskName)
+	}
+	if !success && chain.TimedOut(taskName) {
+		recordStepFailure(s.TaskStepService, s.logger, s.App.Config, videoID, step, model.TaskStepStatusTimeout, types.TaskErrorFromContext(result))
+		s.logger.Errorf("⏱️ %s", errorMsg)
+		return fmt.Errorf("%w: %s", errStepTimedOut, errorMsg)
+	}
+	if reason, skipped := types.TakeSkipReason(result); skipped && success {
+		if err := s.TaskStepService.SkipTaskStep(videoID, taskName, model.TaskStepSkippedByTask, reason); err != nil {
+			s.logger.Errorf("更新任务步骤状态失败: %v", err)
+		}
+		if err := s.TaskStepService.UpdateTaskStepResult(videoID, taskName, result); err != nil {
+			s.logger.Errorf("更新任务步骤结果失败: %v", err)
+		}
+		s.logger.Infof("⏭️ 任务 %s 已跳过: %s", taskName, reason)
+		return nil
+	}
 	if success {
 		if err := s.TaskStepService.UpdateTaskStepStatus(videoID, taskName, "completed"); err != nil {
 			s.logger.Errorf("更新任务步骤状态失败: %v", err)
@@ -251,28 +447,101 @@ func (s *UploadScheduler) executeUploadTask(videoID, taskName string) error {
 		s.logger.Infof("任务 %s 执行成功", taskName)
 		return nil
 	} else {
-		if err := s.TaskStepService.UpdateTaskStepStatus(videoID, taskName, "failed", errorMsg); err != nil {
-			s.logger.Errorf("更新任务步骤状态失败: %v", err)
-		}
+		recordStepFailure(s.TaskStepService, s.logger, s.App.Config, videoID, step, model.TaskStepStatusFailed, types.TaskErrorFromContext(result))
 		s.logger.Errorf("任务 %s 执行失败: %s", taskName, errorMsg)
 		return fmt.Errorf("任务执行失败: %s", errorMsg)
 	}
 }
 
+// releaseVideo 释放当前实例持有的视频租约
+// 实例关闭时保留租约，由关闭流程统一回收（中断的上传标记为上传失败）
+func (s *UploadScheduler) releaseVideo(id uint) {
+	if s.Cancels.ShuttingDown() {
+		return
+	}
+	if err := s.SavedVideoService.ReleaseVideo(id, s.Lease); err != nil {
+		s.logger.Errorf("释放视频租约失败: %v", err)
+	}
+}
+
 // ExecuteManualUpload 手动执行上传任务（用于 Web 界面手动触发）
-func (s *UploadScheduler) ExecuteManualUpload(videoID, taskType string) error {
-	s.logger.Infof("🎯 手动执行上传任务: VideoID=%s, TaskType=%s", videoID, taskType)
-	
-	var taskName string
+// video 是调用方将视频状态更新为上传中（201/301）之前读取的记录，上传未能开始时恢复为 video.Status
+func (s *UploadScheduler) ExecuteManualUpload(video *model.SavedVideo, taskType string) error {
+	s.logger.Infof("🎯 手动执行上传任务: VideoID=%s, TaskType=%s", video.VideoID, taskType)
+
+	// 这里根据结果将视频更新为完成或失败状态
+	var task string
+	var uploading, succeeded, failed model.VideoStatus
 	switch taskType {
 	case "video":
-		taskName = "上传到Bilibili"
+		task, uploading, succeeded, failed = "upload_video", model.VideoStatusUploading, model.VideoStatusUploaded, model.VideoStatusUploadFailed
 	case "subtitle":
-		taskName = "上传字幕到Bilibili"
+		task, uploading, succeeded, failed = "upload_subtitle", model.VideoStatusSubtitleUploading, model.VideoStatusCompleted, model.VideoStatusSubtitleFailed
 	default:
 		return fmt.Errorf("未知的任务类型: %s", taskType)
 	}
-	
-	return s.executeUploadTask(videoID, taskName)
+
+	if s.stopping.Load() {
+		err := fmt.Errorf("实例正在关闭，请稍后重试")
+		s.restoreManualUpload(video, uploading, err)
+		return err
+	}
+	s.manual.Add(1)
+	defer s.manual.Done()
+
+	// 获取视频租约，状态更新完成后再释放，避免其他实例把上传中的视频当作中断任务回收
+	acquired, err := s.SavedVideoService.AcquireVideo(video.ID, s.Lease)
+	if err != nil {
+		err = fmt.Errorf("获取视频租约失败: %v", err)
+		s.restoreManualUpload(video, uploading, err)
+		return err
+	}
+	if !acquired {
+		err := fmt.Errorf("视频 %s 正由其他实例处理", video.VideoID)
+		s.restoreManualUpload(video, uploading, err)
+		return err
+	}
+	defer s.releaseVideo(video.ID)
+
+	if err := s.executeUploadTask(video.VideoID, task, model.VideoStatusActorAPI); err != nil {
+		// 实例关闭时保持上传中，由关闭流程回收
+		if s.Cancels.ShuttingDown() {
+			return err
+		}
+		// 已被取消时保持取消状态
+		claimed, claimErr := s.SavedVideoService.ClaimVideo(video.ID, uploading, failed, services.StatusChange{
+			Actor:  model.VideoStatusActorAPI,
+			Reason: err.Error(),
+		})
+		if claimErr != nil {
+			s.logger.Errorf("更新视频 %s 状态为 %s 失败: %v", video.VideoID, failed, claimErr)
+		} else if !claimed {
+			s.logger.Infof("视频 %s 已不是 %s 状态（可能已被取消），保持当前状态", video.VideoID, uploading)
+		}
+		return err
+	}
+	if _, err := s.SavedVideoService.ClaimVideo(video.ID, uploading, succeeded, services.StatusChange{
+		Actor:  model.VideoStatusActorAPI,
+		Reason: "手动上传成功",
+	}); err != nil {
+		return fmt.Errorf("更新视频状态失败: %v", err)
+	}
+	return nil
+}
+
+// restoreManualUpload 手动上传未能开始时，将视频从上传中恢复为调用方更新前的状态（200/299 或 300/399）
+// 视频状态已被取消等操作修改时保持不变
+func (s *UploadScheduler) restoreManualUpload(video *model.SavedVideo, uploading model.VideoStatus, cause error) {
+	restored, err := s.SavedVideoService.ClaimVideo(video.ID, uploading, video.Status, services.StatusChange{
+		Actor:  model.VideoStatusActorAPI,
+		Reason: "手动上传未开始: " + cause.Error(),
+	})
+	if err != nil {
+		s.logger.Errorf("恢复视频 %s 的状态为 %s 失败: %v", video.VideoID, video.Status, err)
+		return
+	}
+	if restored {
+		s.logger.Infof("↩️ 视频 %s 手动上传未开始（%v），状态已恢复为 %s", video.VideoID, cause, video.Status)
+	}
 }
 
@@ -0,0 +1,70 @@
+package chain_task
+
+import (
+	"testing"
+	"time"
+
+	"github.com/difyz9/ytb2bili/internal/core/services"
+	"github.com/difyz9/ytb2bili/pkg/store/model"
+	"go.uber.org/zap"
+)
+
+func TestExecuteManualUploadRestoresStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		taskType  string
+		from      model.VideoStatus
+		uploading model.VideoStatus
+		stopping  bool   // 实例正在关闭
+		leasedBy  string // 持有视频租约的其他实例
+	}{
+		{name: "实例关闭中", taskType: "video", from: model.VideoStatusUploadFailed, uploading: model.VideoStatusUploading, stopping: true},
+		{name: "视频正由其他实例处理", taskType: "video", from: model.VideoStatusReady, uploading: model.VideoStatusUploading, leasedBy: "host-b:8096"},
+		{name: "字幕上传", taskType: "subtitle", from: model.VideoStatusSubtitleFailed, uploading: model.VideoStatusSubtitleUploading, stopping: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, &model.SavedVideo{}, &model.VideoStatusHistory{})
+			lease := &services.Lease{Owner: "host-a:8096", TTL: time.Minute}
+			s := &UploadScheduler{
+				SavedVideoService: services.NewSavedVideoService(db, lease),
+				Lease:             lease,
+				logger:            zap.NewNop().Sugar(),
+			}
+			s.stopping.Store(tt.stopping)
+
+			video := &model.SavedVideo{VideoID: "v1", URL: "https://www.youtube.com/watch?v=v1", Status: tt.from}
+			if err := db.Create(video).Error; err != nil {
+				t.Fatal(err)
+			}
+			if tt.leasedBy != "" {
+				expiresAt := time.Now().Add(time.Minute)
+				if err := db.Model(video).Updates(map[string]interface{}{"lease_owner": tt.leasedBy, "lease_expires_at": &expiresAt}).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// 与手动上传接口相同：先更新为上传中，再异步执行上传
+			claimed, err := s.SavedVideoService.ClaimVideo(video.ID, tt.from, tt.uploading, services.StatusChange{Actor: model.VideoStatusActorAPI})
+			if err != nil || !claimed {
+				t.Fatalf("ClaimVideo() = %v, %v", claimed, err)
+			}
+
+			if err := s.ExecuteManualUpload(video, tt.taskType); err == nil {
+				t.Fatal("ExecuteManualUpload() error = nil, want error")
+			}
+
+			saved, err := s.SavedVideoService.GetVideoByVideoID("v1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if saved.Status != tt.from {
+				t.Fatalf("status = %s, want %s", saved.Status, tt.from)
+			}
+			if tt.leasedBy != "" && saved.LeaseOwner != tt.leasedBy {
+				t.Errorf("lease owner = %q, want %q", saved.LeaseOwner, tt.leasedBy)
+			}
+		})
+	}
+}
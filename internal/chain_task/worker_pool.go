@@ -0,0 +1,98 @@
+package chain_task
+
+import (
+	"log"
+	"sync"
+)
+
+// WorkerPool 固定大小的工作池
+// 每个任务以 key（通常是 VideoID）标识，同一个 key 同一时刻只允许一个任务在执行，
+// 从而保证同一个视频不会被两个 worker 同时处理
+type WorkerPool struct {
+	size     int
+	slots    chan struct{}
+	inFlight map[string]struct{}
+	mutex    sync.Mutex
+	wg       sync.WaitGroup
+}
+
+// NewWorkerPool 创建工作池，size 小于 1 时按 1 处理
+func NewWorkerPool(size int) *WorkerPool {
+	if size < 1 {
+		size = 1
+	}
+	return &WorkerPool{
+		size:     size,
+		slots:    make(chan struct{}, size),
+		inFlight: make(map[string]struct{}),
+	}
+}
+
+// Size 工作池大小
+func (p *WorkerPool) Size() int {
+	return p.size
+}
+
+// Available 当前空闲的 worker 数量
+func (p *WorkerPool) Available() int {
+	return p.size - len(p.slots)
+}
+
+// IsBusy 判断指定 key 是否正在执行
+func (p *WorkerPool) IsBusy(key string) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	_, ok := p.inFlight[key]
+	return ok
+}
+
+// TryAcquire 非阻塞地占用一个 worker 并登记 key
+// 工作池已满或 key 已在执行时返回 false
+func (p *WorkerPool) TryAcquire(key string) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if _, ok := p.inFlight[key]; ok {
+		return false
+	}
+
+	select {
+	case p.slots <- struct{}{}:
+		p.inFlight[key] = struct{}{}
+		return true
+	default:
+		return false
+	}
+}
+
+// Release 释放 TryAcquire 占用的 worker
+func (p *WorkerPool) Release(key string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if _, ok := p.inFlight[key]; !ok {
+		return
+	}
+	delete(p.inFlight, key)
+	<-p.slots
+}
+
+// Go 在已占用的 worker 上异步执行 job，执行结束后自动释放
+func (p *WorkerPool) Go(key string, job func()) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer p.Release(key)
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("worker 执行 %s 时发生异常: %v", key, r)
+			}
+		}()
+		job()
+	}()
+}
+
+// Wait 等待所有正在执行的任务结束
+func (p *WorkerPool) Wait() {
+	p.wg.Wait()
+}
@@ -4,9 +4,12 @@ import (
 	"github.com/difyz9/ytb2bili/internal/core/types"
 	"github.com/difyz9/ytb2bili/pkg/cos"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -22,6 +25,8 @@ type AppServer struct {
 	DB        *gorm.DB
 	CosClient *cos.CosClient // COS客户端
 
+	mutex  sync.Mutex
+	server *http.Server // Run 启动的 HTTP 服务，Shutdown 时关闭
 }
 
 // NewServer 创建新的服务器实例
@@ -106,19 +111,42 @@ func (s *AppServer) setupMiddleware() {
 	})
 }
 
-// Run 启动服务器
+// Run 启动服务器，服务器被 Shutdown 关闭时返回 nil
 func (s *AppServer) Run() error {
 	s.Logger.Infof("Starting server on %s", s.Config.Listen)
 	s.Logger.Infof("Environment: %s", s.Config.Environment)
 
 	fmt.Println("listening on ---> ", s.Config.Listen)
 
-	return s.Engine.Run(s.Config.Listen)
+	// 请求的 context 在关闭时取消，SSE 进度推送和日志跟踪等长连接随之结束，不会阻塞关闭
+	base, cancel := context.WithCancel(context.Background())
+	server := &http.Server{
+		Addr:        s.Config.Listen,
+		Handler:     s.Engine,
+		BaseContext: func(net.Listener) context.Context { return base },
+	}
+	server.RegisterOnShutdown(cancel)
+
+	s.mutex.Lock()
+	s.server = server
+	s.mutex.Unlock()
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		cancel()
+		return err
+	}
+	return nil
 }
 
-// Shutdown 优雅关闭服务器
+// Shutdown 优雅关闭服务器：停止接受新连接，等待处理中的请求结束
 func (s *AppServer) Shutdown(ctx context.Context) error {
 	s.Logger.Info("Shutting down server...")
 
-	return nil
+	s.mutex.Lock()
+	server := s.server
+	s.mutex.Unlock()
+	if server == nil {
+		return nil
+	}
+	return server.Shutdown(ctx)
 }
@@ -6,7 +6,17 @@ import (
 
 const TableNameTbChanel = "tb_channel"
 
+// 频道订阅状态
+const (
+	ChannelStatusActive = "active" // 定期检查新上传的视频
+	ChannelStatusPaused = "paused" // 暂停检查
+)
+
+// DefaultChannelCheckLimit 每次检查的最新视频数量
+const DefaultChannelCheckLimit = 15
+
 // TbChannel mapped from table <tb_channel>
+// 订阅的 YouTube 频道：定期检查频道的最新上传，新视频按频道的默认设置加入处理队列
 type TbChannel struct {
 	ChannelId    string `gorm:"column:channel_id;primaryKey;comment:频道ID" json:"channel_id"`       // 频道ID
 	Title        string `gorm:"size:500;column:title;comment:频道标题" json:"title"`                   // 频道标题
@@ -15,7 +25,19 @@ type TbChannel struct {
 	ThumbnailUrl string `gorm:"size:500;column:thumbnail_url;comment:缩略图URL" json:"thumbnail_url"` // 缩略图URL
 	UserId       string `gorm:"column:user_id;comment:用户ID" json:"user_id"`                        // 关联的用户ID
 
-	Status     string    `gorm:"size:10;column:status;comment:状态" json:"status"`        // 状态
+	// 新视频的默认设置
+	Pipeline      string `gorm:"size:100;column:pipeline;comment:流水线" json:"pipeline"`              // 使用的流水线，为空时使用配置的 profile
+	BiliTid       int    `gorm:"column:bili_tid;default:0;comment:投稿分区" json:"bili_tid"`            // 投稿分区ID，0 表示使用配置的 tid
+	TitleTemplate string `gorm:"size:500;column:title_template;comment:标题模板" json:"title_template"` // 投稿标题模板，为空时使用配置的 custom_title_template
+	Priority      int    `gorm:"column:priority;default:0;comment:调度优先级" json:"priority"`           // 新视频的调度优先级
+	CheckLimit    int    `gorm:"column:check_limit;default:0;comment:每次检查的视频数量" json:"check_limit"` // 每次检查的最新视频数量，0 使用默认值
+
+	// 检查状态
+	LastSeenVideoId string     `gorm:"size:100;column:last_seen_video_id;comment:最近看到的视频ID" json:"last_seen_video_id"` // 最近一次检查时最新的视频
+	LastCheckedAt   *time.Time `gorm:"column:last_checked_at;comment:最近检查时间" json:"last_checked_at"`                   // 最近一次检查的时间
+	LastCheckError  string     `gorm:"type:text;column:last_check_error;comment:最近检查错误" json:"last_check_error"`       // 最近一次检查的错误，成功时为空
+
+	Status     string    `gorm:"size:10;column:status;index;comment:状态" json:"status"`  // 状态: active、paused
 	CreateBy   string    `gorm:"size:20;column:create_by;comment:创建者" json:"create_by"` // 创建者
 	CreateTime time.Time `gorm:"column:create_time;comment:创建时间" json:"create_time"`    // 创建时间
 	UpdateBy   string    `gorm:"size:20;column:update_by;comment:更新者" json:"update_by"` // 更新者
@@ -27,3 +49,16 @@ type TbChannel struct {
 func (*TbChannel) TableName() string {
 	return TableNameTbChanel
 }
+
+// VideosURL 频道上传列表（/videos 标签页）的 URL
+func (c *TbChannel) VideosURL() string {
+	return "https://www.youtube.com/channel/" + c.ChannelId + "/videos"
+}
+
+// Limit 每次检查的最新视频数量
+func (c *TbChannel) Limit() int {
+	if c.CheckLimit > 0 {
+		return c.CheckLimit
+	}
+	return DefaultChannelCheckLimit
+}
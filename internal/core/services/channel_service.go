@@ -0,0 +1,126 @@
+package services
+
+import (
+	"time"
+
+	"github.com/difyz9/ytb2bili/internal/core/models"
+	"github.com/difyz9/ytb2bili/pkg/store/model"
+	"github.com/difyz9/ytb2bili/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// ChannelService 频道订阅服务
+// 订阅频道的新视频按频道的默认设置（流水线、投稿分区、标题模板、优先级）加入处理队列，
+// 同一频道的视频作为一个来源（channel:<频道ID>）参与轮转调度
+type ChannelService struct {
+	DB    *gorm.DB
+	Lease *Lease // 当前实例，加入视频时记录在状态变更记录中
+}
+
+// NewChannelService 创建频道订阅服务实例
+func NewChannelService(db *gorm.DB, lease *Lease) *ChannelService {
+	return &ChannelService{
+		DB:    db,
+		Lease: lease,
+	}
+}
+
+// GetByChannelID 根据频道ID获取订阅
+func (s *ChannelService) GetByChannelID(channelID string) (*models.TbChannel, error) {
+	var channel models.TbChannel
+	if err := s.DB.Where("channel_id = ?", channelID).First(&channel).Error; err != nil {
+		return nil, err
+	}
+	return &channel, nil
+}
+
+// ListChannels 获取所有订阅的频道，最近订阅的在前
+func (s *ChannelService) ListChannels() ([]models.TbChannel, error) {
+	var channels []models.TbChannel
+	err := s.DB.Order("create_time DESC").Find(&channels).Error
+	return channels, err
+}
+
+// CreateChannel 创建订阅
+func (s *ChannelService) CreateChannel(channel *models.TbChannel) error {
+	now := time.Now()
+	channel.CreateTime = now
+	channel.UpdateTime = now
+	if channel.Status == "" {
+		channel.Status = models.ChannelStatusActive
+	}
+	return s.DB.Create(channel).Error
+}
+
+// UpdateChannel 修改订阅的设置，updates 的键为列名
+func (s *ChannelService) UpdateChannel(channelID string, updates map[string]interface{}) error {
+	if len(updates) == 0 {
+		return nil
+	}
+	updates["update_time"] = time.Now()
+	return s.DB.Model(&models.TbChannel{}).Where("channel_id = ?", channelID).Updates(updates).Error
+}
+
+// DeleteChannel 取消订阅，已加入处理队列的视频保留
+func (s *ChannelService) DeleteChannel(channelID string) error {
+	return s.DB.Where("channel_id = ?", channelID).Delete(&models.TbChannel{}).Error
+}
+
+// GetDueChannels 获取未暂停、且距离上次检查已超过 interval 的订阅
+func (s *ChannelService) GetDueChannels(interval time.Duration) ([]models.TbChannel, error) {
+	var channels []models.TbChannel
+	err := s.DB.Where("status = ? AND (last_checked_at IS NULL OR last_checked_at <= ?)", models.ChannelStatusActive, time.Now().Add(-interval)).
+		Order("last_checked_at ASC").
+		Find(&channels).Error
+	return channels, err
+}
+
+// ClaimCheck 认领频道的定期检查，多个实例中只有一个实例会在同一周期内检查
+// 认领时更新 last_checked_at，返回 false 表示其他实例已经检查过
+func (s *ChannelService) ClaimCheck(channel *models.TbChannel, interval time.Duration) (bool, error) {
+	now := time.Now()
+	result := s.DB.Model(&models.TbChannel{}).
+		Where("channel_id = ? AND (last_checked_at IS NULL OR last_checked_at <= ?)", channel.ChannelId, now.Add(-interval)).
+		Update("last_checked_at", &now)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RecordCheck 记录一次检查的结果，checkErr 为空表示检查成功；lastSeen 不为空时更新最近看到的视频
+func (s *ChannelService) RecordCheck(channelID, lastSeen, checkErr string) error {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"last_checked_at":  &now,
+		"last_check_error": checkErr,
+	}
+	if lastSeen != "" {
+		updates["last_seen_video_id"] = lastSeen
+	}
+	return s.DB.Model(&models.TbChannel{}).Where("channel_id = ?", channelID).Updates(updates).Error
+}
+
+// AddEntries 按 entries 的顺序为尚未提交过的视频创建待处理（001）的 SavedVideo，使用频道的默认设置
+// 已存在的视频（包括已删除的、单独提交的和播放列表中的）不会被修改，返回新增视频的ID和跳过的数量
+func (s *ChannelService) AddEntries(channel *models.TbChannel, entries []utils.PlaylistEntry) ([]string, int, error) {
+	return addVideoEntries(s.DB, s.Lease, entries, model.SavedVideo{
+		OperationType: "channel",
+		Source:        model.ChannelSource(channel.ChannelId),
+		Priority:      channel.Priority,
+		Pipeline:      channel.Pipeline,
+		BiliTid:       channel.BiliTid,
+		TitleTemplate: channel.TitleTemplate,
+	}, "频道 "+channel.ChannelId+" 的新视频")
+}
+
+// GetChannelVideos 获取频道加入处理队列的视频，最新加入的在前，limit<=0 表示全部
+func (s *ChannelService) GetChannelVideos(channelID string, limit int) ([]model.SavedVideo, error) {
+	var videos []model.SavedVideo
+	query := s.DB.Where("source = ?", model.ChannelSource(channelID)).Order("created_at DESC, id DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Find(&videos).Error
+	return videos, err
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/difyz9/ytb2bili/internal/core/models"
+	"github.com/difyz9/ytb2bili/pkg/store/model"
+	"github.com/difyz9/ytb2bili/pkg/utils"
+)
+
+func TestChannelAddEntries(t *testing.T) {
+	db := newTestDB(t, &models.TbChannel{}, &model.SavedVideo{}, &model.VideoStatusHistory{})
+	service := NewChannelService(db, &Lease{Owner: "test"})
+
+	channel := &models.TbChannel{
+		ChannelId:     "UCtest",
+		Title:         "Test",
+		Pipeline:      "fast",
+		BiliTid:       17,
+		TitleTemplate: "【熟肉】{ai_title}",
+		Priority:      2,
+	}
+	if err := service.CreateChannel(channel); err != nil {
+		t.Fatalf("创建订阅失败: %v", err)
+	}
+
+	// 按上传时间从早到晚加入
+	added, skipped, err := service.AddEntries(channel, []utils.PlaylistEntry{{ID: "v1", URL: "u1"}, {ID: "v2", URL: "u2"}})
+	if err != nil || !slices.Equal(added, []string{"v1", "v2"}) || skipped != 0 {
+		t.Fatalf("AddEntries() = %v, %d, %v", added, skipped, err)
+	}
+	added, skipped, err = service.AddEntries(channel, []utils.PlaylistEntry{{ID: "v2", URL: "u2"}, {ID: "v3", URL: "u3"}})
+	if err != nil || !slices.Equal(added, []string{"v3"}) || skipped != 1 {
+		t.Fatalf("AddEntries() = %v, %d, %v", added, skipped, err)
+	}
+
+	videos, err := service.GetChannelVideos("UCtest", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, video := range videos {
+		if video.Source != "channel:UCtest" || video.Pipeline != "fast" || video.BiliTid != 17 ||
+			video.TitleTemplate != channel.TitleTemplate || video.Priority != 2 {
+			t.Errorf("视频 %s 没有使用频道的默认设置: %+v", video.VideoID, video)
+		}
+	}
+
+	// 新视频进入待处理队列，按上传时间调度
+	if got, want := pendingVideoIDs(t, db), []string{"v1", "v2", "v3"}; !slices.Equal(got, want) {
+		t.Errorf("待处理队列 = %v, want %v", got, want)
+	}
+}
+
+func TestChannelCheckClaims(t *testing.T) {
+	db := newTestDB(t, &models.TbChannel{})
+	service := NewChannelService(db, nil)
+
+	active := &models.TbChannel{ChannelId: "UCactive"}
+	paused := &models.TbChannel{ChannelId: "UCpaused", Status: models.ChannelStatusPaused}
+	for _, channel := range []*models.TbChannel{active, paused} {
+		if err := service.CreateChannel(channel); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	due, err := service.GetDueChannels(time.Hour)
+	if err != nil || len(due) != 1 || due[0].ChannelId != "UCactive" {
+		t.Fatalf("GetDueChannels() = %v, %v", due, err)
+	}
+	first, _ := service.ClaimCheck(active, time.Hour)
+	second, _ := service.ClaimCheck(active, time.Hour)
+	if !first || second {
+		t.Errorf("ClaimCheck() = %v, %v, want true, false", first, second)
+	}
+	if due, _ := service.GetDueChannels(time.Hour); len(due) != 0 {
+		t.Errorf("认领后仍需检查: %v", due)
+	}
+
+	if err := service.RecordCheck("UCactive", "v9", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.RecordCheck("UCactive", "", "network error"); err != nil {
+		t.Fatal(err)
+	}
+	channel, _ := service.GetByChannelID("UCactive")
+	if channel.LastSeenVideoId != "v9" || channel.LastCheckError != "network error" {
+		t.Errorf("RecordCheck() 后 last_seen=%q error=%q", channel.LastSeenVideoId, channel.LastCheckError)
+	}
+}
@@ -0,0 +1,36 @@
+package services
+
+import "sync"
+
+// FairQueue 记录队列中各来源最近一次被调度的顺序，同一优先级的视频在来源之间轮转调度
+// 避免一次导入大量视频的播放列表或频道长时间占满处理和上传队列
+// 调度记录只保存在当前实例内存中，重启后重新开始轮转
+type FairQueue struct {
+	mutex  sync.Mutex
+	seq    uint64
+	served map[string]uint64 // 来源 -> 最近一次被调度的序号
+}
+
+// NewFairQueue 创建来源轮转队列
+func NewFairQueue() *FairQueue {
+	return &FairQueue{
+		served: make(map[string]uint64),
+	}
+}
+
+// pick 从候选来源中选出最久未被调度的来源并记录本次调度
+// 都未被调度过时选择排在前面的来源，sources 按来源中最早的视频排序
+func (q *FairQueue) pick(sources []string) string {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	best := sources[0]
+	for _, source := range sources[1:] {
+		if q.served[source] < q.served[best] {
+			best = source
+		}
+	}
+	q.seq++
+	q.served[best] = q.seq
+	return best
+}
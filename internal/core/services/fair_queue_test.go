@@ -0,0 +1,74 @@
+package services
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/difyz9/ytb2bili/pkg/store/model"
+	"gorm.io/gorm"
+)
+
+// createReadyVideo 创建一个待上传的视频，createdAt 决定同一来源内的先后顺序
+func createReadyVideo(t *testing.T, db *gorm.DB, videoID, source string, priority int, createdAt time.Time) {
+	t.Helper()
+	video := model.SavedVideo{
+		VideoID:  videoID,
+		URL:      "https://www.youtube.com/watch?v=" + videoID,
+		Source:   source,
+		Priority: priority,
+		Status:   model.VideoStatusReady,
+	}
+	video.CreatedAt = createdAt
+	if err := db.Create(&video).Error; err != nil {
+		t.Fatalf("创建视频失败: %v", err)
+	}
+}
+
+// uploadNext 模拟上传调度器的一次调度：取出下一个视频并标记为上传中
+func uploadNext(t *testing.T, service *SavedVideoService, queue *FairQueue) string {
+	t.Helper()
+	videos, err := service.GetReadyVideos(queue, 1)
+	if err != nil {
+		t.Fatalf("获取待上传视频失败: %v", err)
+	}
+	if len(videos) == 0 {
+		return ""
+	}
+	if err := service.DB.Model(&videos[0]).Update("status", model.VideoStatusUploading).Error; err != nil {
+		t.Fatalf("更新视频状态失败: %v", err)
+	}
+	return videos[0].VideoID
+}
+
+func TestGetReadyVideosRotatesAcrossCalls(t *testing.T) {
+	db := newTestDB(t, &model.SavedVideo{})
+	service := NewSavedVideoService(db, nil)
+	queue := NewFairQueue()
+
+	// 播放列表一次导入了大量视频，之后才有单独提交和频道的视频
+	base := time.Now().Add(-time.Hour)
+	playlist := model.PlaylistSource("PL1")
+	for i, id := range []string{"p1", "p2", "p3", "p4"} {
+		createReadyVideo(t, db, id, playlist, 0, base.Add(time.Duration(i)*time.Second))
+	}
+	createReadyVideo(t, db, "m1", "", 0, base.Add(time.Minute))
+	createReadyVideo(t, db, "c1", model.VideoSourceChannel+"UC1", 0, base.Add(2*time.Minute))
+
+	// 调度器每次只取一个视频，轮转状态必须在多次调用之间保留，否则播放列表会一直排在最前
+	var got []string
+	for i := 0; i < 3; i++ {
+		got = append(got, uploadNext(t, service, queue))
+	}
+
+	// 后提交的高优先级视频插队到下一个
+	createReadyVideo(t, db, "urgent", "", 10, time.Now())
+	for id := uploadNext(t, service, queue); id != ""; id = uploadNext(t, service, queue) {
+		got = append(got, id)
+	}
+
+	want := []string{"p1", "m1", "c1", "urgent", "p2", "p3", "p4"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("upload order = %v, want %v", got, want)
+	}
+}
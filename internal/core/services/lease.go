@@ -0,0 +1,55 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/difyz9/ytb2bili/internal/core/types"
+)
+
+// DefaultLeaseTTL 未配置 lease_ttl 时的租约有效期
+const DefaultLeaseTTL = 2 * time.Minute
+
+// Lease 当前实例持有任务的租约
+// 多个实例共享同一个数据库时，视频和任务步骤在执行期间记录持有者（lease_owner）和过期时间（lease_expires_at），
+// 持有者定期续约；实例崩溃后租约过期，其他实例会回收这些任务重新执行
+type Lease struct {
+	Owner string        // 当前实例的标识
+	TTL   time.Duration // 租约有效期
+}
+
+// NewLease 根据配置创建当前实例的租约
+// 未配置 instance_id 时使用"主机名+监听地址"，同一台主机上的实例监听不同端口，重启后标识保持不变
+func NewLease(config *types.AppConfig) *Lease {
+	lease := &Lease{TTL: DefaultLeaseTTL}
+	if config.PipelineConfig != nil {
+		lease.Owner = config.PipelineConfig.InstanceID
+		if config.PipelineConfig.LeaseTTL > 0 {
+			lease.TTL = time.Duration(config.PipelineConfig.LeaseTTL) * time.Second
+		}
+	}
+
+	if lease.Owner == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = "localhost"
+		}
+		lease.Owner = fmt.Sprintf("%s%s", hostname, config.Listen)
+	}
+	return lease
+}
+
+// ExpiresAt 从现在开始计算的租约过期时间
+func (l *Lease) ExpiresAt() time.Time {
+	return time.Now().Add(l.TTL)
+}
+
+// HeartbeatInterval 续约间隔，为租约有效期的三分之一，允许连续两次续约失败
+func (l *Lease) HeartbeatInterval() time.Duration {
+	interval := l.TTL / 3
+	if interval < time.Second {
+		interval = time.Second
+	}
+	return interval
+}
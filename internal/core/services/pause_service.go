@@ -0,0 +1,71 @@
+package services
+
+import (
+	"github.com/difyz9/ytb2bili/pkg/store/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PauseService 流水线暂停状态服务
+// 暂停状态保存在 cw_pause_states 中，所有实例共享，重启后仍然有效
+type PauseService struct {
+	DB    *gorm.DB
+	Lease *Lease // 当前实例，暂停记录中标记执行暂停的实例
+}
+
+// NewPauseService 创建暂停状态服务实例
+func NewPauseService(db *gorm.DB, lease *Lease) *PauseService {
+	return &PauseService{
+		DB:    db,
+		Lease: lease,
+	}
+}
+
+// Pause 暂停 scope，已暂停时更新暂停原因
+func (s *PauseService) Pause(scope, reason string) (*model.PauseState, error) {
+	state := &model.PauseState{
+		Scope:    scope,
+		Reason:   reason,
+		PausedBy: s.Lease.Owner,
+	}
+	err := s.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "scope"}},
+		DoUpdates: clause.AssignmentColumns([]string{"reason", "paused_by"}),
+	}).Create(state).Error
+	if err != nil {
+		return nil, err
+	}
+	return s.FindPause(scope)
+}
+
+// Resume 恢复 scope，返回 scope 之前是否处于暂停状态
+func (s *PauseService) Resume(scope string) (bool, error) {
+	result := s.DB.Where("scope = ?", scope).Delete(&model.PauseState{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ListPauses 获取所有已暂停的范围，按暂停时间先后排列
+func (s *PauseService) ListPauses() ([]model.PauseState, error) {
+	var states []model.PauseState
+	err := s.DB.Order("created_at ASC, id ASC").Find(&states).Error
+	return states, err
+}
+
+// FindPause 返回 scopes 中已暂停的第一个范围，都未暂停时返回 nil
+func (s *PauseService) FindPause(scopes ...string) (*model.PauseState, error) {
+	var states []model.PauseState
+	if err := s.DB.Where("scope IN ?", scopes).Find(&states).Error; err != nil {
+		return nil, err
+	}
+	for _, scope := range scopes {
+		for i := range states {
+			if states[i].Scope == scope {
+				return &states[i], nil
+			}
+		}
+	}
+	return nil, nil
+}
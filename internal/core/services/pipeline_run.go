@@ -0,0 +1,136 @@
+package services
+
+import (
+	"time"
+
+	"github.com/difyz9/ytb2bili/internal/core/types"
+	"github.com/difyz9/ytb2bili/pkg/store/model"
+	"gorm.io/gorm"
+)
+
+// DefaultRunHistoryLimit 查询执行记录时默认返回的最近执行次数
+const DefaultRunHistoryLimit = 20
+
+// StartRun 记录一次任务执行的开始，stepName 为单步重试和上传时执行的步骤
+func (s *TaskStepService) StartRun(videoID, kind, trigger, pipeline, stepName string) (*model.PipelineRun, error) {
+	run := &model.PipelineRun{
+		VideoID:   videoID,
+		Kind:      kind,
+		Trigger:   trigger,
+		Pipeline:  pipeline,
+		StepName:  stepName,
+		Status:    model.RunStatusRunning,
+		Instance:  s.Lease.Owner,
+		StartedAt: time.Now(),
+	}
+	if err := s.DB.Create(run).Error; err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// FinishRun 记录任务执行的结束状态，run 为 nil（开始记录失败）时忽略
+func (s *TaskStepService) FinishRun(run *model.PipelineRun, status, errorMsg string) error {
+	if run == nil {
+		return nil
+	}
+	now := time.Now()
+	return s.DB.Model(&model.PipelineRun{}).
+		Where("id = ? AND status = ?", run.ID, model.RunStatusRunning).
+		Updates(map[string]interface{}{
+			"status":    status,
+			"error_msg": errorMsg,
+			"ended_at":  &now,
+			"duration":  now.Sub(run.StartedAt).Milliseconds(),
+		}).Error
+}
+
+// GetRuns 获取视频最近的执行记录及每次执行的步骤，按时间倒序排列
+func (s *TaskStepService) GetRuns(videoID string, limit int) ([]model.PipelineRun, error) {
+	if limit <= 0 {
+		limit = DefaultRunHistoryLimit
+	}
+	var runs []model.PipelineRun
+	err := s.DB.Where("video_id = ?", videoID).
+		Preload("Steps", func(db *gorm.DB) *gorm.DB {
+			return db.Order("started_at ASC, id ASC")
+		}).
+		Order("started_at DESC, id DESC").
+		Limit(limit).
+		Find(&runs).Error
+	return runs, err
+}
+
+// startStepRun 记录步骤一次执行的开始
+func (s *TaskStepService) startStepRun(runID uint, videoID, stepName string, startedAt time.Time) error {
+	step, err := s.GetTaskStepByName(videoID, stepName)
+	if err != nil {
+		return err
+	}
+	return s.DB.Create(&model.StepRun{
+		RunID:     runID,
+		VideoID:   videoID,
+		StepName:  stepName,
+		Attempt:   step.Attempts,
+		Status:    model.RunStatusRunning,
+		Instance:  s.Lease.Owner,
+		StartedAt: startedAt,
+	}).Error
+}
+
+// finishStepRun 记录步骤最近一次执行的结束状态
+// 执行中的步骤被回收重新排队（pending）时记为 interrupted
+func (s *TaskStepService) finishStepRun(videoID, stepName, status string, taskErr *types.TaskError, endedAt time.Time) error {
+	var stepRun model.StepRun
+	err := s.DB.Where("video_id = ? AND step_name = ? AND status = ?", videoID, stepName, model.RunStatusRunning).
+		Order("id DESC").
+		Limit(1).
+		Find(&stepRun).Error
+	if err != nil || stepRun.ID == 0 {
+		return err
+	}
+
+	if status == model.TaskStepStatusPending {
+		status = model.RunStatusInterrupted
+	}
+	updates := map[string]interface{}{
+		"status":   status,
+		"ended_at": &endedAt,
+		"duration": endedAt.Sub(stepRun.StartedAt).Milliseconds(),
+	}
+	if taskErr != nil {
+		updates["error_msg"] = taskErr.Message
+		updates["error_code"] = string(taskErr.Code)
+		updates["error_category"] = string(taskErr.Category)
+		updates["error_retryable"] = taskErr.Retryable
+		updates["error_detail"] = taskErr.Detail
+	}
+	return s.DB.Model(&model.StepRun{}).Where("id = ?", stepRun.ID).Updates(updates).Error
+}
+
+// setStepRunResult 保存步骤最近一次执行的结果
+func (s *TaskStepService) setStepRunResult(videoID, stepName, resultData string) error {
+	var stepRun model.StepRun
+	err := s.DB.Where("video_id = ? AND step_name = ?", videoID, stepName).
+		Order("id DESC").
+		Limit(1).
+		Find(&stepRun).Error
+	if err != nil || stepRun.ID == 0 {
+		return err
+	}
+	return s.DB.Model(&model.StepRun{}).Where("id = ?", stepRun.ID).Update("result_data", resultData).Error
+}
+
+// interruptRuns 将实例失联时视频仍在执行中的记录标记为 interrupted
+func (s *TaskStepService) interruptRuns(videoID, instance string) error {
+	now := time.Now()
+	query := s.DB.Model(&model.PipelineRun{}).Where("video_id = ? AND status = ?", videoID, model.RunStatusRunning)
+	if instance != "" {
+		query = query.Where("instance = ?", instance)
+	}
+	return query.Updates(map[string]interface{}{
+		"status":    model.RunStatusInterrupted,
+		"error_msg": "执行的实例已失联，任务已被回收",
+		"ended_at":  &now,
+	}).Error
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"time"
+
+	"github.com/difyz9/ytb2bili/pkg/store/model"
+	"github.com/difyz9/ytb2bili/pkg/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PlaylistService 播放列表服务
+// 播放列表中的视频展开为单独的 SavedVideo，同一播放列表的视频作为一个来源参与轮转调度
+type PlaylistService struct {
+	DB    *gorm.DB
+	Lease *Lease // 当前实例，导入视频时记录在状态变更记录中
+}
+
+// NewPlaylistService 创建播放列表服务实例
+func NewPlaylistService(db *gorm.DB, lease *Lease) *PlaylistService {
+	return &PlaylistService{
+		DB:    db,
+		Lease: lease,
+	}
+}
+
+// GetByID 根据ID获取播放列表
+func (s *PlaylistService) GetByID(id uint) (*model.Playlist, error) {
+	var playlist model.Playlist
+	if err := s.DB.Where("id = ?", id).First(&playlist).Error; err != nil {
+		return nil, err
+	}
+	return &playlist, nil
+}
+
+// GetByPlaylistID 根据 YouTube 播放列表ID获取播放列表
+func (s *PlaylistService) GetByPlaylistID(playlistID string) (*model.Playlist, error) {
+	var playlist model.Playlist
+	if err := s.DB.Where("playlist_id = ?", playlistID).First(&playlist).Error; err != nil {
+		return nil, err
+	}
+	return &playlist, nil
+}
+
+// ListPlaylists 获取所有导入的播放列表，最近导入的在前
+func (s *PlaylistService) ListPlaylists() ([]model.Playlist, error) {
+	var playlists []model.Playlist
+	err := s.DB.Order("created_at DESC").Find(&playlists).Error
+	return playlists, err
+}
+
+// SavePlaylist 保存播放列表，同一播放列表再次导入时更新 URL、标题、优先级和自动同步设置
+func (s *PlaylistService) SavePlaylist(playlist *model.Playlist) (*model.Playlist, error) {
+	err := s.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "playlist_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"url", "title", "uploader", "priority", "auto_sync", "updated_at"}),
+	}).Create(playlist).Error
+	if err != nil {
+		return nil, err
+	}
+	return s.GetByPlaylistID(playlist.PlaylistID)
+}
+
+// UpdateSettings 修改播放列表的自动同步和优先级设置，参数为 nil 时不修改
+// 优先级只影响之后导入的视频
+func (s *PlaylistService) UpdateSettings(id uint, autoSync *bool, priority *int) error {
+	updates := map[string]interface{}{}
+	if autoSync != nil {
+		updates["auto_sync"] = *autoSync
+	}
+	if priority != nil {
+		updates["priority"] = *priority
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	return s.DB.Model(&model.Playlist{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// DeletePlaylist 删除播放列表，已导入的视频保留
+func (s *PlaylistService) DeletePlaylist(id uint) error {
+	return s.DB.Delete(&model.Playlist{}, id).Error
+}
+
+// GetAutoSyncPlaylists 获取开启了自动同步、且距离上次同步已超过 interval 的播放列表
+func (s *PlaylistService) GetAutoSyncPlaylists(interval time.Duration) ([]model.Playlist, error) {
+	var playlists []model.Playlist
+	err := s.DB.Where("auto_sync = ? AND (last_synced_at IS NULL OR last_synced_at <= ?)", true, time.Now().Add(-interval)).
+		Order("last_synced_at ASC").
+		Find(&playlists).Error
+	return playlists, err
+}
+
+// ClaimSync 认领播放列表的定期同步，多个实例中只有一个实例会在同一周期内同步
+// 认领时更新 last_synced_at，返回 false 表示其他实例已经同步过
+func (s *PlaylistService) ClaimSync(playlist *model.Playlist, interval time.Duration) (bool, error) {
+	now := time.Now()
+	result := s.DB.Model(&model.Playlist{}).
+		Where("id = ? AND (last_synced_at IS NULL OR last_synced_at <= ?)", playlist.ID, now.Add(-interval)).
+		Update("last_synced_at", &now)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RecordSync 记录一次同步的结果，syncErr 为空表示同步成功
+func (s *PlaylistService) RecordSync(id uint, entryCount int, syncErr string) error {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"last_synced_at":  &now,
+		"last_sync_error": syncErr,
+	}
+	if syncErr == "" {
+		updates["entry_count"] = entryCount
+	}
+	return s.DB.Model(&model.Playlist{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// AddEntries 按播放列表顺序为尚未提交过的视频创建待处理（001）的 SavedVideo
+// 已存在的视频（包括已删除的、单独提交的和其他播放列表中的）不会被修改，返回新增视频的ID和跳过的数量
+func (s *PlaylistService) AddEntries(playlist *model.Playlist, entries []utils.PlaylistEntry) ([]string, int, error) {
+	return addVideoEntries(s.DB, s.Lease, entries, model.SavedVideo{
+		OperationType: "playlist",
+		PlaylistID:    playlist.PlaylistID,
+		Source:        model.PlaylistSource(playlist.PlaylistID),
+		Priority:      playlist.Priority,
+	}, "从播放列表 "+playlist.PlaylistID+" 导入")
+}
+
+// addVideoEntries 按 entries 的顺序为尚未提交过的视频创建待处理（001）的 SavedVideo，其余字段取自 defaults
+// 同一来源的视频按创建顺序调度，因此 entries 的顺序即处理顺序。已存在的视频（包括已删除的）不会被修改，
+// 返回新增视频的ID和跳过的数量
+func addVideoEntries(db *gorm.DB, lease *Lease, entries []utils.PlaylistEntry, defaults model.SavedVideo, reason string) ([]string, int, error) {
+	if len(entries) == 0 {
+		return nil, 0, nil
+	}
+
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.ID)
+	}
+	var existingIDs []string
+	if err := db.Unscoped().Model(&model.SavedVideo{}).Where("video_id IN ?", ids).Pluck("video_id", &existingIDs).Error; err != nil {
+		return nil, 0, err
+	}
+	existing := make(map[string]bool, len(existingIDs))
+	for _, id := range existingIDs {
+		existing[id] = true
+	}
+
+	var added []string
+	skipped := 0
+	savedAt := time.Now().Format("2006-01-02 15:04:05")
+	for _, entry := range entries {
+		if existing[entry.ID] {
+			skipped++
+			continue
+		}
+		existing[entry.ID] = true // 重复出现的视频只导入一次
+
+		video := defaults
+		video.VideoID = entry.ID
+		video.URL = entry.URL
+		video.Title = entry.Title
+		video.Status = model.VideoStatusPending
+		video.Duration = int(entry.Duration)
+		video.SavedAt = savedAt
+		// 其他实例同时导入时视频可能已被创建，忽略唯一索引冲突
+		result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&video)
+		if result.Error != nil {
+			return added, skipped, result.Error
+		}
+		if result.RowsAffected == 0 {
+			skipped++
+			continue
+		}
+		if err := recordVideoStatus(db, lease, &video, "", video.Status, StatusChange{
+			Actor:  model.VideoStatusActorSubmit,
+			Reason: reason,
+		}); err != nil {
+			return added, skipped, err
+		}
+		added = append(added, entry.ID)
+	}
+	return added, skipped, nil
+}
+
+// GetPlaylistVideos 获取从播放列表导入的视频，按导入顺序（即播放列表顺序）排列
+func (s *PlaylistService) GetPlaylistVideos(playlistID string) ([]model.SavedVideo, error) {
+	var videos []model.SavedVideo
+	err := s.DB.Where("playlist_id = ?", playlistID).
+		Order("created_at ASC, id ASC").
+		Find(&videos).Error
+	return videos, err
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/difyz9/ytb2bili/pkg/store/model"
+	"github.com/difyz9/ytb2bili/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// pendingVideoIDs 待处理队列中的视频ID，按调度顺序排列
+func pendingVideoIDs(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+	videos, err := NewSavedVideoService(db, nil).GetPendingVideos(NewFairQueue(), 100)
+	if err != nil {
+		t.Fatalf("获取待处理视频失败: %v", err)
+	}
+	ids := make([]string, 0, len(videos))
+	for _, video := range videos {
+		ids = append(ids, video.VideoID)
+	}
+	return ids
+}
+
+func TestPlaylistAddEntries(t *testing.T) {
+	db := newTestDB(t, &model.Playlist{}, &model.SavedVideo{}, &model.VideoStatusHistory{})
+	service := NewPlaylistService(db, &Lease{Owner: "test"})
+
+	playlist, err := service.SavePlaylist(&model.Playlist{PlaylistID: "PL1", URL: "https://www.youtube.com/playlist?list=PL1", Priority: 3})
+	if err != nil {
+		t.Fatalf("保存播放列表失败: %v", err)
+	}
+	// 已单独提交过的视频
+	if err := db.Create(&model.SavedVideo{VideoID: "b", URL: "https://www.youtube.com/watch?v=b", Status: model.VideoStatusCompleted}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		entries     []utils.PlaylistEntry
+		wantAdded   []string
+		wantSkipped int
+	}{
+		{
+			name:        "按播放列表顺序导入，跳过已提交和重复的视频",
+			entries:     []utils.PlaylistEntry{{ID: "c", URL: "uc"}, {ID: "b", URL: "ub"}, {ID: "a", URL: "ua"}, {ID: "c", URL: "uc"}},
+			wantAdded:   []string{"c", "a"},
+			wantSkipped: 2,
+		},
+		{
+			name:        "再次同步只导入新增的视频",
+			entries:     []utils.PlaylistEntry{{ID: "c", URL: "uc"}, {ID: "a", URL: "ua"}, {ID: "d", URL: "ud"}},
+			wantAdded:   []string{"d"},
+			wantSkipped: 2,
+		},
+		{
+			name: "空播放列表",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, skipped, err := service.AddEntries(playlist, tt.entries)
+			if err != nil {
+				t.Fatalf("导入失败: %v", err)
+			}
+			if !slices.Equal(added, tt.wantAdded) || skipped != tt.wantSkipped {
+				t.Errorf("AddEntries() = %v, %d, want %v, %d", added, skipped, tt.wantAdded, tt.wantSkipped)
+			}
+		})
+	}
+
+	videos, err := service.GetPlaylistVideos("PL1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, video := range videos {
+		if video.Source != "playlist:PL1" || video.Priority != 3 || video.Status != model.VideoStatusPending {
+			t.Errorf("视频 %s: source=%q priority=%d status=%s", video.VideoID, video.Source, video.Priority, video.Status)
+		}
+	}
+
+	// 导入的视频没有字幕，同样进入待处理队列，并保持播放列表顺序
+	if got, want := pendingVideoIDs(t, db), []string{"c", "a", "d"}; !slices.Equal(got, want) {
+		t.Errorf("待处理队列 = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/difyz9/ytb2bili/internal/core/types"
+)
+
+// RetryPolicy 步骤失败后的自动重试策略
+type RetryPolicy struct {
+	MaxAttempts  int           // 最多执行次数（含首次执行）
+	InitialDelay time.Duration // 第一次重试前的等待时间
+	MaxDelay     time.Duration // 重试等待时间上限
+	Multiplier   float64       // 每次重试等待时间的增长倍数
+	Jitter       float64       // 随机抖动比例（0-1）
+}
+
+// DefaultRetryPolicy 未配置 [PipelineConfig.retry] 时使用的重试策略
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:  3,
+	InitialDelay: time.Minute,
+	MaxDelay:     time.Hour,
+	Multiplier:   2,
+	Jitter:       0.2,
+}
+
+// defaultTaskRetry 内置的按任务类型重试策略，可被 [PipelineConfig.step_retry] 按步骤名称覆盖
+// 上传步骤失败时可能已经提交了稿件，自动重试会造成重复投稿，默认只执行一次
+var defaultTaskRetry = map[string]types.RetryPolicyConfig{
+	"upload_video":    {MaxAttempts: 1},
+	"upload_subtitle": {MaxAttempts: 1},
+}
+
+// NewRetryPolicy 根据配置获取步骤的重试策略，task 为步骤执行的任务类型
+// 按步骤覆盖的策略中未设置（为 0）的字段使用全局策略
+func NewRetryPolicy(config *types.AppConfig, stepName, task string) RetryPolicy {
+	policy := DefaultRetryPolicy
+
+	var global *types.RetryPolicyConfig
+	stepOverride, hasStepOverride := defaultTaskRetry[task]
+	if config != nil && config.PipelineConfig != nil {
+		global = config.PipelineConfig.Retry
+		if override := config.PipelineConfig.StepRetry[stepName]; override != nil {
+			stepOverride, hasStepOverride = *override, true
+		}
+	}
+
+	policy.apply(global)
+	if hasStepOverride {
+		policy.apply(&stepOverride)
+	}
+	return policy
+}
+
+// apply 使用配置中非零的字段覆盖策略
+func (p *RetryPolicy) apply(config *types.RetryPolicyConfig) {
+	if config == nil {
+		return
+	}
+	if config.MaxAttempts > 0 {
+		p.MaxAttempts = config.MaxAttempts
+	}
+	if config.InitialDelay > 0 {
+		p.InitialDelay = time.Duration(config.InitialDelay) * time.Second
+	}
+	if config.MaxDelay > 0 {
+		p.MaxDelay = time.Duration(config.MaxDelay) * time.Second
+	}
+	if config.Multiplier > 0 {
+		p.Multiplier = config.Multiplier
+	}
+	if config.Jitter > 0 {
+		p.Jitter = math.Min(config.Jitter, 1)
+	}
+}
+
+// Backoff 计算第 attempt 次执行失败后到下一次重试的等待时间（attempt 从 1 开始）
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := float64(p.InitialDelay) * math.Pow(p.Multiplier, float64(attempt-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		delay *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(delay)
+}
+
+// ShouldRetry 判断第 attempt 次执行失败后是否应该自动重试
+// 不可重试的错误（视频不可用、未登录、缺少配置等）不会自动重试
+func (p RetryPolicy) ShouldRetry(attempt int, taskErr *types.TaskError) bool {
+	return attempt < p.MaxAttempts && (taskErr == nil || taskErr.Retryable)
+}
@@ -1,30 +1,103 @@
 package services
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/difyz9/ytb2bili/pkg/store/model"
 	"gorm.io/gorm"
 )
 
 // SavedVideoService 保存视频服务
+// 视频状态的修改都经过状态机校验，并记录在 cw_video_status_history 中
 type SavedVideoService struct {
-	DB *gorm.DB
+	DB    *gorm.DB
+	Lease *Lease // 当前实例，状态变更记录中标记执行变更的实例
 }
 
 // NewSavedVideoService 创建保存视频服务实例
-func NewSavedVideoService(db *gorm.DB) *SavedVideoService {
+func NewSavedVideoService(db *gorm.DB, lease *Lease) *SavedVideoService {
 	return &SavedVideoService{
-		DB: db,
+		DB:    db,
+		Lease: lease,
 	}
 }
 
-// GetPendingVideos 获取待处理的视频列表（状态为 001 且 subtitles 不为空）
-func (s *SavedVideoService) GetPendingVideos(limit int) ([]model.SavedVideo, error) {
+// GetPendingVideos 获取待处理的视频列表（状态为 001），按优先级和来源轮转排序
+// 不要求提交时附带字幕：从播放列表、频道导入的视频没有字幕，由 Whisper 转录，或由生成字幕步骤跳过
+func (s *SavedVideoService) GetPendingVideos(queue *FairQueue, limit int) ([]model.SavedVideo, error) {
+	return s.nextVideos(queue, limit, func(db *gorm.DB) *gorm.DB {
+		return db.Where("status = ?", model.VideoStatusPending)
+	})
+}
+
+// GetReadyVideos 获取准备就绪待上传的视频列表（状态为 200），按优先级和来源轮转排序
+func (s *SavedVideoService) GetReadyVideos(queue *FairQueue, limit int) ([]model.SavedVideo, error) {
+	return s.nextVideos(queue, limit, func(db *gorm.DB) *gorm.DB {
+		return db.Where("status = ?", model.VideoStatusReady)
+	})
+}
+
+// nextVideos 从 scope 筛选出的视频中选出最多 limit 个视频
+// 优先级高的视频先调度；同一优先级的视频在来源（播放列表、频道、单独提交）之间轮转，同一来源内按提交先后
+func (s *SavedVideoService) nextVideos(queue *FairQueue, limit int, scope func(*gorm.DB) *gorm.DB) ([]model.SavedVideo, error) {
 	var videos []model.SavedVideo
-	err := s.DB.Where("status = ? AND subtitles IS NOT NULL AND subtitles != ''", "001").
-		Order("created_at ASC").
-		Limit(limit).
-		Find(&videos).Error
-	return videos, err
+	var picked []uint
+
+	for len(videos) < limit {
+		candidates := func() *gorm.DB {
+			query := s.DB.Model(&model.SavedVideo{}).Scopes(scope)
+			if len(picked) > 0 {
+				query = query.Where("id NOT IN ?", picked)
+			}
+			return query
+		}
+
+		// 各来源中待调度视频的最高优先级，按来源中最早的视频排序
+		var heads []struct {
+			Source   string
+			Priority int
+		}
+		err := candidates().
+			Select("source, MAX(priority) AS priority").
+			Group("source").
+			Order("MIN(created_at) ASC").
+			Scan(&heads).Error
+		if err != nil {
+			return nil, err
+		}
+		if len(heads) == 0 {
+			break
+		}
+
+		// 只在最高优先级的来源之间轮转
+		top := heads[0].Priority
+		for _, head := range heads[1:] {
+			if head.Priority > top {
+				top = head.Priority
+			}
+		}
+		var sources []string
+		for _, head := range heads {
+			if head.Priority == top {
+				sources = append(sources, head.Source)
+			}
+		}
+		source := queue.pick(sources)
+
+		var video model.SavedVideo
+		err = candidates().
+			Where("source = ? AND priority = ?", source, top).
+			Order("created_at ASC, id ASC").
+			First(&video).Error
+		if err != nil {
+			return nil, err
+		}
+		videos = append(videos, video)
+		picked = append(picked, video.ID)
+	}
+
+	return videos, nil
 }
 
 // GetVideoByID 根据ID获取视频
@@ -47,11 +120,136 @@ func (s *SavedVideoService) GetVideoByVideoID(videoID string) (*model.SavedVideo
 	return &video, nil
 }
 
-// UpdateStatus 更新视频状态
-func (s *SavedVideoService) UpdateStatus(id uint, status string) error {
+// UpdateStatus 按状态机将视频从当前状态更新为 status
+// 状态机不允许该转换时返回 ErrInvalidStatusTransition；当前状态已是 status 时不做修改
+func (s *SavedVideoService) UpdateStatus(id uint, status model.VideoStatus, change StatusChange) error {
+	video, err := s.GetVideoByID(id)
+	if err != nil {
+		return err
+	}
+	if video.Status == status {
+		return nil
+	}
+
+	updated, err := transitionVideo(s.DB, s.Lease, id, video.Status, status, change, nil, nil)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return fmt.Errorf("视频状态已变化，未更新为 %s", status)
+	}
+	return nil
+}
+
+// ClaimVideo 原子地将视频状态从 fromStatus 切换为 toStatus
+// 只有当前状态仍为 fromStatus 时才会更新，返回 false 表示已被其他 worker 抢先认领
+func (s *SavedVideoService) ClaimVideo(id uint, fromStatus, toStatus model.VideoStatus, change StatusChange) (bool, error) {
+	return transitionVideo(s.DB, s.Lease, id, fromStatus, toStatus, change, nil, nil)
+}
+
+// leaseAvailable 租约可被当前实例获取的条件：无人持有、已过期或由当前实例持有
+const leaseAvailable = "(lease_owner = '' OR lease_owner IS NULL OR lease_owner = ? OR lease_expires_at < ?)"
+
+// ClaimVideoWithLease 原子地将视频状态从 fromStatus 切换为 toStatus 并获取视频的租约
+// 视频正被其他实例处理（租约未过期）时返回 false
+func (s *SavedVideoService) ClaimVideoWithLease(id uint, fromStatus, toStatus model.VideoStatus, lease *Lease, change StatusChange) (bool, error) {
+	available := func(query *gorm.DB) *gorm.DB {
+		return query.Where(leaseAvailable, lease.Owner, time.Now())
+	}
+	return transitionVideo(s.DB, lease, id, fromStatus, toStatus, change, available, map[string]interface{}{
+		"lease_owner":      lease.Owner,
+		"lease_expires_at": lease.ExpiresAt(),
+	})
+}
+
+// AcquireVideo 获取视频的租约但不修改状态，用于单步重试和手动上传
+// 租约字段不更新 updated_at，避免影响按 updated_at 计算的字幕上传时间
+func (s *SavedVideoService) AcquireVideo(id uint, lease *Lease) (bool, error) {
+	result := s.DB.Model(&model.SavedVideo{}).
+		Where("id = ?", id).
+		Where(leaseAvailable, lease.Owner, time.Now()).
+		UpdateColumns(map[string]interface{}{
+			"lease_owner":      lease.Owner,
+			"lease_expires_at": lease.ExpiresAt(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ReleaseVideo 释放当前实例持有的视频租约
+func (s *SavedVideoService) ReleaseVideo(id uint, lease *Lease) error {
+	return s.DB.Model(&model.SavedVideo{}).
+		Where("id = ? AND lease_owner = ?", id, lease.Owner).
+		UpdateColumns(map[string]interface{}{
+			"lease_owner":      "",
+			"lease_expires_at": nil,
+		}).Error
+}
+
+// RenewVideoLeases 为当前实例持有的所有视频租约续约
+func (s *SavedVideoService) RenewVideoLeases(lease *Lease) (int64, error) {
+	result := s.DB.Model(&model.SavedVideo{}).
+		Where("lease_owner = ?", lease.Owner).
+		UpdateColumn("lease_expires_at", lease.ExpiresAt())
+	return result.RowsAffected, result.Error
+}
+
+// leaseFree 视频没有被任何实例持有（无租约或租约已过期）
+const leaseFree = "(lease_owner = '' OR lease_owner IS NULL OR lease_expires_at < ?)"
+
+// GetUnleasedVideos 获取处于 statuses 中且没有被任何实例处理的视频，供一致性检查使用
+func (s *SavedVideoService) GetUnleasedVideos(statuses []model.VideoStatus) ([]model.SavedVideo, error) {
+	var videos []model.SavedVideo
+	err := s.DB.Where("status IN ?", statuses).
+		Where(leaseFree, time.Now()).
+		Order("id ASC").
+		Find(&videos).Error
+	return videos, err
+}
+
+// ReconcileVideo 将没有被任何实例处理的视频从 from 修复为 to，同时清除已过期的租约
+// 视频状态已变化或已被其他实例认领时返回 false
+func (s *SavedVideoService) ReconcileVideo(id uint, from, to model.VideoStatus, change StatusChange) (bool, error) {
+	free := func(query *gorm.DB) *gorm.DB {
+		return query.Where(leaseFree, time.Now())
+	}
+	return transitionVideo(s.DB, s.Lease, id, from, to, change, free, map[string]interface{}{
+		"lease_owner":      "",
+		"lease_expires_at": nil,
+	})
+}
+
+// ScheduleRun 将没有被任何实例处理的视频重新排队为待处理，并记录只执行的步骤范围
+// 视频已是待处理时只更新步骤范围；视频状态已变化或正被处理时返回 false
+func (s *SavedVideoService) ScheduleRun(video *model.SavedVideo, runFrom, runUntil string, change StatusChange) (bool, error) {
+	columns := map[string]interface{}{
+		"run_from":  runFrom,
+		"run_until": runUntil,
+	}
+	if video.Status == model.VideoStatusPending {
+		result := s.DB.Model(&model.SavedVideo{}).
+			Where("id = ? AND status = ?", video.ID, video.Status).
+			Where(leaseFree, time.Now()).
+			Updates(columns)
+		return result.RowsAffected == 1, result.Error
+	}
+
+	free := func(query *gorm.DB) *gorm.DB {
+		return query.Where(leaseFree, time.Now())
+	}
+	return transitionVideo(s.DB, s.Lease, video.ID, video.Status, model.VideoStatusPending, change, free, columns)
+}
+
+// ClearRunRange 清除视频的步骤范围，之后再次处理时执行完整的准备阶段
+func (s *SavedVideoService) ClearRunRange(id uint) error {
 	return s.DB.Model(&model.SavedVideo{}).
 		Where("id = ?", id).
-		Update("status", status).Error
+		UpdateColumns(map[string]interface{}{
+			"run_from":  "",
+			"run_until": "",
+		}).Error
 }
 
 // UpdateVideo 更新视频信息
@@ -105,11 +303,72 @@ func (s *SavedVideoService) GetVideosByPlaylistID(playlistID string) ([]model.Sa
 	return videos, err
 }
 
-// UpdateVideoStatus 批量更新视频状态
-func (s *SavedVideoService) UpdateVideoStatus(ids []uint, status string) error {
-	return s.DB.Model(&model.SavedVideo{}).
-		Where("id IN ?", ids).
-		Update("status", status).Error
+// SetPriority 设置视频的调度优先级，数值越大越先处理和上传
+func (s *SavedVideoService) SetPriority(id uint, priority int) error {
+	return s.DB.Model(&model.SavedVideo{}).Where("id = ?", id).Update("priority", priority).Error
+}
+
+// BumpPriority 将视频的优先级提到其他所有视频之上，返回调整后的优先级
+func (s *SavedVideoService) BumpPriority(video *model.SavedVideo) (int, error) {
+	var highest int
+	err := s.DB.Model(&model.SavedVideo{}).
+		Where("id != ?", video.ID).
+		Select("COALESCE(MAX(priority), 0)").
+		Scan(&highest).Error
+	if err != nil {
+		return video.Priority, err
+	}
+	if video.Priority > highest {
+		return video.Priority, nil
+	}
+	return highest + 1, s.SetPriority(video.ID, highest+1)
+}
+
+// DemotePriority 将视频的优先级降到其他所有视频之下，返回调整后的优先级
+func (s *SavedVideoService) DemotePriority(video *model.SavedVideo) (int, error) {
+	var lowest int
+	err := s.DB.Model(&model.SavedVideo{}).
+		Where("id != ?", video.ID).
+		Select("COALESCE(MIN(priority), 0)").
+		Scan(&lowest).Error
+	if err != nil {
+		return video.Priority, err
+	}
+	if video.Priority < lowest {
+		return video.Priority, nil
+	}
+	return lowest - 1, s.SetPriority(video.ID, lowest-1)
+}
+
+// UpdateVideoStatus 批量更新视频状态，逐个按状态机校验，遇到不允许的转换时返回错误
+func (s *SavedVideoService) UpdateVideoStatus(ids []uint, status model.VideoStatus, change StatusChange) error {
+	for _, id := range ids {
+		if err := s.UpdateStatus(id, status, change); err != nil {
+			return fmt.Errorf("更新视频 %d 的状态失败: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// RecordSubmitted 记录视频提交（新建或重新提交）时的状态变更
+// 视频由提交接口直接保存，from 为保存前的状态，新建视频时为空
+func (s *SavedVideoService) RecordSubmitted(video *model.SavedVideo, from model.VideoStatus, reason string) error {
+	if from == video.Status {
+		return nil
+	}
+	return recordVideoStatus(s.DB, s.Lease, video, from, video.Status, StatusChange{
+		Actor:  model.VideoStatusActorSubmit,
+		Reason: reason,
+	})
+}
+
+// GetStatusHistory 获取视频的状态变更记录，按时间先后排列
+func (s *SavedVideoService) GetStatusHistory(videoID string) ([]model.VideoStatusHistory, error) {
+	var history []model.VideoStatusHistory
+	err := s.DB.Where("video_id = ?", videoID).
+		Order("created_at ASC, id ASC").
+		Find(&history).Error
+	return history, err
 }
 
 // GetVideosPaginated 获取分页视频列表（用于前端显示）
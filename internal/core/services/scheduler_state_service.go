@@ -0,0 +1,78 @@
+package services
+
+import (
+	"time"
+
+	"github.com/difyz9/ytb2bili/pkg/store/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SchedulerStateService 定时调度器状态服务
+// 提供基于数据库的领导者选举和执行时间记录，供只能在一个实例上运行的调度器使用
+type SchedulerStateService struct {
+	DB *gorm.DB
+}
+
+// NewSchedulerStateService 创建调度器状态服务实例
+func NewSchedulerStateService(db *gorm.DB) *SchedulerStateService {
+	return &SchedulerStateService{
+		DB: db,
+	}
+}
+
+// ensureState 确保调度器状态记录存在
+func (s *SchedulerStateService) ensureState(name string) error {
+	return s.DB.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.SchedulerState{Name: name}).Error
+}
+
+// AcquireLeadership 竞选调度器的领导者，当前实例已是领导者时续约
+// 领导者租约过期（实例崩溃或失联）后其他实例可以接管
+func (s *SchedulerStateService) AcquireLeadership(name string, lease *Lease) (bool, error) {
+	if err := s.ensureState(name); err != nil {
+		return false, err
+	}
+
+	result := s.DB.Model(&model.SchedulerState{}).
+		Where("name = ?", name).
+		Where("(leader_owner = '' OR leader_owner IS NULL OR leader_owner = ? OR leader_expires_at < ?)", lease.Owner, time.Now()).
+		UpdateColumns(map[string]interface{}{
+			"leader_owner":      lease.Owner,
+			"leader_expires_at": lease.ExpiresAt(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ResignLeadership 放弃调度器的领导者身份，其他实例可以立即接管
+func (s *SchedulerStateService) ResignLeadership(name string, lease *Lease) error {
+	return s.DB.Model(&model.SchedulerState{}).
+		Where("name = ? AND leader_owner = ?", name, lease.Owner).
+		UpdateColumns(map[string]interface{}{
+			"leader_owner":      "",
+			"leader_expires_at": nil,
+		}).Error
+}
+
+// GetLastRunAt 获取调度任务上一次执行的时间，从未执行时返回零值
+func (s *SchedulerStateService) GetLastRunAt(name string) (time.Time, error) {
+	var state model.SchedulerState
+	err := s.DB.Where("name = ?", name).Limit(1).Find(&state).Error
+	if err != nil || state.LastRunAt == nil {
+		return time.Time{}, err
+	}
+	return *state.LastRunAt, nil
+}
+
+// SetLastRunAt 记录调度任务的执行时间
+func (s *SchedulerStateService) SetLastRunAt(name string, t time.Time) error {
+	if err := s.ensureState(name); err != nil {
+		return err
+	}
+	return s.DB.Model(&model.SchedulerState{}).
+		Where("name = ?", name).
+		Update("last_run_at", t).Error
+}
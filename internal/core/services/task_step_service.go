@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
+	"github.com/difyz9/ytb2bili/internal/core/types"
 	"github.com/difyz9/ytb2bili/pkg/store/model"
 
 	"gorm.io/gorm"
@@ -13,57 +15,81 @@ import (
 
 // TaskStepService 任务步骤服务
 type TaskStepService struct {
-	DB *gorm.DB
+	DB    *gorm.DB
+	Lease *Lease // 当前实例的租约，执行中的步骤由当前实例持有
 }
 
 // NewTaskStepService 创建任务步骤服务实例
-func NewTaskStepService(db *gorm.DB) *TaskStepService {
+func NewTaskStepService(db *gorm.DB, lease *Lease) *TaskStepService {
 	return &TaskStepService{
-		DB: db,
+		DB:    db,
+		Lease: lease,
 	}
 }
 
-// InitTaskSteps 初始化视频的任务步骤
-func (s *TaskStepService) InitTaskSteps(videoID string) error {
-	// 定义标准任务步骤
-	steps := []struct {
-		Name     string
-		Order    int
-		CanRetry bool
-	}{
-		{"下载视频", 1, true},
-		{"生成字幕", 2, true},
-		{"翻译字幕", 3, true},
-		{"生成元数据", 4, true},
-		{"上传到Bilibili", 5, true},
-		// {"上传字幕到Bilibili", 6, true},
-	}
-
-	// 检查是否已经初始化过
-	var count int64
-	if err := s.DB.Model(&model.TaskStep{}).Where("video_id = ?", videoID).Count(&count).Error; err != nil {
+// TaskStepDefinition 任务步骤定义（来自任务链的依赖图）
+type TaskStepDefinition struct {
+	Name      string
+	Order     int
+	DependsOn []string
+	CanRetry  bool
+}
+
+// InitTaskSteps 根据任务链的依赖图初始化视频的任务步骤
+// 已存在的步骤只更新顺序和依赖关系，保留执行状态；不在依赖图中且尚未执行的步骤会被移除
+func (s *TaskStepService) InitTaskSteps(videoID string, steps []TaskStepDefinition) error {
+	var existing []model.TaskStep
+	if err := s.DB.Where("video_id = ?", videoID).Find(&existing).Error; err != nil {
 		return err
 	}
 
-	if count > 0 {
-		return nil // 已经初始化过，跳过
+	existingByName := make(map[string]model.TaskStep, len(existing))
+	for _, step := range existing {
+		existingByName[step.StepName] = step
 	}
 
-	// 创建任务步骤记录
+	defined := make(map[string]bool, len(steps))
 	for _, step := range steps {
+		defined[step.Name] = true
+		dependsOn := strings.Join(step.DependsOn, ",")
+
+		if current, ok := existingByName[step.Name]; ok {
+			if err := s.DB.Model(&model.TaskStep{}).
+				Where("id = ?", current.ID).
+				Updates(map[string]interface{}{
+					"step_order": step.Order,
+					"depends_on": dependsOn,
+					"can_retry":  step.CanRetry,
+				}).Error; err != nil {
+				return err
+			}
+			continue
+		}
+
+		// 创建任务步骤记录
 		taskStep := &model.TaskStep{
 			VideoID:   videoID,
 			StepName:  step.Name,
 			StepOrder: step.Order,
+			DependsOn: dependsOn,
 			Status:    model.TaskStepStatusPending,
 			CanRetry:  step.CanRetry,
 		}
-
 		if err := s.DB.Create(taskStep).Error; err != nil {
 			return err
 		}
 	}
 
+	// 移除不属于当前任务链且从未执行过的步骤（例如切换 Whisper 后遗留的"生成字幕"）
+	for _, step := range existing {
+		if defined[step.StepName] || step.Status != model.TaskStepStatusPending {
+			continue
+		}
+		if err := s.DB.Delete(&model.TaskStep{}, step.ID).Error; err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -77,36 +103,298 @@ func (s *TaskStepService) GetTaskStepsByVideoID(videoID string) ([]model.TaskSte
 }
 
 // UpdateTaskStepStatus 更新任务步骤状态
+// 执行中的步骤结束时（completed, failed, timeout, cancelled）会追加一条执行记录
 func (s *TaskStepService) UpdateTaskStepStatus(videoID, stepName, status string, errorMsg ...string) error {
+	var taskErr *types.TaskError
+	if len(errorMsg) > 0 && errorMsg[0] != "" {
+		taskErr = &types.TaskError{Message: errorMsg[0]}
+	}
+	return s.updateTaskStepStatus(videoID, stepName, status, taskErr)
+}
+
+// updateTaskStepStatus 更新任务步骤状态，taskErr 带有错误码时同时保存结构化错误
+func (s *TaskStepService) updateTaskStepStatus(videoID, stepName, status string, taskErr *types.TaskError) error {
 	updates := map[string]interface{}{
 		"status": status,
 	}
 
-	// 设置时间
+	// 设置时间，执行中的步骤由当前实例持有租约，其他状态释放租约
 	now := time.Now()
 	if status == model.TaskStepStatusRunning {
 		updates["start_time"] = &now
-	} else if status == model.TaskStepStatusCompleted || status == model.TaskStepStatusFailed {
+		updates["lease_owner"] = s.Lease.Owner
+		updates["lease_expires_at"] = s.Lease.ExpiresAt()
+	} else {
+		updates["lease_owner"] = ""
+		updates["lease_expires_at"] = nil
+	}
+	if status == model.TaskStepStatusCompleted || status == model.TaskStepStatusFailed ||
+		status == model.TaskStepStatusCancelled || status == model.TaskStepStatusTimeout ||
+		status == model.TaskStepStatusSkipped {
 		updates["end_time"] = &now
 
 		// 计算执行时长
 		var step model.TaskStep
 		if err := s.DB.Where("video_id = ? AND step_name = ?", videoID, stepName).First(&step).Error; err == nil {
+			var duration int64
 			if step.StartTime != nil {
-				duration := now.Sub(*step.StartTime).Milliseconds()
+				duration = now.Sub(*step.StartTime).Milliseconds()
 				updates["duration"] = duration
 			}
+
+			// 记录本次执行
+			if step.Status == model.TaskStepStatusRunning {
+				attempt := model.TaskStepAttempt{
+					Attempt:   step.Attempts,
+					Status:    status,
+					StartTime: step.StartTime,
+					EndTime:   now,
+					Duration:  duration,
+				}
+				if taskErr != nil {
+					attempt.ErrorMsg = taskErr.Message
+					attempt.ErrorCode = string(taskErr.Code)
+				}
+				if history, err := json.Marshal(append(step.AttemptRecords(), attempt)); err == nil {
+					updates["attempt_history"] = string(history)
+				}
+			}
 		}
 	}
 
 	// 设置错误信息
-	if len(errorMsg) > 0 && errorMsg[0] != "" {
-		updates["error_msg"] = errorMsg[0]
+	if taskErr != nil {
+		updates["error_msg"] = taskErr.Message
+		if taskErr.Code != "" {
+			updates["error_code"] = string(taskErr.Code)
+			updates["error_category"] = string(taskErr.Category)
+			updates["error_retryable"] = taskErr.Retryable
+			updates["error_detail"] = taskErr.Detail
+		}
 	}
 
-	return s.DB.Model(&model.TaskStep{}).
+	if err := s.DB.Model(&model.TaskStep{}).
+		Where("video_id = ? AND step_name = ?", videoID, stepName).
+		Updates(updates).Error; err != nil {
+		return err
+	}
+
+	// 结束步骤执行中的执行记录（cw_step_runs）
+	if status != model.TaskStepStatusRunning {
+		return s.finishStepRun(videoID, stepName, status, taskErr, now)
+	}
+	return nil
+}
+
+// StartTaskStep 将步骤标记为执行中并增加执行次数，清除上一次执行的错误（保留在执行记录中）
+// runID 为本次执行所属的 PipelineRun，步骤的每次执行都会记录在 cw_step_runs 中
+func (s *TaskStepService) StartTaskStep(videoID, stepName string, runID uint) error {
+	now := time.Now()
+	updates := newAttemptUpdates()
+	updates["status"] = model.TaskStepStatusRunning
+	updates["start_time"] = &now
+	updates["lease_owner"] = s.Lease.Owner
+	updates["lease_expires_at"] = s.Lease.ExpiresAt()
+	err := s.DB.Model(&model.TaskStep{}).
 		Where("video_id = ? AND step_name = ?", videoID, stepName).
 		Updates(updates).Error
+	if err != nil {
+		return err
+	}
+	return s.startStepRun(runID, videoID, stepName, now)
+}
+
+// StartClaimedStep 开始执行通过 ClaimPendingStep 认领的步骤
+// 步骤保持认领时的运行中状态和租约，只增加执行次数并清除上一次执行的错误和结果（保留在执行记录中）
+func (s *TaskStepService) StartClaimedStep(videoID, stepName string, runID uint) error {
+	updates := newAttemptUpdates()
+	updates["duration"] = 0
+	updates["result_data"] = ""
+	err := s.DB.Model(&model.TaskStep{}).
+		Where("video_id = ? AND step_name = ?", videoID, stepName).
+		Updates(updates).Error
+	if err != nil {
+		return err
+	}
+	return s.startStepRun(runID, videoID, stepName, time.Now())
+}
+
+// newAttemptUpdates 开始一次新的执行时需要更新的字段：增加执行次数，清除排队中的重试、上一次执行的错误和跳过原因
+func newAttemptUpdates() map[string]interface{} {
+	return map[string]interface{}{
+		"end_time":        nil,
+		"attempts":        gorm.Expr("attempts + 1"),
+		"next_retry_at":   nil,
+		"error_msg":       "",
+		"error_code":      "",
+		"error_category":  "",
+		"error_retryable": false,
+		"error_detail":    "",
+		"skip_reason":     "",
+		"skipped_by":      "",
+	}
+}
+
+// SkipTaskStep 将步骤标记为已跳过并记录跳过来源和原因，下游步骤把已跳过的步骤视为已完成
+// 清除排队中的重试和上一次执行的错误（保留在执行记录中）
+func (s *TaskStepService) SkipTaskStep(videoID, stepName, skippedBy, reason string) error {
+	if err := s.DB.Model(&model.TaskStep{}).
+		Where("video_id = ? AND step_name = ?", videoID, stepName).
+		Updates(map[string]interface{}{
+			"skip_reason":     reason,
+			"skipped_by":      skippedBy,
+			"result_data":     "",
+			"next_retry_at":   nil,
+			"error_msg":       "",
+			"error_code":      "",
+			"error_category":  "",
+			"error_retryable": false,
+			"error_detail":    "",
+		}).Error; err != nil {
+		return err
+	}
+	return s.updateTaskStepStatus(videoID, stepName, model.TaskStepStatusSkipped, nil)
+}
+
+// FailTaskStep 记录步骤执行失败（failed 或 timeout）及其结构化错误，并按重试策略决定是否自动重试
+// 需要重试时步骤重新设为待执行并返回下一次重试的时间；不再重试时返回 nil
+func (s *TaskStepService) FailTaskStep(videoID, stepName, status string, taskErr *types.TaskError, policy RetryPolicy) (*time.Time, error) {
+	if err := s.updateTaskStepStatus(videoID, stepName, status, taskErr); err != nil {
+		return nil, err
+	}
+
+	step, err := s.GetTaskStepByName(videoID, stepName)
+	if err != nil {
+		return nil, err
+	}
+	if !step.CanRetry || !policy.ShouldRetry(step.Attempts, taskErr) {
+		return nil, nil
+	}
+
+	// 仅当步骤仍处于失败状态时排队，避免覆盖期间发生的取消
+	nextRetryAt := time.Now().Add(policy.Backoff(step.Attempts))
+	result := s.DB.Model(&model.TaskStep{}).
+		Where("id = ? AND status = ?", step.ID, status).
+		Updates(map[string]interface{}{
+			"status":        model.TaskStepStatusPending,
+			"next_retry_at": &nextRetryAt,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &nextRetryAt, nil
+}
+
+// retryableStepStatuses 可以重新加入执行队列的步骤状态，执行中和已在等待执行的步骤不能重复排队
+var retryableStepStatuses = []string{
+	model.TaskStepStatusFailed,
+	model.TaskStepStatusTimeout,
+	model.TaskStepStatusCancelled,
+	model.TaskStepStatusBlocked,
+}
+
+// QueueRetry 将失败、超时、已取消或被阻塞的步骤设为待执行，并在 retryAt 之后由调度器重新执行
+// 返回 false 表示步骤正在执行或已在等待执行（例如重复点击重试），状态保持不变
+func (s *TaskStepService) QueueRetry(videoID, stepName string, retryAt time.Time) (bool, error) {
+	result := s.DB.Model(&model.TaskStep{}).
+		Where("video_id = ? AND step_name = ? AND status IN ?", videoID, stepName, retryableStepStatuses).
+		Updates(map[string]interface{}{
+			"status":        model.TaskStepStatusPending,
+			"next_retry_at": &retryAt,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ResumeBlockedSteps 将前置步骤已全部完成的被阻塞步骤重新加入执行队列
+// 用于失败的步骤重试成功后继续执行下游步骤，返回重新排队的步骤名称
+func (s *TaskStepService) ResumeBlockedSteps(videoID string) ([]string, error) {
+	steps, err := s.GetTaskStepsByVideoID(videoID)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make(map[string]string, len(steps))
+	for _, step := range steps {
+		statuses[step.StepName] = step.Status
+	}
+
+	now := time.Now()
+	var resumed []string
+	for _, step := range steps {
+		if step.Status != model.TaskStepStatusBlocked {
+			continue
+		}
+
+		ready := true
+		for _, dep := range step.Dependencies() {
+			if status, ok := statuses[dep]; ok && status != model.TaskStepStatusCompleted && status != model.TaskStepStatusSkipped {
+				ready = false
+				break
+			}
+		}
+		if !ready {
+			continue
+		}
+
+		queued, err := s.QueueRetry(videoID, step.StepName, now)
+		if err != nil {
+			return resumed, err
+		}
+		if queued {
+			resumed = append(resumed, step.StepName)
+		}
+	}
+
+	return resumed, nil
+}
+
+// ClaimPendingStep 原子地将待执行步骤标记为运行中
+// 返回 false 表示该步骤已不是 pending 状态（已被其他 worker 认领或被修改）
+func (s *TaskStepService) ClaimPendingStep(videoID, stepName string) (bool, error) {
+	now := time.Now()
+	result := s.DB.Model(&model.TaskStep{}).
+		Where("video_id = ? AND step_name = ? AND status = ?", videoID, stepName, model.TaskStepStatusPending).
+		Updates(map[string]interface{}{
+			"status":           model.TaskStepStatusRunning,
+			"start_time":       &now,
+			"lease_owner":      s.Lease.Owner,
+			"lease_expires_at": s.Lease.ExpiresAt(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// CancelTaskSteps 将视频所有待执行和执行中的步骤标记为已取消
+// 避免取消后的待执行步骤被重试调度重新拾起
+func (s *TaskStepService) CancelTaskSteps(videoID, reason string) error {
+	now := time.Now()
+	if err := s.DB.Model(&model.StepRun{}).
+		Where("video_id = ? AND status = ?", videoID, model.RunStatusRunning).
+		Updates(map[string]interface{}{
+			"status":    model.TaskStepStatusCancelled,
+			"error_msg": reason,
+			"ended_at":  &now,
+		}).Error; err != nil {
+		return err
+	}
+	return s.DB.Model(&model.TaskStep{}).
+		Where("video_id = ? AND status IN ?", videoID, []string{model.TaskStepStatusPending, model.TaskStepStatusRunning}).
+		Updates(map[string]interface{}{
+			"status":           model.TaskStepStatusCancelled,
+			"end_time":         &now,
+			"error_msg":        reason,
+			"next_retry_at":    nil,
+			"lease_owner":      "",
+			"lease_expires_at": nil,
+		}).Error
 }
 
 // UpdateTaskStepResult 更新任务步骤执行结果
@@ -118,20 +406,59 @@ func (s *TaskStepService) UpdateTaskStepResult(videoID, stepName string, resultD
 		}
 	}
 
-	return s.DB.Model(&model.TaskStep{}).
+	if err := s.DB.Model(&model.TaskStep{}).
 		Where("video_id = ? AND step_name = ?", videoID, stepName).
-		Update("result_data", jsonData).Error
+		Update("result_data", jsonData).Error; err != nil {
+		return err
+	}
+	return s.setStepRunResult(videoID, stepName, jsonData)
+}
+
+// LoadStepResults 合并视频已完成和已跳过步骤保存的执行结果，用于单步重试和上传时恢复任务链上下文
+// 按步骤顺序合并，后执行的步骤覆盖先执行的；excludeStep 自身的旧结果不会被合并
+func (s *TaskStepService) LoadStepResults(videoID, excludeStep string) (map[string]interface{}, error) {
+	var steps []model.TaskStep
+	if err := s.DB.Where("video_id = ? AND status IN ? AND step_name <> ?", videoID, []string{model.TaskStepStatusCompleted, model.TaskStepStatusSkipped}, excludeStep).
+		Order("step_order ASC").
+		Find(&steps).Error; err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]interface{})
+	for _, step := range steps {
+		if step.ResultData == "" {
+			continue
+		}
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(step.ResultData), &data); err != nil {
+			log.Printf("解析步骤 %s 的执行结果失败: %v", step.StepName, err)
+			continue
+		}
+		for k, v := range data {
+			results[k] = v
+		}
+	}
+	return results, nil
 }
 
-// ResetTaskStep 重置任务步骤（用于重新执行）
+// ResetTaskStep 重置任务步骤（用于重新执行），同时取消排队中的重试
+// 保留 attempts：执行次数用于给执行记录（attempt_history、cw_step_runs）编号，清零后新的执行会与已有记录重复编号；
+// 因此与手动重试一样，重置后的步骤不会重新获得自动重试次数
 func (s *TaskStepService) ResetTaskStep(videoID, stepName string) error {
 	updates := map[string]interface{}{
-		"status":      model.TaskStepStatusPending,
-		"start_time":  nil,
-		"end_time":    nil,
-		"duration":    0,
-		"error_msg":   "",
-		"result_data": "",
+		"status":          model.TaskStepStatusPending,
+		"start_time":      nil,
+		"end_time":        nil,
+		"duration":        0,
+		"error_msg":       "",
+		"error_code":      "",
+		"error_category":  "",
+		"error_retryable": false,
+		"error_detail":    "",
+		"result_data":     "",
+		"next_retry_at":   nil,
+		"skip_reason":     "",
+		"skipped_by":      "",
 	}
 
 	return s.DB.Model(&model.TaskStep{}).
@@ -139,6 +466,22 @@ func (s *TaskStepService) ResetTaskStep(videoID, stepName string) error {
 		Updates(updates).Error
 }
 
+// ClearOperatorSkips 将视频手动跳过的步骤重置为待执行，用于重新提交或重新执行完整的准备阶段
+// 只执行部分步骤时不调用，手动跳过的步骤保持跳过。返回被重置的步骤数量
+func (s *TaskStepService) ClearOperatorSkips(videoID string) (int64, error) {
+	result := s.DB.Model(&model.TaskStep{}).
+		Where("video_id = ? AND status = ? AND skipped_by = ?", videoID, model.TaskStepStatusSkipped, model.TaskStepSkippedByOperator).
+		Updates(map[string]interface{}{
+			"status":      model.TaskStepStatusPending,
+			"start_time":  nil,
+			"end_time":    nil,
+			"duration":    0,
+			"skip_reason": "",
+			"skipped_by":  "",
+		})
+	return result.RowsAffected, result.Error
+}
+
 // GetTaskStepByName 根据视频ID和步骤名称获取特定步骤
 func (s *TaskStepService) GetTaskStepByName(videoID, stepName string) (*model.TaskStep, error) {
 	var step model.TaskStep
@@ -158,6 +501,7 @@ func (s *TaskStepService) GetTaskProgress(videoID string) (map[string]interface{
 
 	totalSteps := len(steps)
 	completedSteps := 0
+	skippedSteps := 0
 	failedSteps := 0
 	currentStep := ""
 
@@ -165,6 +509,8 @@ func (s *TaskStepService) GetTaskProgress(videoID string) (map[string]interface{
 		switch step.Status {
 		case model.TaskStepStatusCompleted:
 			completedSteps++
+		case model.TaskStepStatusSkipped:
+			skippedSteps++
 		case model.TaskStepStatusFailed:
 			failedSteps++
 		case model.TaskStepStatusRunning:
@@ -175,58 +521,129 @@ func (s *TaskStepService) GetTaskProgress(videoID string) (map[string]interface{
 	progress := map[string]interface{}{
 		"total_steps":      totalSteps,
 		"completed_steps":  completedSteps,
+		"skipped_steps":    skippedSteps,
 		"failed_steps":     failedSteps,
 		"current_step":     currentStep,
 		"progress_percent": 0,
 	}
 
 	if totalSteps > 0 {
-		progress["progress_percent"] = (completedSteps * 100) / totalSteps
+		progress["progress_percent"] = ((completedSteps + skippedSteps) * 100) / totalSteps
 	}
 
 	return progress, nil
 }
 
-// ResetAllRunningTasks 重置所有运行中的任务
-func (s *TaskStepService) ResetAllRunningTasks() error {
-	// 开始事务
-	tx := s.DB.Begin()
+// RenewStepLeases 为当前实例正在执行的所有步骤续约
+func (s *TaskStepService) RenewStepLeases() (int64, error) {
+	result := s.DB.Model(&model.TaskStep{}).
+		Where("lease_owner = ? AND status = ?", s.Lease.Owner, model.TaskStepStatusRunning).
+		UpdateColumn("lease_expires_at", s.Lease.ExpiresAt())
+	return result.RowsAffected, result.Error
+}
+
+// reclaimedVideoStatus 回收执行中的视频时的目标状态
+// 准备阶段重新排队；上传阶段可能已经提交了稿件，标记为上传失败等待人工确认
+var reclaimedVideoStatus = map[model.VideoStatus]model.VideoStatus{
+	model.VideoStatusProcessing:        model.VideoStatusPending,
+	model.VideoStatusUploading:         model.VideoStatusUploadFailed,
+	model.VideoStatusSubtitleUploading: model.VideoStatusSubtitleFailed,
+}
 
-	// 重置所有状态为 Running 的任务步骤为 Pending
-	result := tx.Model(&model.TaskStep{}).
-		Where("status = ?", "Running").
-		Update("status", "Pending")
+// ReclaimOwnLeases 回收当前实例上次运行时遗留的租约，用于启动时恢复崩溃前正在执行的任务
+func (s *TaskStepService) ReclaimOwnLeases() (int, int, error) {
+	return s.reclaimLeases("lease_owner = ?", s.Lease.Owner)
+}
 
-	if result.Error != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to reset running task steps: %v", result.Error)
+// ReclaimExpiredLeases 回收租约已过期的视频和步骤（持有的实例已崩溃或失联）
+// 没有租约却处于执行中超过租约有效期的记录（升级前遗留）同样会被回收
+func (s *TaskStepService) ReclaimExpiredLeases() (int, int, error) {
+	now := time.Now()
+	return s.reclaimLeases(
+		"((lease_owner <> '' AND lease_expires_at < ?) OR ((lease_owner = '' OR lease_owner IS NULL) AND updated_at < ?))",
+		now, now.Add(-s.Lease.TTL),
+	)
+}
+
+// reclaimLeases 回收满足条件的执行中步骤和视频，返回回收的步骤数和视频数
+// 步骤按所属视频的状态处理：准备阶段的视频整体重新排队，步骤恢复为待执行；
+// 上传中的步骤标记为失败（稿件可能已提交，不自动重试）；单步重试中的步骤重新加入重试队列
+func (s *TaskStepService) reclaimLeases(condition string, args ...interface{}) (int, int, error) {
+	var steps []model.TaskStep
+	if err := s.DB.Where("status = ?", model.TaskStepStatusRunning).Where(condition, args...).Find(&steps).Error; err != nil {
+		return 0, 0, err
 	}
 
-	taskStepsAffected := result.RowsAffected
+	videoStatuses := make(map[string]model.VideoStatus)
+	for _, step := range steps {
+		videoStatus, ok := videoStatuses[step.VideoID]
+		if !ok {
+			var video model.SavedVideo
+			if err := s.DB.Select("status").Where("video_id = ?", step.VideoID).First(&video).Error; err == nil {
+				videoStatus = video.Status
+			}
+			videoStatuses[step.VideoID] = videoStatus
+		}
 
-	// 重置相关视频的状态
-	// 将状态为 "002"(处理中) 的视频重置为 "001"(待处理)
-	videoResult := tx.Model(&model.SavedVideo{}).
-		Where("status = ?", "002").
-		Update("status", "001")
+		var err error
+		switch videoStatus {
+		case model.VideoStatusPending, model.VideoStatusProcessing:
+			err = s.updateTaskStepStatus(step.VideoID, step.StepName, model.TaskStepStatusPending, nil)
+		case model.VideoStatusUploading, model.VideoStatusSubtitleUploading:
+			taskErr := types.NewTaskError(types.ErrCodeInterrupted, "lease_owner="+step.LeaseOwner).WithMessage("执行该步骤的实例已失联，请确认稿件状态后手动重试")
+			err = s.updateTaskStepStatus(step.VideoID, step.StepName, model.TaskStepStatusFailed, taskErr)
+		default:
+			// 立即重新加入重试队列
+			if err = s.updateTaskStepStatus(step.VideoID, step.StepName, model.TaskStepStatusPending, nil); err == nil {
+				err = s.DB.Model(&model.TaskStep{}).Where("id = ?", step.ID).Update("next_retry_at", time.Now()).Error
+			}
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("回收任务步骤 %s/%s 失败: %v", step.VideoID, step.StepName, err)
+		}
+	}
 
-	if videoResult.Error != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to reset running video status: %v", videoResult.Error)
+	var videos []model.SavedVideo
+	if err := s.DB.Where(condition, args...).
+		Where("(lease_owner <> '' AND lease_owner IS NOT NULL) OR status IN ?", []model.VideoStatus{model.VideoStatusProcessing, model.VideoStatusUploading, model.VideoStatusSubtitleUploading}).
+		Find(&videos).Error; err != nil {
+		return len(steps), 0, err
 	}
 
-	videosAffected := videoResult.RowsAffected
+	for _, video := range videos {
+		released := map[string]interface{}{
+			"lease_owner":      "",
+			"lease_expires_at": nil,
+		}
+		if err := s.interruptRuns(video.VideoID, video.LeaseOwner); err != nil {
+			return len(steps), 0, fmt.Errorf("回收视频 %s 的执行记录失败: %v", video.VideoID, err)
+		}
 
-	// 提交事务
-	if err := tx.Commit().Error; err != nil {
-		return fmt.Errorf("failed to commit transaction: %v", err)
+		var err error
+		if status, ok := reclaimedVideoStatus[video.Status]; ok {
+			change := StatusChange{Actor: model.VideoStatusActorReclaim, Reason: "执行中的实例已失联，回收任务"}
+			if video.LeaseOwner != "" {
+				change.Reason = fmt.Sprintf("实例 %s 的租约已失效，回收任务", video.LeaseOwner)
+			}
+			_, err = transitionVideo(s.DB, s.Lease, video.ID, video.Status, status, change, nil, released)
+		} else {
+			err = s.DB.Model(&model.SavedVideo{}).
+				Where("id = ? AND status = ?", video.ID, video.Status).
+				Updates(released).Error
+		}
+		if err != nil {
+			return len(steps), 0, fmt.Errorf("回收视频 %s 失败: %v", video.VideoID, err)
+		}
 	}
 
-	log.Printf("Reset %d running task steps and %d videos (from processing to pending status)", taskStepsAffected, videosAffected)
-	return nil
+	if len(steps) > 0 || len(videos) > 0 {
+		log.Printf("Reclaimed %d running task steps and %d videos from stale leases", len(steps), len(videos))
+	}
+	return len(steps), len(videos), nil
 }
 
-// GetPendingSteps 获取所有状态为pending的任务步骤
+// GetPendingSteps 获取所有已到重试时间的待执行步骤
+// 任务链初始化时创建的待执行步骤没有重试时间，由任务链或上传调度器执行，不会被返回
 func (s *TaskStepService) GetPendingSteps() ([]*model.TaskStep, error) {
 	var steps []*model.TaskStep
 
@@ -235,9 +652,10 @@ func (s *TaskStepService) GetPendingSteps() ([]*model.TaskStep, error) {
 		Select("cw_task_steps.*").
 		Joins("INNER JOIN cw_saved_videos ON cw_task_steps.video_id = cw_saved_videos.video_id").
 		Where("cw_task_steps.status = ?", model.TaskStepStatusPending).
+		Where("cw_task_steps.next_retry_at IS NOT NULL AND cw_task_steps.next_retry_at <= ?", time.Now()).
 		Where("cw_task_steps.deleted_at IS NULL").
 		Where("cw_saved_videos.deleted_at IS NULL").
-		Order("cw_task_steps.created_at ASC").
+		Order("cw_task_steps.next_retry_at ASC, cw_task_steps.step_order ASC").
 		Find(&steps)
 
 	if result.Error != nil {
@@ -247,6 +665,20 @@ func (s *TaskStepService) GetPendingSteps() ([]*model.TaskStep, error) {
 	return steps, nil
 }
 
+// GetNextRetryAt 获取尚未到重试时间的待执行步骤中最早的重试时间，没有时返回 nil
+func (s *TaskStepService) GetNextRetryAt() (*time.Time, error) {
+	var step model.TaskStep
+	err := s.DB.Select("next_retry_at").
+		Where("status = ? AND next_retry_at > ?", model.TaskStepStatusPending, time.Now()).
+		Order("next_retry_at ASC").
+		Limit(1).
+		Find(&step).Error
+	if err != nil {
+		return nil, err
+	}
+	return step.NextRetryAt, nil
+}
+
 // DeleteTaskStepsByVideoID 删除指定视频的所有任务步骤（软删除）
 func (s *TaskStepService) DeleteTaskStepsByVideoID(videoID string) error {
 	result := s.DB.Where("video_id = ?", videoID).Delete(&model.TaskStep{})
@@ -0,0 +1,359 @@
+package services
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/difyz9/ytb2bili/internal/core/types"
+	"github.com/difyz9/ytb2bili/pkg/store/model"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// newTestDB 创建迁移了 models 的内存 SQLite 数据库
+func newTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{
+		NamingStrategy: schema.NamingStrategy{TablePrefix: "cw_"},
+		Logger:         logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("获取数据库连接失败: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1) // 内存数据库每个连接是独立的数据库
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("迁移数据库失败: %v", err)
+	}
+	return db
+}
+
+// mustGetStep 读取步骤，不存在时终止测试
+func mustGetStep(t *testing.T, service *TaskStepService, videoID, stepName string) *model.TaskStep {
+	t.Helper()
+	step, err := service.GetTaskStepByName(videoID, stepName)
+	if err != nil {
+		t.Fatalf("读取步骤 %s 失败: %v", stepName, err)
+	}
+	return step
+}
+
+func TestFailTaskStep(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, InitialDelay: time.Minute, MaxDelay: time.Hour, Multiplier: 2}
+
+	tests := []struct {
+		name      string
+		attempts  int
+		canRetry  bool
+		code      types.ErrorCode
+		wantRetry time.Duration // 0 表示不应排队重试
+	}{
+		{name: "临时错误按退避时间排队", attempts: 2, canRetry: true, code: types.ErrCodeNetwork, wantRetry: 2 * time.Minute},
+		{name: "次数用尽后保持失败", attempts: 3, canRetry: true, code: types.ErrCodeNetwork},
+		{name: "永久错误不重试", attempts: 1, canRetry: true, code: types.ErrCodeVideoPrivate},
+		{name: "不可重试的步骤不重试", attempts: 1, canRetry: false, code: types.ErrCodeNetwork},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewTaskStepService(newTestDB(t, &model.TaskStep{}, &model.StepRun{}), nil)
+			step := model.TaskStep{VideoID: "v1", StepName: "下载视频", Status: model.TaskStepStatusRunning, Attempts: tt.attempts}
+			if err := service.DB.Create(&step).Error; err != nil {
+				t.Fatalf("创建步骤失败: %v", err)
+			}
+			// gorm 创建时会忽略 false 零值而使用默认值 true
+			if err := service.DB.Model(&step).Update("can_retry", tt.canRetry).Error; err != nil {
+				t.Fatalf("更新步骤失败: %v", err)
+			}
+
+			before := time.Now()
+			retryAt, err := service.FailTaskStep("v1", "下载视频", model.TaskStepStatusFailed, types.NewTaskError(tt.code, "yt-dlp exited"), policy)
+			if err != nil {
+				t.Fatalf("FailTaskStep() error = %v", err)
+			}
+
+			got := mustGetStep(t, service, "v1", "下载视频")
+			if tt.wantRetry == 0 {
+				if retryAt != nil || got.Status != model.TaskStepStatusFailed || got.NextRetryAt != nil {
+					t.Fatalf("不应重试: retryAt = %v, status = %s, next_retry_at = %v", retryAt, got.Status, got.NextRetryAt)
+				}
+				return
+			}
+
+			if retryAt == nil || got.Status != model.TaskStepStatusPending || got.NextRetryAt == nil {
+				t.Fatalf("应排队重试: retryAt = %v, status = %s, next_retry_at = %v", retryAt, got.Status, got.NextRetryAt)
+			}
+			if delay := retryAt.Sub(before); delay < tt.wantRetry || delay > tt.wantRetry+time.Second {
+				t.Errorf("重试等待 %v, want %v", delay, tt.wantRetry)
+			}
+			if got.ErrorCode != string(tt.code) || !got.ErrorRetryable {
+				t.Errorf("error_code = %q, error_retryable = %v, want %q, true", got.ErrorCode, got.ErrorRetryable, tt.code)
+			}
+		})
+	}
+}
+
+func TestResumeBlockedSteps(t *testing.T) {
+	service := NewTaskStepService(newTestDB(t, &model.TaskStep{}, &model.StepRun{}), nil)
+	steps := []model.TaskStep{
+		{VideoID: "v1", StepName: "下载视频", Status: model.TaskStepStatusCompleted},
+		{VideoID: "v1", StepName: "提取音频", Status: model.TaskStepStatusCompleted, DependsOn: "下载视频"},
+		{VideoID: "v1", StepName: "生成字幕", Status: model.TaskStepStatusBlocked, DependsOn: "提取音频"},
+		{VideoID: "v1", StepName: "翻译字幕", Status: model.TaskStepStatusBlocked, DependsOn: "生成字幕"},
+	}
+	if err := service.DB.Create(&steps).Error; err != nil {
+		t.Fatalf("创建步骤失败: %v", err)
+	}
+
+	resumed, err := service.ResumeBlockedSteps("v1")
+	if err != nil {
+		t.Fatalf("ResumeBlockedSteps() error = %v", err)
+	}
+
+	// 只有前置步骤全部完成的步骤才重新排队，其下游仍被阻塞
+	if len(resumed) != 1 || resumed[0] != "生成字幕" {
+		t.Fatalf("resumed = %v, want [生成字幕]", resumed)
+	}
+	if got := mustGetStep(t, service, "v1", "生成字幕"); got.Status != model.TaskStepStatusPending || got.NextRetryAt == nil {
+		t.Errorf("生成字幕: status = %s, next_retry_at = %v", got.Status, got.NextRetryAt)
+	}
+	if got := mustGetStep(t, service, "v1", "翻译字幕"); got.Status != model.TaskStepStatusBlocked {
+		t.Errorf("翻译字幕: status = %s, want blocked", got.Status)
+	}
+}
+
+func TestClaimPendingStep(t *testing.T) {
+	tests := []struct {
+		status string
+		want   bool
+	}{
+		{model.TaskStepStatusPending, true},
+		{model.TaskStepStatusRunning, false},
+		{model.TaskStepStatusCompleted, false},
+		{model.TaskStepStatusFailed, false},
+		{model.TaskStepStatusCancelled, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			db := newTestDB(t, &model.TaskStep{})
+			service := NewTaskStepService(db, &Lease{Owner: "a", TTL: time.Minute})
+			if err := db.Create(&model.TaskStep{VideoID: "v", StepName: "下载视频", StepOrder: 1, Status: tt.status}).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			claimed, err := service.ClaimPendingStep("v", "下载视频")
+			if err != nil {
+				t.Fatalf("ClaimPendingStep() error = %v", err)
+			}
+			if claimed != tt.want {
+				t.Fatalf("ClaimPendingStep() = %v, want %v", claimed, tt.want)
+			}
+		})
+	}
+}
+
+func TestClaimPendingStepConcurrent(t *testing.T) {
+	db := newTestDB(t, &model.TaskStep{})
+	if err := db.Create(&model.TaskStep{VideoID: "v", StepName: "下载视频", StepOrder: 1, Status: model.TaskStepStatusPending}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 多个实例同时认领同一个步骤，只有一个能认领成功
+	var claims atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(owner string) {
+			defer wg.Done()
+			service := NewTaskStepService(db, &Lease{Owner: owner, TTL: time.Minute})
+			claimed, err := service.ClaimPendingStep("v", "下载视频")
+			if err != nil {
+				t.Errorf("ClaimPendingStep() error = %v", err)
+				return
+			}
+			if claimed {
+				claims.Add(1)
+			}
+		}(string(rune('a' + i)))
+	}
+	wg.Wait()
+
+	if got := claims.Load(); got != 1 {
+		t.Fatalf("claimed %d times, want 1", got)
+	}
+}
+
+func TestStartClaimedStep(t *testing.T) {
+	db := newTestDB(t, &model.TaskStep{}, &model.StepRun{})
+	service := NewTaskStepService(db, &Lease{Owner: "a", TTL: time.Minute})
+	retryAt := time.Now()
+	if err := db.Create(&model.TaskStep{
+		VideoID:     "v",
+		StepName:    "下载视频",
+		StepOrder:   1,
+		Status:      model.TaskStepStatusPending,
+		Attempts:    1,
+		ErrorMsg:    "网络错误",
+		ErrorCode:   "network",
+		ResultData:  `{"old":true}`,
+		NextRetryAt: &retryAt,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	claimed, err := service.ClaimPendingStep("v", "下载视频")
+	if err != nil || !claimed {
+		t.Fatalf("ClaimPendingStep() = %v, %v", claimed, err)
+	}
+	if err := service.StartClaimedStep("v", "下载视频", 1); err != nil {
+		t.Fatalf("StartClaimedStep() error = %v", err)
+	}
+
+	step, err := service.GetTaskStepByName("v", "下载视频")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 认领的步骤保持运行中和租约，不会回到待执行状态
+	if step.Status != model.TaskStepStatusRunning || step.LeaseOwner != "a" || step.LeaseExpiresAt == nil {
+		t.Fatalf("step status = %s, lease owner = %q, lease expires at = %v", step.Status, step.LeaseOwner, step.LeaseExpiresAt)
+	}
+	if step.Attempts != 2 {
+		t.Errorf("step attempts = %d, want 2", step.Attempts)
+	}
+	if step.ErrorMsg != "" || step.ErrorCode != "" || step.ResultData != "" || step.NextRetryAt != nil {
+		t.Errorf("previous attempt not cleared: error = %q/%q, result = %q, next retry = %v", step.ErrorMsg, step.ErrorCode, step.ResultData, step.NextRetryAt)
+	}
+
+	var runs []model.StepRun
+	if err := db.Where("video_id = ?", "v").Find(&runs).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || runs[0].Attempt != 2 || runs[0].Status != model.RunStatusRunning {
+		t.Fatalf("step runs = %+v, want one running attempt 2", runs)
+	}
+}
+
+func TestQueueRetry(t *testing.T) {
+	tests := []struct {
+		status string
+		want   bool
+	}{
+		{model.TaskStepStatusFailed, true},
+		{model.TaskStepStatusTimeout, true},
+		{model.TaskStepStatusCancelled, true},
+		{model.TaskStepStatusBlocked, true},
+		{model.TaskStepStatusRunning, false},
+		{model.TaskStepStatusPending, false},
+		{model.TaskStepStatusCompleted, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			db := newTestDB(t, &model.TaskStep{})
+			service := NewTaskStepService(db, &Lease{Owner: "a", TTL: time.Minute})
+			if err := db.Create(&model.TaskStep{VideoID: "v", StepName: "下载视频", StepOrder: 1, Status: tt.status}).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			queued, err := service.QueueRetry("v", "下载视频", time.Now())
+			if err != nil {
+				t.Fatalf("QueueRetry() error = %v", err)
+			}
+			if queued != tt.want {
+				t.Fatalf("QueueRetry() = %v, want %v", queued, tt.want)
+			}
+			want := tt.status
+			if tt.want {
+				want = model.TaskStepStatusPending
+			}
+			if got := mustGetStep(t, service, "v", "下载视频"); got.Status != want {
+				t.Errorf("status = %s, want %s", got.Status, want)
+			}
+		})
+	}
+}
+
+func TestQueueRetryWhileStepRunning(t *testing.T) {
+	db := newTestDB(t, &model.TaskStep{}, &model.StepRun{})
+	service := NewTaskStepService(db, &Lease{Owner: "a", TTL: time.Minute})
+	if err := db.Create(&model.TaskStep{VideoID: "v", StepName: "下载视频", StepOrder: 1, Status: model.TaskStepStatusFailed, Attempts: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 第一次重试排队后被 worker 认领并开始执行
+	if queued, err := service.QueueRetry("v", "下载视频", time.Now()); err != nil || !queued {
+		t.Fatalf("QueueRetry() = %v, %v, want true", queued, err)
+	}
+	if claimed, err := service.ClaimPendingStep("v", "下载视频"); err != nil || !claimed {
+		t.Fatalf("ClaimPendingStep() = %v, %v, want true", claimed, err)
+	}
+	if err := service.StartClaimedStep("v", "下载视频", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重复点击重试不能把执行中的步骤改回待执行，否则会被另一个 worker 再次认领并行执行
+	if queued, err := service.QueueRetry("v", "下载视频", time.Now()); err != nil || queued {
+		t.Fatalf("执行中 QueueRetry() = %v, %v, want false", queued, err)
+	}
+	if claimed, err := service.ClaimPendingStep("v", "下载视频"); err != nil || claimed {
+		t.Fatalf("执行中 ClaimPendingStep() = %v, %v, want false", claimed, err)
+	}
+
+	step := mustGetStep(t, service, "v", "下载视频")
+	if step.Status != model.TaskStepStatusRunning || step.LeaseOwner != "a" || step.Attempts != 2 || step.NextRetryAt != nil {
+		t.Fatalf("status = %s, lease owner = %q, attempts = %d, next retry = %v", step.Status, step.LeaseOwner, step.Attempts, step.NextRetryAt)
+	}
+}
+
+func TestResetTaskStep(t *testing.T) {
+	db := newTestDB(t, &model.TaskStep{})
+	service := NewTaskStepService(db, &Lease{Owner: "a", TTL: time.Minute})
+	start, end, retryAt := time.Now().Add(-time.Minute), time.Now(), time.Now().Add(time.Hour)
+	if err := db.Create(&model.TaskStep{
+		VideoID:        "v",
+		StepName:       "翻译字幕",
+		StepOrder:      1,
+		Status:         model.TaskStepStatusFailed,
+		StartTime:      &start,
+		EndTime:        &end,
+		Duration:       60000,
+		Attempts:       3,
+		ErrorMsg:       "请求过于频繁",
+		ErrorCode:      string(types.ErrCodeRateLimited),
+		ErrorCategory:  string(types.ErrorCategoryRateLimit),
+		ErrorRetryable: true,
+		ErrorDetail:    "429 Too Many Requests",
+		ResultData:     `{"old":true}`,
+		NextRetryAt:    &retryAt,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := service.ResetTaskStep("v", "翻译字幕"); err != nil {
+		t.Fatalf("ResetTaskStep() error = %v", err)
+	}
+
+	step := mustGetStep(t, service, "v", "翻译字幕")
+	if step.Status != model.TaskStepStatusPending || step.StartTime != nil || step.EndTime != nil || step.Duration != 0 ||
+		step.ResultData != "" || step.NextRetryAt != nil {
+		t.Errorf("step not reset: status = %s, start = %v, end = %v, duration = %d, result = %q, next retry = %v",
+			step.Status, step.StartTime, step.EndTime, step.Duration, step.ResultData, step.NextRetryAt)
+	}
+	// 重置后的步骤不能再显示上一次执行的错误
+	if step.ErrorMsg != "" || step.ErrorCode != "" || step.ErrorCategory != "" || step.ErrorRetryable || step.ErrorDetail != "" {
+		t.Errorf("error not cleared: %q/%q/%q/%v/%q", step.ErrorMsg, step.ErrorCode, step.ErrorCategory, step.ErrorRetryable, step.ErrorDetail)
+	}
+	// 执行次数保留，下一次执行编号为 4
+	if step.Attempts != 3 {
+		t.Errorf("attempts = %d, want 3", step.Attempts)
+	}
+}
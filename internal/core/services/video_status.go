@@ -0,0 +1,70 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/difyz9/ytb2bili/pkg/store/model"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidStatusTransition 状态机不允许的视频状态转换
+var ErrInvalidStatusTransition = errors.New("不允许的视频状态转换")
+
+// StatusChange 视频状态变更的触发者和原因，记录在 cw_video_status_history 中
+type StatusChange struct {
+	Actor  string // 触发者，见 model.VideoStatusActor* 常量
+	Reason string // 变更原因
+}
+
+// transitionVideo 按状态机将视频从 from 转换为 to 并记录状态变更
+// 只有当前状态仍为 from 时才会更新，返回 false 表示状态已被修改；scope 用于追加更新条件（如租约），columns 为同时更新的字段
+func transitionVideo(db *gorm.DB, lease *Lease, id uint, from, to model.VideoStatus, change StatusChange, scope func(*gorm.DB) *gorm.DB, columns map[string]interface{}) (bool, error) {
+	if !from.CanTransitionTo(to) {
+		return false, fmt.Errorf("%w: %s(%s) -> %s(%s)", ErrInvalidStatusTransition, from, from.Label(), to, to.Label())
+	}
+
+	updates := map[string]interface{}{"status": to}
+	for column, value := range columns {
+		updates[column] = value
+	}
+
+	updated := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&model.SavedVideo{}).Where("id = ? AND status = ?", id, from)
+		if scope != nil {
+			query = scope(query)
+		}
+		result := query.Updates(updates)
+		if result.Error != nil || result.RowsAffected != 1 {
+			return result.Error
+		}
+		updated = true
+
+		var video model.SavedVideo
+		if err := tx.Select("id", "video_id").Where("id = ?", id).First(&video).Error; err != nil {
+			return err
+		}
+		return recordVideoStatus(tx, lease, &video, from, to, change)
+	})
+	if err != nil {
+		return false, err
+	}
+	return updated, nil
+}
+
+// recordVideoStatus 写入一条视频状态变更记录
+func recordVideoStatus(db *gorm.DB, lease *Lease, video *model.SavedVideo, from, to model.VideoStatus, change StatusChange) error {
+	history := &model.VideoStatusHistory{
+		SavedVideoID: video.ID,
+		VideoID:      video.VideoID,
+		FromStatus:   from,
+		ToStatus:     to,
+		Actor:        change.Actor,
+		Reason:       change.Reason,
+	}
+	if lease != nil {
+		history.Instance = lease.Owner
+	}
+	return db.Create(history).Error
+}
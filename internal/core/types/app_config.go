@@ -29,6 +29,7 @@ type AppConfig struct {
 	AnalyticsConfig     *AnalyticsConfig     `toml:"AnalyticsConfig"`     // 数据分析配置
 	BilibiliConfig      *BilibiliConfig      `toml:"BilibiliConfig"`      // Bilibili上传配置
 	WhisperConfig       *WhisperConfig       `toml:"WhisperConfig"`       // Whisper 语音识别配置
+	PipelineConfig      *PipelineConfig      `toml:"PipelineConfig"`      // 任务流水线配置
 }
 
 // BilibiliConfig Bilibili上传配置
@@ -180,6 +181,54 @@ type WhisperConfig struct {
 	Threads   int    `toml:"threads"`    // 使用的线程数
 }
 
+// PipelineConfig 任务流水线配置
+type PipelineConfig struct {
+	Workers        int                           `toml:"workers"`                 // 准备阶段并发处理的视频数量（下载、转录、翻译、元数据）
+	ResourceLimits map[string]int                `toml:"resource_limits"`         // 按资源类别限制并发数: cpu, network, llm, upload（<=0 表示不限制）
+	StepTimeout    int                           `toml:"step_timeout"`            // 步骤默认超时时间（秒），0 使用内置默认值，<0 表示不限制
+	StepTimeouts   map[string]int                `toml:"step_timeouts"`           // 按步骤名称覆盖超时时间（秒），<0 表示不限制
+	TimeoutPerMin  map[string]int                `toml:"step_timeout_per_minute"` // 按视频时长延长超时：视频每分钟增加的秒数
+	Retry          *RetryPolicyConfig            `toml:"retry"`                   // 步骤失败后的自动重试策略
+	StepRetry      map[string]*RetryPolicyConfig `toml:"step_retry"`              // 按步骤名称覆盖重试策略，未设置的字段使用全局策略
+	Profile        string                        `toml:"profile"`                 // 使用的流水线名称，为空时使用 default
+	Pipelines      map[string]*PipelineProfile   `toml:"pipelines"`               // 自定义流水线，与内置流水线同名时覆盖内置定义
+	InstanceID     string                        `toml:"instance_id"`             // 实例标识，多个实例共享数据库时用于认领任务，为空时使用"主机名+监听地址"
+	LeaseTTL       int                           `toml:"lease_ttl"`               // 任务租约有效期（秒），实例失联超过该时间后其任务由其他实例回收
+	SweepInterval  int                           `toml:"sweep_interval"`          // 兜底扫描待处理任务的间隔（秒），用于发现其他实例提交的视频，默认 60
+	ShutdownGrace  int                           `toml:"shutdown_grace_period"`   // 关闭时等待执行中的步骤结束的时间（秒），超时后中断并重新排队，默认 60
+	Reconcile      int                           `toml:"reconcile_interval"`      // 一致性检查的间隔（秒），修复状态与步骤、文件不一致的视频，默认 600，<0 表示只在启动时检查
+	PlaylistSync   int                           `toml:"playlist_sync_interval"`  // 自动同步播放列表的间隔（秒），导入播放列表中新增的视频，默认 3600，<0 表示不自动同步
+	ChannelCheck   int                           `toml:"channel_check_interval"`  // 检查订阅频道新视频的间隔（秒），默认 1800，<0 表示不自动检查
+}
+
+// PipelineProfile 流水线定义：按依赖关系组成的任务步骤列表
+type PipelineProfile struct {
+	Description string               `toml:"description"` // 流水线说明
+	Steps       []PipelineStepConfig `toml:"steps"`       // 任务步骤，按展示顺序排列
+}
+
+// PipelineStepConfig 流水线中的一个任务步骤
+// 同一个步骤名称可以配置多次，配合互斥的 when 条件实现"启用 Whisper 时转录，否则使用提交时保存的字幕"等分支
+type PipelineStepConfig struct {
+	Name      string                 `toml:"name"`       // 步骤名称，记录在 cw_task_steps.step_name 中
+	Task      string                 `toml:"task"`       // 任务类型，对应步骤注册表中的名称（download_video、whisper 等）
+	DependsOn []string               `toml:"depends_on"` // 前置步骤名称，被条件排除的前置步骤会被忽略
+	When      string                 `toml:"when"`       // 启用条件（whisper、gemini_video，前缀 ! 表示取反），为空表示始终启用
+	Stage     string                 `toml:"stage"`      // 执行阶段: prepare（默认，准备阶段任务链）或 upload（由上传调度器执行）
+	SkipIf    []string               `toml:"skip_if"`    // 跳过条件（source_chinese、native_subtitles 等，前缀 ! 表示取反），执行前任一条件满足时步骤记录为已跳过
+	Options   map[string]interface{} `toml:"options"`    // 传给任务的参数，例如 whisper 的 language、threads
+}
+
+// RetryPolicyConfig 步骤自动重试策略
+// 第 n 次重试前等待 initial_delay × multiplier^(n-1) 秒（不超过 max_delay），并加入 ±jitter 比例的随机抖动
+type RetryPolicyConfig struct {
+	MaxAttempts  int     `toml:"max_attempts"`  // 最多执行次数（含首次执行），1 表示不自动重试
+	InitialDelay int     `toml:"initial_delay"` // 第一次重试前的等待时间（秒）
+	MaxDelay     int     `toml:"max_delay"`     // 重试等待时间上限（秒）
+	Multiplier   float64 `toml:"multiplier"`    // 每次重试等待时间的增长倍数
+	Jitter       float64 `toml:"jitter"`        // 随机抖动比例（0-1），避免大量步骤同时重试
+}
+
 // NewDefaultConfig 创建默认配置
 func NewDefaultConfig() *AppConfig {
 	return &AppConfig{
@@ -275,6 +324,28 @@ func NewDefaultConfig() *AppConfig {
 			Language:  "en",
 			Threads:   4,
 		},
+
+		// 任务流水线配置（默认值，可被 config.toml 覆盖）
+		PipelineConfig: &PipelineConfig{
+			Workers: 2,
+			ResourceLimits: map[string]int{
+				"cpu":     1, // Whisper 转录、ffmpeg
+				"network": 4, // 视频、封面下载
+				"llm":     3, // 翻译、元数据生成
+				"upload":  1, // 上传到 Bilibili
+			},
+			StepTimeout: 7200, // 2 小时
+			TimeoutPerMin: map[string]int{
+				"Whisper转录": 60, // 转录耗时与视频时长成正比
+			},
+			Retry: &RetryPolicyConfig{
+				MaxAttempts:  3,
+				InitialDelay: 60,
+				MaxDelay:     3600,
+				Multiplier:   2,
+				Jitter:       0.2,
+			},
+		},
 	}
 }
 
@@ -307,6 +378,7 @@ func LoadConfig(configFile string) (*AppConfig, error) {
 		AnalyticsConfig     *AnalyticsConfig     `toml:"AnalyticsConfig"`
 		BilibiliConfig      *BilibiliConfig      `toml:"BilibiliConfig"`
 		WhisperConfig       *WhisperConfig       `toml:"WhisperConfig"`
+		PipelineConfig      *PipelineConfig      `toml:"PipelineConfig"`
 	}
 
 	// 解码TOML配置文件
@@ -344,6 +416,9 @@ func LoadConfig(configFile string) (*AppConfig, error) {
 	if fileConfig.WhisperConfig != nil {
 		config.WhisperConfig = fileConfig.WhisperConfig
 	}
+	if fileConfig.PipelineConfig != nil {
+		config.PipelineConfig = fileConfig.PipelineConfig
+	}
 
 
 	return config, nil
@@ -367,6 +442,7 @@ func SaveConfig(config *AppConfig) error {
 		AnalyticsConfig     *AnalyticsConfig     `toml:"AnalyticsConfig"`
 		BilibiliConfig      *BilibiliConfig      `toml:"BilibiliConfig"`
 		WhisperConfig       *WhisperConfig       `toml:"WhisperConfig"`
+		PipelineConfig      *PipelineConfig      `toml:"PipelineConfig"`
 	}{
 		Listen:              config.Listen,
 		Environment:         config.Environment,
@@ -382,6 +458,7 @@ func SaveConfig(config *AppConfig) error {
 		AnalyticsConfig:     config.AnalyticsConfig,
 		BilibiliConfig:      config.BilibiliConfig,
 		WhisperConfig:       config.WhisperConfig,
+		PipelineConfig:      config.PipelineConfig,
 	}
 
 	buf := new(bytes.Buffer)
@@ -0,0 +1,198 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ErrorInfoKey 任务链上下文中结构化错误（*TaskError）的键，与 "error" 中的错误信息同时写入
+const ErrorInfoKey = "error_info"
+
+// ErrorCategory 错误类别
+type ErrorCategory string
+
+const (
+	ErrorCategoryNetwork   ErrorCategory = "network"    // 网络中断、代理失效、DNS 解析失败
+	ErrorCategoryRateLimit ErrorCategory = "rate_limit" // 请求频率或配额限制
+	ErrorCategoryService   ErrorCategory = "service"    // 远端服务临时异常（5xx）
+	ErrorCategoryTimeout   ErrorCategory = "timeout"    // 执行超时
+	ErrorCategoryContent   ErrorCategory = "content"    // 视频本身不可用：私有、年龄限制、地区限制、版权
+	ErrorCategoryAuth      ErrorCategory = "auth"       // 登录失效、API Key 无效、账号权限不足
+	ErrorCategoryConfig    ErrorCategory = "config"     // 缺少配置或外部工具
+	ErrorCategoryInput     ErrorCategory = "input"      // 前置步骤的产物缺失或格式错误
+	ErrorCategoryInternal  ErrorCategory = "internal"   // 本地文件读写等内部错误
+	ErrorCategoryUnknown   ErrorCategory = "unknown"    // 无法识别的错误
+)
+
+// ErrorCode 错误码
+type ErrorCode string
+
+const (
+	ErrCodeNetwork            ErrorCode = "network_error"
+	ErrCodeProxy              ErrorCode = "proxy_error"
+	ErrCodeRateLimited        ErrorCode = "rate_limited"
+	ErrCodeServiceUnavailable ErrorCode = "service_unavailable"
+	ErrCodeTimeout            ErrorCode = "timeout"
+	ErrCodeVideoPrivate       ErrorCode = "video_private"
+	ErrCodeVideoUnavailable   ErrorCode = "video_unavailable"
+	ErrCodeVideoNotStarted    ErrorCode = "video_not_started"
+	ErrCodeAgeRestricted      ErrorCode = "age_restricted"
+	ErrCodeGeoBlocked         ErrorCode = "geo_blocked"
+	ErrCodeMembersOnly        ErrorCode = "members_only"
+	ErrCodeCopyright          ErrorCode = "copyright"
+	ErrCodeBotCheck           ErrorCode = "bot_check"
+	ErrCodeNotLoggedIn        ErrorCode = "not_logged_in"
+	ErrCodeAuthFailed         ErrorCode = "auth_failed"
+	ErrCodeForbidden          ErrorCode = "forbidden"
+	ErrCodeQuotaExceeded      ErrorCode = "quota_exceeded"
+	ErrCodeNotConfigured      ErrorCode = "not_configured"
+	ErrCodeToolMissing        ErrorCode = "tool_missing"
+	ErrCodeMissingInput       ErrorCode = "missing_input"
+	ErrCodeInvalidInput       ErrorCode = "invalid_input"
+	ErrCodeFileTooLarge       ErrorCode = "file_too_large"
+	ErrCodeRejected           ErrorCode = "rejected"
+	ErrCodeIO                 ErrorCode = "io_error"
+	ErrCodeCommandFailed      ErrorCode = "command_failed"
+	ErrCodeInterrupted        ErrorCode = "interrupted"
+	ErrCodeInternal           ErrorCode = "internal_error"
+	ErrCodeUnknown            ErrorCode = "unknown"
+)
+
+// errorSpec 错误码对应的类别、是否可重试和默认的用户提示
+type errorSpec struct {
+	category  ErrorCategory
+	retryable bool
+	message   string
+}
+
+var errorSpecs = map[ErrorCode]errorSpec{
+	ErrCodeNetwork:            {ErrorCategoryNetwork, true, "网络连接异常，请检查网络状态"},
+	ErrCodeProxy:              {ErrorCategoryNetwork, true, "代理连接失败，请检查代理设置"},
+	ErrCodeRateLimited:        {ErrorCategoryRateLimit, true, "请求频率过快，请稍后重试"},
+	ErrCodeServiceUnavailable: {ErrorCategoryService, true, "服务器临时异常，请稍后重试"},
+	ErrCodeTimeout:            {ErrorCategoryTimeout, true, "执行超时"},
+	ErrCodeVideoPrivate:       {ErrorCategoryContent, false, "视频为私有视频，无法下载"},
+	ErrCodeVideoUnavailable:   {ErrorCategoryContent, false, "视频不可用或已被删除"},
+	ErrCodeVideoNotStarted:    {ErrorCategoryContent, true, "直播或首映尚未开始，稍后自动重试"},
+	ErrCodeAgeRestricted:      {ErrorCategoryContent, false, "视频有年龄限制，需要提供已登录的 cookies"},
+	ErrCodeGeoBlocked:         {ErrorCategoryContent, false, "视频在当前地区不可用，请更换代理地区"},
+	ErrCodeMembersOnly:        {ErrorCategoryContent, false, "视频仅限频道会员观看"},
+	ErrCodeCopyright:          {ErrorCategoryContent, false, "视频因版权问题不可用"},
+	ErrCodeBotCheck:           {ErrorCategoryAuth, false, "YouTube 要求登录验证，请配置 cookies.txt"},
+	ErrCodeNotLoggedIn:        {ErrorCategoryAuth, false, "未登录 Bilibili，请先扫码登录"},
+	ErrCodeAuthFailed:         {ErrorCategoryAuth, false, "登录状态已过期或 API Key 无效"},
+	ErrCodeForbidden:          {ErrorCategoryAuth, false, "账号权限不足或被限制"},
+	ErrCodeQuotaExceeded:      {ErrorCategoryRateLimit, false, "账户余额或配额不足"},
+	ErrCodeNotConfigured:      {ErrorCategoryConfig, false, "缺少必要的配置"},
+	ErrCodeToolMissing:        {ErrorCategoryConfig, false, "缺少必要的外部工具"},
+	ErrCodeMissingInput:       {ErrorCategoryInput, false, "缺少前置步骤生成的文件"},
+	ErrCodeInvalidInput:       {ErrorCategoryInput, false, "输入数据格式错误"},
+	ErrCodeFileTooLarge:       {ErrorCategoryInput, false, "文件过大，超出上传限制"},
+	ErrCodeRejected:           {ErrorCategoryContent, false, "投稿被拒绝"},
+	ErrCodeIO:                 {ErrorCategoryInternal, false, "文件读写失败，请检查磁盘空间和文件权限"},
+	ErrCodeCommandFailed:      {ErrorCategoryInternal, false, "外部命令执行失败"},
+	ErrCodeInterrupted:        {ErrorCategoryInternal, true, "执行该步骤的实例已失联"},
+	ErrCodeInternal:           {ErrorCategoryInternal, false, "步骤执行异常，请查看日志"},
+	ErrCodeUnknown:            {ErrorCategoryUnknown, true, "发生未知错误"},
+}
+
+// TaskError 结构化的任务错误
+// Message 是展示给用户的提示，Detail 保留原始的技术错误信息用于排查
+type TaskError struct {
+	Code      ErrorCode     `json:"code"`
+	Category  ErrorCategory `json:"category"`
+	Retryable bool          `json:"retryable"`
+	Message   string        `json:"message"`
+	Detail    string        `json:"detail,omitempty"`
+}
+
+// NewTaskError 根据错误码创建错误，使用错误码的默认用户提示
+func NewTaskError(code ErrorCode, detail string) *TaskError {
+	spec, ok := errorSpecs[code]
+	if !ok {
+		code, spec = ErrCodeUnknown, errorSpecs[ErrCodeUnknown]
+	}
+	return &TaskError{
+		Code:      code,
+		Category:  spec.category,
+		Retryable: spec.retryable,
+		Message:   spec.message,
+		Detail:    detail,
+	}
+}
+
+// WithMessage 设置展示给用户的提示
+func (e *TaskError) WithMessage(format string, args ...interface{}) *TaskError {
+	e.Message = fmt.Sprintf(format, args...)
+	return e
+}
+
+func (e *TaskError) Error() string {
+	return e.Message
+}
+
+// errorRule 通过关键字识别错误码，按顺序匹配，先匹配的规则优先
+type errorRule struct {
+	code     ErrorCode
+	keywords []string
+}
+
+var errorRules = []errorRule{
+	{ErrCodeVideoPrivate, []string{"private video", "this video is private"}},
+	{ErrCodeAgeRestricted, []string{"sign in to confirm your age", "age-restricted", "inappropriate for some users"}},
+	{ErrCodeBotCheck, []string{"not a bot"}},
+	{ErrCodeMembersOnly, []string{"members-only", "available to this channel's members", "join this channel"}},
+	{ErrCodeGeoBlocked, []string{"not available in your country", "not made this video available in your country", "geo restricted", "geo-restricted"}},
+	{ErrCodeCopyright, []string{"copyright"}},
+	{ErrCodeVideoNotStarted, []string{"live event will begin", "premieres in", "this live event", "is not live yet"}},
+	{ErrCodeVideoUnavailable, []string{"video unavailable", "has been removed", "account associated with this video has been terminated", "does not exist", "404: not found", "http error 404"}},
+	{ErrCodeRateLimited, []string{"429", "too many requests", "rate limit", "rate_limit"}},
+	{ErrCodeQuotaExceeded, []string{"insufficient_quota", "insufficient balance", "quota exceeded", "余额不足"}},
+	{ErrCodeNotLoggedIn, []string{"未登录"}},
+	{ErrCodeAuthFailed, []string{"401", "unauthorized", "invalid api key", "invalid_api_key", "登录状态已过期"}},
+	{ErrCodeForbidden, []string{"403", "forbidden"}},
+	{ErrCodeProxy, []string{"proxyerror", "proxy error", "cannot connect to proxy", "unable to connect to proxy", "socks"}},
+	{ErrCodeTimeout, []string{"timed out", "timeout", "deadline exceeded", "超时"}},
+	{ErrCodeServiceUnavailable, []string{"500", "502", "503", "504", "internal server error", "bad gateway", "service unavailable"}},
+	{ErrCodeNetwork, []string{"connection reset", "connection refused", "broken pipe", "no such host", "temporary failure in name resolution", "network is unreachable", "unexpected eof", "unable to download webpage", "tls handshake"}},
+	{ErrCodeNotConfigured, []string{"未配置", "未启用", "not configured"}},
+	{ErrCodeToolMissing, []string{"未找到 yt-dlp", "executable file not found"}},
+	{ErrCodeFileTooLarge, []string{"file too large"}},
+	{ErrCodeMissingInput, []string{"no such file", "file not found", "不存在", "未找到"}},
+	{ErrCodeInvalidInput, []string{"unsupported url", "解析", "格式错误", "invalid character"}},
+	{ErrCodeIO, []string{"permission denied", "no space left", "read-only file system"}},
+}
+
+// ClassifyError 根据错误信息中的关键字识别错误码，无法识别时返回 ErrCodeUnknown（可重试）
+func ClassifyError(text string) *TaskError {
+	lower := strings.ToLower(text)
+	for _, rule := range errorRules {
+		for _, keyword := range rule.keywords {
+			if strings.Contains(lower, keyword) {
+				return NewTaskError(rule.code, text)
+			}
+		}
+	}
+	return NewTaskError(ErrCodeUnknown, text)
+}
+
+// SetTaskError 将结构化错误写入任务链上下文，context["error"] 中保存用户提示
+func SetTaskError(context map[string]interface{}, err *TaskError) {
+	context["error"] = err.Message
+	context[ErrorInfoKey] = err
+}
+
+// TaskErrorFromContext 从任务链上下文中读取结构化错误
+// 任务只写入了 context["error"] 时，根据错误信息识别错误码；没有错误时返回 nil
+func TaskErrorFromContext(context map[string]interface{}) *TaskError {
+	if err, ok := context[ErrorInfoKey].(*TaskError); ok && err != nil {
+		return err
+	}
+	if msg, ok := context["error"]; ok && msg != nil {
+		text := fmt.Sprintf("%v", msg)
+		err := ClassifyError(text)
+		err.Message = text
+		return err
+	}
+	return nil
+}
@@ -1,9 +1,24 @@
 package types
 
+import "context"
+
+// ResourceClass 任务的资源类别，调度器按类别限制并发数
+type ResourceClass string
+
+const (
+	ResourceClassDefault ResourceClass = "default" // 轻量任务，不限制并发
+	ResourceClassCPU     ResourceClass = "cpu"     // CPU 密集型（Whisper 转录、ffmpeg）
+	ResourceClassNetwork ResourceClass = "network" // 网络下载（yt-dlp、封面）
+	ResourceClassLLM     ResourceClass = "llm"     // 受 AI 服务配额限制（翻译、元数据生成）
+	ResourceClassUpload  ResourceClass = "upload"  // 上传到 Bilibili / COS
+)
+
 // Task 接口定义了任务处理器的基本操作
+// Execute 的 ctx 被取消时，任务应尽快终止子进程、HTTP 请求等并返回 false
 type Task interface {
-	Execute(context map[string]interface{}) bool
+	Execute(ctx context.Context, context map[string]interface{}) bool
 	GetName() string
+	GetResourceClass() ResourceClass
 	InsertTask() error
 	UpdateStatus(status, message string) error
 }
@@ -0,0 +1,25 @@
+package types
+
+import "fmt"
+
+// SkipReasonKey 任务链上下文中跳过原因的键，任务通过 SkipTask 写入，由步骤跟踪读取后移除
+const SkipReasonKey = "skip_reason"
+
+// SkipTask 标记当前任务无需执行（例如没有字幕可翻译），任务随后返回 true
+// 步骤会记录为已跳过（skipped）并保存原因，下游步骤照常执行
+func SkipTask(context map[string]interface{}, reason string) {
+	context[SkipReasonKey] = reason
+}
+
+// TakeSkipReason 读取并移除任务写入的跳过原因，任务没有标记跳过时返回 false
+func TakeSkipReason(context map[string]interface{}) (string, bool) {
+	value, ok := context[SkipReasonKey]
+	if !ok {
+		return "", false
+	}
+	delete(context, SkipReasonKey)
+	if value == nil {
+		return "", true
+	}
+	return fmt.Sprintf("%v", value), true
+}
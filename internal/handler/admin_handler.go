@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/difyz9/ytb2bili/internal/chain_task"
+	"github.com/difyz9/ytb2bili/internal/chain_task/manager"
+	"github.com/difyz9/ytb2bili/internal/core"
+	"github.com/difyz9/ytb2bili/internal/core/services"
+	"github.com/difyz9/ytb2bili/pkg/store/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler 运维接口，暂停和恢复流水线（维护模式），执行一致性检查
+// 例如 B站登录失效时暂停上传，翻译服务故障时暂停翻译步骤，避免步骤大量失败
+type AdminHandler struct {
+	BaseHandler
+	PauseService *services.PauseService
+	Notifier     *manager.JobNotifier
+	Reconciler   *chain_task.Reconciler
+	StepTypes    func() []string // 可以单独暂停的任务类型
+}
+
+func NewAdminHandler(app *core.AppServer, pauseService *services.PauseService, notifier *manager.JobNotifier, reconciler *chain_task.Reconciler, stepTypes func() []string) *AdminHandler {
+	return &AdminHandler{
+		BaseHandler:  BaseHandler{App: app},
+		PauseService: pauseService,
+		Notifier:     notifier,
+		Reconciler:   reconciler,
+		StepTypes:    stepTypes,
+	}
+}
+
+// RegisterRoutes 注册运维相关路由
+func (h *AdminHandler) RegisterRoutes(api *gin.RouterGroup) {
+	admin := api.Group("/admin")
+	{
+		admin.GET("/pause", h.listPauses)
+		admin.POST("/pause/:scope", h.pause)
+		admin.POST("/resume/:scope", h.resume)
+		admin.GET("/reconcile", h.lastReconcile)
+		admin.POST("/reconcile", h.reconcile)
+	}
+}
+
+// PauseRequest 暂停请求
+type PauseRequest struct {
+	Reason string `json:"reason"` // 暂停原因（可选）
+}
+
+// listPauses 获取所有已暂停的范围
+func (h *AdminHandler) listPauses(c *gin.Context) {
+	pauses, err := h.PauseService.ListPauses()
+	if err != nil {
+		h.App.Logger.Errorf("获取暂停状态失败: %v", err)
+		c.JSON(http.StatusInternalServerError, VideoListResponse{
+			Code:    500,
+			Message: "获取暂停状态失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, VideoListResponse{
+		Code:    200,
+		Message: "success",
+		Data: gin.H{
+			"paused": len(pauses) > 0,
+			"pauses": pauses,
+		},
+	})
+}
+
+// pause 暂停准备阶段（prepare）、上传阶段（upload）或某个任务类型（step:<任务类型>）
+func (h *AdminHandler) pause(c *gin.Context) {
+	scope := c.Param("scope")
+	if err := h.validateScope(scope); err != nil {
+		c.JSON(http.StatusBadRequest, VideoListResponse{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// 请求体可以为空
+	var req PauseRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, VideoListResponse{
+				Code:    400,
+				Message: "请求参数错误: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	state, err := h.PauseService.Pause(scope, req.Reason)
+	if err != nil {
+		h.App.Logger.Errorf("暂停 %s 失败: %v", scope, err)
+		c.JSON(http.StatusInternalServerError, VideoListResponse{
+			Code:    500,
+			Message: "暂停失败",
+		})
+		return
+	}
+
+	h.App.Logger.Warnf("⏸️ %s 已暂停: %s", scope, req.Reason)
+	c.JSON(http.StatusOK, VideoListResponse{
+		Code:    200,
+		Message: fmt.Sprintf("%s 已暂停", scope),
+		Data:    state,
+	})
+}
+
+// resume 恢复已暂停的范围，并立即唤醒任务分发
+func (h *AdminHandler) resume(c *gin.Context) {
+	scope := c.Param("scope")
+	if err := h.validateScope(scope); err != nil {
+		c.JSON(http.StatusBadRequest, VideoListResponse{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	resumed, err := h.PauseService.Resume(scope)
+	if err != nil {
+		h.App.Logger.Errorf("恢复 %s 失败: %v", scope, err)
+		c.JSON(http.StatusInternalServerError, VideoListResponse{
+			Code:    500,
+			Message: "恢复失败",
+		})
+		return
+	}
+
+	message := fmt.Sprintf("%s 未暂停", scope)
+	if resumed {
+		message = fmt.Sprintf("%s 已恢复", scope)
+		h.App.Logger.Infof("▶️ %s 已恢复", scope)
+		h.Notifier.Notify()
+	}
+
+	c.JSON(http.StatusOK, VideoListResponse{
+		Code:    200,
+		Message: message,
+		Data: gin.H{
+			"scope":   scope,
+			"resumed": resumed,
+		},
+	})
+}
+
+// lastReconcile 获取最近一次一致性检查的结果
+func (h *AdminHandler) lastReconcile(c *gin.Context) {
+	report := h.Reconciler.LastReport()
+	if report == nil {
+		c.JSON(http.StatusNotFound, VideoListResponse{
+			Code:    404,
+			Message: "尚未执行一致性检查",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, VideoListResponse{
+		Code:    200,
+		Message: "success",
+		Data:    report,
+	})
+}
+
+// reconcile 立即执行一致性检查，?dry_run=true 时只报告需要修复的视频，不做修改
+func (h *AdminHandler) reconcile(c *gin.Context) {
+	dryRun := c.Query("dry_run") == "true"
+	report := h.Reconciler.Reconcile(dryRun)
+	if report.Error != "" {
+		c.JSON(http.StatusInternalServerError, VideoListResponse{
+			Code:    500,
+			Message: "一致性检查失败: " + report.Error,
+			Data:    report,
+		})
+		return
+	}
+
+	message := fmt.Sprintf("检查 %d 个视频，发现 %d 个不一致，修复 %d 个", report.Checked, len(report.Fixes), report.Applied())
+	if dryRun {
+		message = fmt.Sprintf("检查 %d 个视频，发现 %d 个需要修复（预演，未修改）", report.Checked, len(report.Fixes))
+	}
+	c.JSON(http.StatusOK, VideoListResponse{
+		Code:    200,
+		Message: message,
+		Data:    report,
+	})
+}
+
+// validateScope 检查暂停范围是否有效
+func (h *AdminHandler) validateScope(scope string) error {
+	if scope == model.PauseScopePrepare || scope == model.PauseScopeUpload {
+		return nil
+	}
+	if task := strings.TrimPrefix(scope, model.PauseScopeStepPrefix); task != scope {
+		for _, stepType := range h.StepTypes() {
+			if stepType == task {
+				return nil
+			}
+		}
+		return fmt.Errorf("未知的任务类型: %s", task)
+	}
+	return fmt.Errorf("无效的暂停范围: %s，可选值: %s、%s、%s<任务类型>", scope, model.PauseScopePrepare, model.PauseScopeUpload, model.PauseScopeStepPrefix)
+}
@@ -0,0 +1,330 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/difyz9/ytb2bili/internal/chain_task"
+	"github.com/difyz9/ytb2bili/internal/core"
+	"github.com/difyz9/ytb2bili/internal/core/models"
+	"github.com/difyz9/ytb2bili/internal/core/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// channelVideosLimit 频道详情中返回的最近加入的视频数量
+const channelVideosLimit = 50
+
+// ChannelHandler 频道订阅接口，订阅 YouTube 频道后新上传的视频自动加入处理队列
+type ChannelHandler struct {
+	BaseHandler
+	ChannelService *services.ChannelService
+	Watcher        *chain_task.ChannelWatcher
+}
+
+func NewChannelHandler(app *core.AppServer, channelService *services.ChannelService, watcher *chain_task.ChannelWatcher) *ChannelHandler {
+	return &ChannelHandler{
+		BaseHandler:    BaseHandler{App: app},
+		ChannelService: channelService,
+		Watcher:        watcher,
+	}
+}
+
+// RegisterRoutes 注册频道订阅相关路由
+func (h *ChannelHandler) RegisterRoutes(api *gin.RouterGroup) {
+	channels := api.Group("/channels")
+	{
+		channels.GET("", h.listChannels)
+		channels.POST("", h.subscribe)
+		channels.GET("/:id", h.getChannel)
+		channels.PUT("/:id", h.updateChannel)
+		channels.DELETE("/:id", h.unsubscribe)
+		channels.POST("/:id/check", h.checkChannel)
+	}
+}
+
+// SubscribeChannelRequest 订阅频道请求
+type SubscribeChannelRequest struct {
+	URL           string `json:"url" binding:"required"` // 频道 URL、@handle 或频道ID
+	Pipeline      string `json:"pipeline"`               // 新视频使用的流水线，为空时使用配置的 profile
+	BiliTid       int    `json:"bili_tid"`               // 新视频的投稿分区ID，0 表示使用配置的 tid
+	TitleTemplate string `json:"title_template"`         // 新视频的投稿标题模板，支持 {original_title}、{ai_title}
+	Priority      int    `json:"priority"`               // 新视频的调度优先级
+	CheckLimit    int    `json:"check_limit"`            // 每次检查的最新视频数量，0 使用默认值
+	Backfill      int    `json:"backfill"`               // 订阅时同时加入处理队列的最近视频数量，默认只处理之后上传的视频
+	Remark        string `json:"remark"`
+}
+
+// UpdateChannelRequest 修改频道订阅请求，不传的字段保持不变
+type UpdateChannelRequest struct {
+	Pipeline      *string `json:"pipeline"`
+	BiliTid       *int    `json:"bili_tid"`
+	TitleTemplate *string `json:"title_template"`
+	Priority      *int    `json:"priority"`
+	CheckLimit    *int    `json:"check_limit"`
+	Status        *string `json:"status"` // active: 定期检查，paused: 暂停检查
+	Remark        *string `json:"remark"`
+}
+
+// listChannels 获取所有订阅的频道
+func (h *ChannelHandler) listChannels(c *gin.Context) {
+	channels, err := h.ChannelService.ListChannels()
+	if err != nil {
+		h.App.Logger.Errorf("获取频道订阅失败: %v", err)
+		c.JSON(http.StatusInternalServerError, VideoListResponse{
+			Code:    500,
+			Message: "获取频道订阅失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, VideoListResponse{
+		Code:    200,
+		Message: "success",
+		Data:    channels,
+	})
+}
+
+// subscribe 订阅频道，记录频道当前最新的视频
+func (h *ChannelHandler) subscribe(c *gin.Context) {
+	var req SubscribeChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, VideoListResponse{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+	if req.BiliTid < 0 || req.CheckLimit < 0 || req.Backfill < 0 {
+		c.JSON(http.StatusBadRequest, VideoListResponse{
+			Code:    400,
+			Message: "bili_tid、check_limit 和 backfill 不能为负数",
+		})
+		return
+	}
+
+	result, err := h.Watcher.Subscribe(c.Request.Context(), req.URL, models.TbChannel{
+		Pipeline:      req.Pipeline,
+		BiliTid:       req.BiliTid,
+		TitleTemplate: req.TitleTemplate,
+		Priority:      req.Priority,
+		CheckLimit:    req.CheckLimit,
+		Remark:        req.Remark,
+	}, req.Backfill)
+	if err != nil {
+		h.respondWatchError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, VideoListResponse{
+		Code:    200,
+		Message: fmt.Sprintf("已订阅频道 %s，加入 %d 个视频", result.Channel.Title, len(result.Added)),
+		Data:    result,
+	})
+}
+
+// getChannel 获取频道订阅及最近加入处理队列的视频
+func (h *ChannelHandler) getChannel(c *gin.Context) {
+	channel, ok := h.findChannel(c)
+	if !ok {
+		return
+	}
+
+	videos, err := h.ChannelService.GetChannelVideos(channel.ChannelId, channelVideosLimit)
+	if err != nil {
+		h.App.Logger.Errorf("获取频道视频失败: %v", err)
+		c.JSON(http.StatusInternalServerError, VideoListResponse{
+			Code:    500,
+			Message: "获取频道视频失败",
+		})
+		return
+	}
+
+	videoInfos := make([]gin.H, 0, len(videos))
+	for _, video := range videos {
+		videoInfos = append(videoInfos, gin.H{
+			"id":           video.ID,
+			"video_id":     video.VideoID,
+			"title":        video.Title,
+			"status":       video.Status,
+			"status_label": video.Status.Label(),
+			"bili_bvid":    video.BiliBVID,
+			"created_at":   video.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+
+	c.JSON(http.StatusOK, VideoListResponse{
+		Code:    200,
+		Message: "success",
+		Data: gin.H{
+			"channel": channel,
+			"videos":  videoInfos,
+		},
+	})
+}
+
+// updateChannel 修改频道的默认设置或暂停、恢复检查，默认设置只影响之后加入的视频
+func (h *ChannelHandler) updateChannel(c *gin.Context) {
+	channel, ok := h.findChannel(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, VideoListResponse{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Pipeline != nil {
+		if err := h.Watcher.ValidatePipeline(*req.Pipeline); err != nil {
+			c.JSON(http.StatusBadRequest, VideoListResponse{
+				Code:    400,
+				Message: err.Error(),
+			})
+			return
+		}
+		updates["pipeline"] = *req.Pipeline
+	}
+	if req.BiliTid != nil {
+		if *req.BiliTid < 0 {
+			c.JSON(http.StatusBadRequest, VideoListResponse{
+				Code:    400,
+				Message: "bili_tid 不能为负数",
+			})
+			return
+		}
+		updates["bili_tid"] = *req.BiliTid
+	}
+	if req.TitleTemplate != nil {
+		updates["title_template"] = *req.TitleTemplate
+	}
+	if req.Priority != nil {
+		updates["priority"] = *req.Priority
+	}
+	if req.CheckLimit != nil {
+		if *req.CheckLimit < 0 {
+			c.JSON(http.StatusBadRequest, VideoListResponse{
+				Code:    400,
+				Message: "check_limit 不能为负数",
+			})
+			return
+		}
+		updates["check_limit"] = *req.CheckLimit
+	}
+	if req.Status != nil {
+		if *req.Status != models.ChannelStatusActive && *req.Status != models.ChannelStatusPaused {
+			c.JSON(http.StatusBadRequest, VideoListResponse{
+				Code:    400,
+				Message: fmt.Sprintf("status 只能是 %s 或 %s", models.ChannelStatusActive, models.ChannelStatusPaused),
+			})
+			return
+		}
+		updates["status"] = *req.Status
+	}
+	if req.Remark != nil {
+		updates["remark"] = *req.Remark
+	}
+
+	if err := h.ChannelService.UpdateChannel(channel.ChannelId, updates); err != nil {
+		h.App.Logger.Errorf("修改频道订阅 %s 失败: %v", channel.ChannelId, err)
+		c.JSON(http.StatusInternalServerError, VideoListResponse{
+			Code:    500,
+			Message: "修改频道订阅失败",
+		})
+		return
+	}
+
+	updated, err := h.ChannelService.GetByChannelID(channel.ChannelId)
+	if err != nil {
+		updated = channel
+	}
+	c.JSON(http.StatusOK, VideoListResponse{
+		Code:    200,
+		Message: "频道订阅已更新",
+		Data:    updated,
+	})
+}
+
+// unsubscribe 取消订阅，已加入处理队列的视频保留
+func (h *ChannelHandler) unsubscribe(c *gin.Context) {
+	channel, ok := h.findChannel(c)
+	if !ok {
+		return
+	}
+
+	if err := h.ChannelService.DeleteChannel(channel.ChannelId); err != nil {
+		h.App.Logger.Errorf("取消订阅频道 %s 失败: %v", channel.ChannelId, err)
+		c.JSON(http.StatusInternalServerError, VideoListResponse{
+			Code:    500,
+			Message: "取消订阅失败",
+		})
+		return
+	}
+
+	h.App.Logger.Infof("📺 已取消订阅频道 %s（%s）", channel.ChannelId, channel.Title)
+	c.JSON(http.StatusOK, VideoListResponse{
+		Code:    200,
+		Message: "已取消订阅",
+	})
+}
+
+// checkChannel 立即检查频道的最新上传，暂停的频道同样可以手动检查
+func (h *ChannelHandler) checkChannel(c *gin.Context) {
+	channel, ok := h.findChannel(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.Watcher.Check(c.Request.Context(), channel)
+	if err != nil {
+		h.respondWatchError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, VideoListResponse{
+		Code:    200,
+		Message: fmt.Sprintf("检查 %d 个最新视频，新增 %d 个，跳过 %d 个", result.Checked, len(result.Added), result.Skipped),
+		Data:    result,
+	})
+}
+
+// findChannel 按频道ID查找订阅，不存在时返回 404
+func (h *ChannelHandler) findChannel(c *gin.Context) (*models.TbChannel, bool) {
+	channel, err := h.ChannelService.GetByChannelID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, VideoListResponse{
+			Code:    404,
+			Message: "频道订阅不存在",
+		})
+		return nil, false
+	}
+	return channel, true
+}
+
+// respondWatchError 订阅或检查失败：参数无效返回 400，已订阅返回 409，获取频道视频失败返回 502
+func (h *ChannelHandler) respondWatchError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, chain_task.ErrInvalidChannelURL), errors.Is(err, chain_task.ErrInvalidChannelSettings):
+		c.JSON(http.StatusBadRequest, VideoListResponse{
+			Code:    400,
+			Message: err.Error(),
+		})
+	case errors.Is(err, chain_task.ErrChannelExists):
+		c.JSON(http.StatusConflict, VideoListResponse{
+			Code:    409,
+			Message: err.Error(),
+		})
+	default:
+		h.App.Logger.Errorf("检查频道失败: %v", err)
+		c.JSON(http.StatusBadGateway, VideoListResponse{
+			Code:    502,
+			Message: err.Error(),
+		})
+	}
+}
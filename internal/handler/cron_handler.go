@@ -3,7 +3,6 @@ package handler
 import (
 	"github.com/difyz9/ytb2bili/internal/core"
 	"github.com/difyz9/ytb2bili/internal/core/services"
-	"fmt"
 	"github.com/robfig/cron/v3"
 	"gorm.io/gorm"
 	"resty.dev/v3"
@@ -28,16 +27,7 @@ func NewCronHandler(app *core.AppServer, db *gorm.DB, task *cron.Cron) *CronHand
 
 }
 
-func (h *CronHandler) runTask() {
-	fmt.Println("定时任务执行中....")
-
-}
 func (h *CronHandler) SetUp() {
-	_, err := h.Task.AddFunc("0/3 * * * * *", h.runTask)
-	if err != nil {
-		return
-	}
-
 	h.Task.Start() // 启动定时任务
 
 }
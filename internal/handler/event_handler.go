@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/difyz9/ytb2bili/internal/chain_task/manager"
+	"github.com/difyz9/ytb2bili/internal/core"
+	"github.com/difyz9/ytb2bili/internal/core/services"
+	"github.com/difyz9/ytb2bili/pkg/store/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// eventHeartbeatInterval 推送心跳事件的间隔，避免代理因连接空闲而断开
+const eventHeartbeatInterval = 15 * time.Second
+
+// EventHandler 通过 SSE（Server-Sent Events）推送任务执行进度
+type EventHandler struct {
+	BaseHandler
+	SavedVideoService *services.SavedVideoService
+	Progress          *manager.ProgressBus
+}
+
+func NewEventHandler(app *core.AppServer, savedVideoService *services.SavedVideoService, progress *manager.ProgressBus) *EventHandler {
+	return &EventHandler{
+		BaseHandler:       BaseHandler{App: app},
+		SavedVideoService: savedVideoService,
+		Progress:          progress,
+	}
+}
+
+// RegisterRoutes 注册进度事件路由
+func (h *EventHandler) RegisterRoutes(api *gin.RouterGroup) {
+	api.GET("/events", h.streamEvents)
+	api.GET("/videos/:id/events", h.streamVideoEvents)
+}
+
+// streamEvents 推送所有视频的进度事件
+func (h *EventHandler) streamEvents(c *gin.Context) {
+	h.stream(c, "")
+}
+
+// streamVideoEvents 推送单个视频的进度事件
+func (h *EventHandler) streamVideoEvents(c *gin.Context) {
+	idStr := c.Param("id")
+
+	// 尝试解析为数字ID，如果失败则当作video_id处理
+	var savedVideo *model.SavedVideo
+	var err error
+
+	if id, parseErr := strconv.ParseUint(idStr, 10, 32); parseErr == nil {
+		savedVideo, err = h.SavedVideoService.GetByID(uint(id))
+	} else {
+		savedVideo, err = h.SavedVideoService.GetVideoByVideoID(idStr)
+	}
+
+	if err != nil {
+		h.App.Logger.Errorf("获取视频详情失败: %v", err)
+		c.JSON(http.StatusNotFound, VideoListResponse{
+			Code:    404,
+			Message: "视频不存在",
+		})
+		return
+	}
+
+	h.stream(c, savedVideo.VideoID)
+}
+
+// stream 订阅进度事件并以 SSE 格式推送，直到客户端断开
+// 事件名为事件类型（progress、step），数据为 JSON 格式的 manager.ProgressEvent
+func (h *EventHandler) stream(c *gin.Context, videoID string) {
+	events, unsubscribe := h.Progress.Subscribe(videoID)
+	defer unsubscribe()
+
+	heartbeat := time.NewTicker(eventHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent("ping", time.Now().Format("2006-01-02 15:04:05"))
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Type, event)
+			return true
+		case <-heartbeat.C:
+			c.SSEvent("ping", time.Now().Format("2006-01-02 15:04:05"))
+			return true
+		}
+	})
+}
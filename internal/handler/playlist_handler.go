@@ -0,0 +1,256 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/difyz9/ytb2bili/internal/chain_task"
+	"github.com/difyz9/ytb2bili/internal/core"
+	"github.com/difyz9/ytb2bili/internal/core/services"
+	"github.com/difyz9/ytb2bili/pkg/store/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PlaylistHandler 播放列表接口，导入 YouTube 播放列表并展开为单独的视频
+type PlaylistHandler struct {
+	BaseHandler
+	PlaylistService *services.PlaylistService
+	Syncer          *chain_task.PlaylistSyncer
+}
+
+func NewPlaylistHandler(app *core.AppServer, playlistService *services.PlaylistService, syncer *chain_task.PlaylistSyncer) *PlaylistHandler {
+	return &PlaylistHandler{
+		BaseHandler:     BaseHandler{App: app},
+		PlaylistService: playlistService,
+		Syncer:          syncer,
+	}
+}
+
+// RegisterRoutes 注册播放列表相关路由
+func (h *PlaylistHandler) RegisterRoutes(api *gin.RouterGroup) {
+	playlists := api.Group("/playlists")
+	{
+		playlists.GET("", h.listPlaylists)
+		playlists.POST("", h.importPlaylist)
+		playlists.GET("/:id", h.getPlaylist)
+		playlists.PUT("/:id", h.updatePlaylist)
+		playlists.DELETE("/:id", h.deletePlaylist)
+		playlists.POST("/:id/sync", h.syncPlaylist)
+	}
+}
+
+// ImportPlaylistRequest 导入播放列表请求
+type ImportPlaylistRequest struct {
+	URL      string `json:"url" binding:"required"`
+	Priority *int   `json:"priority"`  // 导入的视频使用的调度优先级，不传时新播放列表为 0，已导入的播放列表保持原设置
+	AutoSync *bool  `json:"auto_sync"` // 是否定期同步新增的视频，不传时新播放列表不同步，已导入的播放列表保持原设置
+}
+
+// UpdatePlaylistRequest 修改播放列表设置请求，不传的字段保持不变
+type UpdatePlaylistRequest struct {
+	Priority *int  `json:"priority"`
+	AutoSync *bool `json:"auto_sync"`
+}
+
+// listPlaylists 获取所有导入的播放列表
+func (h *PlaylistHandler) listPlaylists(c *gin.Context) {
+	playlists, err := h.PlaylistService.ListPlaylists()
+	if err != nil {
+		h.App.Logger.Errorf("获取播放列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, VideoListResponse{
+			Code:    500,
+			Message: "获取播放列表失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, VideoListResponse{
+		Code:    200,
+		Message: "success",
+		Data:    playlists,
+	})
+}
+
+// importPlaylist 导入播放列表，按播放列表顺序为尚未提交过的视频创建待处理的记录
+func (h *PlaylistHandler) importPlaylist(c *gin.Context) {
+	var req ImportPlaylistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, VideoListResponse{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	result, err := h.Syncer.Import(c.Request.Context(), req.URL, req.Priority, req.AutoSync)
+	if err != nil {
+		h.respondSyncError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, VideoListResponse{
+		Code:    200,
+		Message: fmt.Sprintf("播放列表共 %d 个视频，新增 %d 个，跳过 %d 个", result.Total, len(result.Added), result.Skipped),
+		Data:    result,
+	})
+}
+
+// getPlaylist 获取播放列表及其导入的视频（按播放列表顺序）
+func (h *PlaylistHandler) getPlaylist(c *gin.Context) {
+	playlist, ok := h.findPlaylist(c)
+	if !ok {
+		return
+	}
+
+	videos, err := h.PlaylistService.GetPlaylistVideos(playlist.PlaylistID)
+	if err != nil {
+		h.App.Logger.Errorf("获取播放列表视频失败: %v", err)
+		c.JSON(http.StatusInternalServerError, VideoListResponse{
+			Code:    500,
+			Message: "获取播放列表视频失败",
+		})
+		return
+	}
+
+	videoInfos := make([]gin.H, 0, len(videos))
+	for _, video := range videos {
+		videoInfos = append(videoInfos, gin.H{
+			"id":           video.ID,
+			"video_id":     video.VideoID,
+			"title":        video.Title,
+			"status":       video.Status,
+			"status_label": video.Status.Label(),
+			"bili_bvid":    video.BiliBVID,
+			"created_at":   video.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+
+	c.JSON(http.StatusOK, VideoListResponse{
+		Code:    200,
+		Message: "success",
+		Data: gin.H{
+			"playlist": playlist,
+			"videos":   videoInfos,
+		},
+	})
+}
+
+// updatePlaylist 修改播放列表的优先级和自动同步设置，优先级只影响之后导入的视频
+func (h *PlaylistHandler) updatePlaylist(c *gin.Context) {
+	playlist, ok := h.findPlaylist(c)
+	if !ok {
+		return
+	}
+
+	var req UpdatePlaylistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, VideoListResponse{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := h.PlaylistService.UpdateSettings(playlist.ID, req.AutoSync, req.Priority); err != nil {
+		h.App.Logger.Errorf("修改播放列表 %s 失败: %v", playlist.PlaylistID, err)
+		c.JSON(http.StatusInternalServerError, VideoListResponse{
+			Code:    500,
+			Message: "修改播放列表失败",
+		})
+		return
+	}
+
+	updated, err := h.PlaylistService.GetByID(playlist.ID)
+	if err != nil {
+		updated = playlist
+	}
+	c.JSON(http.StatusOK, VideoListResponse{
+		Code:    200,
+		Message: "播放列表已更新",
+		Data:    updated,
+	})
+}
+
+// deletePlaylist 删除播放列表，不再同步，已导入的视频保留
+func (h *PlaylistHandler) deletePlaylist(c *gin.Context) {
+	playlist, ok := h.findPlaylist(c)
+	if !ok {
+		return
+	}
+
+	if err := h.PlaylistService.DeletePlaylist(playlist.ID); err != nil {
+		h.App.Logger.Errorf("删除播放列表 %s 失败: %v", playlist.PlaylistID, err)
+		c.JSON(http.StatusInternalServerError, VideoListResponse{
+			Code:    500,
+			Message: "删除播放列表失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, VideoListResponse{
+		Code:    200,
+		Message: "播放列表已删除",
+	})
+}
+
+// syncPlaylist 立即同步播放列表，导入新增的视频
+func (h *PlaylistHandler) syncPlaylist(c *gin.Context) {
+	playlist, ok := h.findPlaylist(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.Syncer.Sync(c.Request.Context(), playlist)
+	if err != nil {
+		h.respondSyncError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, VideoListResponse{
+		Code:    200,
+		Message: fmt.Sprintf("播放列表共 %d 个视频，新增 %d 个，跳过 %d 个", result.Total, len(result.Added), result.Skipped),
+		Data:    result,
+	})
+}
+
+// findPlaylist 按数字ID或 YouTube 播放列表ID查找播放列表，不存在时返回 404
+func (h *PlaylistHandler) findPlaylist(c *gin.Context) (*model.Playlist, bool) {
+	idStr := c.Param("id")
+
+	var playlist *model.Playlist
+	var err error
+
+	if id, parseErr := strconv.ParseUint(idStr, 10, 32); parseErr == nil {
+		playlist, err = h.PlaylistService.GetByID(uint(id))
+	} else {
+		playlist, err = h.PlaylistService.GetByPlaylistID(idStr)
+	}
+
+	if err != nil {
+		c.JSON(http.StatusNotFound, VideoListResponse{
+			Code:    404,
+			Message: "播放列表不存在",
+		})
+		return nil, false
+	}
+	return playlist, true
+}
+
+// respondSyncError 导入或同步失败：URL 无效返回 400，获取播放列表失败返回 502
+func (h *PlaylistHandler) respondSyncError(c *gin.Context, err error) {
+	if errors.Is(err, chain_task.ErrInvalidPlaylistURL) {
+		c.JSON(http.StatusBadRequest, VideoListResponse{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+	h.App.Logger.Errorf("同步播放列表失败: %v", err)
+	c.JSON(http.StatusBadGateway, VideoListResponse{
+		Code:    502,
+		Message: err.Error(),
+	})
+}
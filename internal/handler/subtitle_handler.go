@@ -1,10 +1,15 @@
 package handler
 
 import (
+	"github.com/difyz9/ytb2bili/internal/chain_task"
+	"github.com/difyz9/ytb2bili/internal/chain_task/manager"
 	"github.com/difyz9/ytb2bili/internal/core"
+	"github.com/difyz9/ytb2bili/internal/core/services"
 	"github.com/difyz9/ytb2bili/pkg/store/model"
 	"github.com/difyz9/ytb2bili/pkg/utils"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -14,15 +19,32 @@ import (
 
 type SubtitleHandler struct {
 	BaseHandler
+	SavedVideoService *services.SavedVideoService
+	TaskStepService   *services.TaskStepService
+	Notifier          *manager.JobNotifier // 提交视频后立即唤醒任务分发
+	PlaylistImporter  PlaylistImporter     // 提交播放列表 URL 时展开为单独的视频
 }
 
-func NewSubtitleHandler(app *core.AppServer) *SubtitleHandler {
+// PlaylistImporter 播放列表导入接口
+type PlaylistImporter interface {
+	Import(ctx context.Context, playlistURL string, priority *int, autoSync *bool) (*chain_task.PlaylistSyncResult, error)
+}
+
+func NewSubtitleHandler(app *core.AppServer, savedVideoService *services.SavedVideoService, taskStepService *services.TaskStepService, notifier *manager.JobNotifier) *SubtitleHandler {
 
 	return &SubtitleHandler{
-		BaseHandler: BaseHandler{App: app},
+		BaseHandler:       BaseHandler{App: app},
+		SavedVideoService: savedVideoService,
+		TaskStepService:   taskStepService,
+		Notifier:          notifier,
 	}
 }
 
+// SetPlaylistImporter 设置播放列表导入器
+func (h *SubtitleHandler) SetPlaylistImporter(importer PlaylistImporter) {
+	h.PlaylistImporter = importer
+}
+
 // SaveVideoRequest 保存视频请求
 type SaveVideoRequest struct {
 	URL           string                     `json:"url" binding:"required"`
@@ -31,6 +53,7 @@ type SaveVideoRequest struct {
 	OperationType string                     `json:"operationType"`
 	Subtitles     []model.SavedVideoSubtitle `json:"subtitles"`
 	PlaylistID    string                     `json:"playlistId"`
+	Priority      *int                       `json:"priority"` // 调度优先级，数值越大越先处理，不传时新视频为 0，重新提交的视频保持原优先级
 	Timestamp     string                     `json:"timestamp"`
 	SavedAt       string                     `json:"savedAt"`
 }
@@ -46,6 +69,11 @@ func (h *SubtitleHandler) saveVideoSubtitles(c *gin.Context) {
 	}
 
 	fmt.Println("Received saveVideoSubtitles request for URL:", req.URL)
+	// 播放列表 URL 展开为播放列表中的各个视频
+	if utils.ExtractPlaylistID(req.URL) != "" && h.PlaylistImporter != nil {
+		h.submitPlaylist(c, req)
+		return
+	}
 	// 从 URL 中提取 videoId
 	videoID := utils.ExtractVideoID(req.URL)
 	if videoID == "" {
@@ -87,6 +115,16 @@ func (h *SubtitleHandler) saveVideoSubtitles(c *gin.Context) {
 	isExisting := false
 
 	if err == nil {
+		// 正在处理或上传中的视频不能重新提交，避免覆盖执行中的状态
+		previousStatus := existingVideo.Status
+		if !existingVideo.DeletedAt.Valid && previousStatus != model.VideoStatusPending && !previousStatus.CanTransitionTo(model.VideoStatusPending) {
+			c.JSON(http.StatusConflict, gin.H{
+				"success": false,
+				"message": fmt.Sprintf("Video is %s(%s) and cannot be resubmitted now", previousStatus, previousStatus.Label()),
+			})
+			return
+		}
+
 		// 找到了记录（可能是已删除的），更新字段
 		isExisting = true
 		existingVideo.URL = req.URL
@@ -95,9 +133,14 @@ func (h *SubtitleHandler) saveVideoSubtitles(c *gin.Context) {
 		existingVideo.OperationType = req.OperationType
 		existingVideo.Subtitles = subtitlesJSONStr
 		existingVideo.PlaylistID = req.PlaylistID
+		existingVideo.Source = model.PlaylistSource(req.PlaylistID)
+		if req.Priority != nil {
+			existingVideo.Priority = *req.Priority
+		}
 		existingVideo.Timestamp = req.Timestamp
 		existingVideo.SavedAt = req.SavedAt
-		existingVideo.Status = "001" // 重置状态为待处理
+		existingVideo.Status = model.VideoStatusPending // 重置状态为待处理
+		existingVideo.RunFrom, existingVideo.RunUntil = "", "" // 重新提交后执行完整的准备阶段
 		existingVideo.DeletedAt = gorm.DeletedAt{} // 恢复记录（清除删除标记）
 
 		// 更新到数据库（使用 Unscoped 以便更新已删除的记录）
@@ -110,6 +153,13 @@ func (h *SubtitleHandler) saveVideoSubtitles(c *gin.Context) {
 			return
 		}
 		savedVideo = &existingVideo
+		if err := h.SavedVideoService.RecordSubmitted(savedVideo, previousStatus, "重新提交视频"); err != nil {
+			fmt.Printf("记录视频状态变更失败: %v\n", err)
+		}
+		// 重新提交后执行完整的准备阶段，之前手动跳过的步骤也重新执行
+		if _, err := h.TaskStepService.ClearOperatorSkips(videoID); err != nil {
+			fmt.Printf("重置手动跳过的步骤失败: %v\n", err)
+		}
 		
 		if existingVideo.DeletedAt.Valid {
 			fmt.Printf("✅ 恢复已删除的视频: %s\n", videoID)
@@ -120,15 +170,20 @@ func (h *SubtitleHandler) saveVideoSubtitles(c *gin.Context) {
 			VideoID:       videoID,
 			URL:           req.URL,
 			Title:         req.Title,
-			Status:        "001",
+			Status:        model.VideoStatusPending,
 			Description:   req.Description,
 			OperationType: req.OperationType,
 			Subtitles:     subtitlesJSONStr,
 			PlaylistID:    req.PlaylistID,
+			Source:        model.PlaylistSource(req.PlaylistID),
 			Timestamp:     req.Timestamp,
 			SavedAt:       req.SavedAt,
 		}
 
+		if req.Priority != nil {
+			savedVideo.Priority = *req.Priority
+		}
+
 		// 保存到数据库
 		if err := h.App.DB.Create(savedVideo).Error; err != nil {
 			fmt.Printf("创建视频失败，字幕数据长度: %d\n", len(subtitlesJSONStr))
@@ -138,6 +193,9 @@ func (h *SubtitleHandler) saveVideoSubtitles(c *gin.Context) {
 			})
 			return
 		}
+		if err := h.SavedVideoService.RecordSubmitted(savedVideo, "", "提交视频"); err != nil {
+			fmt.Printf("记录视频状态变更失败: %v\n", err)
+		}
 	} else {
 		// 数据库查询出错
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -147,6 +205,9 @@ func (h *SubtitleHandler) saveVideoSubtitles(c *gin.Context) {
 		return
 	}
 
+	// 立即唤醒任务分发，不必等待下一次扫描
+	h.Notifier.Notify()
+
 	// 计算字幕数量
 	subtitleCount := len(req.Subtitles)
 
@@ -168,6 +229,34 @@ func (h *SubtitleHandler) saveVideoSubtitles(c *gin.Context) {
 	})
 }
 
+// submitPlaylist 导入播放列表，按播放列表顺序为尚未提交过的视频创建待处理的记录
+func (h *SubtitleHandler) submitPlaylist(c *gin.Context, req SaveVideoRequest) {
+	result, err := h.PlaylistImporter.Import(c.Request.Context(), req.URL, req.Priority, nil)
+	if err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, chain_task.ErrInvalidPlaylistURL) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": "Failed to import playlist: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("Playlist imported: %d added, %d skipped", len(result.Added), result.Skipped),
+		"data": gin.H{
+			"playlistId": result.Playlist.PlaylistID,
+			"title":      result.Playlist.Title,
+			"total":      result.Total,
+			"added":      result.Added,
+			"skipped":    result.Skipped,
+		},
+	})
+}
+
 // RegisterRoutes 注册上传相关路由
 func (h *SubtitleHandler) RegisterRoutes(server *core.AppServer) {
 	api := server.Engine.Group("/api/v1")
@@ -2,15 +2,20 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/difyz9/ytb2bili/internal/chain_task"
+	"github.com/difyz9/ytb2bili/internal/chain_task/manager"
 	"github.com/difyz9/ytb2bili/internal/core"
 	"github.com/difyz9/ytb2bili/internal/core/services"
+	"github.com/difyz9/ytb2bili/internal/core/types"
 	"github.com/difyz9/ytb2bili/pkg/store/model"
 
 	"github.com/gin-gonic/gin"
@@ -21,7 +26,17 @@ type VideoHandler struct {
 	SavedVideoService *services.SavedVideoService
 	TaskStepService   *services.TaskStepService
 	UploadScheduler   interface {
-		ExecuteManualUpload(videoID, taskType string) error
+		ExecuteManualUpload(video *model.SavedVideo, taskType string) error
+	}
+	TaskCanceller interface {
+		Cancel(videoID string) bool
+	}
+	JobNotifier interface {
+		Notify()
+	}
+	StepRunner interface {
+		ScheduleRun(video *model.SavedVideo, from, until string) ([]string, error)
+		SkipStep(video *model.SavedVideo, stepName, reason string) ([]string, error)
 	}
 	AnalyticsHandler *AnalyticsHandler
 }
@@ -37,11 +52,33 @@ func NewVideoHandler(app *core.AppServer, savedVideoService *services.SavedVideo
 
 // SetUploadScheduler 设置上传调度器（避免循环依赖）
 func (h *VideoHandler) SetUploadScheduler(scheduler interface {
-	ExecuteManualUpload(videoID, taskType string) error
+	ExecuteManualUpload(video *model.SavedVideo, taskType string) error
 }) {
 	h.UploadScheduler = scheduler
 }
 
+// SetTaskCanceller 设置任务取消器，用于终止视频正在执行的任务
+func (h *VideoHandler) SetTaskCanceller(canceller interface {
+	Cancel(videoID string) bool
+}) {
+	h.TaskCanceller = canceller
+}
+
+// SetJobNotifier 设置任务唤醒器，重试步骤或调整优先级后立即唤醒任务分发
+func (h *VideoHandler) SetJobNotifier(notifier interface {
+	Notify()
+}) {
+	h.JobNotifier = notifier
+}
+
+// SetStepRunner 设置步骤控制器，用于只重新执行视频的部分步骤和手动跳过步骤
+func (h *VideoHandler) SetStepRunner(runner interface {
+	ScheduleRun(video *model.SavedVideo, from, until string) ([]string, error)
+	SkipStep(video *model.SavedVideo, stepName, reason string) ([]string, error)
+}) {
+	h.StepRunner = runner
+}
+
 // RegisterRoutes 注册视频相关路由
 func (h *VideoHandler) RegisterRoutes(api *gin.RouterGroup) {
 	video := api.Group("/videos")
@@ -50,6 +87,14 @@ func (h *VideoHandler) RegisterRoutes(api *gin.RouterGroup) {
 		video.GET("/:id", h.getVideoDetail)
 		video.DELETE("/:id", h.deleteVideo)
 		video.POST("/:id/steps/:stepName/retry", h.retryTaskStep)
+		video.POST("/:id/steps/:stepName/skip", h.skipTaskStep)
+		video.POST("/:id/run", h.runVideoSteps)
+		video.POST("/:id/cancel", h.cancelVideo)
+		video.PUT("/:id/priority", h.setVideoPriority)
+		video.POST("/:id/priority/:action", h.adjustVideoPriority)
+		video.GET("/:id/timeline", h.getVideoTimeline)
+		video.GET("/:id/runs", h.getVideoRuns)
+		video.GET("/:id/steps/:stepName/logs", h.getStepLogs)
 		video.GET("/:id/files", h.getVideoFiles)
 		video.POST("/:id/upload/video", h.manualUploadVideo)
 		video.POST("/:id/upload/subtitle", h.manualUploadSubtitle)
@@ -77,7 +122,9 @@ type VideoInfo struct {
 	VideoID        string                 `json:"video_id"`
 	Title          string                 `json:"title"`
 	URL            string                 `json:"url"`
-	Status         string                 `json:"status"`
+	Status         model.VideoStatus      `json:"status"`
+	Priority       int                    `json:"priority"`
+	Source         string                 `json:"source"`
 	GeneratedTitle string                 `json:"generated_title"`
 	GeneratedDesc  string                 `json:"generated_desc"`
 	GeneratedTags  string                 `json:"generated_tags"`
@@ -93,14 +140,22 @@ type VideoInfo struct {
 
 // TaskStepInfo 任务步骤信息
 type TaskStepInfo struct {
-	StepName  string `json:"step_name"`
-	StepOrder int    `json:"step_order"`
-	Status    string `json:"status"`
-	StartTime string `json:"start_time"`
-	EndTime   string `json:"end_time"`
-	Duration  int64  `json:"duration"`
-	ErrorMsg  string `json:"error_msg"`
-	CanRetry  bool   `json:"can_retry"`
+	StepName       string                  `json:"step_name"`
+	StepOrder      int                     `json:"step_order"`
+	DependsOn      []string                `json:"depends_on"`
+	Status         string                  `json:"status"`
+	StartTime      string                  `json:"start_time"`
+	EndTime        string                  `json:"end_time"`
+	Duration       int64                   `json:"duration"`
+	ErrorMsg       string                  `json:"error_msg"`
+	Error          *types.TaskError        `json:"error,omitempty"` // 结构化错误：错误码、类别、是否可重试
+	CanRetry       bool                    `json:"can_retry"`
+	Attempts       int                     `json:"attempts"`
+	MaxAttempts    int                     `json:"max_attempts"`
+	NextRetryAt    string                  `json:"next_retry_at,omitempty"`
+	SkipReason     string                  `json:"skip_reason,omitempty"` // 跳过原因，步骤为 skipped 时有效
+	SkippedBy      string                  `json:"skipped_by,omitempty"`  // 跳过来源: condition、task、operator
+	AttemptHistory []model.TaskStepAttempt `json:"attempt_history"`
 }
 
 // getVideoList 获取视频列表
@@ -142,6 +197,8 @@ func (h *VideoHandler) getVideoList(c *gin.Context) {
 			Title:          sv.Title,
 			URL:            sv.URL,
 			Status:         sv.Status,
+			Priority:       sv.Priority,
+			Source:         sv.Source,
 			GeneratedTitle: sv.GeneratedTitle,
 			GeneratedDesc:  sv.GeneratedDesc,
 			GeneratedTags:  sv.GeneratedTags,
@@ -195,16 +252,32 @@ func (h *VideoHandler) getVideoDetail(c *gin.Context) {
 		h.App.Logger.Errorf("获取任务步骤失败: %v", err)
 	}
 
+	// 按视频使用的流水线获取步骤的任务类型，用于确定内置的重试策略
+	pipeline, err := chain_task.ResolveVideoPipeline(h.App.Config, savedVideo)
+	if err != nil {
+		h.App.Logger.Warnf("加载视频 %s 的流水线失败: %v", savedVideo.VideoID, err)
+	}
+
 	// 转换任务步骤格式
 	var taskStepInfos []TaskStepInfo
 	for _, step := range taskSteps {
+		var task string
+		if pipeline != nil {
+			if definition, ok := pipeline.Step(step.StepName); ok {
+				task = definition.Task
+			}
+		}
 		stepInfo := TaskStepInfo{
-			StepName:  step.StepName,
-			StepOrder: step.StepOrder,
-			Status:    step.Status,
-			Duration:  step.Duration,
-			ErrorMsg:  step.ErrorMsg,
-			CanRetry:  step.CanRetry,
+			StepName:       step.StepName,
+			StepOrder:      step.StepOrder,
+			DependsOn:      step.Dependencies(),
+			Status:         step.Status,
+			Duration:       step.Duration,
+			ErrorMsg:       step.ErrorMsg,
+			CanRetry:       step.CanRetry,
+			Attempts:       step.Attempts,
+			MaxAttempts:    services.NewRetryPolicy(h.App.Config, step.StepName, task).MaxAttempts,
+			AttemptHistory: step.AttemptRecords(),
 		}
 
 		if step.StartTime != nil {
@@ -213,6 +286,22 @@ func (h *VideoHandler) getVideoDetail(c *gin.Context) {
 		if step.EndTime != nil {
 			stepInfo.EndTime = step.EndTime.Format("2006-01-02 15:04:05")
 		}
+		if step.ErrorCode != "" {
+			stepInfo.Error = &types.TaskError{
+				Code:      types.ErrorCode(step.ErrorCode),
+				Category:  types.ErrorCategory(step.ErrorCategory),
+				Retryable: step.ErrorRetryable,
+				Message:   step.ErrorMsg,
+				Detail:    step.ErrorDetail,
+			}
+		}
+		if step.NextRetryAt != nil && step.Status == model.TaskStepStatusPending {
+			stepInfo.NextRetryAt = step.NextRetryAt.Format("2006-01-02 15:04:05")
+		}
+		if step.Status == model.TaskStepStatusSkipped {
+			stepInfo.SkipReason = step.SkipReason
+			stepInfo.SkippedBy = step.SkippedBy
+		}
 
 		taskStepInfos = append(taskStepInfos, stepInfo)
 	}
@@ -235,6 +324,8 @@ func (h *VideoHandler) getVideoDetail(c *gin.Context) {
 		Title:          savedVideo.Title,
 		URL:            savedVideo.URL,
 		Status:         savedVideo.Status,
+		Priority:       savedVideo.Priority,
+		Source:         savedVideo.Source,
 		GeneratedTitle: savedVideo.GeneratedTitle,
 		GeneratedDesc:  savedVideo.GeneratedDesc,
 		GeneratedTags:  savedVideo.GeneratedTags,
@@ -300,8 +391,9 @@ func (h *VideoHandler) retryTaskStep(c *gin.Context) {
 	// 重新执行任务步骤
 	h.App.Logger.Infof("🔄 用户请求重试任务步骤: %s - %s", savedVideo.VideoID, stepName)
 
-	// 重置任务步骤状态为待执行
-	err = h.TaskStepService.UpdateTaskStepStatus(savedVideo.VideoID, stepName, "pending")
+	// 重置任务步骤状态为待执行，立即加入重试队列（手动重试不受最大执行次数限制）
+	// 只重试已结束或被阻塞的步骤，正在执行或已在等待执行的步骤不会被重复排队
+	queued, err := h.TaskStepService.QueueRetry(savedVideo.VideoID, stepName, time.Now())
 	if err != nil {
 		h.App.Logger.Errorf("更新任务步骤状态失败: %v", err)
 		c.JSON(http.StatusInternalServerError, VideoListResponse{
@@ -310,8 +402,18 @@ func (h *VideoHandler) retryTaskStep(c *gin.Context) {
 		})
 		return
 	}
+	if !queued {
+		c.JSON(http.StatusConflict, VideoListResponse{
+			Code:    409,
+			Message: fmt.Sprintf("任务步骤 %s 不能重试：只能重试失败、超时、已取消或被阻塞的步骤，该步骤可能正在执行或已在等待执行", stepName),
+		})
+		return
+	}
 
 	h.App.Logger.Infof("✅ 任务步骤 %s 已重置为待执行状态，等待调度器处理", stepName)
+	if h.JobNotifier != nil {
+		h.JobNotifier.Notify()
+	}
 
 	c.JSON(http.StatusOK, VideoListResponse{
 		Code:    200,
@@ -320,11 +422,605 @@ func (h *VideoHandler) retryTaskStep(c *gin.Context) {
 			"video_id":  savedVideo.VideoID,
 			"step_name": stepName,
 			"status":    "pending",
-			"message":   "任务已重置，将在下次调度时重新执行",
+			"message":   "任务已重置，将立即重新执行",
+		},
+	})
+}
+
+// SkipStepRequest 手动跳过步骤请求
+type SkipStepRequest struct {
+	Reason string `json:"reason"` // 跳过原因，为空时记录为"手动跳过"
+}
+
+// skipTaskStep 手动跳过任务步骤，下游步骤把已跳过的步骤视为已完成
+// 例如翻译反复失败但原字幕可以直接使用时跳过翻译，继续生成元数据和上传
+func (h *VideoHandler) skipTaskStep(c *gin.Context) {
+	var req SkipStepRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, VideoListResponse{
+				Code:    400,
+				Message: "请求参数错误: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	stepName := c.Param("stepName")
+
+	savedVideo, ok := h.loadVideo(c)
+	if !ok {
+		return
+	}
+
+	if h.StepRunner == nil {
+		c.JSON(http.StatusServiceUnavailable, VideoListResponse{
+			Code:    503,
+			Message: "任务调度器未启用",
+		})
+		return
+	}
+
+	h.App.Logger.Infof("⏭️ 用户请求跳过任务步骤: %s - %s", savedVideo.VideoID, stepName)
+
+	resumed, err := h.StepRunner.SkipStep(savedVideo, stepName, strings.TrimSpace(req.Reason))
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, chain_task.ErrStepNotSkippable):
+			status = http.StatusBadRequest
+		case errors.Is(err, chain_task.ErrVideoBusy):
+			status = http.StatusConflict
+		default:
+			h.App.Logger.Errorf("跳过任务步骤失败: %v", err)
+		}
+		c.JSON(status, VideoListResponse{
+			Code:    status,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, VideoListResponse{
+		Code:    200,
+		Message: fmt.Sprintf("任务步骤 %s 已跳过", stepName),
+		Data: gin.H{
+			"video_id":  savedVideo.VideoID,
+			"step_name": stepName,
+			"status":    model.TaskStepStatusSkipped,
+			"resumed":   resumed,
+		},
+	})
+}
+
+// RunStepsRequest 重新执行部分步骤请求，from 和 until 都为空时重新执行完整的准备阶段
+type RunStepsRequest struct {
+	From  string `json:"from"`  // 从该步骤开始（含下游步骤），为空表示从头开始
+	Until string `json:"until"` // 执行到该步骤后停止（含上游步骤），为空表示执行到准备阶段结束
+}
+
+// runVideoSteps 重新执行视频准备阶段的部分步骤
+// 例如修改提示词后只重新翻译和生成元数据，或只处理到字幕以便人工校对；
+// 只执行部分步骤时手动跳过的步骤保持跳过，重新执行完整的准备阶段时一并重置
+func (h *VideoHandler) runVideoSteps(c *gin.Context) {
+	var req RunStepsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, VideoListResponse{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	savedVideo, ok := h.loadVideo(c)
+	if !ok {
+		return
+	}
+
+	if h.StepRunner == nil {
+		c.JSON(http.StatusServiceUnavailable, VideoListResponse{
+			Code:    503,
+			Message: "任务调度器未启用",
+		})
+		return
+	}
+
+	h.App.Logger.Infof("🎯 用户请求重新执行步骤: %s (from: %q, until: %q)", savedVideo.VideoID, req.From, req.Until)
+
+	steps, err := h.StepRunner.ScheduleRun(savedVideo, req.From, req.Until)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, chain_task.ErrInvalidRunRange):
+			status = http.StatusBadRequest
+		case errors.Is(err, chain_task.ErrVideoBusy):
+			status = http.StatusConflict
+		default:
+			h.App.Logger.Errorf("重新执行步骤失败: %v", err)
+		}
+		c.JSON(status, VideoListResponse{
+			Code:    status,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, VideoListResponse{
+		Code:    200,
+		Message: fmt.Sprintf("已加入执行队列，将重新执行 %d 个步骤", len(steps)),
+		Data: gin.H{
+			"video_id": savedVideo.VideoID,
+			"from":     req.From,
+			"until":    req.Until,
+			"steps":    steps,
+			"status":   model.VideoStatusPending,
+		},
+	})
+}
+
+// SetPriorityRequest 设置视频优先级请求
+type SetPriorityRequest struct {
+	Priority *int `json:"priority" binding:"required"`
+}
+
+// setVideoPriority 设置视频的调度优先级，数值越大越先处理和上传
+func (h *VideoHandler) setVideoPriority(c *gin.Context) {
+	var req SetPriorityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, VideoListResponse{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	savedVideo, ok := h.loadVideo(c)
+	if !ok {
+		return
+	}
+
+	if err := h.SavedVideoService.SetPriority(savedVideo.ID, *req.Priority); err != nil {
+		h.App.Logger.Errorf("更新视频优先级失败: %v", err)
+		c.JSON(http.StatusInternalServerError, VideoListResponse{
+			Code:    500,
+			Message: "更新视频优先级失败",
+		})
+		return
+	}
+
+	h.respondPriority(c, savedVideo, *req.Priority)
+}
+
+// adjustVideoPriority 调整视频的调度优先级
+// action 为 bump 时提到所有视频之前，为 demote 时降到所有视频之后
+func (h *VideoHandler) adjustVideoPriority(c *gin.Context) {
+	action := c.Param("action")
+	if action != "bump" && action != "demote" {
+		c.JSON(http.StatusBadRequest, VideoListResponse{
+			Code:    400,
+			Message: "不支持的操作，可选值: bump, demote",
+		})
+		return
+	}
+
+	savedVideo, ok := h.loadVideo(c)
+	if !ok {
+		return
+	}
+
+	var priority int
+	var err error
+	if action == "bump" {
+		priority, err = h.SavedVideoService.BumpPriority(savedVideo)
+	} else {
+		priority, err = h.SavedVideoService.DemotePriority(savedVideo)
+	}
+	if err != nil {
+		h.App.Logger.Errorf("调整视频优先级失败: %v", err)
+		c.JSON(http.StatusInternalServerError, VideoListResponse{
+			Code:    500,
+			Message: "调整视频优先级失败",
+		})
+		return
+	}
+
+	h.respondPriority(c, savedVideo, priority)
+}
+
+// respondPriority 返回调整后的优先级，并唤醒任务分发按新的优先级调度
+func (h *VideoHandler) respondPriority(c *gin.Context, savedVideo *model.SavedVideo, priority int) {
+	h.App.Logger.Infof("✅ 视频 %s 的优先级已从 %d 调整为 %d", savedVideo.VideoID, savedVideo.Priority, priority)
+	if h.JobNotifier != nil {
+		h.JobNotifier.Notify()
+	}
+
+	c.JSON(http.StatusOK, VideoListResponse{
+		Code:    200,
+		Message: "视频优先级已更新",
+		Data: gin.H{
+			"video_id": savedVideo.VideoID,
+			"priority": priority,
+			"source":   savedVideo.Source,
+			"status":   savedVideo.Status,
 		},
 	})
 }
 
+// loadVideo 按数字ID或 video_id 查找视频，不存在时返回 404
+func (h *VideoHandler) loadVideo(c *gin.Context) (*model.SavedVideo, bool) {
+	idStr := c.Param("id")
+
+	var savedVideo *model.SavedVideo
+	var err error
+
+	if id, parseErr := strconv.ParseUint(idStr, 10, 32); parseErr == nil {
+		savedVideo, err = h.SavedVideoService.GetByID(uint(id))
+	} else {
+		savedVideo, err = h.SavedVideoService.GetVideoByVideoID(idStr)
+	}
+
+	if err != nil {
+		h.App.Logger.Errorf("获取视频详情失败: %v", err)
+		c.JSON(http.StatusNotFound, VideoListResponse{
+			Code:    404,
+			Message: "视频不存在",
+		})
+		return nil, false
+	}
+	return savedVideo, true
+}
+
+// cancelVideo 取消视频的处理
+// 终止正在执行的子进程和网络请求，正在执行及待执行的步骤标记为已取消，视频状态更新为 998(已取消)
+func (h *VideoHandler) cancelVideo(c *gin.Context) {
+	savedVideo, ok := h.loadVideo(c)
+	if !ok {
+		return
+	}
+
+	// 待处理、处理中、准备上传、上传中的视频允许取消
+	if !savedVideo.Status.CanTransitionTo(model.VideoStatusCancelled) {
+		c.JSON(http.StatusBadRequest, VideoListResponse{
+			Code:    400,
+			Message: fmt.Sprintf("当前状态 %s(%s) 不允许取消", savedVideo.Status, savedVideo.Status.Label()),
+		})
+		return
+	}
+
+	h.App.Logger.Infof("⏹️ 用户请求取消视频处理: %s (%s)", savedVideo.VideoID, savedVideo.Title)
+
+	// 先更新视频状态，确保任务退出后不会再写入成功或失败状态
+	claimed, err := h.SavedVideoService.ClaimVideo(savedVideo.ID, savedVideo.Status, model.VideoStatusCancelled, services.StatusChange{
+		Actor:  model.VideoStatusActorAPI,
+		Reason: "用户取消",
+	})
+	if err != nil {
+		h.App.Logger.Errorf("更新视频状态失败: %v", err)
+		c.JSON(http.StatusInternalServerError, VideoListResponse{
+			Code:    500,
+			Message: "更新视频状态失败",
+		})
+		return
+	}
+	if !claimed {
+		c.JSON(http.StatusConflict, VideoListResponse{
+			Code:    409,
+			Message: "视频状态已变化，请刷新后重试",
+		})
+		return
+	}
+
+	// 通知正在执行的任务终止
+	running := false
+	if h.TaskCanceller != nil {
+		running = h.TaskCanceller.Cancel(savedVideo.VideoID)
+	}
+
+	if err := h.TaskStepService.CancelTaskSteps(savedVideo.VideoID, "用户取消"); err != nil {
+		h.App.Logger.Errorf("更新任务步骤状态失败: %v", err)
+	}
+
+	h.App.Logger.Infof("✅ 视频 %s 已取消 (正在执行的任务: %v)", savedVideo.VideoID, running)
+
+	c.JSON(http.StatusOK, VideoListResponse{
+		Code:    200,
+		Message: "视频处理已取消",
+		Data: gin.H{
+			"video_id": savedVideo.VideoID,
+			"status":   model.VideoStatusCancelled,
+			"running":  running,
+		},
+	})
+}
+
+// VideoStatusEvent 视频状态时间线中的一次状态变更
+type VideoStatusEvent struct {
+	FromStatus model.VideoStatus `json:"from_status"`
+	FromLabel  string            `json:"from_label,omitempty"`
+	ToStatus   model.VideoStatus `json:"to_status"`
+	ToLabel    string            `json:"to_label"`
+	Actor      string            `json:"actor"`
+	Instance   string            `json:"instance,omitempty"`
+	Reason     string            `json:"reason"`
+	CreatedAt  string            `json:"created_at"`
+}
+
+// getVideoTimeline 获取视频的状态变更时间线
+func (h *VideoHandler) getVideoTimeline(c *gin.Context) {
+	savedVideo, ok := h.loadVideo(c)
+	if !ok {
+		return
+	}
+
+	history, err := h.SavedVideoService.GetStatusHistory(savedVideo.VideoID)
+	if err != nil {
+		h.App.Logger.Errorf("获取视频状态变更记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, VideoListResponse{
+			Code:    500,
+			Message: "获取视频状态变更记录失败",
+		})
+		return
+	}
+
+	events := make([]VideoStatusEvent, 0, len(history))
+	for _, item := range history {
+		event := VideoStatusEvent{
+			FromStatus: item.FromStatus,
+			ToStatus:   item.ToStatus,
+			ToLabel:    item.ToStatus.Label(),
+			Actor:      item.Actor,
+			Instance:   item.Instance,
+			Reason:     item.Reason,
+			CreatedAt:  item.CreatedAt.Format("2006-01-02 15:04:05"),
+		}
+		if item.FromStatus != "" {
+			event.FromLabel = item.FromStatus.Label()
+		}
+		events = append(events, event)
+	}
+
+	c.JSON(http.StatusOK, VideoListResponse{
+		Code:    200,
+		Message: "success",
+		Data: gin.H{
+			"video_id":     savedVideo.VideoID,
+			"status":       savedVideo.Status,
+			"status_label": savedVideo.Status.Label(),
+			"timeline":     events,
+		},
+	})
+}
+
+// RunInfo 一次任务执行的记录
+type RunInfo struct {
+	ID        uint          `json:"id"`
+	Kind      string        `json:"kind"`
+	Trigger   string        `json:"trigger"`
+	Pipeline  string        `json:"pipeline"`
+	StepName  string        `json:"step_name,omitempty"`
+	Status    string        `json:"status"`
+	Instance  string        `json:"instance"`
+	ErrorMsg  string        `json:"error_msg,omitempty"`
+	StartedAt string        `json:"started_at"`
+	EndedAt   string        `json:"ended_at,omitempty"`
+	Duration  int64         `json:"duration"`
+	Steps     []StepRunInfo `json:"steps"`
+}
+
+// StepRunInfo 步骤一次执行的记录
+type StepRunInfo struct {
+	StepName  string           `json:"step_name"`
+	Attempt   int              `json:"attempt"`
+	Status    string           `json:"status"`
+	Instance  string           `json:"instance"`
+	Error     *types.TaskError `json:"error,omitempty"`
+	Result    json.RawMessage  `json:"result,omitempty"`
+	StartedAt string           `json:"started_at"`
+	EndedAt   string           `json:"ended_at,omitempty"`
+	Duration  int64            `json:"duration"`
+}
+
+// getVideoRuns 获取视频的执行记录（每次任务链、单步重试和上传的执行），按时间倒序排列
+func (h *VideoHandler) getVideoRuns(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(services.DefaultRunHistoryLimit)))
+	if limit < 1 || limit > 100 {
+		limit = services.DefaultRunHistoryLimit
+	}
+
+	savedVideo, ok := h.loadVideo(c)
+	if !ok {
+		return
+	}
+
+	runs, err := h.TaskStepService.GetRuns(savedVideo.VideoID, limit)
+	if err != nil {
+		h.App.Logger.Errorf("获取执行记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, VideoListResponse{
+			Code:    500,
+			Message: "获取执行记录失败",
+		})
+		return
+	}
+
+	runInfos := make([]RunInfo, 0, len(runs))
+	for _, run := range runs {
+		runInfo := RunInfo{
+			ID:        run.ID,
+			Kind:      run.Kind,
+			Trigger:   run.Trigger,
+			Pipeline:  run.Pipeline,
+			StepName:  run.StepName,
+			Status:    run.Status,
+			Instance:  run.Instance,
+			ErrorMsg:  run.ErrorMsg,
+			StartedAt: run.StartedAt.Format("2006-01-02 15:04:05"),
+			Duration:  run.Duration,
+			Steps:     make([]StepRunInfo, 0, len(run.Steps)),
+		}
+		if run.EndedAt != nil {
+			runInfo.EndedAt = run.EndedAt.Format("2006-01-02 15:04:05")
+		}
+
+		for _, stepRun := range run.Steps {
+			stepInfo := StepRunInfo{
+				StepName:  stepRun.StepName,
+				Attempt:   stepRun.Attempt,
+				Status:    stepRun.Status,
+				Instance:  stepRun.Instance,
+				StartedAt: stepRun.StartedAt.Format("2006-01-02 15:04:05"),
+				Duration:  stepRun.Duration,
+			}
+			if stepRun.EndedAt != nil {
+				stepInfo.EndedAt = stepRun.EndedAt.Format("2006-01-02 15:04:05")
+			}
+			if stepRun.ErrorMsg != "" || stepRun.ErrorCode != "" {
+				stepInfo.Error = &types.TaskError{
+					Code:      types.ErrorCode(stepRun.ErrorCode),
+					Category:  types.ErrorCategory(stepRun.ErrorCategory),
+					Retryable: stepRun.ErrorRetryable,
+					Message:   stepRun.ErrorMsg,
+					Detail:    stepRun.ErrorDetail,
+				}
+			}
+			if stepRun.ResultData != "" && json.Valid([]byte(stepRun.ResultData)) {
+				stepInfo.Result = json.RawMessage(stepRun.ResultData)
+			}
+			runInfo.Steps = append(runInfo.Steps, stepInfo)
+		}
+
+		runInfos = append(runInfos, runInfo)
+	}
+
+	c.JSON(http.StatusOK, VideoListResponse{
+		Code:    200,
+		Message: "success",
+		Data: gin.H{
+			"video_id": savedVideo.VideoID,
+			"runs":     runInfos,
+		},
+	})
+}
+
+// defaultStepLogTail 获取步骤日志时默认返回的行数
+const defaultStepLogTail = 200
+
+// stepLogFollowInterval 跟踪步骤日志时检查新日志的间隔
+const stepLogFollowInterval = time.Second
+
+// getStepLogs 获取步骤日志（任务日志及 yt-dlp、ffmpeg 等外部命令的输出）
+// tail 为返回的最后行数（0 表示全部）；follow=true 时以文本流持续输出新写入的日志，直到步骤结束或客户端断开
+func (h *VideoHandler) getStepLogs(c *gin.Context) {
+	stepName := c.Param("stepName")
+
+	tail, err := strconv.Atoi(c.DefaultQuery("tail", strconv.Itoa(defaultStepLogTail)))
+	if err != nil || tail < 0 {
+		c.JSON(http.StatusBadRequest, VideoListResponse{
+			Code:    400,
+			Message: "tail 参数无效",
+		})
+		return
+	}
+	follow, _ := strconv.ParseBool(c.DefaultQuery("follow", "false"))
+
+	savedVideo, ok := h.loadVideo(c)
+	if !ok {
+		return
+	}
+
+	step, err := h.TaskStepService.GetTaskStepByName(savedVideo.VideoID, stepName)
+	if err != nil {
+		c.JSON(http.StatusNotFound, VideoListResponse{
+			Code:    404,
+			Message: "任务步骤不存在",
+		})
+		return
+	}
+
+	projectRoot, err := filepath.Abs(h.App.Config.FileUpDir)
+	if err != nil {
+		h.App.Logger.Errorf("获取文件上传目录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, VideoListResponse{
+			Code:    500,
+			Message: "获取步骤日志失败",
+		})
+		return
+	}
+	logPath := manager.StepLogFile(manager.VideoWorkDir(projectRoot, savedVideo.VideoID, savedVideo.CreatedAt), stepName)
+
+	// 步骤尚未执行时日志文件不存在
+	lines, offset, err := manager.ReadStepLog(logPath, tail)
+	if err != nil && !os.IsNotExist(err) {
+		h.App.Logger.Errorf("读取步骤日志失败: %v", err)
+		c.JSON(http.StatusInternalServerError, VideoListResponse{
+			Code:    500,
+			Message: "获取步骤日志失败",
+		})
+		return
+	}
+
+	if follow {
+		h.followStepLog(c, savedVideo.VideoID, stepName, logPath, lines, offset)
+		return
+	}
+
+	if lines == nil {
+		lines = []string{}
+	}
+	c.JSON(http.StatusOK, VideoListResponse{
+		Code:    200,
+		Message: "success",
+		Data: gin.H{
+			"video_id":  savedVideo.VideoID,
+			"step_name": stepName,
+			"status":    step.Status,
+			"lines":     lines,
+		},
+	})
+}
+
+// followStepLog 以文本流输出步骤日志，步骤处于待执行或执行中时持续输出新写入的日志
+func (h *VideoHandler) followStepLog(c *gin.Context, videoID, stepName, logPath string, lines []string, offset int64) {
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	for _, line := range lines {
+		fmt.Fprintln(c.Writer, line)
+	}
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(stepLogFollowInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-ticker.C:
+		}
+
+		// 先获取步骤状态再读取日志，保证步骤结束前写入的日志都已输出
+		active := true
+		if step, err := h.TaskStepService.GetTaskStepByName(videoID, stepName); err != nil ||
+			(step.Status != model.TaskStepStatusPending && step.Status != model.TaskStepStatusRunning) {
+			active = false
+		}
+
+		var err error
+		offset, err = manager.CopyStepLog(c.Writer, logPath, offset)
+		if err != nil {
+			h.App.Logger.Errorf("读取步骤日志失败: %v", err)
+			return
+		}
+		c.Writer.Flush()
+
+		if !active {
+			return
+		}
+	}
+}
+
 // deleteVideo 删除视频及其相关数据
 func (h *VideoHandler) deleteVideo(c *gin.Context) {
 	idStr := c.Param("id")
@@ -564,7 +1260,7 @@ func (h *VideoHandler) manualUploadVideo(c *gin.Context) {
 	}
 
 	// 检查视频状态是否允许上传
-	if savedVideo.Status != "200" && savedVideo.Status != "299" {
+	if savedVideo.Status != model.VideoStatusReady && savedVideo.Status != model.VideoStatusUploadFailed {
 		c.JSON(http.StatusBadRequest, VideoListResponse{
 			Code:    400,
 			Message: fmt.Sprintf("当前状态 %s 不允许上传视频，只有状态为 200(准备就绪) 或 299(上传失败) 的视频才能上传", savedVideo.Status),
@@ -583,8 +1279,12 @@ func (h *VideoHandler) manualUploadVideo(c *gin.Context) {
 
 	h.App.Logger.Infof("🚀 用户手动触发视频上传: %s (%s)", savedVideo.VideoID, savedVideo.Title)
 
-	// 更新状态为上传中
-	if err := h.SavedVideoService.UpdateStatus(savedVideo.ID, "201"); err != nil {
+	// 更新状态为上传中，状态已被定时任务或其他实例修改时不重复上传
+	claimed, err := h.SavedVideoService.ClaimVideo(savedVideo.ID, savedVideo.Status, model.VideoStatusUploading, services.StatusChange{
+		Actor:  model.VideoStatusActorAPI,
+		Reason: "手动上传视频",
+	})
+	if err != nil {
 		h.App.Logger.Errorf("更新视频状态失败: %v", err)
 		c.JSON(http.StatusInternalServerError, VideoListResponse{
 			Code:    500,
@@ -592,17 +1292,21 @@ func (h *VideoHandler) manualUploadVideo(c *gin.Context) {
 		})
 		return
 	}
+	if !claimed {
+		c.JSON(http.StatusConflict, VideoListResponse{
+			Code:    409,
+			Message: "视频状态已变化，可能正在上传中，请刷新后重试",
+		})
+		return
+	}
 
 	// 异步执行上传任务
 	go func() {
-		if err := h.UploadScheduler.ExecuteManualUpload(savedVideo.VideoID, "video"); err != nil {
+		// 上传结果对应的状态（300/299）由 ExecuteManualUpload 更新，上传未能开始时恢复为 savedVideo.Status
+		if err := h.UploadScheduler.ExecuteManualUpload(savedVideo, "video"); err != nil {
 			h.App.Logger.Errorf("手动上传视频失败: %v", err)
-			// 上传失败，更新状态为 299
-			h.SavedVideoService.UpdateStatus(savedVideo.ID, "299")
 		} else {
 			h.App.Logger.Infof("✅ 手动上传视频成功: %s", savedVideo.VideoID)
-			// 上传成功，更新状态为 300
-			h.SavedVideoService.UpdateStatus(savedVideo.ID, "300")
 		}
 	}()
 
@@ -611,7 +1315,7 @@ func (h *VideoHandler) manualUploadVideo(c *gin.Context) {
 		Message: "视频上传任务已启动",
 		Data: gin.H{
 			"video_id": savedVideo.VideoID,
-			"status":   "201",
+			"status":   model.VideoStatusUploading,
 			"message":  "视频正在后台上传中，请稍后刷新查看结果",
 		},
 	})
@@ -641,7 +1345,7 @@ func (h *VideoHandler) manualUploadSubtitle(c *gin.Context) {
 	}
 
 	// 检查视频状态是否允许上传字幕
-	if savedVideo.Status != "300" && savedVideo.Status != "399" {
+	if savedVideo.Status != model.VideoStatusUploaded && savedVideo.Status != model.VideoStatusSubtitleFailed {
 		c.JSON(http.StatusBadRequest, VideoListResponse{
 			Code:    400,
 			Message: fmt.Sprintf("当前状态 %s 不允许上传字幕，只有状态为 300(视频已上传) 或 399(字幕上传失败) 的视频才能上传字幕", savedVideo.Status),
@@ -669,8 +1373,12 @@ func (h *VideoHandler) manualUploadSubtitle(c *gin.Context) {
 
 	h.App.Logger.Infof("🚀 用户手动触发字幕上传: %s (%s)", savedVideo.VideoID, savedVideo.Title)
 
-	// 更新状态为上传字幕中
-	if err := h.SavedVideoService.UpdateStatus(savedVideo.ID, "301"); err != nil {
+	// 更新状态为上传字幕中，状态已被定时任务或其他实例修改时不重复上传
+	claimed, err := h.SavedVideoService.ClaimVideo(savedVideo.ID, savedVideo.Status, model.VideoStatusSubtitleUploading, services.StatusChange{
+		Actor:  model.VideoStatusActorAPI,
+		Reason: "手动上传字幕",
+	})
+	if err != nil {
 		h.App.Logger.Errorf("更新视频状态失败: %v", err)
 		c.JSON(http.StatusInternalServerError, VideoListResponse{
 			Code:    500,
@@ -678,17 +1386,21 @@ func (h *VideoHandler) manualUploadSubtitle(c *gin.Context) {
 		})
 		return
 	}
+	if !claimed {
+		c.JSON(http.StatusConflict, VideoListResponse{
+			Code:    409,
+			Message: "视频状态已变化，可能正在上传中，请刷新后重试",
+		})
+		return
+	}
 
 	// 异步执行上传字幕任务
 	go func() {
-		if err := h.UploadScheduler.ExecuteManualUpload(savedVideo.VideoID, "subtitle"); err != nil {
+		// 上传结果对应的状态（400/399）由 ExecuteManualUpload 更新，上传未能开始时恢复为 savedVideo.Status
+		if err := h.UploadScheduler.ExecuteManualUpload(savedVideo, "subtitle"); err != nil {
 			h.App.Logger.Errorf("手动上传字幕失败: %v", err)
-			// 上传失败，更新状态为 399
-			h.SavedVideoService.UpdateStatus(savedVideo.ID, "399")
 		} else {
 			h.App.Logger.Infof("✅ 手动上传字幕成功: %s", savedVideo.VideoID)
-			// 上传成功，更新状态为 400
-			h.SavedVideoService.UpdateStatus(savedVideo.ID, "400")
 		}
 	}()
 
@@ -697,7 +1409,7 @@ func (h *VideoHandler) manualUploadSubtitle(c *gin.Context) {
 		Message: "字幕上传任务已启动",
 		Data: gin.H{
 			"video_id": savedVideo.VideoID,
-			"status":   "301",
+			"status":   model.VideoStatusSubtitleUploading,
 			"message":  "字幕正在后台上传中，请稍后刷新查看结果",
 		},
 	})
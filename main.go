@@ -2,6 +2,7 @@ package main
 
 import (
 	"github.com/difyz9/ytb2bili/internal/chain_task"
+	"github.com/difyz9/ytb2bili/internal/chain_task/manager"
 	"github.com/difyz9/ytb2bili/internal/core"
 	"github.com/difyz9/ytb2bili/internal/core/services"
 	"github.com/difyz9/ytb2bili/internal/core/types"
@@ -26,8 +27,51 @@ import (
 	"time"
 )
 
+// serverShutdownTimeout 关闭时等待处理中的 HTTP 请求结束的时间
+const serverShutdownTimeout = 10 * time.Second
+
+// stopTimeoutMargin 关闭应用程序的总超时时间在任务宽限期之外额外留出的时间
+const stopTimeoutMargin = 30 * time.Second
+
+// checkpointTimeout 宽限期结束后中断任务，等待任务保存状态并退出的时间
+const checkpointTimeout = 10 * time.Second
+
 // AppLifecycle 应用程序生命周期
 type AppLifecycle struct {
+	Config           *types.AppConfig
+	Logger           *zap.SugaredLogger
+	Server           *core.AppServer
+	Cron             *cron.Cron
+	ChainTaskHandler *chain_task.ChainTaskHandler
+	UploadScheduler  *chain_task.UploadScheduler
+	Cancels          *manager.CancelRegistry
+	TaskStepService  *services.TaskStepService
+	DB               *gorm.DB
+}
+
+// NewAppLifecycle 创建应用程序生命周期
+func NewAppLifecycle(
+	config *types.AppConfig,
+	logger *zap.SugaredLogger,
+	server *core.AppServer,
+	cronTask *cron.Cron,
+	chainTaskHandler *chain_task.ChainTaskHandler,
+	uploadScheduler *chain_task.UploadScheduler,
+	cancels *manager.CancelRegistry,
+	taskStepService *services.TaskStepService,
+	db *gorm.DB,
+) *AppLifecycle {
+	return &AppLifecycle{
+		Config:           config,
+		Logger:           logger,
+		Server:           server,
+		Cron:             cronTask,
+		ChainTaskHandler: chainTaskHandler,
+		UploadScheduler:  uploadScheduler,
+		Cancels:          cancels,
+		TaskStepService:  taskStepService,
+		DB:               db,
+	}
 }
 
 // OnStart 应用程序启动时执行
@@ -37,11 +81,74 @@ func (l *AppLifecycle) OnStart(context.Context) error {
 }
 
 // OnStop 应用程序停止时执行
-func (l *AppLifecycle) OnStop(context.Context) error {
+// 依次关闭 HTTP 服务、停止分发新任务、在宽限期内等待执行中的步骤结束，
+// 超过宽限期的步骤被中断并按崩溃恢复的规则重新排队，最后停止定时任务并关闭数据库
+func (l *AppLifecycle) OnStop(ctx context.Context) error {
 	log.Println("AppLifecycle OnStop")
+
+	// 1. 关闭 HTTP 服务，不再接受新的提交
+	serverCtx, cancel := context.WithTimeout(ctx, serverShutdownTimeout)
+	if err := l.Server.Shutdown(serverCtx); err != nil {
+		l.Logger.Warnf("关闭 HTTP 服务失败: %v", err)
+	}
+	cancel()
+
+	// 2. 停止分发新任务和开始新的上传
+	l.ChainTaskHandler.Stop()
+	l.UploadScheduler.Stop()
+
+	// 3. 宽限期内等待执行中的步骤结束
+	grace := chain_task.ShutdownGracePeriod(l.Config)
+	l.Logger.Infof("⏳ 等待执行中的任务结束（最长 %v）", grace)
+	if err := l.drain(ctx, grace); err != nil {
+		// 4. 超过宽限期，中断剩余任务，任务不写入终态、保留租约，由下面统一回收
+		interrupted := l.Cancels.Shutdown()
+		l.Logger.Warnf("⚠️ 宽限期内任务未结束，已中断 %d 个执行中的任务", interrupted)
+		if err := l.drain(ctx, checkpointTimeout); err != nil {
+			l.Logger.Warnf("等待被中断的任务退出超时: %v", err)
+		}
+	}
+
+	// 5. 停止定时任务（租约续期、上传调度、清理等），等待执行中的定时任务结束
+	select {
+	case <-l.Cron.Stop().Done():
+	case <-ctx.Done():
+		l.Logger.Warn("等待定时任务结束超时")
+	}
+
+	// 6. 回收当前实例仍持有的租约：准备步骤重新排队，中断的上传标记为失败，其余步骤加入重试
+	steps, videos, err := l.TaskStepService.ReclaimOwnLeases()
+	if err != nil {
+		l.Logger.Errorf("❌ 回收运行中任务失败: %v", err)
+	} else if steps > 0 || videos > 0 {
+		l.Logger.Infof("♻️ 已回收 %d 个中断的步骤、%d 个视频，重启后继续处理", steps, videos)
+	}
+
+	// 7. 放弃上传调度器的领导者身份，其他实例可以立即接管
+	l.UploadScheduler.Resign()
+
+	// 8. 关闭数据库连接
+	sqlDB, err := l.DB.DB()
+	if err != nil {
+		return err
+	}
+	if err := sqlDB.Close(); err != nil {
+		return err
+	}
+	l.Logger.Info("✓ 数据库连接已关闭")
 	return nil
 }
 
+// drain 在 timeout 内等待准备阶段和上传阶段执行中的任务结束
+func (l *AppLifecycle) drain(ctx context.Context, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err := l.ChainTaskHandler.Drain(ctx); err != nil {
+		return err
+	}
+	return l.UploadScheduler.Drain(ctx)
+}
+
 func main() {
 
 	configFile := os.Getenv("CONFIG_FILE")
@@ -100,7 +207,17 @@ func main() {
 		// 服务层
 		fx.Provide(services.NewVideoService),
 		fx.Provide(services.NewSavedVideoService),
+		// 任务租约（多个实例共享数据库时用于认领视频和任务步骤）
+		fx.Provide(services.NewLease),
 		fx.Provide(services.NewTaskStepService),
+		// 调度器状态（单例调度器的领导者选举和执行时间）
+		fx.Provide(services.NewSchedulerStateService),
+		// 暂停状态（维护模式，暂停准备阶段、上传阶段或单个任务类型）
+		fx.Provide(services.NewPauseService),
+		// 播放列表（导入的 YouTube 播放列表和同步状态）
+		fx.Provide(services.NewPlaylistService),
+		// 频道订阅（订阅的 YouTube 频道和新视频的默认设置）
+		fx.Provide(services.NewChannelService),
 
 		// 注册cron
 		fx.Provide(func() *cron.Cron {
@@ -113,9 +230,7 @@ func main() {
 		}),
 
 		// 生命周期管理
-		fx.Provide(func() *AppLifecycle {
-			return &AppLifecycle{}
-		}),
+		fx.Provide(NewAppLifecycle),
 
 		// 初始化数据库
 		fx.Invoke(func(db *gorm.DB, logger *zap.SugaredLogger) error {
@@ -129,6 +244,15 @@ func main() {
 			return checkYtDlpInstallation(logger, config)
 		}),
 
+		// 资源调度器（按资源类别限制步骤并发数，准备阶段和上传阶段共享）
+		fx.Provide(manager.NewResourceScheduler),
+		// 取消注册表（记录正在执行的任务，供取消接口终止）
+		fx.Provide(manager.NewCancelRegistry),
+		// 进度事件总线（任务发布下载、识别、翻译、上传进度，SSE 接口推送给前端）
+		fx.Provide(manager.NewProgressBus),
+		// 任务唤醒器（提交、重试时立即分发任务，替代定时轮询）
+		fx.Provide(manager.NewJobNotifier),
+
 		fx.Provide(chain_task.NewChainTaskHandler),
 		fx.Invoke(func(h *chain_task.ChainTaskHandler) {
 			// 设置并启动任务消费者（准备阶段：下载、字幕、翻译、元数据）
@@ -142,6 +266,24 @@ func main() {
 			s.SetUp()
 		}),
 
+		// 一致性检查（启动时和定期修复状态与步骤、文件不一致的视频）
+		fx.Provide(chain_task.NewReconciler),
+		fx.Invoke(func(r *chain_task.Reconciler) {
+			r.SetUp()
+		}),
+
+		// 播放列表同步（定期导入自动同步的播放列表中新增的视频）
+		fx.Provide(chain_task.NewPlaylistSyncer),
+		fx.Invoke(func(s *chain_task.PlaylistSyncer) {
+			s.SetUp()
+		}),
+
+		// 频道订阅检查（定期把订阅频道新上传的视频加入处理队列）
+		fx.Provide(chain_task.NewChannelWatcher),
+		fx.Invoke(func(w *chain_task.ChannelWatcher) {
+			w.SetUp()
+		}),
+
 		// 初始化应用服务器和基础路由
 		fx.Invoke(func(
 			server *core.AppServer,
@@ -150,6 +292,16 @@ func main() {
 			savedVideoService *services.SavedVideoService,
 			taskStepService *services.TaskStepService,
 			uploadScheduler *chain_task.UploadScheduler,
+			cancelRegistry *manager.CancelRegistry,
+			progressBus *manager.ProgressBus,
+			jobNotifier *manager.JobNotifier,
+			pauseService *services.PauseService,
+			reconciler *chain_task.Reconciler,
+			playlistService *services.PlaylistService,
+			playlistSyncer *chain_task.PlaylistSyncer,
+			channelService *services.ChannelService,
+			channelWatcher *chain_task.ChannelWatcher,
+			chainTaskHandler *chain_task.ChainTaskHandler,
 			analyticsMiddleware *analytics.Middleware,
 			analyticsClient *analytics.Client,
 		) {
@@ -163,14 +315,22 @@ func main() {
 			}
 
 			// 注册所有 Handler 路由（包括连接 VideoHandler 和 UploadScheduler）
-			registerHandlers(server, logger, savedVideoService, taskStepService, uploadScheduler, analyticsClient)
+			registerHandlers(server, logger, savedVideoService, taskStepService, uploadScheduler, cancelRegistry, progressBus, jobNotifier, pauseService, reconciler, playlistService, playlistSyncer, channelService, channelWatcher, chainTaskHandler, analyticsClient)
 
-			// 健康检查
+			// 健康检查（包含流水线的暂停状态）
 			server.Engine.GET("/health", func(c *gin.Context) {
+				status := "ok"
+				pauses, err := pauseService.ListPauses()
+				if err != nil {
+					logger.Errorf("获取暂停状态失败: %v", err)
+					status = "degraded"
+				}
 				c.JSON(200, gin.H{
-					"status":  "ok",
+					"status":  status,
 					"message": "Bili Up Backend API is running",
 					"time":    time.Now().Format(time.RFC3339),
+					"paused":  len(pauses) > 0,
+					"pauses":  pauses,
 				})
 			})
 
@@ -233,8 +393,8 @@ func main() {
 
 	log.Println("🛑 Shutting down gracefully...")
 
-	// 关闭应用程序
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// 关闭应用程序（宽限期之外留出关闭服务、中断任务和回收租约的时间）
+	ctx, cancel := context.WithTimeout(context.Background(), chain_task.ShutdownGracePeriod(config)+stopTimeoutMargin)
 	defer cancel()
 	if err := app.Stop(ctx); err != nil {
 		log.Fatal(err)
@@ -251,6 +411,16 @@ func registerHandlers(
 	savedVideoService *services.SavedVideoService,
 	taskStepService *services.TaskStepService,
 	uploadScheduler *chain_task.UploadScheduler,
+	cancelRegistry *manager.CancelRegistry,
+	progressBus *manager.ProgressBus,
+	jobNotifier *manager.JobNotifier,
+	pauseService *services.PauseService,
+	reconciler *chain_task.Reconciler,
+	playlistService *services.PlaylistService,
+	playlistSyncer *chain_task.PlaylistSyncer,
+	channelService *services.ChannelService,
+	channelWatcher *chain_task.ChannelWatcher,
+	chainTaskHandler *chain_task.ChainTaskHandler,
 	analyticsClient *analytics.Client,
 ) {
 	logger.Info("Registering handlers...")
@@ -271,7 +441,9 @@ func registerHandlers(
 	logger.Info("✓ Category routes registered")
 
 	// 字幕 Handler
-	subtitleHandler := handler.NewSubtitleHandler(server)
+	subtitleHandler := handler.NewSubtitleHandler(server, savedVideoService, taskStepService, jobNotifier)
+	// 提交播放列表 URL 时展开为单独的视频
+	subtitleHandler.SetPlaylistImporter(playlistSyncer)
 	subtitleHandler.RegisterRoutes(server)
 	logger.Info("✓ Subtitle routes registered")
 
@@ -284,9 +456,35 @@ func registerHandlers(
 	videoHandler.AnalyticsHandler = analyticsHandler
 	// 设置上传调度器（避免循环依赖）
 	videoHandler.SetUploadScheduler(uploadScheduler)
+	// 设置任务取消器
+	videoHandler.SetTaskCanceller(cancelRegistry)
+	// 设置任务唤醒器
+	videoHandler.SetJobNotifier(jobNotifier)
+	// 连接任务分发器（只重新执行部分步骤）
+	videoHandler.SetStepRunner(chainTaskHandler)
 	videoHandler.RegisterRoutes(server.Engine.Group("/api/v1"))
 	logger.Info("✓ Video routes registered")
 
+	// 进度事件 Handler
+	eventHandler := handler.NewEventHandler(server, savedVideoService, progressBus)
+	eventHandler.RegisterRoutes(server.Engine.Group("/api/v1"))
+	logger.Info("✓ Event routes registered")
+
+	// 运维 Handler（暂停和恢复流水线）
+	adminHandler := handler.NewAdminHandler(server, pauseService, jobNotifier, reconciler, chain_task.RegisteredSteps)
+	adminHandler.RegisterRoutes(server.Engine.Group("/api/v1"))
+	logger.Info("✓ Admin routes registered")
+
+	// 播放列表 Handler（导入和同步 YouTube 播放列表）
+	playlistHandler := handler.NewPlaylistHandler(server, playlistService, playlistSyncer)
+	playlistHandler.RegisterRoutes(server.Engine.Group("/api/v1"))
+	logger.Info("✓ Playlist routes registered")
+
+	// 频道订阅 Handler（订阅 YouTube 频道，自动处理新上传的视频）
+	channelHandler := handler.NewChannelHandler(server, channelService, channelWatcher)
+	channelHandler.RegisterRoutes(server.Engine.Group("/api/v1"))
+	logger.Info("✓ Channel routes registered")
+
 	// 配置 Handler
 	configHandler := handler.NewConfigHandler(server)
 	configHandler.RegisterRoutes(server)
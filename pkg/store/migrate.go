@@ -1,6 +1,7 @@
 package store
 
 import (
+	"github.com/difyz9/ytb2bili/internal/core/models"
 	"github.com/difyz9/ytb2bili/pkg/store/model"
 	"gorm.io/gorm"
 )
@@ -11,5 +12,12 @@ func MigrateDatabase(db *gorm.DB) error {
 		&model.User{},
 		&model.SavedVideo{},
 		&model.TaskStep{},
+		&model.SchedulerState{},
+		&model.VideoStatusHistory{},
+		&model.PipelineRun{},
+		&model.StepRun{},
+		&model.PauseState{},
+		&model.Playlist{},
+		&models.TbChannel{},
 	)
 }
@@ -78,8 +78,9 @@ type SavedVideo struct {
 	VideoID          string `gorm:"type:varchar(100);uniqueIndex;not null" json:"video_id"`    // 视频ID（唯一）
 	URL              string `gorm:"type:varchar(500);not null;index" json:"url"`               // 视频URL
 	Title            string `gorm:"type:varchar(500)" json:"title"`                            // 视频标题
-	Status           string `gorm:"type:varchar(20)" json:"status"`                            // 视频状态
+	Status           VideoStatus `gorm:"type:varchar(20)" json:"status"`                       // 视频状态，见 VideoStatus
 	Description      string `gorm:"type:text" json:"description"`                              // 视频描述
+	Duration         int    `gorm:"type:int" json:"duration"`                                  // 视频时长（秒）
 	GeneratedTitle   string `gorm:"type:varchar(500)" json:"generated_title"`                  // AI生成的标题
 	GeneratedDesc    string `gorm:"type:text" json:"generated_desc"`                           // AI生成的描述
 	GeneratedTags    string `gorm:"type:varchar(1000)" json:"generated_tags"`                  // AI生成的标签（逗号分隔）
@@ -88,11 +89,39 @@ type SavedVideo struct {
 	OperationType    string `gorm:"type:varchar(50)" json:"operation_type"`                    // 操作类型 (download/upload等)
 	Subtitles        string `gorm:"type:longtext" json:"subtitles"`                           // 字幕JSON字符串
 	PlaylistID       string `gorm:"type:varchar(100);index" json:"playlist_id"`                // 播放列表ID
+	Source           string `gorm:"type:varchar(150);default:'';index" json:"source"`          // 视频来源（播放列表、频道），同一优先级的视频在来源之间轮转调度
+	Priority         int    `gorm:"type:int;default:0;index" json:"priority"`                  // 调度优先级，数值越大越先处理和上传
+	RunFrom          string `gorm:"type:varchar(100);default:''" json:"run_from"`               // 只执行部分步骤：从该步骤开始（含下游步骤），为空表示从头开始
+	RunUntil         string `gorm:"type:varchar(100);default:''" json:"run_until"`              // 只执行部分步骤：执行到该步骤后停止（含上游步骤），为空表示执行到准备阶段结束
+	Pipeline         string `gorm:"type:varchar(100);default:''" json:"pipeline"`               // 使用的流水线，为空时使用配置的 profile
+	BiliTid          int    `gorm:"type:int;default:0" json:"bili_tid"`                          // 投稿分区ID，0 表示使用配置的 tid
+	TitleTemplate    string `gorm:"type:varchar(500);default:''" json:"title_template"`         // 投稿标题模板，为空时使用配置的 custom_title_template
 	Timestamp        string `gorm:"type:varchar(50)" json:"timestamp"`                         // 时间戳
 	SavedAt          string `gorm:"type:varchar(50)" json:"saved_at"`                          // 保存时间
+	LeaseOwner       string     `gorm:"type:varchar(255);index" json:"lease_owner"`         // 正在处理该视频的实例
+	LeaseExpiresAt   *time.Time `gorm:"type:datetime" json:"lease_expires_at"`              // 租约过期时间，过期后由其他实例回收
 }
 
 // TableName 指定表名
 func (SavedVideo) TableName() string {
 	return "cw_saved_videos"
 }
+
+// 视频来源前缀，SavedVideo.Source 由前缀和播放列表ID或频道ID组成
+const (
+	VideoSourcePlaylist = "playlist:"
+	VideoSourceChannel  = "channel:"
+)
+
+// PlaylistSource 返回播放列表对应的视频来源，playlistID 为空时返回空字符串（单独提交的视频）
+func PlaylistSource(playlistID string) string {
+	if playlistID == "" {
+		return ""
+	}
+	return VideoSourcePlaylist + playlistID
+}
+
+// ChannelSource 返回订阅频道对应的视频来源
+func ChannelSource(channelID string) string {
+	return VideoSourceChannel + channelID
+}
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// 暂停范围
+const (
+	PauseScopePrepare    = "prepare" // 准备阶段：不再分发新视频和待重试的步骤，执行中的视频在下一个步骤开始前等待恢复
+	PauseScopeUpload     = "upload"  // 上传阶段：上传调度器不再上传视频和字幕
+	PauseScopeStepPrefix = "step:"   // 任务类型，例如 step:translate_subtitle，该类型的步骤在开始前等待恢复
+)
+
+// StepPauseScope 返回任务类型对应的暂停范围
+func StepPauseScope(task string) string {
+	return PauseScopeStepPrefix + task
+}
+
+// PauseState 流水线的暂停状态（维护模式）
+// 记录存在即表示该范围已暂停，恢复时删除记录；保存在数据库中，重启后仍然有效，所有实例共享
+type PauseState struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Scope     string    `gorm:"type:varchar(100);not null;uniqueIndex" json:"scope"` // 暂停范围，见 PauseScope* 常量
+	Reason    string    `gorm:"type:varchar(500)" json:"reason"`                     // 暂停原因
+	PausedBy  string    `gorm:"type:varchar(255)" json:"paused_by"`                  // 执行暂停的实例
+	CreatedAt time.Time `json:"paused_at"`                                           // 暂停时间
+}
+
+// TableName 指定表名
+func (PauseState) TableName() string {
+	return "cw_pause_states"
+}
@@ -0,0 +1,73 @@
+package model
+
+import "time"
+
+// PipelineRun 一次任务执行：完整的准备阶段任务链、单步重试或上传
+// 与 TaskStep 不同，每次执行都会新增一条记录，重试后仍可查看之前的错误、耗时和结果
+type PipelineRun struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	VideoID   string     `gorm:"type:varchar(100);not null;index" json:"video_id"` // 关联的视频ID
+	Kind      string     `gorm:"type:varchar(20);not null" json:"kind"`            // 执行类型: chain, range, step, upload
+	Trigger   string     `gorm:"type:varchar(50)" json:"trigger"`                  // 触发者，见 VideoStatusActor* 常量
+	Pipeline  string     `gorm:"type:varchar(100)" json:"pipeline"`                // 使用的流水线
+	StepName  string     `gorm:"type:varchar(100)" json:"step_name,omitempty"`     // 单步重试和上传时执行的步骤，部分步骤执行时为步骤范围
+	Status    string     `gorm:"type:varchar(20);not null;index" json:"status"`    // 执行状态: running, completed, failed, cancelled, timeout, interrupted
+	Instance  string     `gorm:"type:varchar(255)" json:"instance"`                // 执行的实例
+	ErrorMsg  string     `gorm:"type:text" json:"error_msg,omitempty"`             // 错误信息
+	StartedAt time.Time  `json:"started_at"`                                       // 开始时间
+	EndedAt   *time.Time `json:"ended_at"`                                         // 结束时间
+	Duration  int64      `gorm:"type:bigint" json:"duration"`                      // 执行时长（毫秒）
+	Steps     []StepRun  `gorm:"foreignKey:RunID" json:"steps"`                    // 本次执行的步骤
+}
+
+// TableName 指定表名
+func (PipelineRun) TableName() string {
+	return "cw_pipeline_runs"
+}
+
+// GetID 获取执行记录的 ID，run 为 nil（记录失败）时返回 0
+func (r *PipelineRun) GetID() uint {
+	if r == nil {
+		return 0
+	}
+	return r.ID
+}
+
+// 执行类型
+const (
+	PipelineRunKindChain  = "chain"  // 准备阶段任务链
+	PipelineRunKindRange  = "range"  // 准备阶段的部分步骤（从指定步骤开始或执行到指定步骤）
+	PipelineRunKindStep   = "step"   // 单步重试
+	PipelineRunKindUpload = "upload" // 上传阶段
+)
+
+// 执行状态，结束状态与 TaskStepStatus* 一致
+const (
+	RunStatusRunning     = "running"     // 执行中
+	RunStatusInterrupted = "interrupted" // 执行的实例崩溃或失联，任务已被回收
+)
+
+// StepRun 步骤的一次执行
+type StepRun struct {
+	ID             uint       `gorm:"primarykey" json:"id"`
+	RunID          uint       `gorm:"index" json:"run_id"` // 所属的 PipelineRun
+	VideoID        string     `gorm:"type:varchar(100);not null;index:idx_step_run_video_step" json:"video_id"`
+	StepName       string     `gorm:"type:varchar(100);not null;index:idx_step_run_video_step" json:"step_name"`
+	Attempt        int        `gorm:"type:int" json:"attempt"`                           // 步骤的第几次执行
+	Status         string     `gorm:"type:varchar(20);not null" json:"status"`           // 执行状态，同 PipelineRun.Status
+	Instance       string     `gorm:"type:varchar(255)" json:"instance"`                 // 执行的实例
+	ErrorMsg       string     `gorm:"type:text" json:"error_msg,omitempty"`              // 错误信息
+	ErrorCode      string     `gorm:"type:varchar(50)" json:"error_code,omitempty"`      // 错误码
+	ErrorCategory  string     `gorm:"type:varchar(30)" json:"error_category,omitempty"`  // 错误类别
+	ErrorRetryable bool       `gorm:"type:boolean;default:false" json:"error_retryable"` // 错误是否可以通过重试恢复
+	ErrorDetail    string     `gorm:"type:text" json:"error_detail,omitempty"`           // 原始错误信息
+	ResultData     string     `gorm:"type:longtext" json:"result_data,omitempty"`        // 执行结果数据（JSON）
+	StartedAt      time.Time  `json:"started_at"`                                        // 开始时间
+	EndedAt        *time.Time `json:"ended_at"`                                          // 结束时间
+	Duration       int64      `gorm:"type:bigint" json:"duration"`                       // 执行时长（毫秒）
+}
+
+// TableName 指定表名
+func (StepRun) TableName() string {
+	return "cw_step_runs"
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// Playlist 导入的 YouTube 播放列表
+// 导入时展开为单独的视频（SavedVideo.PlaylistID 为播放列表ID），开启自动同步后定期导入播放列表中新增的视频
+type Playlist struct {
+	BaseModel
+	PlaylistID    string     `gorm:"type:varchar(100);uniqueIndex;not null" json:"playlist_id"` // YouTube 播放列表ID
+	URL           string     `gorm:"type:varchar(500);not null" json:"url"`                     // 播放列表 URL
+	Title         string     `gorm:"type:varchar(500)" json:"title"`                            // 播放列表标题
+	Uploader      string     `gorm:"type:varchar(255)" json:"uploader"`                         // 播放列表创建者
+	Priority      int        `gorm:"type:int;default:0" json:"priority"`                        // 导入的视频使用的调度优先级
+	AutoSync      bool       `gorm:"type:boolean;default:false;index" json:"auto_sync"`         // 是否定期同步新增的视频
+	EntryCount    int        `gorm:"type:int;default:0" json:"entry_count"`                     // 最近一次同步时播放列表中的视频数量
+	LastSyncedAt  *time.Time `gorm:"type:datetime" json:"last_synced_at"`                       // 最近一次同步的时间
+	LastSyncError string     `gorm:"type:text" json:"last_sync_error"`                          // 最近一次同步的错误，成功时为空
+}
+
+// TableName 指定表名
+func (Playlist) TableName() string {
+	return "cw_playlists"
+}
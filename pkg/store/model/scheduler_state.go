@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// SchedulerState 定时调度器的持久化状态
+// 多个实例共享数据库时，单例调度器（如上传调度器）通过 Leader 字段选举唯一的执行实例；
+// LastRunAt 记录上一次执行时间，重启后沿用，不会在每次部署后立即执行
+type SchedulerState struct {
+	BaseModel
+	Name            string     `gorm:"type:varchar(100);not null;uniqueIndex" json:"name"` // 调度器或调度任务名称
+	LeaderOwner     string     `gorm:"type:varchar(255)" json:"leader_owner"`              // 当前执行调度的实例
+	LeaderExpiresAt *time.Time `gorm:"type:datetime" json:"leader_expires_at"`             // 选举租约过期时间，过期后其他实例可以接管
+	LastRunAt       *time.Time `gorm:"type:datetime" json:"last_run_at"`                   // 上一次执行时间
+}
+
+// TableName 指定表名
+func (SchedulerState) TableName() string {
+	return "cw_scheduler_states"
+}
@@ -1,6 +1,8 @@
 package model
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
 )
 
@@ -9,14 +11,37 @@ type TaskStep struct {
 	BaseModel
 	VideoID     string    `gorm:"type:varchar(100);not null;index" json:"video_id"`       // 关联的视频ID
 	StepName    string    `gorm:"type:varchar(100);not null" json:"step_name"`            // 步骤名称
-	StepOrder   int       `gorm:"type:int;not null" json:"step_order"`                    // 步骤顺序
-	Status      string    `gorm:"type:varchar(20);not null" json:"status"`                // 步骤状态: pending, running, completed, failed, skipped
+	StepOrder   int       `gorm:"type:int;not null" json:"step_order"`                    // 步骤顺序（拓扑顺序）
+	DependsOn   string    `gorm:"type:varchar(255)" json:"depends_on"`                    // 前置步骤名称，多个以逗号分隔
+	Status      string    `gorm:"type:varchar(20);not null" json:"status"`                // 步骤状态: pending, running, completed, failed, skipped, blocked, cancelled, timeout
 	StartTime   *time.Time `gorm:"type:datetime" json:"start_time"`                       // 开始时间
 	EndTime     *time.Time `gorm:"type:datetime" json:"end_time"`                         // 结束时间
 	Duration    int64     `gorm:"type:bigint" json:"duration"`                            // 执行时长（毫秒）
 	ErrorMsg    string    `gorm:"type:text" json:"error_msg"`                             // 错误信息
+	ErrorCode   string    `gorm:"type:varchar(50)" json:"error_code"`                     // 错误码，见 types.ErrorCode
+	ErrorCategory string  `gorm:"type:varchar(30)" json:"error_category"`                 // 错误类别: network, rate_limit, content, auth, config 等
+	ErrorRetryable bool   `gorm:"type:boolean;default:false" json:"error_retryable"`      // 错误是否可以通过重试恢复
+	ErrorDetail string    `gorm:"type:text" json:"error_detail"`                          // 原始错误信息，用于排查
 	ResultData  string    `gorm:"type:longtext" json:"result_data"`                       // 步骤执行结果数据（JSON）
+	SkipReason  string    `gorm:"type:varchar(500);default:''" json:"skip_reason"`        // 跳过原因，步骤为 skipped 时有效
+	SkippedBy   string    `gorm:"type:varchar(20);default:''" json:"skipped_by"`          // 跳过来源: condition（skip_if 条件）、task（任务自行跳过）、operator（手动跳过）
 	CanRetry    bool      `gorm:"type:boolean;default:true" json:"can_retry"`             // 是否可以重试
+	Attempts    int       `gorm:"type:int;default:0" json:"attempts"`                     // 已执行次数（含自动重试和手动重试）
+	NextRetryAt *time.Time `gorm:"type:datetime;index" json:"next_retry_at"`              // 排队等待重试的时间，到期后由调度器执行
+	AttemptHistory string `gorm:"type:text" json:"-"`                                     // 每次执行的记录（JSON 数组），通过 AttemptRecords 读取
+	LeaseOwner  string    `gorm:"type:varchar(255);index" json:"lease_owner"`             // 正在执行该步骤的实例
+	LeaseExpiresAt *time.Time `gorm:"type:datetime" json:"lease_expires_at"`              // 租约过期时间，过期后由其他实例回收
+}
+
+// TaskStepAttempt 步骤的一次执行记录
+type TaskStepAttempt struct {
+	Attempt   int        `json:"attempt"`              // 第几次执行
+	Status    string     `json:"status"`               // 执行结果: completed, failed, timeout, cancelled, skipped
+	ErrorMsg  string     `json:"error_msg,omitempty"`  // 错误信息
+	ErrorCode string     `json:"error_code,omitempty"` // 错误码
+	StartTime *time.Time `json:"start_time"`           // 开始时间
+	EndTime   time.Time  `json:"end_time"`             // 结束时间
+	Duration  int64      `json:"duration"`             // 执行时长（毫秒）
 }
 
 // TableName 指定表名
@@ -24,6 +49,23 @@ func (TaskStep) TableName() string {
 	return "cw_task_steps"
 }
 
+// Dependencies 获取前置步骤名称列表
+func (t *TaskStep) Dependencies() []string {
+	if t.DependsOn == "" {
+		return nil
+	}
+	return strings.Split(t.DependsOn, ",")
+}
+
+// AttemptRecords 获取步骤的执行记录，按执行顺序排列
+func (t *TaskStep) AttemptRecords() []TaskStepAttempt {
+	var records []TaskStepAttempt
+	if t.AttemptHistory != "" {
+		_ = json.Unmarshal([]byte(t.AttemptHistory), &records)
+	}
+	return records
+}
+
 // TaskStepStatus 任务步骤状态常量
 const (
 	TaskStepStatusPending   = "pending"   // 待执行
@@ -31,4 +73,15 @@ const (
 	TaskStepStatusCompleted = "completed" // 已完成
 	TaskStepStatusFailed    = "failed"    // 失败
 	TaskStepStatusSkipped   = "skipped"   // 跳过
+	TaskStepStatusBlocked   = "blocked"   // 前置步骤失败，未执行
+	TaskStepStatusCancelled = "cancelled" // 已取消
+	TaskStepStatusTimeout   = "timeout"   // 执行超时
+)
+
+// 步骤被跳过的来源，记录在 TaskStep.SkippedBy 中
+// 手动跳过的步骤在重新执行任务链时保持跳过，条件和任务自行跳过的步骤每次执行时重新判断
+const (
+	TaskStepSkippedByCondition = "condition" // 满足流水线配置的 skip_if 条件
+	TaskStepSkippedByTask      = "task"      // 任务执行时判断无需处理（例如没有字幕可翻译）
+	TaskStepSkippedByOperator  = "operator"  // 通过 API 手动跳过
 )
\ No newline at end of file
@@ -0,0 +1,114 @@
+package model
+
+import "time"
+
+// VideoStatus 视频（SavedVideo）的处理状态
+type VideoStatus string
+
+// 视频状态常量
+const (
+	VideoStatusPending           VideoStatus = "001" // 待处理
+	VideoStatusProcessing        VideoStatus = "002" // 处理中（准备阶段）
+	VideoStatusStopped           VideoStatus = "003" // 已在指定步骤后停止（准备阶段未全部完成），等待继续执行
+	VideoStatusReady             VideoStatus = "200" // 准备就绪，等待上传
+	VideoStatusUploading         VideoStatus = "201" // 上传视频中
+	VideoStatusUploadFailed      VideoStatus = "299" // 视频上传失败
+	VideoStatusUploaded          VideoStatus = "300" // 视频已上传，待上传字幕
+	VideoStatusSubtitleUploading VideoStatus = "301" // 上传字幕中
+	VideoStatusSubtitleFailed    VideoStatus = "399" // 字幕上传失败
+	VideoStatusCompleted         VideoStatus = "400" // 全部完成
+	VideoStatusCancelled         VideoStatus = "998" // 已取消
+	VideoStatusFailed            VideoStatus = "999" // 处理失败
+)
+
+var videoStatusLabels = map[VideoStatus]string{
+	VideoStatusPending:           "待处理",
+	VideoStatusProcessing:        "处理中",
+	VideoStatusStopped:           "已停止",
+	VideoStatusReady:             "准备就绪",
+	VideoStatusUploading:         "上传视频中",
+	VideoStatusUploadFailed:      "视频上传失败",
+	VideoStatusUploaded:          "视频已上传",
+	VideoStatusSubtitleUploading: "上传字幕中",
+	VideoStatusSubtitleFailed:    "字幕上传失败",
+	VideoStatusCompleted:         "全部完成",
+	VideoStatusCancelled:         "已取消",
+	VideoStatusFailed:            "处理失败",
+}
+
+// videoStatusTransitions 允许的状态转换
+//
+//	001 ──> 002 ──> 200 ──> 201 ──> 300 ──> 301 ──> 400
+//	         │               │               │
+//	         ├─> 999 ─┘      └─> 299 ─┘      └─> 399 ─┘（重试成功后继续推进）
+//	         └─> 003（执行到指定步骤后停止，继续执行时回到 001）
+//
+// 待处理、处理中、已停止、准备就绪、上传中的视频可以取消（998）；执行中的视频被回收时退回待处理或上传失败；
+// 不在执行中的视频重新提交后回到待处理（001）；手动上传未能开始时上传中的视频恢复为上传前的状态
+var videoStatusTransitions = map[VideoStatus][]VideoStatus{
+	VideoStatusPending:           {VideoStatusProcessing, VideoStatusCancelled},
+	VideoStatusProcessing:        {VideoStatusReady, VideoStatusFailed, VideoStatusCancelled, VideoStatusPending, VideoStatusStopped},
+	VideoStatusStopped:           {VideoStatusPending, VideoStatusCancelled},
+	VideoStatusReady:             {VideoStatusUploading, VideoStatusCancelled, VideoStatusPending},
+	VideoStatusUploading:         {VideoStatusUploaded, VideoStatusUploadFailed, VideoStatusCancelled, VideoStatusReady},
+	VideoStatusUploadFailed:      {VideoStatusUploading, VideoStatusUploaded, VideoStatusPending},
+	VideoStatusUploaded:          {VideoStatusSubtitleUploading, VideoStatusCancelled, VideoStatusPending},
+	VideoStatusSubtitleUploading: {VideoStatusCompleted, VideoStatusSubtitleFailed, VideoStatusCancelled, VideoStatusUploaded},
+	VideoStatusSubtitleFailed:    {VideoStatusSubtitleUploading, VideoStatusCompleted, VideoStatusPending},
+	VideoStatusCompleted:         {VideoStatusPending},
+	VideoStatusCancelled:         {VideoStatusPending},
+	VideoStatusFailed:            {VideoStatusReady, VideoStatusPending},
+}
+
+// Label 状态的中文名称
+func (s VideoStatus) Label() string {
+	if label, ok := videoStatusLabels[s]; ok {
+		return label
+	}
+	return string(s)
+}
+
+// Valid 判断是否为已定义的状态
+func (s VideoStatus) Valid() bool {
+	_, ok := videoStatusTransitions[s]
+	return ok
+}
+
+// CanTransitionTo 判断是否允许从当前状态转换为 to
+func (s VideoStatus) CanTransitionTo(to VideoStatus) bool {
+	for _, next := range videoStatusTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// 状态变更的触发者
+const (
+	VideoStatusActorSubmit          = "submit"           // 用户提交或重新提交视频
+	VideoStatusActorPipeline        = "pipeline"         // 准备阶段任务链
+	VideoStatusActorUploadScheduler = "upload_scheduler" // 定时上传
+	VideoStatusActorRetry           = "retry"            // 单步重试
+	VideoStatusActorAPI             = "api"              // 管理接口（取消、手动上传等）
+	VideoStatusActorReclaim         = "lease_reclaim"    // 回收失联实例遗留的任务
+	VideoStatusActorReconcile       = "reconcile"        // 一致性检查修复状态与步骤、文件不一致的视频
+)
+
+// VideoStatusHistory 视频状态变更记录
+type VideoStatusHistory struct {
+	ID           uint        `gorm:"primarykey" json:"id"`
+	SavedVideoID uint        `gorm:"not null;index" json:"saved_video_id"`             // 关联的 SavedVideo ID
+	VideoID      string      `gorm:"type:varchar(100);not null;index" json:"video_id"` // 关联的视频ID
+	FromStatus   VideoStatus `gorm:"type:varchar(20)" json:"from_status"`              // 变更前的状态，新建视频时为空
+	ToStatus     VideoStatus `gorm:"type:varchar(20);not null" json:"to_status"`       // 变更后的状态
+	Actor        string      `gorm:"type:varchar(50)" json:"actor"`                    // 触发者，见 VideoStatusActor* 常量
+	Instance     string      `gorm:"type:varchar(255)" json:"instance"`                // 执行变更的实例
+	Reason       string      `gorm:"type:text" json:"reason"`                          // 变更原因
+	CreatedAt    time.Time   `gorm:"index" json:"created_at"`
+}
+
+// TableName 指定表名
+func (VideoStatusHistory) TableName() string {
+	return "cw_video_status_history"
+}
@@ -2,7 +2,9 @@ package utils
 
 import (
 	"bufio"
+	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
@@ -42,8 +44,13 @@ func TranscodeVideo(inputVideoPath, outputVideoPath, preset string, crf int, aud
 
 // ExtractWaveAudio 从视频文件中分离出WAV格式的音频
 func ExtractWaveAudio(inputFile, outputFile string) error {
+	return ExtractWaveAudioContext(context.Background(), inputFile, outputFile, os.Stderr)
+}
+
+// ExtractWaveAudioContext 与 ExtractWaveAudio 相同，ctx 被取消时终止 ffmpeg 进程，ffmpeg 的输出写入 output
+func ExtractWaveAudioContext(ctx context.Context, inputFile, outputFile string, output io.Writer) error {
 	// 构造 ffmpeg 命令，提取音频并转换为WAV格式
-	cmd := exec.Command(
+	cmd := exec.CommandContext(ctx,
 		"ffmpeg",
 		"-y",                    // 覆盖输出文件
 		"-i", inputFile,         // 输入文件
@@ -55,16 +62,19 @@ func ExtractWaveAudio(inputFile, outputFile string) error {
 	)
 
 	// 设置标准输出和标准错误
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd.Stdout = output
+	cmd.Stderr = output
 
 	// 执行命令
 	err := cmd.Run()
 	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("ffmpeg 提取WAV音频已取消: %v", ctx.Err())
+		}
 		return fmt.Errorf("ffmpeg 提取WAV音频失败: %v", err)
 	}
 
-	fmt.Printf("成功从 %s 提取WAV音频到 %s\n", inputFile, outputFile)
+	fmt.Fprintf(output, "成功从 %s 提取WAV音频到 %s\n", inputFile, outputFile)
 	return nil
 }
 
@@ -179,6 +179,35 @@ func HasChinese(text string) bool {
 	return false
 }
 
+// ChineseRatio 中文字符在文字（不含数字、标点和空白）中的占比，没有文字时返回 0
+func ChineseRatio(text string) float64 {
+	var han, letters int
+	for _, char := range text {
+		if !unicode.IsLetter(char) {
+			continue
+		}
+		letters++
+		if unicode.Is(unicode.Scripts["Han"], char) {
+			han++
+		}
+	}
+	if letters == 0 {
+		return 0
+	}
+	return float64(han) / float64(letters)
+}
+
+// IsChineseLanguage 判断语言代码是否为中文（zh、zh-Hans、zh-CN、cmn、yue 等）
+func IsChineseLanguage(lang string) bool {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	for _, prefix := range []string{"zh", "cmn", "yue"} {
+		if lang == prefix || strings.HasPrefix(lang, prefix+"-") || strings.HasPrefix(lang, prefix+"_") {
+			return true
+		}
+	}
+	return false
+}
+
 func StringToUint(s string) uint {
 	// 先转换为 uint64
 	val, err := strconv.ParseUint(s, 10, 64)
@@ -95,3 +95,50 @@ func ExtractVideoID(videoURL string) string {
 	}
 	return RandString(12)
 }
+
+// ExtractPlaylistID 从 YouTube 播放列表页面 URL（youtube.com/playlist?list=...）中提取播放列表ID
+// 带有 list 参数的单个视频 URL（watch?v=...&list=...）不视为播放列表，返回空字符串
+func ExtractPlaylistID(playlistURL string) string {
+	parsedURL, err := url.Parse(playlistURL)
+	if err != nil {
+		return ""
+	}
+	if !strings.Contains(parsedURL.Host, "youtube.com") || strings.TrimSuffix(parsedURL.Path, "/") != "/playlist" {
+		return ""
+	}
+	return parsedURL.Query().Get("list")
+}
+
+// youtubeChannelIDPattern YouTube 频道ID（UC 开头，共 24 个字符）
+var youtubeChannelIDPattern = regexp.MustCompile(`^UC[0-9A-Za-z_-]{22}$`)
+
+// ChannelVideosURL 返回 YouTube 频道上传列表（/videos 标签页）的 URL，不是频道时返回空字符串
+// 支持的格式:
+// https://www.youtube.com/@handle、https://www.youtube.com/channel/UCxxxx、https://www.youtube.com/c/name、https://www.youtube.com/user/name
+// @handle
+// UCxxxx（频道ID）
+func ChannelVideosURL(channelURL string) string {
+	channelURL = strings.TrimSpace(channelURL)
+	if strings.HasPrefix(channelURL, "@") {
+		return "https://www.youtube.com/" + channelURL + "/videos"
+	}
+	if youtubeChannelIDPattern.MatchString(channelURL) {
+		return "https://www.youtube.com/channel/" + channelURL + "/videos"
+	}
+
+	parsedURL, err := url.Parse(channelURL)
+	if err != nil || !strings.Contains(parsedURL.Host, "youtube.com") {
+		return ""
+	}
+	segments := strings.Split(strings.Trim(parsedURL.Path, "/"), "/")
+	var base string
+	switch {
+	case strings.HasPrefix(segments[0], "@"):
+		base = segments[0]
+	case len(segments) >= 2 && (segments[0] == "channel" || segments[0] == "c" || segments[0] == "user"):
+		base = segments[0] + "/" + segments[1]
+	default:
+		return ""
+	}
+	return "https://www.youtube.com/" + base + "/videos"
+}
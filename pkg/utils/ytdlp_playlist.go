@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// PlaylistEntry 播放列表中的一个视频
+type PlaylistEntry struct {
+	ID         string  `json:"id"`
+	Title      string  `json:"title"`
+	URL        string  `json:"url"`
+	Duration   float64 `json:"duration"`
+	LiveStatus string  `json:"live_status"` // 直播状态: is_upcoming（预告）、is_live（直播中）、was_live、not_live 等，可能为空
+}
+
+// FlatPlaylist 不解析视频详情的播放列表（yt-dlp --flat-playlist），Entries 按播放列表顺序排列
+type FlatPlaylist struct {
+	ID        string          `json:"id"`
+	Title     string          `json:"title"`
+	Uploader  string          `json:"uploader"`
+	ChannelID string          `json:"channel_id"` // 播放列表或上传列表所属频道的ID
+	Channel   string          `json:"channel"`    // 频道名称
+	Entries   []PlaylistEntry `json:"entries"`
+}
+
+// FlatPlaylistOptions 获取播放列表的参数
+type FlatPlaylistOptions struct {
+	Cookies string // cookies.txt 路径，为空时不使用
+	Proxy   string // 代理地址，为空时不使用
+	Limit   int    // 只获取前 Limit 个视频，<=0 表示全部
+}
+
+// FlatPlaylist 使用 yt-dlp 获取播放列表（或频道上传列表）中的视频，不下载视频，也不逐个解析视频详情
+// 已删除或不可用的视频（没有视频ID）会被忽略
+func (m *YtDlpManager) FlatPlaylist(ctx context.Context, playlistURL string, options FlatPlaylistOptions) (*FlatPlaylist, error) {
+	if !m.IsInstalled() {
+		return nil, fmt.Errorf("yt-dlp 未安装")
+	}
+
+	args := []string{"--flat-playlist", "--dump-single-json", "--no-warnings"}
+	if options.Limit > 0 {
+		args = append(args, "--playlist-end", strconv.Itoa(options.Limit))
+	}
+	if options.Cookies != "" {
+		args = append(args, "--cookies", options.Cookies)
+	}
+	if options.Proxy != "" {
+		args = append(args, "--proxy", options.Proxy)
+	}
+	args = append(args, playlistURL)
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, m.binaryPath, args...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("获取播放列表失败: %v: %s", err, msg)
+		}
+		return nil, fmt.Errorf("获取播放列表失败: %v", err)
+	}
+
+	var playlist FlatPlaylist
+	if err := json.Unmarshal(output, &playlist); err != nil {
+		return nil, fmt.Errorf("解析播放列表失败: %v", err)
+	}
+
+	entries := playlist.Entries[:0]
+	for _, entry := range playlist.Entries {
+		if entry.ID == "" {
+			continue
+		}
+		if entry.URL == "" || !strings.HasPrefix(entry.URL, "http") {
+			entry.URL = "https://www.youtube.com/watch?v=" + entry.ID
+		}
+		entries = append(entries, entry)
+	}
+	playlist.Entries = entries
+	return &playlist, nil
+}
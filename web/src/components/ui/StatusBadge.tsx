@@ -57,6 +57,12 @@ const STATUS_CONFIG = {
     icon: CheckCircle,
     description: '所有任务已完成'
   },
+  '998': {
+    label: '已取消',
+    className: 'status-cancelled',
+    icon: XCircle,
+    description: '任务已被用户取消'
+  },
   '999': {
     label: '任务失败',
     className: 'status-failed',
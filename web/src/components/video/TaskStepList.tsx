@@ -69,7 +69,8 @@ export default function TaskStepList({ steps, onRetryStep, isRetrying = false }:
   };
 
   const canRetryStep = (step: TaskStep) => {
-    return step.can_retry && (step.status === 'failed' || step.status === 'skipped');
+    // 与后端一致：只能重试失败、超时、已取消或被阻塞的步骤
+    return step.can_retry && ['failed', 'timeout', 'cancelled', 'blocked'].includes(step.status);
   };
 
   // 按步骤顺序排序
@@ -128,12 +129,24 @@ export default function TaskStepList({ steps, onRetryStep, isRetrying = false }:
                         <span>结束: {formatTime(step.end_time)}</span>
                       )}
                       <span>耗时: {formatDuration(step.duration)}</span>
+                      {step.attempts !== undefined && step.attempts > 1 && (
+                        <span>第 {step.attempts} 次执行</span>
+                      )}
+                      {step.next_retry_at && (
+                        <span>将于 {formatTime(step.next_retry_at)} 自动重试</span>
+                      )}
                     </div>
 
                     {/* 错误信息 */}
                     {step.error_msg && (
                       <div className="mt-2 p-2 bg-red-50 border border-red-200 rounded text-xs text-red-700">
                         {step.error_msg}
+                        {step.error && (
+                          <div className="mt-1 text-red-500">
+                            [{step.error.category}/{step.error.code}]
+                            {!step.error.retryable && ' 此错误无法通过自动重试恢复'}
+                          </div>
+                        )}
                       </div>
                     )}
 
@@ -28,12 +28,35 @@ export interface TaskStep {
   end_time?: string;
   duration?: number; // 持续时间，毫秒
   error_msg?: string;
+  error?: TaskError; // 结构化错误
   result_data?: any;
   can_retry: boolean;
+  attempts?: number; // 已执行次数
+  max_attempts?: number; // 自动重试的最多执行次数
+  next_retry_at?: string; // 等待自动重试的时间
+  attempt_history?: TaskStepAttempt[];
   created_at: string;
   updated_at: string;
 }
 
+export interface TaskError {
+  code: string;
+  category: string;
+  retryable: boolean;
+  message: string;
+  detail?: string;
+}
+
+export interface TaskStepAttempt {
+  attempt: number;
+  status: TaskStepStatus;
+  error_msg?: string;
+  error_code?: string;
+  start_time?: string;
+  end_time: string;
+  duration: number; // 毫秒
+}
+
 export interface TaskProgress {
   total_steps: number;
   completed_steps: number;
@@ -136,6 +159,8 @@ export const TASK_STEP_STATUS_MAP = {
   'completed': { label: '已完成', className: 'bg-green-100 text-green-800', color: 'green' },
   'failed': { label: '失败', className: 'bg-red-100 text-red-800', color: 'red' },
   'skipped': { label: '已跳过', className: 'bg-yellow-100 text-yellow-800', color: 'yellow' },
+  'cancelled': { label: '已取消', className: 'bg-gray-100 text-gray-600', color: 'gray' },
+  'timeout': { label: '超时', className: 'bg-orange-100 text-orange-800', color: 'orange' },
 } as const;
 
 export const TASK_STEP_NAMES = {